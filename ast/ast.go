@@ -140,6 +140,7 @@ const (
 	SortType
 	SortComponent
 	SortInstance
+	SortValue
 )
 
 func (s Sort) String() string {
@@ -166,6 +167,8 @@ func (s Sort) String() string {
 		return "component"
 	case SortInstance:
 		return "instance"
+	case SortValue:
+		return "value"
 	default:
 		return fmt.Sprintf("unknown - %v", int(s))
 	}
@@ -254,33 +257,36 @@ type F32Type struct{}
 type F64Type struct{}
 type CharType struct{}
 type StringType struct{}
-
-func (*BoolType) isValType()   {}
-func (*BoolType) isDefType()   {}
-func (*S8Type) isValType()     {}
-func (*S8Type) isDefType()     {}
-func (*U8Type) isValType()     {}
-func (*U8Type) isDefType()     {}
-func (*S16Type) isValType()    {}
-func (*S16Type) isDefType()    {}
-func (*U16Type) isValType()    {}
-func (*U16Type) isDefType()    {}
-func (*S32Type) isValType()    {}
-func (*S32Type) isDefType()    {}
-func (*U32Type) isValType()    {}
-func (*U32Type) isDefType()    {}
-func (*S64Type) isValType()    {}
-func (*S64Type) isDefType()    {}
-func (*U64Type) isValType()    {}
-func (*U64Type) isDefType()    {}
-func (*F32Type) isValType()    {}
-func (*F32Type) isDefType()    {}
-func (*F64Type) isValType()    {}
-func (*F64Type) isDefType()    {}
-func (*CharType) isValType()   {}
-func (*CharType) isDefType()   {}
-func (*StringType) isValType() {}
-func (*StringType) isDefType() {}
+type ErrorContextType struct{}
+
+func (*BoolType) isValType()         {}
+func (*BoolType) isDefType()         {}
+func (*S8Type) isValType()           {}
+func (*S8Type) isDefType()           {}
+func (*U8Type) isValType()           {}
+func (*U8Type) isDefType()           {}
+func (*S16Type) isValType()          {}
+func (*S16Type) isDefType()          {}
+func (*U16Type) isValType()          {}
+func (*U16Type) isDefType()          {}
+func (*S32Type) isValType()          {}
+func (*S32Type) isDefType()          {}
+func (*U32Type) isValType()          {}
+func (*U32Type) isDefType()          {}
+func (*S64Type) isValType()          {}
+func (*S64Type) isDefType()          {}
+func (*U64Type) isValType()          {}
+func (*U64Type) isDefType()          {}
+func (*F32Type) isValType()          {}
+func (*F32Type) isDefType()          {}
+func (*F64Type) isValType()          {}
+func (*F64Type) isDefType()          {}
+func (*CharType) isValType()         {}
+func (*CharType) isDefType()         {}
+func (*StringType) isValType()       {}
+func (*StringType) isDefType()       {}
+func (*ErrorContextType) isValType() {}
+func (*ErrorContextType) isDefType() {}
 
 // RecordType represents a record (struct-like) type
 type RecordType struct {
@@ -318,6 +324,15 @@ type ListType struct {
 func (*ListType) isValType() {}
 func (*ListType) isDefType() {}
 
+// FixedListType represents a list with a fixed number of elements
+type FixedListType struct {
+	Element DefValType
+	Length  uint32
+}
+
+func (*FixedListType) isValType() {}
+func (*FixedListType) isDefType() {}
+
 // TupleType represents a tuple type
 type TupleType struct {
 	Types []DefValType
@@ -375,6 +390,22 @@ type BorrowType struct {
 func (*BorrowType) isValType() {}
 func (*BorrowType) isDefType() {}
 
+// StreamType represents a stream of values passed between components
+type StreamType struct {
+	Element DefValType // nil for a stream without elements
+}
+
+func (*StreamType) isValType() {}
+func (*StreamType) isDefType() {}
+
+// FutureType represents a single value that becomes available later
+type FutureType struct {
+	Value DefValType // nil for a future without a value
+}
+
+func (*FutureType) isValType() {}
+func (*FutureType) isDefType() {}
+
 // ResourceType represents a resource type definition
 type ResourceType struct {
 	Rep  CoreValType // Representation type (currently always i32)
@@ -771,6 +802,32 @@ type SubResourceBound struct{}
 
 func (*SubResourceBound) isTypeBound() {}
 
+// ValueExternDesc describes a value import/export
+type ValueExternDesc struct {
+	Bound ValueBound
+}
+
+func (*ValueExternDesc) isExternDesc() {}
+
+// ValueBound defines the bound for a value import/export
+type ValueBound interface {
+	isValueBound()
+}
+
+// ValueEqBound indicates the value is the same as another value
+type ValueEqBound struct {
+	ValueIdx uint32
+}
+
+func (*ValueEqBound) isValueBound() {}
+
+// ValueTypeBound gives the type of the value
+type ValueTypeBound struct {
+	Type DefValType
+}
+
+func (*ValueTypeBound) isValueBound() {}
+
 // Import represents a component import
 type Import struct {
 	ImportName string
@@ -788,6 +845,16 @@ type Export struct {
 
 func (*Export) isDefinition() {}
 
+// Start calls a component function during instantiation. Its results are
+// appended to the value index space.
+type Start struct {
+	FuncIdx uint32
+	Args    []uint32
+	Results uint32
+}
+
+func (*Start) isDefinition() {}
+
 // Canon represents a canonical definition
 type Canon struct {
 	Def CanonDef
@@ -859,6 +926,24 @@ type PostReturnOpt struct {
 
 func (*PostReturnOpt) isCanonOpt() {}
 
+// AsyncOpt selects the async ABI
+type AsyncOpt struct{}
+
+func (*AsyncOpt) isCanonOpt() {}
+
+// CallbackOpt specifies the callback function of an async lift
+type CallbackOpt struct {
+	FuncIdx uint32
+}
+
+func (*CallbackOpt) isCanonOpt() {}
+
+// AlwaysTaskReturnOpt requires an async lift to return its result with
+// task.return, even if it doesn't use the async ABI otherwise
+type AlwaysTaskReturnOpt struct{}
+
+func (*AlwaysTaskReturnOpt) isCanonOpt() {}
+
 // CanonResourceNew creates a new resource
 type CanonResourceNew struct {
 	TypeIdx uint32
@@ -869,6 +954,7 @@ func (*CanonResourceNew) isCanonDef() {}
 // CanonResourceDrop drops a resource handle
 type CanonResourceDrop struct {
 	TypeIdx uint32
+	Async   bool
 }
 
 func (*CanonResourceDrop) isCanonDef() {}
@@ -880,6 +966,226 @@ type CanonResourceRep struct {
 
 func (*CanonResourceRep) isCanonDef() {}
 
+// CanonTaskReturn returns the result of the current async task
+type CanonTaskReturn struct {
+	Result  DefValType // nil if the task's function has no result
+	Options []CanonOpt
+}
+
+func (*CanonTaskReturn) isCanonDef() {}
+
+// CanonTaskCancel acknowledges the cancellation of the current task
+type CanonTaskCancel struct{}
+
+func (*CanonTaskCancel) isCanonDef() {}
+
+// CanonContextGet reads a slot of the current task's context
+type CanonContextGet struct {
+	Slot uint32
+}
+
+func (*CanonContextGet) isCanonDef() {}
+
+// CanonContextSet writes a slot of the current task's context
+type CanonContextSet struct {
+	Slot uint32
+}
+
+func (*CanonContextSet) isCanonDef() {}
+
+// CanonBackpressureSet turns backpressure on or off for the current instance
+type CanonBackpressureSet struct{}
+
+func (*CanonBackpressureSet) isCanonDef() {}
+
+// CanonBackpressureInc increments the backpressure counter of the current
+// instance
+type CanonBackpressureInc struct{}
+
+func (*CanonBackpressureInc) isCanonDef() {}
+
+// CanonBackpressureDec decrements the backpressure counter of the current
+// instance
+type CanonBackpressureDec struct{}
+
+func (*CanonBackpressureDec) isCanonDef() {}
+
+// CanonYield lets other tasks run before the current one continues
+type CanonYield struct {
+	Cancellable bool
+}
+
+func (*CanonYield) isCanonDef() {}
+
+// CanonSubtaskCancel cancels a subtask
+type CanonSubtaskCancel struct {
+	Async bool
+}
+
+func (*CanonSubtaskCancel) isCanonDef() {}
+
+// CanonSubtaskDrop drops a resolved subtask
+type CanonSubtaskDrop struct{}
+
+func (*CanonSubtaskDrop) isCanonDef() {}
+
+// CanonWaitableSetNew creates a waitable set
+type CanonWaitableSetNew struct{}
+
+func (*CanonWaitableSetNew) isCanonDef() {}
+
+// CanonWaitableSetWait waits for an event on a waitable set
+type CanonWaitableSetWait struct {
+	Cancellable bool
+	MemoryIdx   uint32
+}
+
+func (*CanonWaitableSetWait) isCanonDef() {}
+
+// CanonWaitableSetPoll checks a waitable set for an event without waiting
+type CanonWaitableSetPoll struct {
+	Cancellable bool
+	MemoryIdx   uint32
+}
+
+func (*CanonWaitableSetPoll) isCanonDef() {}
+
+// CanonWaitableSetDrop drops a waitable set
+type CanonWaitableSetDrop struct{}
+
+func (*CanonWaitableSetDrop) isCanonDef() {}
+
+// CanonWaitableJoin adds a waitable to a waitable set, or removes it from its
+// set
+type CanonWaitableJoin struct{}
+
+func (*CanonWaitableJoin) isCanonDef() {}
+
+// CanonStreamNew creates a stream, returning its readable and writable ends
+type CanonStreamNew struct {
+	TypeIdx uint32
+}
+
+func (*CanonStreamNew) isCanonDef() {}
+
+// CanonStreamRead reads from the readable end of a stream
+type CanonStreamRead struct {
+	TypeIdx uint32
+	Options []CanonOpt
+}
+
+func (*CanonStreamRead) isCanonDef() {}
+
+// CanonStreamWrite writes to the writable end of a stream
+type CanonStreamWrite struct {
+	TypeIdx uint32
+	Options []CanonOpt
+}
+
+func (*CanonStreamWrite) isCanonDef() {}
+
+// CanonStreamCancelRead cancels a read in progress on a stream
+type CanonStreamCancelRead struct {
+	TypeIdx uint32
+	Async   bool
+}
+
+func (*CanonStreamCancelRead) isCanonDef() {}
+
+// CanonStreamCancelWrite cancels a write in progress on a stream
+type CanonStreamCancelWrite struct {
+	TypeIdx uint32
+	Async   bool
+}
+
+func (*CanonStreamCancelWrite) isCanonDef() {}
+
+// CanonStreamDropReadable drops the readable end of a stream
+type CanonStreamDropReadable struct {
+	TypeIdx uint32
+}
+
+func (*CanonStreamDropReadable) isCanonDef() {}
+
+// CanonStreamDropWritable drops the writable end of a stream
+type CanonStreamDropWritable struct {
+	TypeIdx uint32
+}
+
+func (*CanonStreamDropWritable) isCanonDef() {}
+
+// CanonFutureNew creates a future, returning its readable and writable ends
+type CanonFutureNew struct {
+	TypeIdx uint32
+}
+
+func (*CanonFutureNew) isCanonDef() {}
+
+// CanonFutureRead reads the value of a future
+type CanonFutureRead struct {
+	TypeIdx uint32
+	Options []CanonOpt
+}
+
+func (*CanonFutureRead) isCanonDef() {}
+
+// CanonFutureWrite writes the value of a future
+type CanonFutureWrite struct {
+	TypeIdx uint32
+	Options []CanonOpt
+}
+
+func (*CanonFutureWrite) isCanonDef() {}
+
+// CanonFutureCancelRead cancels a read in progress on a future
+type CanonFutureCancelRead struct {
+	TypeIdx uint32
+	Async   bool
+}
+
+func (*CanonFutureCancelRead) isCanonDef() {}
+
+// CanonFutureCancelWrite cancels a write in progress on a future
+type CanonFutureCancelWrite struct {
+	TypeIdx uint32
+	Async   bool
+}
+
+func (*CanonFutureCancelWrite) isCanonDef() {}
+
+// CanonFutureDropReadable drops the readable end of a future
+type CanonFutureDropReadable struct {
+	TypeIdx uint32
+}
+
+func (*CanonFutureDropReadable) isCanonDef() {}
+
+// CanonFutureDropWritable drops the writable end of a future
+type CanonFutureDropWritable struct {
+	TypeIdx uint32
+}
+
+func (*CanonFutureDropWritable) isCanonDef() {}
+
+// CanonErrorContextNew creates an error context from a debug message
+type CanonErrorContextNew struct {
+	Options []CanonOpt
+}
+
+func (*CanonErrorContextNew) isCanonDef() {}
+
+// CanonErrorContextDebugMessage returns the debug message of an error context
+type CanonErrorContextDebugMessage struct {
+	Options []CanonOpt
+}
+
+func (*CanonErrorContextDebugMessage) isCanonDef() {}
+
+// CanonErrorContextDrop drops an error context
+type CanonErrorContextDrop struct{}
+
+func (*CanonErrorContextDrop) isCanonDef() {}
+
 // Core WebAssembly Module Sections
 
 // CoreImport represents a core module import
@@ -47,6 +47,8 @@ func defToWAT(def Definition, level int) string {
 		return exportToWAT(d)
 	case *Canon:
 		return canonToWAT(d)
+	case *Start:
+		return startToWAT(d)
 	default:
 		return fmt.Sprintf("(; unknown definition type: %T ;)", def)
 	}
@@ -242,6 +244,8 @@ func sortToString(sort Sort) string {
 		return "component"
 	case SortInstance:
 		return "instance"
+	case SortValue:
+		return "value"
 	default:
 		return fmt.Sprintf("unknown-sort-%d", sort)
 	}
@@ -307,12 +311,16 @@ func defTypeToWAT(dt DefType, level int) string {
 		return "char"
 	case *StringType:
 		return "string"
+	case *ErrorContextType:
+		return "error-context"
 	case *RecordType:
 		return recordTypeToWAT(t)
 	case *VariantType:
 		return variantTypeToWAT(t)
 	case *ListType:
 		return listTypeToWAT(t)
+	case *FixedListType:
+		return fixedListTypeToWAT(t)
 	case *TupleType:
 		return tupleTypeToWAT(t)
 	case *FlagsType:
@@ -327,6 +335,10 @@ func defTypeToWAT(dt DefType, level int) string {
 		return fmt.Sprintf("(own %d)", t.TypeIdx)
 	case *BorrowType:
 		return fmt.Sprintf("(borrow %d)", t.TypeIdx)
+	case *StreamType:
+		return streamTypeToWAT(t)
+	case *FutureType:
+		return futureTypeToWAT(t)
 	case *ResourceType:
 		return resourceTypeToWAT(t)
 	case *FuncType:
@@ -370,12 +382,16 @@ func valTypeToWAT(vt DefValType) string {
 		return "char"
 	case *StringType:
 		return "string"
+	case *ErrorContextType:
+		return "error-context"
 	case *RecordType:
 		return recordTypeToWAT(t)
 	case *VariantType:
 		return variantTypeToWAT(t)
 	case *ListType:
 		return listTypeToWAT(t)
+	case *FixedListType:
+		return fixedListTypeToWAT(t)
 	case *TupleType:
 		return tupleTypeToWAT(t)
 	case *FlagsType:
@@ -390,6 +406,10 @@ func valTypeToWAT(vt DefValType) string {
 		return fmt.Sprintf("(own %d)", t.TypeIdx)
 	case *BorrowType:
 		return fmt.Sprintf("(borrow %d)", t.TypeIdx)
+	case *StreamType:
+		return streamTypeToWAT(t)
+	case *FutureType:
+		return futureTypeToWAT(t)
 	case *TypeIdx:
 		return fmt.Sprintf("%d", t.Idx)
 	default:
@@ -432,6 +452,24 @@ func listTypeToWAT(lt *ListType) string {
 	return fmt.Sprintf("(list %s)", valTypeToWAT(lt.Element))
 }
 
+func fixedListTypeToWAT(lt *FixedListType) string {
+	return fmt.Sprintf("(list %s %d)", valTypeToWAT(lt.Element), lt.Length)
+}
+
+func streamTypeToWAT(st *StreamType) string {
+	if st.Element == nil {
+		return "(stream)"
+	}
+	return fmt.Sprintf("(stream %s)", valTypeToWAT(st.Element))
+}
+
+func futureTypeToWAT(ft *FutureType) string {
+	if ft.Value == nil {
+		return "(future)"
+	}
+	return fmt.Sprintf("(future %s)", valTypeToWAT(ft.Value))
+}
+
 func tupleTypeToWAT(tt *TupleType) string {
 	var b strings.Builder
 	b.WriteString("(tuple")
@@ -619,11 +657,37 @@ func externDescToWAT(desc ExternDesc, level int) string {
 		b.WriteString(typeBoundToWAT(d.Bound))
 		b.WriteString(")")
 		return b.String()
+	case *ValueExternDesc:
+		return "(value " + valueBoundToWAT(d.Bound) + ")"
 	default:
 		return fmt.Sprintf("(; unknown extern desc: %T ;)", desc)
 	}
 }
 
+func valueBoundToWAT(bound ValueBound) string {
+	switch b := bound.(type) {
+	case *ValueEqBound:
+		return fmt.Sprintf("(eq %d)", b.ValueIdx)
+	case *ValueTypeBound:
+		return valTypeToWAT(b.Type)
+	default:
+		return fmt.Sprintf("(; unknown value bound: %T ;)", bound)
+	}
+}
+
+func startToWAT(s *Start) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("(start %d", s.FuncIdx))
+	for _, arg := range s.Args {
+		b.WriteString(fmt.Sprintf(" (value %d)", arg))
+	}
+	for i := uint32(0); i < s.Results; i++ {
+		b.WriteString(" (result (value))")
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
 func typeBoundToWAT(bound TypeBound) string {
 	switch b := bound.(type) {
 	case *EqBound:
@@ -688,6 +752,9 @@ func canonDefToWAT(def CanonDef) string {
 		var b strings.Builder
 		b.WriteString("(canon resource.drop ")
 		b.WriteString(fmt.Sprintf("%d", d.TypeIdx))
+		if d.Async {
+			b.WriteString(" async")
+		}
 		b.WriteString(")")
 		return b.String()
 	case *CanonResourceRep:
@@ -696,11 +763,129 @@ func canonDefToWAT(def CanonDef) string {
 		b.WriteString(fmt.Sprintf("%d", d.TypeIdx))
 		b.WriteString(")")
 		return b.String()
+	case *CanonTaskReturn:
+		var b strings.Builder
+		b.WriteString("(canon task.return")
+		if d.Result != nil {
+			b.WriteString(" (result ")
+			b.WriteString(valTypeToWAT(d.Result))
+			b.WriteString(")")
+		}
+		for _, opt := range d.Options {
+			b.WriteString(" ")
+			b.WriteString(canonOptToWAT(opt))
+		}
+		b.WriteString(")")
+		return b.String()
+	case *CanonTaskCancel:
+		return "(canon task.cancel)"
+	case *CanonContextGet:
+		return fmt.Sprintf("(canon context.get i32 %d)", d.Slot)
+	case *CanonContextSet:
+		return fmt.Sprintf("(canon context.set i32 %d)", d.Slot)
+	case *CanonBackpressureSet:
+		return "(canon backpressure.set)"
+	case *CanonBackpressureInc:
+		return "(canon backpressure.inc)"
+	case *CanonBackpressureDec:
+		return "(canon backpressure.dec)"
+	case *CanonYield:
+		if d.Cancellable {
+			return "(canon thread.yield cancellable)"
+		}
+		return "(canon thread.yield)"
+	case *CanonSubtaskCancel:
+		if d.Async {
+			return "(canon subtask.cancel async)"
+		}
+		return "(canon subtask.cancel)"
+	case *CanonSubtaskDrop:
+		return "(canon subtask.drop)"
+	case *CanonWaitableSetNew:
+		return "(canon waitable-set.new)"
+	case *CanonWaitableSetWait:
+		if d.Cancellable {
+			return fmt.Sprintf("(canon waitable-set.wait cancellable (memory %d))", d.MemoryIdx)
+		}
+		return fmt.Sprintf("(canon waitable-set.wait (memory %d))", d.MemoryIdx)
+	case *CanonWaitableSetPoll:
+		if d.Cancellable {
+			return fmt.Sprintf("(canon waitable-set.poll cancellable (memory %d))", d.MemoryIdx)
+		}
+		return fmt.Sprintf("(canon waitable-set.poll (memory %d))", d.MemoryIdx)
+	case *CanonWaitableSetDrop:
+		return "(canon waitable-set.drop)"
+	case *CanonWaitableJoin:
+		return "(canon waitable.join)"
+	case *CanonStreamNew:
+		return fmt.Sprintf("(canon stream.new %d)", d.TypeIdx)
+	case *CanonStreamRead:
+		return canonCopyToWAT("stream.read", d.TypeIdx, d.Options)
+	case *CanonStreamWrite:
+		return canonCopyToWAT("stream.write", d.TypeIdx, d.Options)
+	case *CanonStreamCancelRead:
+		return canonCancelToWAT("stream.cancel-read", d.TypeIdx, d.Async)
+	case *CanonStreamCancelWrite:
+		return canonCancelToWAT("stream.cancel-write", d.TypeIdx, d.Async)
+	case *CanonStreamDropReadable:
+		return fmt.Sprintf("(canon stream.drop-readable %d)", d.TypeIdx)
+	case *CanonStreamDropWritable:
+		return fmt.Sprintf("(canon stream.drop-writable %d)", d.TypeIdx)
+	case *CanonFutureNew:
+		return fmt.Sprintf("(canon future.new %d)", d.TypeIdx)
+	case *CanonFutureRead:
+		return canonCopyToWAT("future.read", d.TypeIdx, d.Options)
+	case *CanonFutureWrite:
+		return canonCopyToWAT("future.write", d.TypeIdx, d.Options)
+	case *CanonFutureCancelRead:
+		return canonCancelToWAT("future.cancel-read", d.TypeIdx, d.Async)
+	case *CanonFutureCancelWrite:
+		return canonCancelToWAT("future.cancel-write", d.TypeIdx, d.Async)
+	case *CanonFutureDropReadable:
+		return fmt.Sprintf("(canon future.drop-readable %d)", d.TypeIdx)
+	case *CanonFutureDropWritable:
+		return fmt.Sprintf("(canon future.drop-writable %d)", d.TypeIdx)
+	case *CanonErrorContextNew:
+		return canonOptsToWAT("error-context.new", d.Options)
+	case *CanonErrorContextDebugMessage:
+		return canonOptsToWAT("error-context.debug-message", d.Options)
+	case *CanonErrorContextDrop:
+		return "(canon error-context.drop)"
 	default:
 		return fmt.Sprintf("(; unknown canon def: %T ;)", def)
 	}
 }
 
+func canonOptsToWAT(name string, opts []CanonOpt) string {
+	var b strings.Builder
+	b.WriteString("(canon ")
+	b.WriteString(name)
+	for _, opt := range opts {
+		b.WriteString(" ")
+		b.WriteString(canonOptToWAT(opt))
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+func canonCopyToWAT(name string, typeIdx uint32, opts []CanonOpt) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("(canon %s %d", name, typeIdx))
+	for _, opt := range opts {
+		b.WriteString(" ")
+		b.WriteString(canonOptToWAT(opt))
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+func canonCancelToWAT(name string, typeIdx uint32, async bool) string {
+	if async {
+		return fmt.Sprintf("(canon %s %d async)", name, typeIdx)
+	}
+	return fmt.Sprintf("(canon %s %d)", name, typeIdx)
+}
+
 func canonOptToWAT(opt CanonOpt) string {
 	switch o := opt.(type) {
 	case *StringEncodingOpt:
@@ -722,6 +907,12 @@ func canonOptToWAT(opt CanonOpt) string {
 		return fmt.Sprintf("(realloc %d)", o.FuncIdx)
 	case *PostReturnOpt:
 		return fmt.Sprintf("(post-return %d)", o.FuncIdx)
+	case *AsyncOpt:
+		return "async"
+	case *CallbackOpt:
+		return fmt.Sprintf("(callback %d)", o.FuncIdx)
+	case *AlwaysTaskReturnOpt:
+		return "always-task-return"
 	default:
 		return fmt.Sprintf("(; unknown canon opt: %T ;)", opt)
 	}
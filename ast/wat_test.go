@@ -100,6 +100,32 @@ func TestListType(t *testing.T) {
 	}
 }
 
+func TestFixedListType(t *testing.T) {
+	lt := &FixedListType{Element: &F32Type{}, Length: 4}
+	result := fixedListTypeToWAT(lt)
+	expected := "(list f32 4)"
+
+	if result != expected {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+}
+
+func TestStartAndValueImport(t *testing.T) {
+	tests := []struct {
+		def      Definition
+		expected string
+	}{
+		{&Import{ImportName: "n", Desc: &ValueExternDesc{Bound: &ValueTypeBound{Type: &U32Type{}}}}, "(import \"n\" (value u32))"},
+		{&Import{ImportName: "m", Desc: &ValueExternDesc{Bound: &ValueEqBound{ValueIdx: 0}}}, "(import \"m\" (value (eq 0)))"},
+		{&Start{FuncIdx: 1, Args: []uint32{0, 1}, Results: 1}, "(start 1 (value 0) (value 1) (result (value)))"},
+	}
+	for _, tt := range tests {
+		if result := defToWAT(tt.def, 0); result != tt.expected {
+			t.Errorf("Expected %s, got %s", tt.expected, result)
+		}
+	}
+}
+
 func TestFuncType(t *testing.T) {
 	ft := &FuncType{
 		Params: []FuncParam{
@@ -208,6 +234,62 @@ func TestCanonLift(t *testing.T) {
 	t.Logf("Canon lift WAT: %s", result)
 }
 
+func TestAsyncCanonDefs(t *testing.T) {
+	tests := []struct {
+		name     string
+		def      CanonDef
+		expected string
+	}{
+		{
+			"async lift",
+			&CanonLift{CoreFuncIdx: 1, Options: []CanonOpt{&AsyncOpt{}, &CallbackOpt{FuncIdx: 2}}},
+			"(canon lift (core func 1) async (callback 2))",
+		},
+		{"task.return", &CanonTaskReturn{Result: &U32Type{}, Options: []CanonOpt{&MemoryOpt{}}}, "(canon task.return (result u32) (memory 0))"},
+		{"task.return without result", &CanonTaskReturn{}, "(canon task.return)"},
+		{"context.get", &CanonContextGet{Slot: 0}, "(canon context.get i32 0)"},
+		{"yield", &CanonYield{Cancellable: true}, "(canon thread.yield cancellable)"},
+		{"waitable-set.wait", &CanonWaitableSetWait{MemoryIdx: 1}, "(canon waitable-set.wait (memory 1))"},
+		{"subtask.drop", &CanonSubtaskDrop{}, "(canon subtask.drop)"},
+		{"stream.new", &CanonStreamNew{TypeIdx: 3}, "(canon stream.new 3)"},
+		{"stream.read", &CanonStreamRead{TypeIdx: 3, Options: []CanonOpt{&MemoryOpt{}, &AsyncOpt{}}}, "(canon stream.read 3 (memory 0) async)"},
+		{"stream.cancel-write", &CanonStreamCancelWrite{TypeIdx: 3, Async: true}, "(canon stream.cancel-write 3 async)"},
+		{"future.drop-readable", &CanonFutureDropReadable{TypeIdx: 4}, "(canon future.drop-readable 4)"},
+		{"error-context.new", &CanonErrorContextNew{Options: []CanonOpt{&MemoryOpt{}, &StringEncodingOpt{Encoding: StringEncodingUTF16}}}, "(canon error-context.new (memory 0) (string-encoding=utf16))"},
+		{"error-context.drop", &CanonErrorContextDrop{}, "(canon error-context.drop)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := canonDefToWAT(tt.def); result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestStreamAndFutureTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		vt       DefValType
+		expected string
+	}{
+		{"stream", &StreamType{Element: &U8Type{}}, "(stream u8)"},
+		{"stream without elements", &StreamType{}, "(stream)"},
+		{"future", &FutureType{Value: &StringType{}}, "(future string)"},
+		{"future without a value", &FutureType{}, "(future)"},
+		{"stream of error-context", &StreamType{Element: &ErrorContextType{}}, "(stream error-context)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := valTypeToWAT(tt.vt); result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestResultType(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/partite-ai/wacogo/componentmodel"
+	"github.com/partite-ai/wacogo/parser"
+	"github.com/tetratelabs/wazero"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s wit <component.wasm>\n", os.Args[0])
+	os.Exit(1)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "wit":
+		if len(os.Args) < 3 {
+			usage()
+		}
+		wit(os.Args[2])
+	default:
+		usage()
+	}
+}
+
+// wit prints the imports and exports of a component as a WIT world.
+func wit(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read file: %v\n", err)
+		os.Exit(1)
+	}
+	p := parser.NewParser(bytes.NewReader(data))
+	component, err := p.ParseComponent()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse component: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Building the component compiles its core modules.
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, componentmodel.NewRuntimeConfig())
+	defer runtime.Close(ctx)
+	comp, err := componentmodel.NewBuilder(runtime).Build(ctx, component)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to build component: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(comp.ToWIT())
+}
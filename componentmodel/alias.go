@@ -16,8 +16,8 @@ func newInstanceExportAliasDefinition[T comparable, TT Type](
 	instanceIdx uint32,
 	exportName string,
 	sort sort[T, TT],
-) *exportAliasDefinition[T, TT, *Instance, *instanceType] {
-	return &exportAliasDefinition[T, TT, *Instance, *instanceType]{
+) *exportAliasDefinition[T, TT, *Instance, *InstanceType] {
+	return &exportAliasDefinition[T, TT, *Instance, *InstanceType]{
 		instanceIdx:  instanceIdx,
 		exportName:   exportName,
 		sort:         sort,
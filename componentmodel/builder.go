@@ -12,17 +12,37 @@ import (
 // Builder constructs a model from an AST
 type Builder struct {
 	runtime            wazero.Runtime
+	limits             Limits
 	componentIDCounter uint32
 	canonIDCounter     uint32
 }
 
-// NewBuilder creates a new model builder
+// NewBuilder creates a new model builder. Once the context of a call or an
+// instantiation is done, the core modules of the instance are closed.
+//
+// The runtime must be created with NewRuntimeConfig, or another configuration
+// with WithCloseOnContextDone(true), for a done context to stop guest code.
+// On any other runtime, such as one created by wazero.NewRuntime, a guest that
+// loops without calling the host keeps running after the context is done.
 func NewBuilder(runtime wazero.Runtime) *Builder {
 	return &Builder{
 		runtime: runtime,
 	}
 }
 
+// WithLimits sets the limits of the instances of components built afterwards.
+func (b *Builder) WithLimits(limits Limits) *Builder {
+	b.limits = limits
+	return b
+}
+
+// NewRuntimeConfig returns a wazero runtime configuration for running
+// components. It enables WithCloseOnContextDone, which compiles the checks
+// that let closing a module stop guest code running in it.
+func NewRuntimeConfig() wazero.RuntimeConfig {
+	return wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+}
+
 // Build constructs a model Component from an AST component
 func (b *Builder) Build(ctx context.Context, astComp *ast.Component) (*Component, error) {
 	comp, err := b.buildComponent(ctx, astComp, nil)
@@ -61,27 +81,13 @@ func (b *Builder) buildComponent(ctx context.Context, astComp *ast.Component, pa
 		}
 	}
 
-	comp, err := newComponent(id, b.runtime, definitions, componentScope, imports, exports)
+	comp, err := newComponent(id, b.runtime, b.limits, definitions, componentScope, imports, exports)
 	if err != nil {
 		return nil, err
 	}
 
-	importTypes := make(map[string]Type)
-	for name, tr := range imports {
-		typ, err := tr.resolveType(componentScope)
-		if err != nil {
-			return nil, err
-		}
-		importTypes[name] = typ
-	}
-
-	exportTypes := make(map[string]Type)
-	for name, export := range exports {
-		typ, err := export.typ(componentScope)
-		if err != nil {
-			return nil, err
-		}
-		exportTypes[name] = typ
+	if err := comp.resolveExterns(); err != nil {
+		return nil, err
 	}
 
 	return comp, nil
@@ -109,6 +115,8 @@ func (b *Builder) buildDefinition(ctx context.Context, bc *buildContext, astDef
 		return b.buildExport(bc, d)
 	case *ast.Canon:
 		return b.buildCanon(bc, d)
+	case *ast.Start:
+		return b.buildStart(bc, d)
 	default:
 		return fmt.Errorf("unsupported definition type: %T", astDef)
 	}
@@ -240,6 +248,8 @@ func (b *Builder) buildExportAlias(bc *buildContext, sort ast.Sort, alias *ast.E
 		return addExportAliasDefinitionToScope(bc, sortComponent, alias.InstanceIdx, alias.Name)
 	case ast.SortInstance:
 		return addExportAliasDefinitionToScope(bc, sortInstance, alias.InstanceIdx, alias.Name)
+	case ast.SortValue:
+		return addExportAliasDefinitionToScope(bc, sortValue, alias.InstanceIdx, alias.Name)
 	default:
 		return fmt.Errorf("unsupported export alias sort: %v", sort)
 	}
@@ -300,13 +310,13 @@ func (b *Builder) buildImport(bc *buildContext, astImport *ast.Import) error {
 				sortType, astImport.ImportName, tr,
 			))
 		case ast.SortComponent:
-			tr := newIndexTypeResolverOf[*componentType](sortType, desc.TypeIdx, "")
+			tr := newIndexTypeResolverOf[*ComponentType](sortType, desc.TypeIdx, "")
 			bc.imports[astImport.ImportName] = tr
 			return addDefinitionToBuildContext(bc, sortComponent, newImportDefinition(
 				sortComponent, astImport.ImportName, tr,
 			))
 		case ast.SortInstance:
-			tr := newIndexTypeResolverOf[*instanceType](sortType, desc.TypeIdx, "")
+			tr := newIndexTypeResolverOf[*InstanceType](sortType, desc.TypeIdx, "")
 			bc.imports[astImport.ImportName] = tr
 			return addDefinitionToBuildContext(bc, sortInstance, newImportDefinition(
 				sortInstance, astImport.ImportName, tr,
@@ -332,6 +342,15 @@ func (b *Builder) buildImport(bc *buildContext, astImport *ast.Import) error {
 		return addDefinitionToBuildContext(bc, sortType, newImportDefinition(
 			sortType, astImport.ImportName, importType,
 		))
+	case *ast.ValueExternDesc:
+		tr, err := astValueBoundToTypeResolver(bc.defs, desc.Bound)
+		if err != nil {
+			return err
+		}
+		bc.imports[astImport.ImportName] = tr
+		return addDefinitionToBuildContext(bc, sortValue, newImportDefinition(
+			sortValue, astImport.ImportName, tr,
+		))
 	default:
 		return fmt.Errorf("unsupported import description type: %T", astImport.Desc)
 	}
@@ -342,7 +361,7 @@ func (b *Builder) buildExport(bc *buildContext, astExport *ast.Export) error {
 	var exportType typeResolver
 	if astExport.ExternDesc != nil {
 		var err error
-		_, exportTypeResolver, err := astExternDescToTypeResolver(astExport.ExternDesc)
+		_, exportTypeResolver, err := astExternDescToTypeResolver(bc.defs, astExport.ExternDesc)
 		if err != nil {
 			return fmt.Errorf("failed to resolve export type: %w", err)
 		}
@@ -360,10 +379,22 @@ func (b *Builder) buildExport(bc *buildContext, astExport *ast.Export) error {
 		return addExportToComponent(bc, sortComponent, astExport.ExportName, astExport.SortIdx.Idx, exportType)
 	case ast.SortInstance:
 		return addExportToComponent(bc, sortInstance, astExport.ExportName, astExport.SortIdx.Idx, exportType)
+	case ast.SortValue:
+		return addExportToComponent(bc, sortValue, astExport.ExportName, astExport.SortIdx.Idx, exportType)
 	}
 	return fmt.Errorf("unsupported export sort: %v", astExport.SortIdx.Sort)
 }
 
+func (b *Builder) buildStart(bc *buildContext, astStart *ast.Start) error {
+	def := newStartDefinition(astStart)
+	bc.defs.binders = append(bc.defs.binders, def)
+	values := sortDefsFor(bc.defs, sortValue)
+	for range astStart.Results {
+		values.items = append(values.items, startResultDefinition{})
+	}
+	return def.bindType(bc.scope)
+}
+
 func addExportToComponent[V any, T Type](bc *buildContext, sort sort[V, T], exportName string, idx uint32, expectedTypeResolver typeResolver) error {
 	if idx >= sortDefsFor(bc.defs, sort).len() {
 		return fmt.Errorf("export `%s`: index out of bounds", exportName)
@@ -374,7 +405,7 @@ func addExportToComponent[V any, T Type](bc *buildContext, sort sort[V, T], expo
 	}
 
 	forceTypeReplace := expectedTypeResolver != nil
-	if it, ok := any(actualType).(*instanceType); ok {
+	if it, ok := any(actualType).(*InstanceType); ok {
 		actualType = any(it.clone()).(T)
 		forceTypeReplace = true
 	}
@@ -446,6 +477,226 @@ func (b *Builder) buildCanon(bc *buildContext, astCanon *ast.Canon) error {
 			return err
 		}
 		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonTaskReturn:
+		var resultTypeResolver typeResolver
+		if def.Result != nil {
+			var err error
+			resultTypeResolver, err = astDefTypeToTypeResolver(bc.defs, def.Result, true)
+			if err != nil {
+				return err
+			}
+		}
+		b.canonIDCounter++
+		fnDef, err := canonTaskReturn(b.canonIDCounter, def, resultTypeResolver)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonContextGet:
+		b.canonIDCounter++
+		fnDef, err := canonContextGet(b.canonIDCounter, def)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonContextSet:
+		b.canonIDCounter++
+		fnDef, err := canonContextSet(b.canonIDCounter, def)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonBackpressureSet:
+		b.canonIDCounter++
+		fnDef, err := canonBackpressureSet(b.canonIDCounter)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonBackpressureInc:
+		b.canonIDCounter++
+		fnDef, err := canonBackpressureInc(b.canonIDCounter)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonBackpressureDec:
+		b.canonIDCounter++
+		fnDef, err := canonBackpressureDec(b.canonIDCounter)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonYield:
+		b.canonIDCounter++
+		fnDef, err := canonYield(b.canonIDCounter, def)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonWaitableSetNew:
+		b.canonIDCounter++
+		fnDef, err := canonWaitableSetNew(b.canonIDCounter)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonWaitableSetWait:
+		b.canonIDCounter++
+		fnDef, err := canonWaitableSetWait(b.canonIDCounter, def)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonWaitableSetPoll:
+		b.canonIDCounter++
+		fnDef, err := canonWaitableSetPoll(b.canonIDCounter, def)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonWaitableSetDrop:
+		b.canonIDCounter++
+		fnDef, err := canonWaitableSetDrop(b.canonIDCounter)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonWaitableJoin:
+		b.canonIDCounter++
+		fnDef, err := canonWaitableJoin(b.canonIDCounter)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonSubtaskDrop:
+		b.canonIDCounter++
+		fnDef, err := canonSubtaskDrop(b.canonIDCounter)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonStreamNew:
+		b.canonIDCounter++
+		fnDef, err := canonStreamNew(b.canonIDCounter, def)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonStreamRead:
+		b.canonIDCounter++
+		fnDef, err := canonStreamRead(b.canonIDCounter, def)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonStreamWrite:
+		b.canonIDCounter++
+		fnDef, err := canonStreamWrite(b.canonIDCounter, def)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonStreamCancelRead:
+		b.canonIDCounter++
+		fnDef, err := canonStreamCancel(b.canonIDCounter, def.TypeIdx, false)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonStreamCancelWrite:
+		b.canonIDCounter++
+		fnDef, err := canonStreamCancel(b.canonIDCounter, def.TypeIdx, true)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonStreamDropReadable:
+		b.canonIDCounter++
+		fnDef, err := canonStreamDrop(b.canonIDCounter, def.TypeIdx, false)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonStreamDropWritable:
+		b.canonIDCounter++
+		fnDef, err := canonStreamDrop(b.canonIDCounter, def.TypeIdx, true)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonFutureNew:
+		b.canonIDCounter++
+		fnDef, err := canonFutureNew(b.canonIDCounter, def)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonFutureRead:
+		b.canonIDCounter++
+		fnDef, err := canonFutureRead(b.canonIDCounter, def)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonFutureWrite:
+		b.canonIDCounter++
+		fnDef, err := canonFutureWrite(b.canonIDCounter, def)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonFutureCancelRead:
+		b.canonIDCounter++
+		fnDef, err := canonFutureCancel(b.canonIDCounter, def.TypeIdx, false)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonFutureCancelWrite:
+		b.canonIDCounter++
+		fnDef, err := canonFutureCancel(b.canonIDCounter, def.TypeIdx, true)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonFutureDropReadable:
+		b.canonIDCounter++
+		fnDef, err := canonFutureDrop(b.canonIDCounter, def.TypeIdx, false)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonFutureDropWritable:
+		b.canonIDCounter++
+		fnDef, err := canonFutureDrop(b.canonIDCounter, def.TypeIdx, true)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonErrorContextNew:
+		b.canonIDCounter++
+		fnDef, err := canonErrorContextNew(b.canonIDCounter, def)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonErrorContextDebugMessage:
+		b.canonIDCounter++
+		fnDef, err := canonErrorContextDebugMessage(b.canonIDCounter, def)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonErrorContextDrop:
+		b.canonIDCounter++
+		fnDef, err := canonErrorContextDrop(b.canonIDCounter)
+		if err != nil {
+			return err
+		}
+		return addDefinitionToBuildContext(bc, sortCoreFunction, fnDef)
+	case *ast.CanonTaskCancel, *ast.CanonSubtaskCancel:
+		return fmt.Errorf("task cancellation is not supported: %T", def)
 	default:
 		return fmt.Errorf("unsupported canon def: %T", def)
 	}
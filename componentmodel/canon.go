@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/partite-ai/wacogo/ast"
-	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
 )
 
@@ -21,9 +20,19 @@ type LiftLoadContext struct {
 	stringEncoding stringEncoding
 	realloc        func(originalPtr, originalSize, alignment, newSize uint32) (uint32, error)
 	postreturn     api.Function
+	async          bool
+	callback       api.Function
+	taskReturn     bool
 	lentHandles    []ResourceHandle
 }
 
+func (llc *LiftLoadContext) limits() Limits {
+	if llc.instance == nil {
+		return Limits{}
+	}
+	return llc.instance.limits
+}
+
 type stringEncoding int
 
 const (
@@ -42,6 +51,7 @@ func canonLower(id uint32, astDef *ast.CanonLower) (definition[*coreFunction, *c
 type coreFunctionLoweredDefinition struct {
 	id     string
 	astDef *ast.CanonLower
+	stub   stubModule
 }
 
 func (d *coreFunctionLoweredDefinition) isDefinition() {}
@@ -51,7 +61,11 @@ func (d *coreFunctionLoweredDefinition) createType(scope *scope) (*coreFunctionT
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve function type for canon lower: %w", err)
 	}
-	flatParamTypes, flatResultTypes, _, _ := loweredCoreFunctionTypesFromFunctionType(fnType)
+	lowerTypes := loweredCoreFunctionTypesFromFunctionType
+	if hasCanonOpt[*ast.AsyncOpt](d.astDef.Options) {
+		lowerTypes = asyncLoweredCoreFunctionTypesFromFunctionType
+	}
+	flatParamTypes, flatResultTypes, _, _ := lowerTypes(fnType)
 	paramTypes := make([]Type, len(flatParamTypes))
 	for i, vt := range flatParamTypes {
 		paramTypes[i] = coreTypeWasmConstTypeFromWazero(vt)
@@ -71,105 +85,94 @@ func (d *coreFunctionLoweredDefinition) createInstance(ctx context.Context, scop
 
 	fnTyp := fn.funcTyp
 
+	if hasCanonOpt[*ast.AsyncOpt](d.astDef.Options) {
+		return d.createAsyncInstance(ctx, scope, fn)
+	}
+
 	flatParamTypes, flatResultTypes, paramsFlat, returnFlat := loweredCoreFunctionTypesFromFunctionType(fnTyp)
 
-	mod, err := scope.runtime.NewHostModuleBuilder(d.id).NewFunctionBuilder().
-		WithGoModuleFunction(
-			api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
-				llc, err := newLiftLoadContext(ctx, d.astDef.Options, scope)
-				if err != nil {
-					panic(fmt.Errorf("failed to create lift/load context for canon lower: %w", err))
+	return newStubCoreFunction(ctx, scope, d.id, &d.stub,
+		func(ctx context.Context, mod api.Module, stack []uint64) {
+			llc, err := newLiftLoadContext(ctx, d.astDef.Options, scope)
+			if err != nil {
+				panic(fmt.Errorf("failed to create lift/load context for canon lower: %w", err))
+			}
+			defer func() {
+				for _, rh := range llc.lentHandles {
+					rh.Drop()
 				}
-				defer func() {
-					for _, rh := range llc.lentHandles {
-						rh.Drop()
-					}
-					llc.lentHandles = nil
-				}()
+				llc.lentHandles = nil
+			}()
 
-				if err := llc.instance.checkLeave(); err != nil {
-					panic(fmt.Errorf("cannot leave component instance during canon lower: %w", err))
-				}
+			if err := llc.instance.checkLeave(); err != nil {
+				panic(fmt.Errorf("cannot leave component instance during canon lower: %w", err))
+			}
 
-				remainingParams := stack
-				itr := func() uint64 {
-					val := remainingParams[0]
-					remainingParams = remainingParams[1:]
-					return val
-				}
+			remainingParams := stack
+			itr := func() uint64 {
+				val := remainingParams[0]
+				remainingParams = remainingParams[1:]
+				return val
+			}
 
-				var paramValues []Value
-				if paramsFlat {
+			var paramValues []Value
+			if paramsFlat {
 
-					paramValues = make([]Value, 0, len(fnTyp.Parameters))
-					for i, pType := range fnTyp.Parameters {
-						val, err := pType.Type.liftFlat(llc, itr)
-						if err != nil {
-							panic(fmt.Errorf("failed to load parameter %d for canon lower: %w", i, err))
-						}
-						paramValues = append(paramValues, val)
-					}
-				} else {
-					offset := uint32(itr())
-					paramTypes := make([]ValueType, len(fnTyp.Parameters))
-					for i, p := range fnTyp.Parameters {
-						paramTypes[i] = p.Type
-					}
-					tt := NewTupleType(paramTypes...)
-					if offset != alignTo(offset, tt.alignment()) {
-						panic(fmt.Errorf("unaligned pointer for canon lower parameters"))
-					}
-					tup, err := tt.load(llc, offset)
+				paramValues = make([]Value, 0, len(fnTyp.Parameters))
+				for i, pType := range fnTyp.Parameters {
+					val, err := pType.Type.liftFlat(llc, itr)
 					if err != nil {
-						panic(fmt.Errorf("failed to load parameters for canon lower: %w", err))
+						panic(fmt.Errorf("failed to load parameter %d for canon lower: %w", i, err))
 					}
-					paramValues = tup.(Record).fields
+					paramValues = append(paramValues, val)
 				}
-
-				result, err := fn.invoke(ctx, paramValues)
-				if err != nil {
-					panic(fmt.Errorf("failed to call core function for canon lower: %w", err))
+			} else {
+				offset := uint32(itr())
+				paramTypes := make([]ValueType, len(fnTyp.Parameters))
+				for i, p := range fnTyp.Parameters {
+					paramTypes[i] = p.Type
 				}
-
-				if fnTyp.ResultType != nil {
-					func() {
-						defer llc.instance.preventLeave()()
-						if returnFlat {
-							flatResults, err := fnTyp.ResultType.lowerFlat(llc, result)
-							if err != nil {
-								panic(fmt.Errorf("failed to lower result for canon lower: %w", err))
-							}
-							copy(stack, flatResults)
-						} else {
-							offset := uint32(itr())
-							if offset != alignTo(offset, fnTyp.ResultType.alignment()) {
-								panic(fmt.Errorf("unaligned pointer for canon lower results"))
-							}
-							err := fnTyp.ResultType.store(llc, offset, result)
-							if err != nil {
-								panic(fmt.Errorf("failed to store result for canon lower: %w", err))
-							}
-						}
-					}()
+				tt := NewTupleType(paramTypes...)
+				if offset != alignTo(offset, tt.alignment()) {
+					panic(fmt.Errorf("unaligned pointer for canon lower parameters"))
 				}
-			}),
-			flatParamTypes,
-			flatResultTypes,
-		).
-		Export("stub_function").
-		Compile(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create canon lower stub module: %w", err)
-	}
+				tup, err := tt.load(llc, offset)
+				if err != nil {
+					panic(fmt.Errorf("failed to load parameters for canon lower: %w", err))
+				}
+				paramValues = tup.(Record).fields
+			}
 
-	modInst, err := scope.runtime.InstantiateModule(ctx, mod, wazero.NewModuleConfig().WithName(""))
-	if err != nil {
-		return nil, fmt.Errorf("failed to instantiate canon lower stub module: %w", err)
-	}
+			result, err := fn.invoke(ctx, paramValues)
+			if err != nil {
+				panic(fmt.Errorf("failed to call core function for canon lower: %w", err))
+			}
 
-	return newCoreFunction(
-		modInst, "stub_function", mod.ExportedFunctions()["stub_function"],
-	), nil
+			if fnTyp.ResultType != nil {
+				func() {
+					defer llc.instance.preventLeave()()
+					if returnFlat {
+						flatResults, err := fnTyp.ResultType.lowerFlat(llc, result)
+						if err != nil {
+							panic(fmt.Errorf("failed to lower result for canon lower: %w", err))
+						}
+						copy(stack, flatResults)
+					} else {
+						offset := uint32(itr())
+						if offset != alignTo(offset, fnTyp.ResultType.alignment()) {
+							panic(fmt.Errorf("unaligned pointer for canon lower results"))
+						}
+						err := fnTyp.ResultType.store(llc, offset, result)
+						if err != nil {
+							panic(fmt.Errorf("failed to store result for canon lower: %w", err))
+						}
+					}
+				}()
+			}
+		},
+		flatParamTypes,
+		flatResultTypes,
+	)
 }
 
 func loweredCoreFunctionTypesFromFunctionType(fnType *FunctionType) ([]api.ValueType, []api.ValueType, bool, bool) {
@@ -209,6 +212,7 @@ func canonResourceNew(id uint32, astDef *ast.CanonResourceNew) (definition[*core
 type coreFunctionResourceNewDefinition struct {
 	id     string
 	astDef *ast.CanonResourceNew
+	stub   stubModule
 }
 
 func (d *coreFunctionResourceNewDefinition) isDefinition() {}
@@ -243,35 +247,23 @@ func (d *coreFunctionResourceNewDefinition) createInstance(ctx context.Context,
 		return nil, fmt.Errorf("canon resource.new type is not a resource")
 	}
 
-	mod, err := scope.runtime.NewHostModuleBuilder(d.id).NewFunctionBuilder().
-		WithGoModuleFunction(
-			api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
-				rep := uint32(stack[0])
-				instance := scope.instance
-				if err := scope.instance.checkLeave(); err != nil {
-					panic(fmt.Errorf("cannot leave component instance during canon resource.new: %w", err))
-				}
-				handle := NewResourceHandle(instance, resourceType, rep)
-				handleIdx := instance.loweredHandles.add(handle)
-				stack[0] = uint64(handleIdx)
-			}),
-			[]api.ValueType{api.ValueTypeI32},
-			[]api.ValueType{api.ValueTypeI32},
-		).
-		Export("stub_function").
-		Compile(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create resource new stub module: %w", err)
-	}
-
-	modInst, err := scope.runtime.InstantiateModule(ctx, mod, wazero.NewModuleConfig().WithName(""))
-	if err != nil {
-		return nil, fmt.Errorf("failed to instantiate resource new stub module: %w", err)
-	}
-
-	return newCoreFunction(
-		modInst, "stub_function", mod.ExportedFunctions()["stub_function"],
-	), nil
+	return newStubCoreFunction(ctx, scope, d.id, &d.stub,
+		func(ctx context.Context, mod api.Module, stack []uint64) {
+			rep := uint32(stack[0])
+			instance := scope.instance
+			if err := scope.instance.checkLeave(); err != nil {
+				panic(fmt.Errorf("cannot leave component instance during canon resource.new: %w", err))
+			}
+			handle := NewResourceHandle(instance, resourceType, rep)
+			handleIdx, err := instance.loweredHandles.add(handle)
+			if err != nil {
+				panic(fmt.Errorf("canon resource.new failed: %w", err))
+			}
+			stack[0] = uint64(handleIdx)
+		},
+		[]api.ValueType{api.ValueTypeI32},
+		[]api.ValueType{api.ValueTypeI32},
+	)
 }
 
 func canonResourceDrop(id uint32, astDef *ast.CanonResourceDrop) (definition[*coreFunction, *coreFunctionType], error) {
@@ -284,6 +276,7 @@ func canonResourceDrop(id uint32, astDef *ast.CanonResourceDrop) (definition[*co
 type coreFunctionResourceDropDefinition struct {
 	id     string
 	astDef *ast.CanonResourceDrop
+	stub   stubModule
 }
 
 func (d *coreFunctionResourceDropDefinition) isDefinition() {}
@@ -312,40 +305,25 @@ func (d *coreFunctionResourceDropDefinition) createInstance(ctx context.Context,
 	if !ok {
 		return nil, fmt.Errorf("canon drop type is not a resource")
 	}
-	mod, err := scope.runtime.NewHostModuleBuilder(d.id).NewFunctionBuilder().
-		WithGoModuleFunction(
-			api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
-				instance := scope.instance
-				if err := scope.instance.checkLeave(); err != nil {
-					panic(fmt.Errorf("cannot leave component instance during canon resource.drop: %w", err))
-				}
-				resourceIdx := uint32(stack[0])
-				handle := instance.loweredHandles.remove(uint32(resourceIdx))
-				if handle.resourceType() != resourceType {
-					panic(fmt.Errorf("resource type mismatch in canon drop"))
-				}
-				if handle.isBorrowed() {
-					panic(fmt.Errorf("cannot drop resource with outstanding lends"))
-				}
-				handle.Drop()
-			}),
-			[]api.ValueType{api.ValueTypeI32},
-			[]api.ValueType{},
-		).
-		Export("stub_function").
-		Compile(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create resource drop stub module: %w", err)
-	}
-
-	modInst, err := scope.runtime.InstantiateModule(ctx, mod, wazero.NewModuleConfig().WithName(""))
-	if err != nil {
-		return nil, fmt.Errorf("failed to instantiate resource drop stub module: %w", err)
-	}
-
-	return newCoreFunction(
-		modInst, "stub_function", mod.ExportedFunctions()["stub_function"],
-	), nil
+	return newStubCoreFunction(ctx, scope, d.id, &d.stub,
+		func(ctx context.Context, mod api.Module, stack []uint64) {
+			instance := scope.instance
+			if err := scope.instance.checkLeave(); err != nil {
+				panic(fmt.Errorf("cannot leave component instance during canon resource.drop: %w", err))
+			}
+			resourceIdx := uint32(stack[0])
+			handle := instance.loweredHandles.remove(uint32(resourceIdx))
+			if handle.resourceType() != resourceType {
+				panic(fmt.Errorf("resource type mismatch in canon drop"))
+			}
+			if handle.isBorrowed() {
+				panic(fmt.Errorf("cannot drop resource with outstanding lends"))
+			}
+			handle.Drop()
+		},
+		[]api.ValueType{api.ValueTypeI32},
+		[]api.ValueType{},
+	)
 }
 
 func canonResourceRep(id uint32, astDef *ast.CanonResourceRep) (definition[*coreFunction, *coreFunctionType], error) {
@@ -358,6 +336,7 @@ func canonResourceRep(id uint32, astDef *ast.CanonResourceRep) (definition[*core
 type coreFunctionResourceRepDefinition struct {
 	id     string
 	astDef *ast.CanonResourceRep
+	stub   stubModule
 }
 
 func (d *coreFunctionResourceRepDefinition) isDefinition() {}
@@ -391,39 +370,24 @@ func (d *coreFunctionResourceRepDefinition) createInstance(ctx context.Context,
 	if !ok {
 		return nil, fmt.Errorf("canon resource.rep type is not a resource")
 	}
-	mod, err := scope.runtime.NewHostModuleBuilder(d.id).NewFunctionBuilder().
-		WithGoModuleFunction(
-			api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
-				instance := scope.instance
-				resourceIdx := uint32(stack[0])
-				handle := instance.loweredHandles.get(uint32(resourceIdx))
-				if handle.resourceType() != resourceType {
-					panic(fmt.Errorf("resource type mismatch in canon drop"))
-				}
-				rep := handle.Resource()
-				if u32, ok := rep.(uint32); ok {
-					stack[0] = uint64(u32)
-				} else {
-					panic(fmt.Errorf("resource representation is not uint32"))
-				}
-			}),
-			[]api.ValueType{api.ValueTypeI32},
-			[]api.ValueType{api.ValueTypeI32},
-		).
-		Export("stub_function").
-		Compile(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create resource drop stub module: %w", err)
-	}
-
-	modInst, err := scope.runtime.InstantiateModule(ctx, mod, wazero.NewModuleConfig().WithName(""))
-	if err != nil {
-		return nil, fmt.Errorf("failed to instantiate resource drop stub module: %w", err)
-	}
-
-	return newCoreFunction(
-		modInst, "stub_function", mod.ExportedFunctions()["stub_function"],
-	), nil
+	return newStubCoreFunction(ctx, scope, d.id, &d.stub,
+		func(ctx context.Context, mod api.Module, stack []uint64) {
+			instance := scope.instance
+			resourceIdx := uint32(stack[0])
+			handle := instance.loweredHandles.get(uint32(resourceIdx))
+			if handle.resourceType() != resourceType {
+				panic(fmt.Errorf("resource type mismatch in canon drop"))
+			}
+			rep := handle.Resource()
+			if u32, ok := rep.(uint32); ok {
+				stack[0] = uint64(u32)
+			} else {
+				panic(fmt.Errorf("resource representation is not uint32"))
+			}
+		},
+		[]api.ValueType{api.ValueTypeI32},
+		[]api.ValueType{api.ValueTypeI32},
+	)
 }
 
 func canonLift(id uint32, astDef *ast.CanonLift) (definition[*Function, *FunctionType], error) {
@@ -468,19 +432,28 @@ func (d *functionLiftedDefinition) createInstance(ctx context.Context, scope *sc
 
 	_, _, paramsFlat, returnFlat := liftedCoreFunctionTypesFromFunctionType(fnType)
 
-	return NewFunction(
+	newFunction := NewFunction
+	if hasCanonOpt[*ast.AsyncOpt](d.astDef.Options) {
+		newFunction = NewAsyncFunction
+	}
+
+	return newFunction(
 		fnType,
 		func(ctx context.Context, params []Value) (Value, error) {
 			inst := scope.instance
 			if err := inst.enter(ctx); err != nil {
 				return nil, err
 			}
+			stopInterrupt := inst.interruptOnDone(ctx)
 
 			result, err := func() (Value, error) {
 				llc, err := newLiftLoadContext(ctx, d.astDef.Options, scope)
 				if err != nil {
 					return nil, fmt.Errorf("failed to create lift/load context for canon lower: %w", err)
 				}
+				t := newTask(ctx, inst, fnType.ResultType, llc.taskReturn)
+				ctx := withInstance(withTask(ctx, t), inst)
+				llc.ctx = ctx
 
 				flatParams, err := func() ([]uint64, error) {
 					defer inst.preventLeave()()
@@ -522,7 +495,22 @@ func (d *functionLiftedDefinition) createInstance(ctx context.Context, scope *sc
 				coreFnInst := coreFn.module.ExportedFunction(coreFn.name)
 				results, err := coreFnInst.Call(ctx, flatParams...)
 				if err != nil {
-					return nil, fmt.Errorf("failed to call core function for canon lift: %w", err)
+					return nil, inst.trapError(ctx, fmt.Errorf("failed to call core function for canon lift: %w", err))
+				}
+
+				if llc.taskReturn {
+					if llc.callback != nil {
+						if len(results) != 1 {
+							return nil, fmt.Errorf("async core function with a callback must return a callback code")
+						}
+						if err := runCallbackLoop(ctx, inst, llc.callback, uint32(results[0])); err != nil {
+							return nil, inst.trapError(ctx, fmt.Errorf("failed to run callback for canon lift: %w", err))
+						}
+					}
+					if !t.returned {
+						return nil, fmt.Errorf("task exited without calling task.return")
+					}
+					return t.result, nil
 				}
 
 				var returnValue Value
@@ -557,11 +545,14 @@ func (d *functionLiftedDefinition) createInstance(ctx context.Context, scope *sc
 					defer llc.instance.preventLeave()()
 					_, err := llc.postreturn.Call(ctx, results...)
 					if err != nil {
-						return nil, fmt.Errorf("failed to call post return function for canon lift: %w", err)
+						return nil, inst.trapError(ctx, fmt.Errorf("failed to call post return function for canon lift: %w", err))
 					}
 				}
 				return returnValue, nil
 			}()
+			if !stopInterrupt() {
+				result, err = nil, &InterruptedError{cause: ctx.Err()}
+			}
 
 			exitErr := inst.exit()
 			if err != nil {
@@ -629,7 +620,7 @@ func newLiftLoadContext(ctx context.Context, opts []ast.CanonOpt, scope *scope)
 			}
 			reallocFn := coreFn.module.ExportedFunction(coreFn.name)
 			llc.realloc = func(originalPtr, originalSize, alignment, newSize uint32) (uint32, error) {
-				results, err := reallocFn.Call(ctx, uint64(originalPtr), uint64(originalSize), uint64(alignment), uint64(newSize))
+				results, err := reallocFn.Call(withInstance(ctx, scope.instance), uint64(originalPtr), uint64(originalSize), uint64(alignment), uint64(newSize))
 				if err != nil || len(results) != 1 {
 					return 0, err
 				}
@@ -646,6 +637,17 @@ func newLiftLoadContext(ctx context.Context, opts []ast.CanonOpt, scope *scope)
 			}
 			postReturnFn := coreFn.module.ExportedFunction(coreFn.name)
 			llc.postreturn = postReturnFn
+		case *ast.AsyncOpt:
+			llc.async = true
+			llc.taskReturn = true
+		case *ast.CallbackOpt:
+			coreFn, err := sortScopeFor(scope, sortCoreFunction).getInstance(o.FuncIdx)
+			if err != nil {
+				return nil, err
+			}
+			llc.callback = coreFn.module.ExportedFunction(coreFn.name)
+		case *ast.AlwaysTaskReturnOpt:
+			llc.taskReturn = true
 		default:
 			return nil, fmt.Errorf("unknown canon lift/load option: %T", opt)
 		}
@@ -653,3 +655,12 @@ func newLiftLoadContext(ctx context.Context, opts []ast.CanonOpt, scope *scope)
 
 	return llc, nil
 }
+
+func hasCanonOpt[T ast.CanonOpt](opts []ast.CanonOpt) bool {
+	for _, opt := range opts {
+		if _, ok := opt.(T); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,575 @@
+package componentmodel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/partite-ai/wacogo/ast"
+	"github.com/tetratelabs/wazero/api"
+)
+
+const maxFlatAsyncParams = 4
+
+func asyncLoweredCoreFunctionTypesFromFunctionType(fnType *FunctionType) ([]api.ValueType, []api.ValueType, bool, bool) {
+	var flatParamTypes []api.ValueType
+	for _, p := range fnType.Parameters {
+		flatParamTypes = append(flatParamTypes, p.Type.flatTypes()...)
+	}
+
+	paramsFlat := true
+	if len(flatParamTypes) > maxFlatAsyncParams {
+		paramsFlat = false
+		flatParamTypes = []api.ValueType{api.ValueTypeI32}
+	}
+
+	// Results are always stored through a pointer once the subtask returns.
+	if fnType.ResultType != nil {
+		flatParamTypes = append(flatParamTypes, api.ValueTypeI32)
+	}
+	return flatParamTypes, []api.ValueType{api.ValueTypeI32}, paramsFlat, false
+}
+
+func (d *coreFunctionLoweredDefinition) createAsyncInstance(ctx context.Context, scope *scope, fn *Function) (*coreFunction, error) {
+	fnTyp := fn.funcTyp
+	flatParamTypes, flatResultTypes, paramsFlat, _ := asyncLoweredCoreFunctionTypesFromFunctionType(fnTyp)
+
+	return newStubCoreFunction(ctx, scope, d.id, &d.stub, func(ctx context.Context, mod api.Module, stack []uint64) {
+		llc, err := newLiftLoadContext(ctx, d.astDef.Options, scope)
+		if err != nil {
+			panic(fmt.Errorf("failed to create lift/load context for canon lower: %w", err))
+		}
+		inst := llc.instance
+
+		if err := inst.checkLeave(); err != nil {
+			panic(fmt.Errorf("cannot leave component instance during canon lower: %w", err))
+		}
+
+		remainingParams := stack
+		itr := func() uint64 {
+			val := remainingParams[0]
+			remainingParams = remainingParams[1:]
+			return val
+		}
+
+		var paramValues []Value
+		if paramsFlat {
+			paramValues = make([]Value, 0, len(fnTyp.Parameters))
+			for i, pType := range fnTyp.Parameters {
+				val, err := pType.Type.liftFlat(llc, itr)
+				if err != nil {
+					panic(fmt.Errorf("failed to load parameter %d for canon lower: %w", i, err))
+				}
+				paramValues = append(paramValues, val)
+			}
+		} else {
+			offset := uint32(itr())
+			paramTypes := make([]ValueType, len(fnTyp.Parameters))
+			for i, p := range fnTyp.Parameters {
+				paramTypes[i] = p.Type
+			}
+			tt := NewTupleType(paramTypes...)
+			if offset != alignTo(offset, tt.alignment()) {
+				panic(fmt.Errorf("unaligned pointer for canon lower parameters"))
+			}
+			tup, err := tt.load(llc, offset)
+			if err != nil {
+				panic(fmt.Errorf("failed to load parameters for canon lower: %w", err))
+			}
+			paramValues = tup.(Record).fields
+		}
+
+		var resultOffset uint32
+		if fnTyp.ResultType != nil {
+			resultOffset = uint32(itr())
+			if resultOffset != alignTo(resultOffset, fnTyp.ResultType.alignment()) {
+				panic(fmt.Errorf("unaligned pointer for canon lower results"))
+			}
+		}
+
+		// The result is stored and the lent handles are returned once the
+		// caller learns that the callee returned.
+		complete := func(result Value) error {
+			defer func() {
+				for _, rh := range llc.lentHandles {
+					rh.Drop()
+				}
+				llc.lentHandles = nil
+			}()
+			if fnTyp.ResultType == nil {
+				return nil
+			}
+			defer inst.preventLeave()()
+			if err := fnTyp.ResultType.store(llc, resultOffset, result); err != nil {
+				return fmt.Errorf("failed to store result for canon lower: %w", err)
+			}
+			return nil
+		}
+
+		if !fn.async {
+			result, err := fn.invoke(ctx, paramValues)
+			if err != nil {
+				panic(fmt.Errorf("failed to call function for canon lower: %w", err))
+			}
+			if err := complete(result); err != nil {
+				panic(err)
+			}
+			stack[0] = uint64(subtaskReturned)
+			return
+		}
+
+		st := &subtask{}
+		inst.mu.Lock()
+		idx, err := inst.waitables.add(st)
+		inst.mu.Unlock()
+		if err != nil {
+			panic(fmt.Errorf("failed to start subtask for canon lower: %w", err))
+		}
+
+		go func() {
+			result, err := fn.invoke(ctx, paramValues)
+
+			inst.mu.Lock()
+			defer inst.mu.Unlock()
+			st.setEvent(&event{
+				code:    eventSubtask,
+				index:   idx,
+				payload: uint32(subtaskReturned),
+				deliver: func() error {
+					st.returned = true
+					if err != nil {
+						return fmt.Errorf("subtask %d failed: %w", idx, err)
+					}
+					return complete(result)
+				},
+			})
+		}()
+
+		stack[0] = uint64(subtaskStarted) | uint64(idx)<<4
+	}, flatParamTypes, flatResultTypes)
+}
+
+// runCallbackLoop drives an async lifted function that uses a callback until
+// it exits, starting from the code returned by its core function.
+func runCallbackLoop(ctx context.Context, inst *Instance, callback api.Function, code uint32) error {
+	for {
+		var e *event
+		switch code & 0xf {
+		case callbackCodeExit:
+			return nil
+		case callbackCodeYield:
+			inst.yield()
+			e = &event{code: eventNone}
+		case callbackCodeWait, callbackCodePoll:
+			s, err := inst.waitableSet(code >> 4)
+			if err != nil {
+				return err
+			}
+			e, err = inst.waitForEvent(ctx, s, code&0xf == callbackCodePoll)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("invalid callback code %d", code)
+		}
+
+		results, err := callback.Call(ctx, uint64(e.code), uint64(e.index), uint64(e.payload))
+		if err != nil {
+			return err
+		}
+		if len(results) != 1 {
+			return fmt.Errorf("callback must return a callback code")
+		}
+		code = uint32(results[0])
+	}
+}
+
+// coreFunctionBuiltinDefinition is a canon built-in with a fixed core
+// function type.
+type coreFunctionBuiltinDefinition struct {
+	id       string
+	params   []api.ValueType
+	results  []api.ValueType
+	validate func(scope *scope) error
+	newFunc  func(scope *scope) (api.GoModuleFunc, error)
+	stub     stubModule
+}
+
+func (d *coreFunctionBuiltinDefinition) isDefinition() {}
+
+func (d *coreFunctionBuiltinDefinition) createType(scope *scope) (*coreFunctionType, error) {
+	if d.validate != nil {
+		if err := d.validate(scope); err != nil {
+			return nil, err
+		}
+	}
+	paramTypes := make([]Type, len(d.params))
+	for i, vt := range d.params {
+		paramTypes[i] = coreTypeWasmConstTypeFromWazero(vt)
+	}
+	resultTypes := make([]Type, len(d.results))
+	for i, vt := range d.results {
+		resultTypes[i] = coreTypeWasmConstTypeFromWazero(vt)
+	}
+	return newCoreFunctionType(paramTypes, resultTypes), nil
+}
+
+func (d *coreFunctionBuiltinDefinition) createInstance(ctx context.Context, scope *scope) (*coreFunction, error) {
+	fn, err := d.newFunc(scope)
+	if err != nil {
+		return nil, err
+	}
+	return newStubCoreFunction(ctx, scope, d.id, &d.stub, fn, d.params, d.results)
+}
+
+func i32s(n int) []api.ValueType {
+	types := make([]api.ValueType, n)
+	for i := range types {
+		types[i] = api.ValueTypeI32
+	}
+	return types
+}
+
+func canonTaskReturn(id uint32, astDef *ast.CanonTaskReturn, resultTypeResolver typeResolver) (definition[*coreFunction, *coreFunctionType], error) {
+	return &coreFunctionTaskReturnDefinition{
+		id:                 fmt.Sprintf("canon_task_return_%d", id),
+		astDef:             astDef,
+		resultTypeResolver: resultTypeResolver,
+	}, nil
+}
+
+type coreFunctionTaskReturnDefinition struct {
+	id                 string
+	astDef             *ast.CanonTaskReturn
+	resultTypeResolver typeResolver
+	stub               stubModule
+}
+
+func (d *coreFunctionTaskReturnDefinition) isDefinition() {}
+
+func (d *coreFunctionTaskReturnDefinition) resultType(scope *scope) (ValueType, error) {
+	if d.resultTypeResolver == nil {
+		return nil, nil
+	}
+	typ, err := d.resultTypeResolver.resolveType(scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve result type for canon task.return: %w", err)
+	}
+	vt, ok := typ.(ValueType)
+	if !ok {
+		return nil, fmt.Errorf("canon task.return result type is not a value type")
+	}
+	return vt, nil
+}
+
+func (d *coreFunctionTaskReturnDefinition) flatParamTypes(resultType ValueType) ([]api.ValueType, bool) {
+	if resultType == nil {
+		return nil, true
+	}
+	flatTypes := resultType.flatTypes()
+	if len(flatTypes) > maxFlatParams {
+		return []api.ValueType{api.ValueTypeI32}, false
+	}
+	return flatTypes, true
+}
+
+func (d *coreFunctionTaskReturnDefinition) createType(scope *scope) (*coreFunctionType, error) {
+	resultType, err := d.resultType(scope)
+	if err != nil {
+		return nil, err
+	}
+	flatTypes, _ := d.flatParamTypes(resultType)
+	paramTypes := make([]Type, len(flatTypes))
+	for i, vt := range flatTypes {
+		paramTypes[i] = coreTypeWasmConstTypeFromWazero(vt)
+	}
+	return newCoreFunctionType(paramTypes, []Type{}), nil
+}
+
+func (d *coreFunctionTaskReturnDefinition) createInstance(ctx context.Context, scope *scope) (*coreFunction, error) {
+	resultType, err := d.resultType(scope)
+	if err != nil {
+		return nil, err
+	}
+	flatTypes, flat := d.flatParamTypes(resultType)
+
+	return newStubCoreFunction(ctx, scope, d.id, &d.stub, func(ctx context.Context, mod api.Module, stack []uint64) {
+		t, err := currentTask(ctx, scope.instance)
+		if err != nil {
+			panic(fmt.Errorf("canon task.return: %w", err))
+		}
+		llc, err := newLiftLoadContext(ctx, d.astDef.Options, scope)
+		if err != nil {
+			panic(fmt.Errorf("failed to create lift/load context for canon task.return: %w", err))
+		}
+
+		var result Value
+		if resultType != nil {
+			if flat {
+				remaining := stack
+				result, err = resultType.liftFlat(llc, func() uint64 {
+					val := remaining[0]
+					remaining = remaining[1:]
+					return val
+				})
+			} else {
+				offset := uint32(stack[0])
+				if offset != alignTo(offset, resultType.alignment()) {
+					panic(fmt.Errorf("unaligned pointer for canon task.return result"))
+				}
+				result, err = resultType.load(llc, offset)
+			}
+			if err != nil {
+				panic(fmt.Errorf("failed to lift result for canon task.return: %w", err))
+			}
+		}
+
+		if err := t.taskReturn(resultType, result); err != nil {
+			panic(err)
+		}
+	}, flatTypes, nil)
+}
+
+func canonContextGet(id uint32, astDef *ast.CanonContextGet) (definition[*coreFunction, *coreFunctionType], error) {
+	return &coreFunctionBuiltinDefinition{
+		id:       fmt.Sprintf("canon_context_get_%d", id),
+		results:  i32s(1),
+		validate: validateContextSlot(astDef.Slot),
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				t, err := currentTask(ctx, scope.instance)
+				if err != nil {
+					panic(fmt.Errorf("canon context.get: %w", err))
+				}
+				stack[0] = uint64(t.context[astDef.Slot])
+			}, nil
+		},
+	}, nil
+}
+
+func canonContextSet(id uint32, astDef *ast.CanonContextSet) (definition[*coreFunction, *coreFunctionType], error) {
+	return &coreFunctionBuiltinDefinition{
+		id:       fmt.Sprintf("canon_context_set_%d", id),
+		params:   i32s(1),
+		validate: validateContextSlot(astDef.Slot),
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				t, err := currentTask(ctx, scope.instance)
+				if err != nil {
+					panic(fmt.Errorf("canon context.set: %w", err))
+				}
+				t.context[astDef.Slot] = uint32(stack[0])
+			}, nil
+		},
+	}, nil
+}
+
+func validateContextSlot(slot uint32) func(*scope) error {
+	return func(*scope) error {
+		if slot >= maxContextSlots {
+			return fmt.Errorf("invalid context slot %d", slot)
+		}
+		return nil
+	}
+}
+
+func canonBackpressureSet(id uint32) (definition[*coreFunction, *coreFunctionType], error) {
+	return newBackpressureDefinition(fmt.Sprintf("canon_backpressure_set_%d", id), 1, func(backpressure uint32, arg uint32) (uint32, error) {
+		if arg != 0 {
+			return 1, nil
+		}
+		return 0, nil
+	}), nil
+}
+
+func canonBackpressureInc(id uint32) (definition[*coreFunction, *coreFunctionType], error) {
+	return newBackpressureDefinition(fmt.Sprintf("canon_backpressure_inc_%d", id), 0, func(backpressure uint32, _ uint32) (uint32, error) {
+		if backpressure == 0xffff {
+			return 0, fmt.Errorf("backpressure counter overflow")
+		}
+		return backpressure + 1, nil
+	}), nil
+}
+
+func canonBackpressureDec(id uint32) (definition[*coreFunction, *coreFunctionType], error) {
+	return newBackpressureDefinition(fmt.Sprintf("canon_backpressure_dec_%d", id), 0, func(backpressure uint32, _ uint32) (uint32, error) {
+		if backpressure == 0 {
+			return 0, fmt.Errorf("backpressure counter underflow")
+		}
+		return backpressure - 1, nil
+	}), nil
+}
+
+func newBackpressureDefinition(id string, numParams int, update func(backpressure uint32, arg uint32) (uint32, error)) *coreFunctionBuiltinDefinition {
+	return &coreFunctionBuiltinDefinition{
+		id:     id,
+		params: i32s(numParams),
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				var arg uint32
+				if numParams > 0 {
+					arg = uint32(stack[0])
+				}
+				inst := scope.instance
+				inst.mu.Lock()
+				defer inst.mu.Unlock()
+				backpressure, err := update(inst.backpressure, arg)
+				if err != nil {
+					panic(err)
+				}
+				inst.backpressure = backpressure
+				if backpressure == 0 {
+					inst.notifyReleased()
+				}
+			}, nil
+		},
+	}
+}
+
+func canonYield(id uint32, astDef *ast.CanonYield) (definition[*coreFunction, *coreFunctionType], error) {
+	return &coreFunctionBuiltinDefinition{
+		id:      fmt.Sprintf("canon_yield_%d", id),
+		results: i32s(1),
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				if err := scope.instance.checkLeave(); err != nil {
+					panic(fmt.Errorf("cannot yield: %w", err))
+				}
+				scope.instance.yield()
+				// Tasks are never cancelled, so the yield never reports it.
+				stack[0] = 0
+			}, nil
+		},
+	}, nil
+}
+
+func canonWaitableSetNew(id uint32) (definition[*coreFunction, *coreFunctionType], error) {
+	return &coreFunctionBuiltinDefinition{
+		id:      fmt.Sprintf("canon_waitable_set_new_%d", id),
+		results: i32s(1),
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				inst := scope.instance
+				inst.mu.Lock()
+				defer inst.mu.Unlock()
+				idx, err := inst.waitableSets.add(newWaitableSet())
+				if err != nil {
+					panic(fmt.Errorf("canon waitable-set.new failed: %w", err))
+				}
+				stack[0] = uint64(idx)
+			}, nil
+		},
+	}, nil
+}
+
+func canonWaitableSetWait(id uint32, astDef *ast.CanonWaitableSetWait) (definition[*coreFunction, *coreFunctionType], error) {
+	return newWaitableSetWaitDefinition(fmt.Sprintf("canon_waitable_set_wait_%d", id), astDef.MemoryIdx, false), nil
+}
+
+func canonWaitableSetPoll(id uint32, astDef *ast.CanonWaitableSetPoll) (definition[*coreFunction, *coreFunctionType], error) {
+	return newWaitableSetWaitDefinition(fmt.Sprintf("canon_waitable_set_poll_%d", id), astDef.MemoryIdx, true), nil
+}
+
+func newWaitableSetWaitDefinition(id string, memoryIdx uint32, poll bool) *coreFunctionBuiltinDefinition {
+	return &coreFunctionBuiltinDefinition{
+		id:      id,
+		params:  i32s(2),
+		results: i32s(1),
+		validate: func(scope *scope) error {
+			_, err := sortScopeFor(scope, sortCoreMemory).getType(memoryIdx)
+			return err
+		},
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			mem, err := sortScopeFor(scope, sortCoreMemory).getInstance(memoryIdx)
+			if err != nil {
+				return nil, err
+			}
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				inst := scope.instance
+				if err := inst.checkLeave(); err != nil {
+					panic(fmt.Errorf("cannot wait: %w", err))
+				}
+				s, err := inst.waitableSet(uint32(stack[0]))
+				if err != nil {
+					panic(err)
+				}
+				ptr := uint32(stack[1])
+				if ptr != alignTo(ptr, 4) {
+					panic(fmt.Errorf("unaligned pointer for event payload"))
+				}
+				e, err := inst.waitForEvent(ctx, s, poll)
+				if err != nil {
+					panic(err)
+				}
+				if !mem.memory.WriteUint32Le(ptr, e.index) || !mem.memory.WriteUint32Le(ptr+4, e.payload) {
+					panic(fmt.Errorf("event payload pointer out of bounds"))
+				}
+				stack[0] = uint64(e.code)
+			}, nil
+		},
+	}
+}
+
+func canonWaitableSetDrop(id uint32) (definition[*coreFunction, *coreFunctionType], error) {
+	return &coreFunctionBuiltinDefinition{
+		id:     fmt.Sprintf("canon_waitable_set_drop_%d", id),
+		params: i32s(1),
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				inst := scope.instance
+				inst.mu.Lock()
+				defer inst.mu.Unlock()
+				s := inst.waitableSets.get(uint32(stack[0]))
+				if len(s.members) > 0 {
+					panic(fmt.Errorf("cannot drop a waitable set that still has waitables"))
+				}
+				if s.waiters > 0 {
+					panic(fmt.Errorf("cannot drop a waitable set that is being waited on"))
+				}
+				inst.waitableSets.remove(uint32(stack[0]))
+			}, nil
+		},
+	}, nil
+}
+
+func canonWaitableJoin(id uint32) (definition[*coreFunction, *coreFunctionType], error) {
+	return &coreFunctionBuiltinDefinition{
+		id:     fmt.Sprintf("canon_waitable_join_%d", id),
+		params: i32s(2),
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				inst := scope.instance
+				inst.mu.Lock()
+				defer inst.mu.Unlock()
+				w := inst.waitables.get(uint32(stack[0])).state()
+				var s *waitableSet
+				if setIdx := uint32(stack[1]); setIdx != 0 {
+					s = inst.waitableSets.get(setIdx)
+				}
+				w.join(s)
+			}, nil
+		},
+	}, nil
+}
+
+func canonSubtaskDrop(id uint32) (definition[*coreFunction, *coreFunctionType], error) {
+	return &coreFunctionBuiltinDefinition{
+		id:     fmt.Sprintf("canon_subtask_drop_%d", id),
+		params: i32s(1),
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				inst := scope.instance
+				inst.mu.Lock()
+				defer inst.mu.Unlock()
+				st, ok := inst.waitables.get(uint32(stack[0])).(*subtask)
+				if !ok {
+					panic(fmt.Errorf("waitable %d is not a subtask", stack[0]))
+				}
+				if !st.returned {
+					panic(fmt.Errorf("cannot drop a subtask that has not returned"))
+				}
+				st.join(nil)
+				inst.waitables.remove(uint32(stack[0]))
+			}, nil
+		},
+	}, nil
+}
@@ -0,0 +1,196 @@
+package componentmodel
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/partite-ai/wacogo/ast"
+	"github.com/tetratelabs/wazero"
+)
+
+// asyncMemoryModule is
+//
+//	(module (memory (export "mem") 1))
+var asyncMemoryModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	0x05, 0x03, 0x01, 0x00, 0x01, 0x07, 0x07, 0x01, 0x03, 0x6d, 0x65, 0x6d, 0x02, 0x00,
+}
+
+// asyncCoreModule is
+//
+//	(module
+//	  (import "host" "lower" (func $lower (param i32 i32) (result i32)))
+//	  (import "host" "task_return" (func $task_return (param i32)))
+//	  (import "host" "ws_new" (func $ws_new (result i32)))
+//	  (import "host" "ws_wait" (func $ws_wait (param i32 i32) (result i32)))
+//	  (import "host" "ws_poll" (func $ws_poll (param i32 i32) (result i32)))
+//	  (import "host" "join" (func $join (param i32 i32)))
+//	  (import "host" "subtask_drop" (func $subtask_drop (param i32)))
+//	  (import "host" "mem" (memory 1))
+//	  (func (export "run") (param $x i32) (result i32)
+//	    (local $st i32)
+//	    ;; Call the host with x, storing the result at 8. It must not finish
+//	    ;; before the call returns.
+//	    (local.set $st (call $lower (local.get $x) (i32.const 8)))
+//	    (if (i32.ne (i32.and (local.get $st) (i32.const 15)) (i32.const 1))
+//	      (then unreachable))
+//	    ;; Wait for the subtask in a new waitable set, kept at 20.
+//	    (i32.store (i32.const 20) (call $ws_new))
+//	    (call $join (i32.shr_u (local.get $st) (i32.const 4)) (i32.load (i32.const 20)))
+//	    (if (i32.ne (call $ws_wait (i32.load (i32.const 20)) (i32.const 24)) (i32.const 1))
+//	      (then unreachable))
+//	    (if (i32.ne (i32.load (i32.const 24)) (i32.shr_u (local.get $st) (i32.const 4)))
+//	      (then unreachable))
+//	    (if (i32.ne (i32.load (i32.const 28)) (i32.const 2))
+//	      (then unreachable))
+//	    (call $subtask_drop (i32.load (i32.const 24)))
+//	    ;; The set is empty once the subtask is dropped.
+//	    (if (call $ws_poll (i32.load (i32.const 20)) (i32.const 24))
+//	      (then unreachable))
+//	    ;; Call the host again with its first result, storing the result at
+//	    ;; 12, and let the callback wait for it.
+//	    (local.set $st (call $lower (i32.load (i32.const 8)) (i32.const 12)))
+//	    (if (i32.ne (i32.and (local.get $st) (i32.const 15)) (i32.const 1))
+//	      (then unreachable))
+//	    (call $join (i32.shr_u (local.get $st) (i32.const 4)) (i32.load (i32.const 20)))
+//	    ;; WAIT on the set.
+//	    (i32.or (i32.shl (i32.load (i32.const 20)) (i32.const 4)) (i32.const 2)))
+//	  (func (export "callback") (param $code i32) (param $index i32) (param $payload i32) (result i32)
+//	    ;; The event must be the return of the subtask.
+//	    (if (i32.ne (local.get $code) (i32.const 1))
+//	      (then unreachable))
+//	    (if (i32.ne (local.get $payload) (i32.const 2))
+//	      (then unreachable))
+//	    (call $subtask_drop (local.get $index))
+//	    (call $task_return (i32.load (i32.const 12)))
+//	    ;; EXIT
+//	    (i32.const 0)))
+var asyncCoreModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	0x01, 0x20, 0x06, 0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7f, 0x60, 0x01, 0x7f, 0x00, 0x60, 0x00, 0x01,
+	0x7f, 0x60, 0x02, 0x7f, 0x7f, 0x00, 0x60, 0x01, 0x7f, 0x01, 0x7f, 0x60, 0x03, 0x7f, 0x7f, 0x7f,
+	0x01, 0x7f,
+	0x02, 0x79, 0x08, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x05, 0x6c, 0x6f, 0x77, 0x65, 0x72, 0x00, 0x00,
+	0x04, 0x68, 0x6f, 0x73, 0x74, 0x0b, 0x74, 0x61, 0x73, 0x6b, 0x5f, 0x72, 0x65, 0x74, 0x75, 0x72,
+	0x6e, 0x00, 0x01, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x06, 0x77, 0x73, 0x5f, 0x6e, 0x65, 0x77, 0x00,
+	0x02, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x07, 0x77, 0x73, 0x5f, 0x77, 0x61, 0x69, 0x74, 0x00, 0x00,
+	0x04, 0x68, 0x6f, 0x73, 0x74, 0x07, 0x77, 0x73, 0x5f, 0x70, 0x6f, 0x6c, 0x6c, 0x00, 0x00, 0x04,
+	0x68, 0x6f, 0x73, 0x74, 0x04, 0x6a, 0x6f, 0x69, 0x6e, 0x00, 0x03, 0x04, 0x68, 0x6f, 0x73, 0x74,
+	0x0c, 0x73, 0x75, 0x62, 0x74, 0x61, 0x73, 0x6b, 0x5f, 0x64, 0x72, 0x6f, 0x70, 0x00, 0x01, 0x04,
+	0x68, 0x6f, 0x73, 0x74, 0x03, 0x6d, 0x65, 0x6d, 0x02, 0x00, 0x01,
+	0x03, 0x03, 0x02, 0x04, 0x05,
+	0x07, 0x12, 0x02, 0x03, 0x72, 0x75, 0x6e, 0x00, 0x07, 0x08, 0x63, 0x61, 0x6c, 0x6c, 0x62, 0x61,
+	0x63, 0x6b, 0x00, 0x08,
+	0x0a, 0xbd, 0x01, 0x02,
+	0x98, 0x01, 0x01, 0x01, 0x7f, 0x20, 0x00, 0x41, 0x08, 0x10, 0x00, 0x21, 0x01, 0x20, 0x01, 0x41,
+	0x0f, 0x71, 0x41, 0x01, 0x47, 0x04, 0x40, 0x00, 0x0b, 0x41, 0x14, 0x10, 0x02, 0x36, 0x02, 0x00,
+	0x20, 0x01, 0x41, 0x04, 0x76, 0x41, 0x14, 0x28, 0x02, 0x00, 0x10, 0x05, 0x41, 0x14, 0x28, 0x02,
+	0x00, 0x41, 0x18, 0x10, 0x03, 0x41, 0x01, 0x47, 0x04, 0x40, 0x00, 0x0b, 0x41, 0x18, 0x28, 0x02,
+	0x00, 0x20, 0x01, 0x41, 0x04, 0x76, 0x47, 0x04, 0x40, 0x00, 0x0b, 0x41, 0x1c, 0x28, 0x02, 0x00,
+	0x41, 0x02, 0x47, 0x04, 0x40, 0x00, 0x0b, 0x41, 0x18, 0x28, 0x02, 0x00, 0x10, 0x06, 0x41, 0x14,
+	0x28, 0x02, 0x00, 0x41, 0x18, 0x10, 0x04, 0x04, 0x40, 0x00, 0x0b, 0x41, 0x08, 0x28, 0x02, 0x00,
+	0x41, 0x0c, 0x10, 0x00, 0x21, 0x01, 0x20, 0x01, 0x41, 0x0f, 0x71, 0x41, 0x01, 0x47, 0x04, 0x40,
+	0x00, 0x0b, 0x20, 0x01, 0x41, 0x04, 0x76, 0x41, 0x14, 0x28, 0x02, 0x00, 0x10, 0x05, 0x41, 0x14,
+	0x28, 0x02, 0x00, 0x41, 0x04, 0x74, 0x41, 0x02, 0x72, 0x0b,
+	0x21, 0x00, 0x20, 0x00, 0x41, 0x01, 0x47, 0x04, 0x40, 0x00, 0x0b, 0x20, 0x02, 0x41, 0x02, 0x47,
+	0x04, 0x40, 0x00, 0x0b, 0x20, 0x01, 0x10, 0x06, 0x41, 0x0c, 0x28, 0x02, 0x00, 0x10, 0x01, 0x41,
+	0x00, 0x0b,
+}
+
+// newAsyncComponent builds a component that lifts the "run" function of
+// asyncCoreModule with the async ABI and a callback, and lowers its "double"
+// import with the async ABI.
+func newAsyncComponent(t *testing.T) *Component {
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, NewRuntimeConfig())
+	t.Cleanup(func() { runtime.Close(ctx) })
+	coreFunc := func(idx uint32) ast.CoreSortIdx {
+		return ast.CoreSortIdx{Sort: ast.CoreSortFunc, Idx: idx}
+	}
+	comp, err := NewBuilder(runtime).Build(ctx, &ast.Component{
+		Definitions: []ast.Definition{
+			&ast.Type{DefType: &ast.FuncType{
+				Params:  []ast.FuncParam{{Label: "x", Type: &ast.U32Type{}}},
+				Results: &ast.U32Type{},
+			}},
+			&ast.Import{ImportName: "double", Desc: &ast.SortExternDesc{Sort: ast.SortFunc, TypeIdx: 0}},
+			&ast.CoreModule{Raw: asyncMemoryModule},
+			&ast.CoreInstance{Expr: &ast.CoreInstantiate{ModuleIdx: 0}},
+			&ast.Alias{Sort: ast.SortCoreMemory, Target: &ast.CoreExportAlias{InstanceIdx: 0, Name: "mem"}},
+			&ast.Canon{Def: &ast.CanonLower{FuncIdx: 0, Options: []ast.CanonOpt{&ast.AsyncOpt{}, &ast.MemoryOpt{MemoryIdx: 0}}}},
+			&ast.Canon{Def: &ast.CanonTaskReturn{Result: &ast.U32Type{}}},
+			&ast.Canon{Def: &ast.CanonWaitableSetNew{}},
+			&ast.Canon{Def: &ast.CanonWaitableSetWait{MemoryIdx: 0}},
+			&ast.Canon{Def: &ast.CanonWaitableSetPoll{MemoryIdx: 0}},
+			&ast.Canon{Def: &ast.CanonWaitableJoin{}},
+			&ast.Canon{Def: &ast.CanonSubtaskDrop{}},
+			&ast.CoreInstance{Expr: &ast.CoreInlineExports{Exports: []ast.CoreInlineExport{
+				{Name: "lower", SortIdx: coreFunc(0)},
+				{Name: "task_return", SortIdx: coreFunc(1)},
+				{Name: "ws_new", SortIdx: coreFunc(2)},
+				{Name: "ws_wait", SortIdx: coreFunc(3)},
+				{Name: "ws_poll", SortIdx: coreFunc(4)},
+				{Name: "join", SortIdx: coreFunc(5)},
+				{Name: "subtask_drop", SortIdx: coreFunc(6)},
+				{Name: "mem", SortIdx: ast.CoreSortIdx{Sort: ast.CoreSortMemory, Idx: 0}},
+			}}},
+			&ast.CoreModule{Raw: asyncCoreModule},
+			&ast.CoreInstance{Expr: &ast.CoreInstantiate{ModuleIdx: 1, Args: []ast.CoreInstantiateArg{
+				{Name: "host", CoreInstanceIdx: 1},
+			}}},
+			&ast.Alias{Sort: ast.SortCoreFunc, Target: &ast.CoreExportAlias{InstanceIdx: 2, Name: "run"}},
+			&ast.Alias{Sort: ast.SortCoreFunc, Target: &ast.CoreExportAlias{InstanceIdx: 2, Name: "callback"}},
+			&ast.Canon{Def: &ast.CanonLift{
+				CoreFuncIdx:     7,
+				Options:         []ast.CanonOpt{&ast.AsyncOpt{}, &ast.CallbackOpt{FuncIdx: 8}},
+				FunctionTypeIdx: 0,
+			}},
+			&ast.Export{ExportName: "run", SortIdx: ast.SortIdx{Sort: ast.SortFunc, Idx: 1}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to build component: %v", err)
+	}
+	return comp
+}
+
+func TestAsyncLiftAndLower(t *testing.T) {
+	ctx := context.Background()
+	var calls atomic.Int32
+	double := NewAsyncFunction(&FunctionType{
+		Parameters: []*FunctionParameter{{Name: "x", Type: U32Type{}}},
+		ResultType: U32Type{},
+	}, func(ctx context.Context, params []Value) (Value, error) {
+		calls.Add(1)
+		return params[0].(U32) * 2, nil
+	})
+
+	inst, err := newAsyncComponent(t).Instantiate(ctx, map[string]any{"double": double})
+	if err != nil {
+		t.Fatalf("Instantiate failed: %v", err)
+	}
+	defer inst.Close(ctx)
+	run, ok := inst.Export("run")
+	if !ok {
+		t.Fatal("run is not exported")
+	}
+	result, err := run.(*Function).Invoke(ctx, U32(5))
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if result != U32(20) {
+		t.Errorf("run(5) = %v, want 20", result)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("double called %d times, want 2", n)
+	}
+
+	// Every subtask was dropped, so nothing is left in the waitables table.
+	inst.mu.Lock()
+	_, found := inst.waitables.lookup(1)
+	inst.mu.Unlock()
+	if found {
+		t.Error("a subtask is left in the waitables table")
+	}
+}
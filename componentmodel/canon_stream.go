@@ -0,0 +1,666 @@
+package componentmodel
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/partite-ai/wacogo/ast"
+	"github.com/tetratelabs/wazero/api"
+)
+
+const copyResultBlocked = 0xffffffff
+
+type copyStatus uint32
+
+const (
+	copyCompleted copyStatus = iota
+	copyDropped
+	copyCancelled
+)
+
+// copyEnd is the part of a stream or future end that tracks a read or write
+// in progress.
+type copyEnd struct {
+	waitableState
+	copying *pendingCopy
+}
+
+type pendingCopy struct {
+	cancel    chan struct{}
+	cancelled bool
+}
+
+// copyOp is a read or write on a stream or future end.
+type copyOp struct {
+	// try performs as much of the copy as it can without blocking, returning
+	// the result to report to the guest.
+	try func() (result uint32, done bool, err error)
+	// ready returns nil if try can make progress, and otherwise a channel that
+	// is closed when that may have changed.
+	ready func() <-chan struct{}
+}
+
+type streamEnd struct {
+	copyEnd
+	stream   *streamState
+	writable bool
+}
+
+type futureEnd struct {
+	copyEnd
+	future   *futureState
+	writable bool
+}
+
+// runCopy runs op on end. A copy that can't complete right away blocks the
+// task, or for an async copy, returns copyResultBlocked and reports the result
+// with an event once it completes.
+func (i *Instance) runCopy(ctx context.Context, end *copyEnd, idx uint32, async bool, code eventCode, op copyOp) (uint32, error) {
+	i.mu.Lock()
+	busy := end.copying != nil
+	i.mu.Unlock()
+	if busy {
+		return 0, fmt.Errorf("a copy is already in progress")
+	}
+
+	result, done, err := op.try()
+	if err != nil || done {
+		return result, err
+	}
+
+	if !async {
+		for {
+			if ready := op.ready(); ready != nil {
+				resume := i.suspend()
+				err := waitForChange(ctx, ready)
+				resume()
+				if err != nil {
+					return 0, err
+				}
+				continue
+			}
+			result, done, err := op.try()
+			if err != nil || done {
+				return result, err
+			}
+		}
+	}
+
+	cp := &pendingCopy{cancel: make(chan struct{})}
+	i.mu.Lock()
+	end.copying = cp
+	i.mu.Unlock()
+
+	go func() {
+		for ready := op.ready(); ready != nil; ready = op.ready() {
+			select {
+			case <-ready:
+			case <-cp.cancel:
+				return
+			}
+		}
+
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		if cp.cancelled {
+			return
+		}
+		e := &event{code: code, index: idx}
+		e.deliver = func() error {
+			i.mu.Lock()
+			end.copying = nil
+			i.mu.Unlock()
+			result, done, err := op.try()
+			if err != nil {
+				return err
+			}
+			if !done {
+				result = uint32(copyCompleted)
+			}
+			e.payload = result
+			return nil
+		}
+		end.setEvent(e)
+	}()
+	return copyResultBlocked, nil
+}
+
+// cancelCopy stops the copy in progress on end. A copy that already completed
+// reports its result instead of being cancelled.
+func (i *Instance) cancelCopy(end *copyEnd) (uint32, error) {
+	i.mu.Lock()
+	cp := end.copying
+	if cp == nil {
+		i.mu.Unlock()
+		return 0, fmt.Errorf("no copy is in progress")
+	}
+	if e := end.pending; e != nil {
+		end.pending = nil
+		i.mu.Unlock()
+		if err := e.deliver(); err != nil {
+			return 0, err
+		}
+		return e.payload, nil
+	}
+	cp.cancelled = true
+	close(cp.cancel)
+	end.copying = nil
+	i.mu.Unlock()
+	return uint32(copyCancelled), nil
+}
+
+func copyResult(status copyStatus, count int) uint32 {
+	return uint32(status) | uint32(count)<<4
+}
+
+func lookupStreamEnd(inst *Instance, idx uint32, writable bool) (*streamEnd, error) {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	w, ok := inst.waitables.lookup(idx)
+	if !ok {
+		return nil, fmt.Errorf("unknown handle index %d", idx)
+	}
+	end, ok := w.(*streamEnd)
+	if !ok || end.writable != writable {
+		return nil, fmt.Errorf("handle %d is not the %s end of a stream", idx, endName(writable))
+	}
+	return end, nil
+}
+
+func lookupFutureEnd(inst *Instance, idx uint32, writable bool) (*futureEnd, error) {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	w, ok := inst.waitables.lookup(idx)
+	if !ok {
+		return nil, fmt.Errorf("unknown handle index %d", idx)
+	}
+	end, ok := w.(*futureEnd)
+	if !ok || end.writable != writable {
+		return nil, fmt.Errorf("handle %d is not the %s end of a future", idx, endName(writable))
+	}
+	return end, nil
+}
+
+func endName(writable bool) string {
+	if writable {
+		return "writable"
+	}
+	return "readable"
+}
+
+// takeStreamEnd removes the readable end of a stream from the instance, to
+// pass it on.
+func takeStreamEnd(inst *Instance, idx uint32) (*streamEnd, error) {
+	end, err := lookupStreamEnd(inst, idx, false)
+	if err != nil {
+		return nil, err
+	}
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	if end.copying != nil {
+		return nil, fmt.Errorf("cannot pass on a stream while a read is in progress")
+	}
+	end.join(nil)
+	inst.waitables.remove(idx)
+	return end, nil
+}
+
+// takeFutureEnd removes the readable end of a future from the instance, to
+// pass it on.
+func takeFutureEnd(inst *Instance, idx uint32) (*futureEnd, error) {
+	end, err := lookupFutureEnd(inst, idx, false)
+	if err != nil {
+		return nil, err
+	}
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	if end.copying != nil {
+		return nil, fmt.Errorf("cannot pass on a future while a read is in progress")
+	}
+	end.join(nil)
+	inst.waitables.remove(idx)
+	return end, nil
+}
+
+func resolveStreamType(scope *scope, typeIdx uint32) (*StreamType, error) {
+	typ, err := sortScopeFor(scope, sortType).getType(typeIdx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve stream type: %w", err)
+	}
+	st, ok := typ.(*StreamType)
+	if !ok {
+		return nil, fmt.Errorf("type %d is not a stream type", typeIdx)
+	}
+	return st, nil
+}
+
+func resolveFutureType(scope *scope, typeIdx uint32) (*FutureType, error) {
+	typ, err := sortScopeFor(scope, sortType).getType(typeIdx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve future type: %w", err)
+	}
+	ft, ok := typ.(*FutureType)
+	if !ok {
+		return nil, fmt.Errorf("type %d is not a future type", typeIdx)
+	}
+	return ft, nil
+}
+
+func canonStreamNew(id uint32, astDef *ast.CanonStreamNew) (definition[*coreFunction, *coreFunctionType], error) {
+	return &coreFunctionBuiltinDefinition{
+		id:      fmt.Sprintf("canon_stream_new_%d", id),
+		results: []api.ValueType{api.ValueTypeI64},
+		validate: func(scope *scope) error {
+			_, err := resolveStreamType(scope, astDef.TypeIdx)
+			return err
+		},
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			st, err := resolveStreamType(scope, astDef.TypeIdx)
+			if err != nil {
+				return nil, err
+			}
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				inst := scope.instance
+				state := newStreamState(st.ElementType)
+				inst.mu.Lock()
+				defer inst.mu.Unlock()
+				readIdx, writeIdx, err := addWaitablePair(inst, &streamEnd{stream: state}, &streamEnd{stream: state, writable: true})
+				if err != nil {
+					panic(fmt.Errorf("canon stream.new failed: %w", err))
+				}
+				stack[0] = uint64(readIdx) | uint64(writeIdx)<<32
+			}, nil
+		},
+	}, nil
+}
+
+func canonStreamRead(id uint32, astDef *ast.CanonStreamRead) (definition[*coreFunction, *coreFunctionType], error) {
+	return newStreamCopyDefinition(fmt.Sprintf("canon_stream_read_%d", id), astDef.TypeIdx, astDef.Options, false), nil
+}
+
+func canonStreamWrite(id uint32, astDef *ast.CanonStreamWrite) (definition[*coreFunction, *coreFunctionType], error) {
+	return newStreamCopyDefinition(fmt.Sprintf("canon_stream_write_%d", id), astDef.TypeIdx, astDef.Options, true), nil
+}
+
+func newStreamCopyDefinition(id string, typeIdx uint32, opts []ast.CanonOpt, write bool) *coreFunctionBuiltinDefinition {
+	return &coreFunctionBuiltinDefinition{
+		id:      id,
+		params:  i32s(3),
+		results: i32s(1),
+		validate: func(scope *scope) error {
+			_, err := resolveStreamType(scope, typeIdx)
+			return err
+		},
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			st, err := resolveStreamType(scope, typeIdx)
+			if err != nil {
+				return nil, err
+			}
+			elemType := st.ElementType
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				inst := scope.instance
+				idx, ptr, n := uint32(stack[0]), uint32(stack[1]), int(uint32(stack[2]))
+				end, err := lookupStreamEnd(inst, idx, write)
+				if err != nil {
+					panic(err)
+				}
+				if err := checkOptionalTypesCompatible(elemType, end.stream.elementType, newTypeChecker()); err != nil {
+					panic(fmt.Errorf("stream element type mismatch: %w", err))
+				}
+				llc, err := newLiftLoadContext(ctx, opts, scope)
+				if err != nil {
+					panic(fmt.Errorf("failed to create lift/load context for stream copy: %w", err))
+				}
+				if elemType != nil && ptr != alignTo(ptr, elemType.alignment()) {
+					panic(fmt.Errorf("unaligned pointer for stream copy"))
+				}
+
+				op, code := streamReadOp(llc, end.stream, elemType, ptr, n), eventStreamRead
+				if write {
+					op, code = streamWriteOp(llc, end.stream, elemType, ptr, n), eventStreamWrite
+				}
+				result, err := inst.runCopy(ctx, &end.copyEnd, idx, llc.async, code, op)
+				if err != nil {
+					panic(err)
+				}
+				stack[0] = uint64(result)
+			}, nil
+		},
+	}
+}
+
+func streamReadOp(llc *LiftLoadContext, state *streamState, elemType ValueType, ptr uint32, n int) copyOp {
+	return copyOp{
+		try: func() (uint32, bool, error) {
+			if n == 0 {
+				return copyResult(copyCompleted, 0), true, nil
+			}
+			vals, err := state.read(n)
+			if err == io.EOF {
+				return copyResult(copyDropped, 0), true, nil
+			}
+			if len(vals) == 0 {
+				return 0, false, nil
+			}
+			if elemType != nil {
+				defer llc.instance.preventLeave()()
+				for i, v := range vals {
+					if err := elemType.store(llc, ptr+uint32(i)*elemType.elementSize(), v); err != nil {
+						return 0, false, fmt.Errorf("failed to store stream element %d: %w", i, err)
+					}
+				}
+			}
+			return copyResult(copyCompleted, len(vals)), true, nil
+		},
+		ready: state.readReady,
+	}
+}
+
+func streamWriteOp(llc *LiftLoadContext, state *streamState, elemType ValueType, ptr uint32, n int) copyOp {
+	return copyOp{
+		try: func() (uint32, bool, error) {
+			space, err := state.writeSpace()
+			if err != nil {
+				return copyResult(copyDropped, 0), true, nil
+			}
+			if n == 0 {
+				return copyResult(copyCompleted, 0), true, nil
+			}
+			if space == 0 {
+				return 0, false, nil
+			}
+			count := min(space, n)
+			vals := make([]Value, count)
+			if elemType != nil {
+				for i := range vals {
+					v, err := elemType.load(llc, ptr+uint32(i)*elemType.elementSize())
+					if err != nil {
+						return 0, false, fmt.Errorf("failed to load stream element %d: %w", i, err)
+					}
+					vals[i] = v
+				}
+			}
+			state.write(vals)
+			return copyResult(copyCompleted, count), true, nil
+		},
+		ready: state.writeReady,
+	}
+}
+
+func canonStreamCancel(id uint32, typeIdx uint32, write bool) (definition[*coreFunction, *coreFunctionType], error) {
+	name := "read"
+	if write {
+		name = "write"
+	}
+	return &coreFunctionBuiltinDefinition{
+		id:      fmt.Sprintf("canon_stream_cancel_%s_%d", name, id),
+		params:  i32s(1),
+		results: i32s(1),
+		validate: func(scope *scope) error {
+			_, err := resolveStreamType(scope, typeIdx)
+			return err
+		},
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				end, err := lookupStreamEnd(scope.instance, uint32(stack[0]), write)
+				if err != nil {
+					panic(err)
+				}
+				result, err := scope.instance.cancelCopy(&end.copyEnd)
+				if err != nil {
+					panic(fmt.Errorf("cannot cancel stream %s: %w", name, err))
+				}
+				stack[0] = uint64(result)
+			}, nil
+		},
+	}, nil
+}
+
+func canonStreamDrop(id uint32, typeIdx uint32, writable bool) (definition[*coreFunction, *coreFunctionType], error) {
+	return &coreFunctionBuiltinDefinition{
+		id:     fmt.Sprintf("canon_stream_drop_%s_%d", endName(writable), id),
+		params: i32s(1),
+		validate: func(scope *scope) error {
+			_, err := resolveStreamType(scope, typeIdx)
+			return err
+		},
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				inst := scope.instance
+				idx := uint32(stack[0])
+				end, err := lookupStreamEnd(inst, idx, writable)
+				if err != nil {
+					panic(err)
+				}
+				inst.mu.Lock()
+				if end.copying != nil {
+					inst.mu.Unlock()
+					panic(fmt.Errorf("cannot drop a stream while a copy is in progress"))
+				}
+				end.join(nil)
+				inst.waitables.remove(idx)
+				inst.mu.Unlock()
+				if writable {
+					end.stream.dropWriter()
+				} else {
+					end.stream.dropReader()
+				}
+			}, nil
+		},
+	}, nil
+}
+
+func canonFutureNew(id uint32, astDef *ast.CanonFutureNew) (definition[*coreFunction, *coreFunctionType], error) {
+	return &coreFunctionBuiltinDefinition{
+		id:      fmt.Sprintf("canon_future_new_%d", id),
+		results: []api.ValueType{api.ValueTypeI64},
+		validate: func(scope *scope) error {
+			_, err := resolveFutureType(scope, astDef.TypeIdx)
+			return err
+		},
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			ft, err := resolveFutureType(scope, astDef.TypeIdx)
+			if err != nil {
+				return nil, err
+			}
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				inst := scope.instance
+				state := newFutureState(ft.ValueType)
+				inst.mu.Lock()
+				defer inst.mu.Unlock()
+				readIdx, writeIdx, err := addWaitablePair(inst, &futureEnd{future: state}, &futureEnd{future: state, writable: true})
+				if err != nil {
+					panic(fmt.Errorf("canon future.new failed: %w", err))
+				}
+				stack[0] = uint64(readIdx) | uint64(writeIdx)<<32
+			}, nil
+		},
+	}, nil
+}
+
+func canonFutureRead(id uint32, astDef *ast.CanonFutureRead) (definition[*coreFunction, *coreFunctionType], error) {
+	return newFutureCopyDefinition(fmt.Sprintf("canon_future_read_%d", id), astDef.TypeIdx, astDef.Options, false), nil
+}
+
+func canonFutureWrite(id uint32, astDef *ast.CanonFutureWrite) (definition[*coreFunction, *coreFunctionType], error) {
+	return newFutureCopyDefinition(fmt.Sprintf("canon_future_write_%d", id), astDef.TypeIdx, astDef.Options, true), nil
+}
+
+func newFutureCopyDefinition(id string, typeIdx uint32, opts []ast.CanonOpt, write bool) *coreFunctionBuiltinDefinition {
+	return &coreFunctionBuiltinDefinition{
+		id:      id,
+		params:  i32s(2),
+		results: i32s(1),
+		validate: func(scope *scope) error {
+			_, err := resolveFutureType(scope, typeIdx)
+			return err
+		},
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			ft, err := resolveFutureType(scope, typeIdx)
+			if err != nil {
+				return nil, err
+			}
+			valueType := ft.ValueType
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				inst := scope.instance
+				idx, ptr := uint32(stack[0]), uint32(stack[1])
+				end, err := lookupFutureEnd(inst, idx, write)
+				if err != nil {
+					panic(err)
+				}
+				if err := checkOptionalTypesCompatible(valueType, end.future.valueType, newTypeChecker()); err != nil {
+					panic(fmt.Errorf("future value type mismatch: %w", err))
+				}
+				llc, err := newLiftLoadContext(ctx, opts, scope)
+				if err != nil {
+					panic(fmt.Errorf("failed to create lift/load context for future copy: %w", err))
+				}
+				if valueType != nil && ptr != alignTo(ptr, valueType.alignment()) {
+					panic(fmt.Errorf("unaligned pointer for future copy"))
+				}
+
+				op, code := futureReadOp(llc, end.future, valueType, ptr), eventFutureRead
+				if write {
+					op, code = futureWriteOp(llc, end.future, valueType, ptr), eventFutureWrite
+				}
+				result, err := inst.runCopy(ctx, &end.copyEnd, idx, llc.async, code, op)
+				if err != nil {
+					panic(err)
+				}
+				stack[0] = uint64(result)
+			}, nil
+		},
+	}
+}
+
+func futureReadOp(llc *LiftLoadContext, state *futureState, valueType ValueType, ptr uint32) copyOp {
+	return copyOp{
+		try: func() (uint32, bool, error) {
+			v, ok, err := state.read()
+			if !ok {
+				return 0, false, nil
+			}
+			if err == io.EOF {
+				return uint32(copyDropped), true, nil
+			}
+			if err != nil {
+				return 0, false, err
+			}
+			if valueType != nil {
+				defer llc.instance.preventLeave()()
+				if err := valueType.store(llc, ptr, v); err != nil {
+					return 0, false, fmt.Errorf("failed to store future value: %w", err)
+				}
+			}
+			return uint32(copyCompleted), true, nil
+		},
+		ready: state.readReady,
+	}
+}
+
+func futureWriteOp(llc *LiftLoadContext, state *futureState, valueType ValueType, ptr uint32) copyOp {
+	return copyOp{
+		try: func() (uint32, bool, error) {
+			state.mu.Lock()
+			readDropped := state.readDropped
+			state.mu.Unlock()
+			if readDropped {
+				return uint32(copyDropped), true, nil
+			}
+			var v Value
+			if valueType != nil {
+				var err error
+				v, err = valueType.load(llc, ptr)
+				if err != nil {
+					return 0, false, fmt.Errorf("failed to load future value: %w", err)
+				}
+			}
+			if err := state.write(v); err == errStreamClosed {
+				return uint32(copyDropped), true, nil
+			} else if err != nil {
+				return 0, false, err
+			}
+			return uint32(copyCompleted), true, nil
+		},
+		ready: func() <-chan struct{} { return nil },
+	}
+}
+
+func canonFutureCancel(id uint32, typeIdx uint32, write bool) (definition[*coreFunction, *coreFunctionType], error) {
+	name := "read"
+	if write {
+		name = "write"
+	}
+	return &coreFunctionBuiltinDefinition{
+		id:      fmt.Sprintf("canon_future_cancel_%s_%d", name, id),
+		params:  i32s(1),
+		results: i32s(1),
+		validate: func(scope *scope) error {
+			_, err := resolveFutureType(scope, typeIdx)
+			return err
+		},
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				end, err := lookupFutureEnd(scope.instance, uint32(stack[0]), write)
+				if err != nil {
+					panic(err)
+				}
+				result, err := scope.instance.cancelCopy(&end.copyEnd)
+				if err != nil {
+					panic(fmt.Errorf("cannot cancel future %s: %w", name, err))
+				}
+				stack[0] = uint64(result)
+			}, nil
+		},
+	}, nil
+}
+
+func canonFutureDrop(id uint32, typeIdx uint32, writable bool) (definition[*coreFunction, *coreFunctionType], error) {
+	return &coreFunctionBuiltinDefinition{
+		id:     fmt.Sprintf("canon_future_drop_%s_%d", endName(writable), id),
+		params: i32s(1),
+		validate: func(scope *scope) error {
+			_, err := resolveFutureType(scope, typeIdx)
+			return err
+		},
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				inst := scope.instance
+				idx := uint32(stack[0])
+				end, err := lookupFutureEnd(inst, idx, writable)
+				if err != nil {
+					panic(err)
+				}
+				inst.mu.Lock()
+				if end.copying != nil {
+					inst.mu.Unlock()
+					panic(fmt.Errorf("cannot drop a future while a copy is in progress"))
+				}
+				end.join(nil)
+				inst.waitables.remove(idx)
+				inst.mu.Unlock()
+				if writable {
+					end.future.dropWriter()
+				} else {
+					end.future.dropReader()
+				}
+			}, nil
+		},
+	}, nil
+}
+
+// addWaitablePair adds both ends of a new stream or future to the waitables of
+// inst, which must be locked.
+func addWaitablePair(inst *Instance, readEnd, writeEnd waitable) (uint32, uint32, error) {
+	readIdx, err := inst.waitables.add(readEnd)
+	if err != nil {
+		return 0, 0, err
+	}
+	writeIdx, err := inst.waitables.add(writeEnd)
+	if err != nil {
+		inst.waitables.remove(readIdx)
+		return 0, 0, err
+	}
+	return readIdx, writeIdx, nil
+}
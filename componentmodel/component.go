@@ -13,9 +13,12 @@ type Component struct {
 	componentScope *scope
 	importTypes    map[string]typeResolver
 	exports        map[string]componentExport
+	limits         Limits
+	importExterns  []Extern
+	exportExterns  []Extern
 }
 
-func newComponent(id string, runtime wazero.Runtime, definitions *definitions, scope *scope, imports map[string]typeResolver, exports map[string]componentExport) (*Component, error) {
+func newComponent(id string, runtime wazero.Runtime, limits Limits, definitions *definitions, scope *scope, imports map[string]typeResolver, exports map[string]componentExport) (*Component, error) {
 	typeScope := sortScopeFor(scope, sortType)
 	for idx := range typeScope.items {
 		t, err := typeScope.getType(uint32(idx))
@@ -33,10 +36,70 @@ func newComponent(id string, runtime wazero.Runtime, definitions *definitions, s
 		exports:        exports,
 		importTypes:    imports,
 		componentScope: scope,
+		limits:         limits,
 	}, nil
 }
 
 func (c *Component) Instantiate(ctx context.Context, args map[string]any) (*Instance, error) {
+	instanceArgs, err := newInstanceArguments(args)
+	if err != nil {
+		return nil, err
+	}
+	return c.instantiate(ctx, instanceArgs, nil)
+}
+
+// Prepare links the component against args and type checks it once, returning
+// an InstancePre that creates instances with those imports.
+func (c *Component) Prepare(ctx context.Context, args map[string]any) (*InstancePre, error) {
+	instanceArgs, err := newInstanceArguments(args)
+	if err != nil {
+		return nil, err
+	}
+	typeScope := c.componentScope.instanceScope(nil, instanceArgs)
+	types := make([]Type, len(c.definitions.binders))
+	for i, binder := range c.definitions.binders {
+		if err := binder.bindType(typeScope); err != nil {
+			return nil, err
+		}
+		b, ok := binder.(typedBinder)
+		if !ok {
+			continue
+		}
+		typ, err := b.boundType(typeScope)
+		if err != nil {
+			return nil, err
+		}
+		if isInstanceIndependent(typ) {
+			types[i] = typ
+		}
+	}
+	return &InstancePre{component: c, args: instanceArgs, types: types}, nil
+}
+
+// isInstanceIndependent reports whether a type resolved by Prepare can be
+// shared by every instance. Resources defined by the component belong to a
+// single instance, and component types carry a scope of their own, so types
+// containing either are resolved again for each instance.
+func isInstanceIndependent(t Type) bool {
+	independent := true
+	walkTypes(t, func(t Type) error {
+		switch t := t.(type) {
+		case *ResourceType:
+			if t.instance == nil || t.instance == resourceTypeBoundMarker {
+				independent = false
+			}
+		case *ComponentType:
+			independent = false
+		}
+		if !independent {
+			return errSkipChildren
+		}
+		return nil
+	})
+	return independent
+}
+
+func newInstanceArguments(args map[string]any) (map[string]*instanceArgument, error) {
 	instanceArgs := make(map[string]*instanceArgument, len(args))
 	for name, val := range args {
 		switch v := val.(type) {
@@ -44,25 +107,65 @@ func (c *Component) Instantiate(ctx context.Context, args map[string]any) (*Inst
 			instanceArgs[name] = &instanceArgument{val: v, typ: newInstanceType(v.exportSpecs, nil)}
 		case *Function:
 			instanceArgs[name] = &instanceArgument{val: v, typ: v.funcTyp}
+		case Value:
+			// Values don't carry their type, so a value argument takes the
+			// type of the import and is checked against it when bound.
+			instanceArgs[name] = &instanceArgument{val: v, typ: importPlaceholderType{}}
 		default:
 			return nil, fmt.Errorf("unsupported argument type for %s: %T", name, val)
 		}
 	}
-	return c.instantiate(ctx, instanceArgs)
+	return instanceArgs, nil
 }
 
-func (c *Component) instantiate(ctx context.Context, args map[string]*instanceArgument) (*Instance, error) {
+// InstancePre is a component that has been linked against a fixed set of
+// imports. It can be instantiated any number of times, concurrently.
+type InstancePre struct {
+	component *Component
+	args      map[string]*instanceArgument
+	// types holds the type of each definition that is the same in every
+	// instance, indexed like the definition binders, and nil for the rest.
+	types []Type
+}
+
+// Instantiate creates a new instance of the prepared component.
+func (p *InstancePre) Instantiate(ctx context.Context) (*Instance, error) {
+	return p.component.instantiate(ctx, p.args, p.types)
+}
+
+// instantiate creates an instance of the component. types holds the types
+// bound by Prepare, if any; see InstancePre.
+func (c *Component) instantiate(ctx context.Context, args map[string]*instanceArgument, types []Type) (*Instance, error) {
 	instance := newInstance()
+	instance.setLimits(c.limits)
 	instanceScope := c.componentScope.instanceScope(instance, args)
 
 	instance.enter(ctx)
 	defer instance.exit()
 
-	for _, def := range c.definitions.binders {
-		if err := def.bindInstance(ctx, instanceScope); err != nil {
+	// Start functions and core module start sections run guest code.
+	stopInterrupt := instance.interruptOnDone(ctx)
+	for i, def := range c.definitions.binders {
+		var prepared Type
+		if types != nil {
+			prepared = types[i]
+		}
+		err := def.bindInstance(ctx, instanceScope, prepared)
+		if err == nil && ctx != nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			stopInterrupt()
+			err = instance.trapError(ctx, err)
+			// Release what was instantiated before the failure.
+			instance.close(context.WithoutCancel(ctx))
 			return nil, err
 		}
 	}
+	if !stopInterrupt() {
+		instance.close(context.WithoutCancel(ctx))
+		return nil, &InterruptedError{cause: ctx.Err()}
+	}
 
 	for exportName, export := range c.exports {
 		typ, err := export.typ(instanceScope)
@@ -99,6 +202,9 @@ func (c *Component) clone(newParentScope *scope) (*Component, error) {
 		componentScope: componentScope,
 		importTypes:    c.importTypes,
 		exports:        c.exports,
+		limits:         c.limits,
+		importExterns:  c.importExterns,
+		exportExterns:  c.exportExterns,
 	}, nil
 }
 
@@ -148,7 +254,7 @@ func newComponentDefinition(comp *Component) *componentDefinition {
 
 func (d *componentDefinition) isDefinition() {}
 
-func (d *componentDefinition) createType(scope *scope) (*componentType, error) {
+func (d *componentDefinition) createType(scope *scope) (*ComponentType, error) {
 	clone, err := d.comp.clone(scope)
 	if err != nil {
 		return nil, err
@@ -177,31 +283,31 @@ func (d *componentDefinition) createType(scope *scope) (*componentType, error) {
 }
 
 func (d *componentDefinition) createInstance(ctx context.Context, scope *scope) (*Component, error) {
-	ct := scope.currentType.(*componentType)
+	ct := scope.currentType.(*ComponentType)
 	return ct.component, nil
 }
 
-type componentType struct {
+type ComponentType struct {
 	imports   map[string]Type
 	exports   map[string]Type
 	component *Component
 }
 
-func newComponentType(imports map[string]Type, exports map[string]Type, component *Component) *componentType {
-	return &componentType{
+func newComponentType(imports map[string]Type, exports map[string]Type, component *Component) *ComponentType {
+	return &ComponentType{
 		imports:   imports,
 		exports:   exports,
 		component: component,
 	}
 }
 
-func (ct *componentType) isType() {}
+func (ct *ComponentType) isType() {}
 
-func (ct *componentType) typeName() string {
+func (ct *ComponentType) typeName() string {
 	return "component"
 }
 
-func (ct *componentType) instanceType(scope *scope, args map[string]Type) (*instanceType, error) {
+func (ct *ComponentType) instanceType(scope *scope, args map[string]Type) (*InstanceType, error) {
 	instantiateArgs := make(map[string]*instanceArgument)
 	for name, typ := range args {
 		instantiateArgs[name] = &instanceArgument{typ: typ}
@@ -224,7 +330,7 @@ func (ct *componentType) instanceType(scope *scope, args map[string]Type) (*inst
 	return newInstanceType(exportSpecs, nil), nil
 }
 
-func (ct *componentType) checkType(other Type, typeChecker typeChecker) error {
+func (ct *ComponentType) checkType(other Type, typeChecker typeChecker) error {
 	otherCt, err := assertTypeKindIsSame(ct, other)
 	if err != nil {
 		return err
@@ -254,7 +360,7 @@ func (ct *componentType) checkType(other Type, typeChecker typeChecker) error {
 	return nil
 }
 
-func (ct *componentType) typeSize() int {
+func (ct *ComponentType) typeSize() int {
 	size := 1
 	for _, importType := range ct.imports {
 		size += importType.typeSize()
@@ -265,7 +371,7 @@ func (ct *componentType) typeSize() int {
 	return size
 }
 
-func (ct *componentType) typeDepth() int {
+func (ct *ComponentType) typeDepth() int {
 	maxDepth := 0
 	for _, importType := range ct.imports {
 		if d := importType.typeDepth(); d > maxDepth {
@@ -313,7 +419,7 @@ func (r *componentTypeResolver) resolveType(scope *scope) (Type, error) {
 			return nil, err
 		}
 		rt, isResourceType := typ.getType().(*ResourceType)
-		if _, exportAlias := def.(*exportAliasDefinition[Type, Type, *Instance, *instanceType]); isResourceType && exportAlias {
+		if _, exportAlias := def.(*exportAliasDefinition[Type, Type, *Instance, *InstanceType]); isResourceType && exportAlias {
 			aliasResources[rt] = struct{}{}
 			continue
 		}
@@ -0,0 +1,220 @@
+package componentmodel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/partite-ai/wacogo/ast"
+	"github.com/tetratelabs/wazero"
+)
+
+func TestInstancePre(t *testing.T) {
+	ctx := context.Background()
+	comp := newStartComponent(t)
+
+	mistyped := NewFunction(&FunctionType{
+		Parameters: []*FunctionParameter{{Name: "x", Type: StringType{}}},
+		ResultType: U32Type{},
+	}, nil)
+	if _, err := comp.Prepare(ctx, map[string]any{"n": U32(21), "double": mistyped}); err == nil {
+		t.Error("Prepare with a mistyped function import succeeded")
+	}
+
+	double := NewFunction(&FunctionType{
+		Parameters: []*FunctionParameter{{Name: "x", Type: U32Type{}}},
+		ResultType: U32Type{},
+	}, func(ctx context.Context, params []Value) (Value, error) {
+		return params[0].(U32) * 2, nil
+	})
+	pre, err := comp.Prepare(ctx, map[string]any{"n": U32(21), "double": double})
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	// The imported function type is resolved once and shared by the instances.
+	if pre.types[2] != double.funcTyp {
+		t.Errorf("prepared type of the function import = %#v, want the argument type", pre.types[2])
+	}
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			inst, err := pre.Instantiate(ctx)
+			if err != nil {
+				t.Errorf("Instantiate failed: %v", err)
+				return
+			}
+			if r, ok := inst.Export("r"); !ok || r != U32(42) {
+				t.Errorf("Export(\"r\") = %v, %v, want 42", r, ok)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestInstancePreLocalResource(t *testing.T) {
+	ctx := context.Background()
+	comp, err := NewBuilder(nil).Build(ctx, &ast.Component{
+		Definitions: []ast.Definition{
+			&ast.Type{DefType: &ast.ResourceType{Rep: ast.CoreNumTypeI32}},
+			&ast.Export{ExportName: "r", SortIdx: ast.SortIdx{Sort: ast.SortType, Idx: 0}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	pre, err := comp.Prepare(ctx, nil)
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+
+	// A resource defined by the component is a new type in every instance.
+	var types []Type
+	for range 2 {
+		inst, err := pre.Instantiate(ctx)
+		if err != nil {
+			t.Fatalf("Instantiate failed: %v", err)
+		}
+		rt, ok := inst.Exports()[0].Type.(*ResourceType)
+		if !ok || rt.instance != inst {
+			t.Fatalf("exported type = %#v, want a resource of the instance", inst.Exports()[0].Type)
+		}
+		types = append(types, rt)
+	}
+	if types[0] == types[1] {
+		t.Error("instances share the resource type defined by the component")
+	}
+}
+
+func TestInstantiateCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	double := NewFunction(&FunctionType{
+		Parameters: []*FunctionParameter{{Name: "x", Type: U32Type{}}},
+		ResultType: U32Type{},
+	}, func(ctx context.Context, params []Value) (Value, error) {
+		cancel()
+		return nil, ctx.Err()
+	})
+	_, err := newStartComponent(t).Instantiate(ctx, map[string]any{"n": U32(21), "double": double})
+	var interrupted *InterruptedError
+	if !errors.As(err, &interrupted) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Instantiate returned %v, want an InterruptedError wrapping %v", err, context.Canceled)
+	}
+}
+
+// benchmarkCoreModule is
+//
+//	(module
+//	  (import "host" "log" (func (param i32)))
+//	  (func (export "run") (param i32) (result i32)
+//	    local.get 0
+//	    call 0
+//	    local.get 0
+//	    i32.const 1
+//	    i32.add))
+var benchmarkCoreModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	0x01, 0x0a, 0x02, 0x60, 0x01, 0x7f, 0x00, 0x60, 0x01, 0x7f, 0x01, 0x7f,
+	0x02, 0x0c, 0x01, 0x04, 'h', 'o', 's', 't', 0x03, 'l', 'o', 'g', 0x00, 0x00,
+	0x03, 0x02, 0x01, 0x01,
+	0x07, 0x07, 0x01, 0x03, 'r', 'u', 'n', 0x00, 0x01,
+	0x0a, 0x0d, 0x01, 0x0b, 0x00, 0x20, 0x00, 0x10, 0x00, 0x20, 0x00, 0x41, 0x01, 0x6a, 0x0b,
+}
+
+// newBenchmarkComponent builds a component that lowers its "log" import into
+// a core module and lifts the module's "run" function.
+func newBenchmarkComponent(b *testing.B) (*Component, map[string]any) {
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, NewRuntimeConfig())
+	b.Cleanup(func() { runtime.Close(ctx) })
+
+	log := NewFunction(&FunctionType{
+		Parameters: []*FunctionParameter{{Name: "x", Type: U32Type{}}},
+	}, func(ctx context.Context, params []Value) (Value, error) {
+		return nil, nil
+	})
+	return buildBenchmarkComponent(b, runtime), map[string]any{"log": log}
+}
+
+// buildBenchmarkComponent builds the component of newBenchmarkComponent with
+// runtime. Each build compiles its own stub modules.
+func buildBenchmarkComponent(b *testing.B, runtime wazero.Runtime) *Component {
+	comp, err := NewBuilder(runtime).Build(context.Background(), &ast.Component{
+		Definitions: []ast.Definition{
+			&ast.Type{DefType: &ast.FuncType{
+				Params: []ast.FuncParam{{Label: "x", Type: &ast.U32Type{}}},
+			}},
+			&ast.Import{ImportName: "log", Desc: &ast.SortExternDesc{Sort: ast.SortFunc, TypeIdx: 0}},
+			&ast.CoreModule{Raw: benchmarkCoreModule},
+			&ast.Canon{Def: &ast.CanonLower{FuncIdx: 0}},
+			&ast.CoreInstance{Expr: &ast.CoreInlineExports{Exports: []ast.CoreInlineExport{
+				{Name: "log", SortIdx: ast.CoreSortIdx{Sort: ast.CoreSortFunc, Idx: 0}},
+			}}},
+			&ast.CoreInstance{Expr: &ast.CoreInstantiate{ModuleIdx: 0, Args: []ast.CoreInstantiateArg{
+				{Name: "host", CoreInstanceIdx: 0},
+			}}},
+			&ast.Alias{Sort: ast.SortCoreFunc, Target: &ast.CoreExportAlias{InstanceIdx: 1, Name: "run"}},
+			&ast.Type{DefType: &ast.FuncType{
+				Params:  []ast.FuncParam{{Label: "x", Type: &ast.U32Type{}}},
+				Results: &ast.U32Type{},
+			}},
+			&ast.Canon{Def: &ast.CanonLift{CoreFuncIdx: 1, FunctionTypeIdx: 1}},
+			&ast.Export{ExportName: "run", SortIdx: ast.SortIdx{Sort: ast.SortFunc, Idx: 1}},
+		},
+	})
+	if err != nil {
+		b.Fatalf("failed to build component: %v", err)
+	}
+	return comp
+}
+
+// BenchmarkInstantiateUncompiled is the baseline for the other benchmarks: it
+// instantiates a freshly built component every time, so no stub module has
+// been compiled yet.
+func BenchmarkInstantiateUncompiled(b *testing.B) {
+	ctx := context.Background()
+	_, args := newBenchmarkComponent(b)
+	runtime := wazero.NewRuntimeWithConfig(ctx, NewRuntimeConfig())
+	b.Cleanup(func() { runtime.Close(ctx) })
+	for b.Loop() {
+		b.StopTimer()
+		comp := buildBenchmarkComponent(b, runtime)
+		b.StartTimer()
+		inst, err := comp.Instantiate(ctx, args)
+		if err != nil {
+			b.Fatalf("Instantiate failed: %v", err)
+		}
+		inst.Close(ctx)
+	}
+}
+
+func BenchmarkInstantiate(b *testing.B) {
+	ctx := context.Background()
+	comp, args := newBenchmarkComponent(b)
+	for b.Loop() {
+		inst, err := comp.Instantiate(ctx, args)
+		if err != nil {
+			b.Fatalf("Instantiate failed: %v", err)
+		}
+		inst.Close(ctx)
+	}
+}
+
+func BenchmarkInstancePreInstantiate(b *testing.B) {
+	ctx := context.Background()
+	comp, args := newBenchmarkComponent(b)
+	pre, err := comp.Prepare(ctx, args)
+	if err != nil {
+		b.Fatalf("Prepare failed: %v", err)
+	}
+	for b.Loop() {
+		inst, err := pre.Instantiate(ctx)
+		if err != nil {
+			b.Fatalf("Instantiate failed: %v", err)
+		}
+		inst.Close(ctx)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/partite-ai/wacogo/ast"
 	"github.com/partite-ai/wacogo/wasm"
@@ -243,22 +244,30 @@ func (d *coreInstantiateDefinition) createInstance(ctx context.Context, scope *s
 	cnf := wazero.NewModuleConfig().
 		WithName("") // Always anonymous for core modules - this will get assigned during instantiation
 
-	modCtx := experimental.WithImportResolver(ctx, experimental.ImportResolver(func(name string) api.Module {
+	// The module's start function runs core code of the instance.
+	modCtx := experimental.WithImportResolver(withInstance(ctx, scope.instance), experimental.ImportResolver(func(name string) api.Module {
 		if name == "$$BLANK$$" {
 			name = ""
 		}
 		return importModules[name]
 	}))
+	if err := checkMemoryLimit(coreMod.additionalExterns, scope.instance.limits); err != nil {
+		return nil, err
+	}
+	modCtx = withMemoryLimiter(modCtx, scope.instance.limits)
 	modInst, err := scope.runtime.InstantiateModule(modCtx, coreMod.module, cnf)
 	if err != nil {
 		return nil, err
 	}
+	scope.instance.addModule(modInst)
 
 	return newCoreInstance(modInst, coreMod.additionalExterns)
 }
 
 type coreInlineExportsDefinition struct {
-	astDef *ast.CoreInlineExports
+	astDef   *ast.CoreInlineExports
+	mu       sync.Mutex
+	compiled map[string]wazero.CompiledModule
 }
 
 func newCoreInlineExportsDefinition(
@@ -449,13 +458,38 @@ func (d *coreInlineExportsDefinition) createInstance(ctx context.Context, scope
 	modCtx := experimental.WithImportResolver(ctx, experimental.ImportResolver(func(name string) api.Module {
 		return modMap[name]
 	}))
-	modInst, err := scope.runtime.InstantiateWithConfig(modCtx, synthModule, cnf)
+	compiled, err := d.compile(ctx, scope.runtime, synthModule)
 	if err != nil {
 		return nil, err
 	}
+	modInst, err := scope.runtime.InstantiateModule(modCtx, compiled, cnf)
+	if err != nil {
+		return nil, err
+	}
+	scope.instance.addModule(modInst)
 
 	return newCoreInstance(modInst, &wasm.Externs{
 		Imports: &wasm.Imports{},
 		Exports: additionalExports,
 	})
 }
+
+// compile compiles the synthesized module. It only depends on the types of the
+// exports, so it is compiled once and shared between instances.
+func (d *coreInlineExportsDefinition) compile(ctx context.Context, runtime wazero.Runtime, binary []byte) (wazero.CompiledModule, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := string(binary)
+	if mod, ok := d.compiled[key]; ok {
+		return mod, nil
+	}
+	mod, err := runtime.CompileModule(ctx, binary)
+	if err != nil {
+		return nil, err
+	}
+	if d.compiled == nil {
+		d.compiled = make(map[string]wazero.CompiledModule)
+	}
+	d.compiled[key] = mod
+	return mod, nil
+}
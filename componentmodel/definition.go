@@ -60,9 +60,10 @@ func (d *sortDefinitions[V, T]) get(index uint32) (definition[V, T], error) {
 }
 
 func (d *sortDefinitions[V, T]) add(def definition[V, T]) uint32 {
+	idx := uint32(len(d.items))
 	d.items = append(d.items, def)
-	d.definitions.binders = append(d.definitions.binders, &definitionBinderImpl[V, T]{def: def, sort: d.sort})
-	return uint32(len(d.items) - 1)
+	d.definitions.binders = append(d.definitions.binders, &definitionBinderImpl[V, T]{def: def, sort: d.sort, idx: idx})
+	return idx
 }
 
 func (d *sortDefinitions[V, T]) len() uint32 {
@@ -84,12 +85,22 @@ func sortDefsFor[V any, T Type](defs *definitions, sort sort[V, T]) *sortDefinit
 
 type definitionBinder interface {
 	bindType(scope *scope) error
-	bindInstance(ctx context.Context, scope *scope) error
+	// bindInstance binds the definition and its value in scope. A non-nil
+	// prepared type is the type the definition was bound to by Prepare, and
+	// is used instead of resolving and checking the type again.
+	bindInstance(ctx context.Context, scope *scope, prepared Type) error
+}
+
+// typedBinder is a definition binder that binds a definition of its own, with
+// a type that can be looked up once bound.
+type typedBinder interface {
+	boundType(scope *scope) (Type, error)
 }
 
 type definitionBinderImpl[V any, T Type] struct {
 	sort sort[V, T]
 	def  definition[V, T]
+	idx  uint32
 }
 
 func (b *definitionBinderImpl[V, T]) bindType(scope *scope) error {
@@ -109,10 +120,31 @@ func (b *definitionBinderImpl[V, T]) bindType(scope *scope) error {
 	return nil
 }
 
-func (b *definitionBinderImpl[V, T]) bindInstance(ctx context.Context, scope *scope) error {
-	typ, err := b.def.createType(scope)
-	if err != nil {
-		return err
+// imported reports the name and sort of the import the binder defines, if
+// it defines one.
+func (b *definitionBinderImpl[V, T]) imported() (string, genericSort, bool) {
+	d, ok := b.def.(*importDefinition[V, T])
+	if !ok {
+		return "", nil, false
+	}
+	return d.importName, b.sort, true
+}
+
+// boundType returns the type of the definition bound in scope.
+func (b *definitionBinderImpl[V, T]) boundType(scope *scope) (Type, error) {
+	return sortScopeFor(scope, b.sort).getType(b.idx)
+}
+
+func (b *definitionBinderImpl[V, T]) bindInstance(ctx context.Context, scope *scope, prepared Type) error {
+	var typ T
+	if prepared != nil {
+		typ = prepared.(T)
+	} else {
+		var err error
+		typ, err = b.def.createType(scope)
+		if err != nil {
+			return err
+		}
 	}
 	scope.currentType = typ
 	defer func() { scope.currentType = nil }()
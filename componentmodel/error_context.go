@@ -0,0 +1,167 @@
+package componentmodel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/partite-ai/wacogo/ast"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// ErrorContext is an immutable value that carries debugging information, such
+// as a message, along with an error. Components must not rely on its contents.
+type ErrorContext struct {
+	message string
+}
+
+// NewErrorContext creates an error context with the given debug message.
+func NewErrorContext(message string) *ErrorContext {
+	return &ErrorContext{message: message}
+}
+
+func (e *ErrorContext) isValue() {}
+
+// DebugMessage returns the debug message of the error context.
+func (e *ErrorContext) DebugMessage() string {
+	return e.message
+}
+
+func (e *ErrorContext) Error() string {
+	return e.message
+}
+
+type ErrorContextType struct {
+	primitiveValueType[ErrorContextType, *ErrorContext]
+}
+
+func (t ErrorContextType) typeName() string    { return "error-context" }
+func (t ErrorContextType) alignment() uint32   { return 4 }
+func (t ErrorContextType) elementSize() uint32 { return 4 }
+func (t ErrorContextType) flatTypes() []api.ValueType {
+	return []api.ValueType{api.ValueTypeI32}
+}
+
+func (t ErrorContextType) liftFlat(llc *LiftLoadContext, itr func() uint64) (Value, error) {
+	return llc.instance.errorContext(api.DecodeU32(itr()))
+}
+
+func (t ErrorContextType) load(llc *LiftLoadContext, offset uint32) (Value, error) {
+	idx, ok := llc.memory.ReadUint32Le(offset)
+	if !ok {
+		return nil, fmt.Errorf("failed to read error-context index at offset %d", offset)
+	}
+	return llc.instance.errorContext(idx)
+}
+
+func (t ErrorContextType) lowerFlat(llc *LiftLoadContext, val Value) ([]uint64, error) {
+	idx, err := llc.instance.addErrorContext(val.(*ErrorContext))
+	if err != nil {
+		return nil, err
+	}
+	return []uint64{uint64(idx)}, nil
+}
+
+func (t ErrorContextType) store(llc *LiftLoadContext, offset uint32, val Value) error {
+	idx, err := llc.instance.addErrorContext(val.(*ErrorContext))
+	if err != nil {
+		return err
+	}
+	if !llc.memory.WriteUint32Le(offset, idx) {
+		return fmt.Errorf("failed to write error-context index at offset %d", offset)
+	}
+	return nil
+}
+
+// errorContext returns the error context at idx. Error contexts stay in the
+// table when they are passed on, since they are immutable.
+func (i *Instance) errorContext(idx uint32) (*ErrorContext, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	e, ok := i.errorContexts.lookup(idx)
+	if !ok {
+		return nil, fmt.Errorf("unknown error-context index %d", idx)
+	}
+	return e, nil
+}
+
+func (i *Instance) addErrorContext(e *ErrorContext) (uint32, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.errorContexts.add(e)
+}
+
+func canonErrorContextNew(id uint32, astDef *ast.CanonErrorContextNew) (definition[*coreFunction, *coreFunctionType], error) {
+	return &coreFunctionBuiltinDefinition{
+		id:      fmt.Sprintf("canon_error_context_new_%d", id),
+		params:  i32s(2),
+		results: i32s(1),
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				llc, err := newLiftLoadContext(ctx, astDef.Options, scope)
+				if err != nil {
+					panic(fmt.Errorf("failed to create lift/load context for error-context.new: %w", err))
+				}
+				if llc.memory == nil {
+					panic(fmt.Errorf("error-context.new requires a memory option"))
+				}
+				msg, err := StringType{}.readString(llc, uint32(stack[0]), uint32(stack[1]))
+				if err != nil {
+					panic(fmt.Errorf("failed to read error-context debug message: %w", err))
+				}
+				idx, err := scope.instance.addErrorContext(NewErrorContext(string(msg)))
+				if err != nil {
+					panic(fmt.Errorf("canon error-context.new failed: %w", err))
+				}
+				stack[0] = uint64(idx)
+			}, nil
+		},
+	}, nil
+}
+
+func canonErrorContextDebugMessage(id uint32, astDef *ast.CanonErrorContextDebugMessage) (definition[*coreFunction, *coreFunctionType], error) {
+	return &coreFunctionBuiltinDefinition{
+		id:     fmt.Sprintf("canon_error_context_debug_message_%d", id),
+		params: i32s(2),
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				e, err := scope.instance.errorContext(uint32(stack[0]))
+				if err != nil {
+					panic(err)
+				}
+				llc, err := newLiftLoadContext(ctx, astDef.Options, scope)
+				if err != nil {
+					panic(fmt.Errorf("failed to create lift/load context for error-context.debug-message: %w", err))
+				}
+				if llc.memory == nil || llc.realloc == nil {
+					panic(fmt.Errorf("error-context.debug-message requires memory and realloc options"))
+				}
+				ptr := uint32(stack[1])
+				if ptr != alignTo(ptr, StringType{}.alignment()) {
+					panic(fmt.Errorf("unaligned pointer for error-context debug message"))
+				}
+				if err := (StringType{}).store(llc, ptr, String(e.message)); err != nil {
+					panic(fmt.Errorf("failed to store error-context debug message: %w", err))
+				}
+			}, nil
+		},
+	}, nil
+}
+
+func canonErrorContextDrop(id uint32) (definition[*coreFunction, *coreFunctionType], error) {
+	return &coreFunctionBuiltinDefinition{
+		id:     fmt.Sprintf("canon_error_context_drop_%d", id),
+		params: i32s(1),
+		newFunc: func(scope *scope) (api.GoModuleFunc, error) {
+			return func(ctx context.Context, mod api.Module, stack []uint64) {
+				inst := scope.instance
+				idx := uint32(stack[0])
+				inst.mu.Lock()
+				defer inst.mu.Unlock()
+				if _, ok := inst.errorContexts.lookup(idx); !ok {
+					panic(fmt.Errorf("unknown error-context index %d", idx))
+				}
+				inst.errorContexts.remove(idx)
+			}, nil
+		},
+	}, nil
+}
@@ -0,0 +1,32 @@
+package componentmodel
+
+import "testing"
+
+func TestErrorContextLiftLower(t *testing.T) {
+	inst := newInstance()
+	llc := &LiftLoadContext{instance: inst}
+	e := NewErrorContext("boom")
+
+	flat, err := ErrorContextType{}.lowerFlat(llc, e)
+	if err != nil {
+		t.Fatalf("lowerFlat failed: %v", err)
+	}
+
+	// Lifting an error context leaves it in the table, so it can be passed
+	// on more than once.
+	for range 2 {
+		itr := func() uint64 { return flat[0] }
+		v, err := ErrorContextType{}.liftFlat(llc, itr)
+		if err != nil {
+			t.Fatalf("liftFlat failed: %v", err)
+		}
+		if v.(*ErrorContext).DebugMessage() != "boom" {
+			t.Errorf("DebugMessage() = %q, want %q", v.(*ErrorContext).DebugMessage(), "boom")
+		}
+	}
+
+	inst.errorContexts.remove(uint32(flat[0]))
+	if _, err := (ErrorContextType{}).liftFlat(llc, func() uint64 { return flat[0] }); err == nil {
+		t.Error("liftFlat() of a dropped error context succeeded")
+	}
+}
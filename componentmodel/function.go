@@ -9,6 +9,7 @@ import (
 type Function struct {
 	funcTyp *FunctionType
 	invoke  func(ctx context.Context, params []Value) (Value, error)
+	async   bool
 }
 
 func NewFunction(
@@ -21,6 +22,20 @@ func NewFunction(
 	}
 }
 
+// NewAsyncFunction creates a function that may block for a long time, such as
+// one waiting on I/O. Callers using the async ABI run it in its own goroutine
+// and continue while it completes.
+func NewAsyncFunction(
+	typ *FunctionType,
+	invoke func(ctx context.Context, params []Value) (Value, error),
+) *Function {
+	return &Function{
+		funcTyp: typ,
+		invoke:  invoke,
+		async:   true,
+	}
+}
+
 func (f *Function) Invoke(ctx context.Context, params ...Value) (Value, error) {
 	return f.invoke(ctx, params)
 }
@@ -32,11 +32,34 @@ func (hi *Instance) Instance() *componentmodel.Instance {
 	return hi.instanceBuilder.Build()
 }
 
+// ToWIT renders the types and functions added to the instance as a WIT
+// interface, for comparing against the interfaces a component imports.
+func (hi *Instance) ToWIT(name string) string {
+	return hi.Instance().ToWIT(name)
+}
+
 func (hi *Instance) AddTypeExport(name string, typ componentmodel.Type) {
 	hi.instanceBuilder.AddTypeExport(name, typ)
 }
 
+var contextType = reflect.TypeFor[context.Context]()
+
 func (hi *Instance) AddFunction(name string, fn any) error {
+	return hi.addFunction(name, fn, componentmodel.NewFunction)
+}
+
+// AddAsyncFunction is like AddFunction, but for functions that may block for
+// a long time. Guests calling it through the async ABI continue running while
+// it completes in its own goroutine.
+func (hi *Instance) AddAsyncFunction(name string, fn any) error {
+	return hi.addFunction(name, fn, componentmodel.NewAsyncFunction)
+}
+
+func (hi *Instance) addFunction(
+	name string,
+	fn any,
+	newFunction func(*componentmodel.FunctionType, func(context.Context, []componentmodel.Value) (componentmodel.Value, error)) *componentmodel.Function,
+) error {
 	fnType := reflect.TypeOf(fn)
 	if fnType.Kind() != reflect.Func {
 		return fmt.Errorf("expected a function, found %s", fnType.Kind())
@@ -47,7 +70,15 @@ func (hi *Instance) AddFunction(name string, fn any) error {
 	var resultConverter converter
 	var resultType componentmodel.ValueType
 
-	for i := 0; i < fnType.NumIn(); i++ {
+	// A leading context.Context parameter receives the context of the call and
+	// isn't part of the component function's signature.
+	takesContext := fnType.NumIn() > 0 && fnType.In(0) == contextType
+	firstParam := 0
+	if takesContext {
+		firstParam = 1
+	}
+
+	for i := firstParam; i < fnType.NumIn(); i++ {
 		paramType := fnType.In(i)
 		vt, ok := valueTypeFor(hi, paramType)
 		if !ok {
@@ -59,7 +90,7 @@ func (hi *Instance) AddFunction(name string, fn any) error {
 		}
 		paramConverters = append(paramConverters, converter)
 		paramTypes = append(paramTypes, &componentmodel.FunctionParameter{
-			Name: fmt.Sprintf("param%d", i),
+			Name: fmt.Sprintf("param%d", i-firstParam),
 			Type: vt,
 		})
 	}
@@ -85,7 +116,7 @@ func (hi *Instance) AddFunction(name string, fn any) error {
 	}
 
 	hi.instanceBuilder.AddFunctionExport(name, func(instance *componentmodel.Instance) *componentmodel.Function {
-		return componentmodel.NewFunction(
+		return newFunction(
 			&componentmodel.FunctionType{
 				Parameters: paramTypes,
 				ResultType: resultType,
@@ -99,6 +130,9 @@ func (hi *Instance) AddFunction(name string, fn any) error {
 					hostInstance: hi,
 				}
 				var hostParams []reflect.Value
+				if takesContext {
+					hostParams = append(hostParams, reflect.ValueOf(&ctx).Elem())
+				}
 				for i, param := range params {
 					hostParam := paramConverters[i].toHost(cc, param)
 					hostParams = append(hostParams, reflect.ValueOf(hostParam))
@@ -122,3 +156,10 @@ func (hi *Instance) MustAddFunction(name string, fn any) {
 		panic(err)
 	}
 }
+
+func (hi *Instance) MustAddAsyncFunction(name string, fn any) {
+	err := hi.AddAsyncFunction(name, fn)
+	if err != nil {
+		panic(err)
+	}
+}
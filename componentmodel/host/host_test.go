@@ -0,0 +1,114 @@
+package host
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/partite-ai/wacogo/ast"
+	"github.com/partite-ai/wacogo/componentmodel"
+	"github.com/tetratelabs/wazero"
+)
+
+// memoryCoreModule is
+//
+//	(module
+//	  (memory (export "mem") 1))
+var memoryCoreModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	0x05, 0x03, 0x01, 0x00, 0x01,
+	0x07, 0x07, 0x01, 0x03, 'm', 'e', 'm', 0x02, 0x00,
+}
+
+// scaleCoreModule is
+//
+//	(module
+//	  (import "host" "scale" (func (param f32 f32 f32 f32 i32)))
+//	  (import "host" "mem" (memory 1))
+//	  (func (export "run") (param f32 f32 f32 f32) (result i32)
+//	    local.get 0
+//	    local.get 1
+//	    local.get 2
+//	    local.get 3
+//	    i32.const 0
+//	    call 0
+//	    i32.const 0))
+var scaleCoreModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	0x01, 0x11, 0x02, 0x60, 0x05, 0x7d, 0x7d, 0x7d, 0x7d, 0x7f, 0x00, 0x60, 0x04, 0x7d, 0x7d, 0x7d, 0x7d, 0x01, 0x7f,
+	0x02, 0x1a, 0x02, 0x04, 'h', 'o', 's', 't', 0x05, 's', 'c', 'a', 'l', 'e', 0x00, 0x00,
+	0x04, 'h', 'o', 's', 't', 0x03, 'm', 'e', 'm', 0x02, 0x00, 0x01,
+	0x03, 0x02, 0x01, 0x01,
+	0x07, 0x07, 0x01, 0x03, 'r', 'u', 'n', 0x00, 0x01,
+	0x0a, 0x12, 0x01, 0x10, 0x00, 0x20, 0x00, 0x20, 0x01, 0x20, 0x02, 0x20, 0x03, 0x41, 0x00, 0x10, 0x00, 0x41, 0x00, 0x0b,
+}
+
+// TestArrayRoundTrip passes a [4]float32 from a guest to a host function and
+// back as a list<f32, 4>.
+func TestArrayRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, componentmodel.NewRuntimeConfig())
+	defer runtime.Close(ctx)
+
+	hi := NewInstance()
+	var got [4]float32
+	hi.MustAddFunction("scale", func(v [4]float32) [4]float32 {
+		got = v
+		for i := range v {
+			v[i] *= 2
+		}
+		return v
+	})
+	scale, _ := hi.Instance().Export("scale")
+
+	vec4 := &ast.FixedListType{Element: &ast.F32Type{}, Length: 4}
+	comp, err := componentmodel.NewBuilder(runtime).Build(ctx, &ast.Component{
+		Definitions: []ast.Definition{
+			&ast.Type{DefType: &ast.FuncType{
+				Params:  []ast.FuncParam{{Label: "v", Type: vec4}},
+				Results: vec4,
+			}},
+			&ast.Import{ImportName: "scale", Desc: &ast.SortExternDesc{Sort: ast.SortFunc, TypeIdx: 0}},
+			&ast.CoreModule{Raw: memoryCoreModule},
+			&ast.CoreInstance{Expr: &ast.CoreInstantiate{ModuleIdx: 0}},
+			&ast.Alias{Sort: ast.SortCoreMemory, Target: &ast.CoreExportAlias{InstanceIdx: 0, Name: "mem"}},
+			&ast.Canon{Def: &ast.CanonLower{FuncIdx: 0, Options: []ast.CanonOpt{&ast.MemoryOpt{MemoryIdx: 0}}}},
+			&ast.CoreInstance{Expr: &ast.CoreInlineExports{Exports: []ast.CoreInlineExport{
+				{Name: "scale", SortIdx: ast.CoreSortIdx{Sort: ast.CoreSortFunc, Idx: 0}},
+				{Name: "mem", SortIdx: ast.CoreSortIdx{Sort: ast.CoreSortMemory, Idx: 0}},
+			}}},
+			&ast.CoreModule{Raw: scaleCoreModule},
+			&ast.CoreInstance{Expr: &ast.CoreInstantiate{ModuleIdx: 1, Args: []ast.CoreInstantiateArg{
+				{Name: "host", CoreInstanceIdx: 1},
+			}}},
+			&ast.Alias{Sort: ast.SortCoreFunc, Target: &ast.CoreExportAlias{InstanceIdx: 2, Name: "run"}},
+			&ast.Canon{Def: &ast.CanonLift{CoreFuncIdx: 1, FunctionTypeIdx: 0, Options: []ast.CanonOpt{&ast.MemoryOpt{MemoryIdx: 0}}}},
+			&ast.Export{ExportName: "run", SortIdx: ast.SortIdx{Sort: ast.SortFunc, Idx: 1}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	inst, err := comp.Instantiate(ctx, map[string]any{"scale": scale})
+	if err != nil {
+		t.Fatalf("Instantiate failed: %v", err)
+	}
+	defer inst.Close(ctx)
+	run, ok := inst.Export("run")
+	if !ok {
+		t.Fatal("run is not exported")
+	}
+
+	in := componentmodel.List{componentmodel.F32(1), componentmodel.F32(-2.5), componentmodel.F32(0.25), componentmodel.F32(1e10)}
+	result, err := run.(*componentmodel.Function).Invoke(ctx, in)
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if want := [4]float32{1, -2.5, 0.25, 1e10}; got != want {
+		t.Errorf("host function received %v, want %v", got, want)
+	}
+	want := componentmodel.List{componentmodel.F32(2), componentmodel.F32(-5), componentmodel.F32(0.5), componentmodel.F32(2e10)}
+	if list, ok := result.(componentmodel.List); !ok || !slices.Equal(list, want) {
+		t.Errorf("run returned %v, want %v", result, want)
+	}
+}
@@ -0,0 +1,30 @@
+package host
+
+import (
+	"io"
+
+	"github.com/partite-ai/wacogo/componentmodel"
+)
+
+// ByteStream is a stream<u8> passed to or from a host function. It reads as
+// an io.Reader and can be consumed into an io.Writer with WriteTo.
+type ByteStream struct {
+	*componentmodel.Stream
+}
+
+// NewByteStream returns a stream<u8> that feeds a component from r.
+func NewByteStream(r io.Reader) ByteStream {
+	return ByteStream{componentmodel.NewReaderStream(r)}
+}
+
+func (ByteStream) ValueType(inst *Instance) componentmodel.ValueType {
+	return componentmodel.NewStreamType(componentmodel.U8Type{})
+}
+
+func (ByteStream) ToHost(v componentmodel.Value) any {
+	return ByteStream{v.(*componentmodel.Stream)}
+}
+
+func (ByteStream) FromHost(v any) componentmodel.Value {
+	return v.(ByteStream).Stream
+}
@@ -22,12 +22,12 @@ type ConstructableTuple[TF any] interface {
 	impl() *tupleImpl[TF]
 }
 
-type SettableTuple[T TupleType, TF any] struct {
+type SettableTuple[T ConstructableTuple[TF], TF any] struct {
 	*tupleImpl[TF]
 }
 
 func (sr SettableTuple[T, TF]) Tuple() T {
-	return any(Tuple[TF]{sr.tupleImpl}).(T)
+	return T{tupleImpl: sr.tupleImpl}
 }
 
 func (sr SettableTuple[T, TF]) settableTuple(T) {}
@@ -63,6 +63,10 @@ func valueTypeFor(inst *Instance, t reflect.Type) (componentmodel.ValueType, boo
 		return componentmodel.ByteArrayType{}, true
 	}
 
+	if t == reflect.TypeFor[*componentmodel.ErrorContext]() {
+		return componentmodel.ErrorContextType{}, true
+	}
+
 	// Resource Handle
 	type handleType interface {
 		resourceType() reflect.Type
@@ -100,6 +104,15 @@ func valueTypeFor(inst *Instance, t reflect.Type) (componentmodel.ValueType, boo
 		return &componentmodel.ListType{ElementType: elemType}, true
 	}
 
+	// Array type
+	if t.Kind() == reflect.Array && t.Len() > 0 {
+		elemType, ok := valueTypeFor(inst, t.Elem())
+		if !ok {
+			return nil, false
+		}
+		return &componentmodel.FixedListType{ElementType: elemType, Length: uint32(t.Len())}, true
+	}
+
 	// Record type
 	if t.ConvertibleTo(reflect.TypeFor[RecordType]()) {
 		recordMeta := reflect.Zero(t).Interface().(interface {
@@ -139,6 +152,21 @@ func ResourceTypeFor[T any](inst *Instance, owner *Instance) *componentmodel.Res
 	panic(fmt.Sprintf("ResourceTypeFor: unsupported type %T", *new(T)))
 }
 
+// ResourceTypeWithDestructor is like ResourceTypeFor for a resource type
+// owned by inst, but destroys dropped resources with destructor rather than
+// by closing them. It must be called before the type is first looked up.
+func ResourceTypeWithDestructor[T any](inst *Instance, destructor func(ctx context.Context, res T)) *componentmodel.ResourceType {
+	t := reflect.TypeFor[T]()
+	if _, ok := inst.resourceTypes[t]; ok {
+		panic(fmt.Sprintf("ResourceTypeWithDestructor: resource type %v already created", t))
+	}
+	rt := inst.instanceBuilder.CreateResourceType(t, func(ctx context.Context, res any) {
+		destructor(ctx, res.(T))
+	})
+	inst.resourceTypes[t] = rt
+	return rt
+}
+
 func resourceTypeFor[T any](inst, owner *Instance) (*componentmodel.ResourceType, bool) {
 	t := reflect.TypeFor[T]()
 
@@ -222,6 +222,32 @@ func (lc *listConverter) fromHost(cc *callContext, v any) componentmodel.Value {
 	return srv.Interface().(componentmodel.Value)
 }
 
+type arrayConverter struct {
+	elemConverter converter
+	typ           reflect.Type
+}
+
+func (ac *arrayConverter) toHost(cc *callContext, v componentmodel.Value) any {
+	srv := reflect.ValueOf(v)
+	trv := reflect.New(ac.typ).Elem()
+	for i := range srv.Len() {
+		elemValue := srv.Index(i)
+		hostElem := ac.elemConverter.toHost(cc, elemValue.Interface().(componentmodel.Value))
+		trv.Index(i).Set(reflect.ValueOf(hostElem))
+	}
+	return trv.Interface()
+}
+
+func (ac *arrayConverter) fromHost(cc *callContext, v any) componentmodel.Value {
+	rv := reflect.ValueOf(v)
+	length := rv.Len()
+	list := make(componentmodel.List, length)
+	for i := range length {
+		list[i] = ac.elemConverter.fromHost(cc, rv.Index(i).Interface())
+	}
+	return list
+}
+
 func converterFor(t reflect.Type) converter {
 	switch t {
 	case reflect.TypeFor[componentmodel.Bool](), reflect.TypeFor[componentmodel.U8](),
@@ -230,7 +256,8 @@ func converterFor(t reflect.Type) converter {
 		reflect.TypeFor[componentmodel.S16](), reflect.TypeFor[componentmodel.S32](),
 		reflect.TypeFor[componentmodel.S64](), reflect.TypeFor[componentmodel.F32](),
 		reflect.TypeFor[componentmodel.F64](), reflect.TypeFor[componentmodel.String](),
-		reflect.TypeFor[componentmodel.Char](), reflect.TypeFor[componentmodel.ByteArray]():
+		reflect.TypeFor[componentmodel.Char](), reflect.TypeFor[componentmodel.ByteArray](),
+		reflect.TypeFor[*componentmodel.ErrorContext]():
 		return identityConverter{}
 	}
 
@@ -307,6 +334,17 @@ func converterFor(t reflect.Type) converter {
 		}
 	}
 
+	// Fixed-length lists
+	if t.Kind() == reflect.Array {
+		elemConverter := converterFor(t.Elem())
+		if elemConverter != nil {
+			return &arrayConverter{
+				elemConverter: elemConverter,
+				typ:           t,
+			}
+		}
+	}
+
 	if t.ConvertibleTo(reflect.TypeFor[Convertable]()) {
 		return convertableConverter{
 			typ: t,
@@ -179,3 +179,13 @@ func VariantTest[
 	label, _ := vv.value.hostValue()
 	return label == caseLabel
 }
+
+func VariantCaseLabel[
+	V VariantImpl,
+](
+	v V,
+) string {
+	vv := (struct{ value variantAccessor })(v)
+	label, _ := vv.value.hostValue()
+	return label
+}
@@ -49,7 +49,7 @@ func (d *importDefinition[V, T]) createType(scope *scope) (T, error) {
 	}
 
 	// Every instance of an imported instance type should have unique resource placeholder identities.
-	if it, ok := argType.(*instanceType); ok {
+	if it, ok := argType.(*InstanceType); ok {
 		argType = it.clone()
 	}
 
@@ -68,7 +68,7 @@ func (d *importDefinition[V, T]) createInstance(ctx context.Context, scope *scop
 			return scope.currentType.(V), nil
 		}
 		if int(d.sort) == int(sortInstance) && isStaticallyKnownInstanceType(scope.currentType) {
-			return synthesizeRidiculousEmptyInstance(scope.currentType.(*instanceType)).(V), nil
+			return synthesizeRidiculousEmptyInstance(scope.currentType.(*InstanceType)).(V), nil
 		}
 		return zero[V](), err
 	}
@@ -76,6 +76,11 @@ func (d *importDefinition[V, T]) createInstance(ctx context.Context, scope *scop
 	if !ok {
 		return zero[V](), fmt.Errorf("export %s in import instance is not of expected type", d.importName)
 	}
+	if vt, ok := scope.currentType.(ValueType); ok && int(d.sort) == int(sortValue) {
+		if !vt.supportsValue(any(typedVal).(Value)) {
+			return zero[V](), fmt.Errorf("value import %s is not of expected type %s", d.importName, vt.typeName())
+		}
+	}
 	return typedVal, nil
 }
 
@@ -98,7 +103,7 @@ func isStaticallyKnownInstanceType(t Type) bool {
 	// and just return an empty instance type. This is ridiculous, but the spec tests call for it.
 
 	switch it := t.(type) {
-	case *instanceType:
+	case *InstanceType:
 		if len(it.exports) == 0 {
 			return true
 		}
@@ -122,7 +127,7 @@ func isStaticallyKnownInstanceType(t Type) bool {
 	}
 }
 
-func synthesizeRidiculousEmptyInstance(typ *instanceType) any {
+func synthesizeRidiculousEmptyInstance(typ *InstanceType) any {
 	instance := newInstance()
 	for name, exportSpec := range typ.exports {
 		switch exportSpec.sort {
@@ -131,7 +136,7 @@ func synthesizeRidiculousEmptyInstance(typ *instanceType) any {
 			instance.exports[name] = exportInst
 			continue
 		case sortInstance:
-			instance.exports[name] = synthesizeRidiculousEmptyInstance(exportSpec.typ.(*instanceType))
+			instance.exports[name] = synthesizeRidiculousEmptyInstance(exportSpec.typ.(*InstanceType))
 		default:
 			continue
 		}
@@ -2,12 +2,17 @@ package componentmodel
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"slices"
+	"sync"
 
 	"github.com/partite-ai/wacogo/ast"
 	"github.com/partite-ai/wacogo/wasm"
 	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/sys"
 )
 
 type InstanceBuilder struct {
@@ -71,37 +76,222 @@ func (b *InstanceBuilder) Build() *Instance {
 type Instance struct {
 	exports        map[string]any
 	exportSpecs    map[string]*exportSpec
+	mu             sync.Mutex
+	released       chan struct{}
 	active         bool
 	mayLeave       bool
 	currentContext context.Context
 	loweredHandles *table[ResourceHandle]
 	borrowCount    uint32
+	backpressure   uint32
+	waitables      *table[waitable]
+	waitableSets   *table[*waitableSet]
+	errorContexts  *table[*ErrorContext]
+	stubs          sync.Map
+	trapped        bool
+	interrupted    bool
+	limits         Limits
+	closed         bool
+	modules        []api.Module
+	children       []*Instance
 }
 
 func newInstance() *Instance {
 	return &Instance{
 		exports:        make(map[string]any),
 		exportSpecs:    make(map[string]*exportSpec),
+		released:       make(chan struct{}),
 		loweredHandles: newTable[ResourceHandle](),
 		mayLeave:       true,
+		waitables:      newTable[waitable](),
+		waitableSets:   newTable[*waitableSet](),
+		errorContexts:  newTable[*ErrorContext](),
 	}
 }
 
+func (i *Instance) setLimits(limits Limits) {
+	i.limits = limits
+	i.loweredHandles.setLimit("resource handles", limits.Handles)
+	i.waitables.setLimit("waitables", limits.Handles)
+	i.waitableSets.setLimit("waitable sets", limits.Handles)
+	i.errorContexts.setLimit("error contexts", limits.Handles)
+}
+
+type instanceContextKey struct{}
+
+// withInstance records that core code of inst runs under ctx, so the shared
+// stub functions it calls can find their implementation in inst.
+func withInstance(ctx context.Context, inst *Instance) context.Context {
+	return context.WithValue(ctx, instanceContextKey{}, inst)
+}
+
+func instanceFromContext(ctx context.Context) *Instance {
+	inst, _ := ctx.Value(instanceContextKey{}).(*Instance)
+	return inst
+}
+
 func (i *Instance) Export(name string) (any, bool) {
 	val, ok := i.exports[name]
 	return val, ok
 }
 
+// enter starts running a new task in the instance. A task started from
+// another task waits until the instance is free and not applying backpressure,
+// but the instance can't be reentered by a task it is itself waiting on.
 func (i *Instance) enter(ctx context.Context) error {
-	if i.active {
-		return fmt.Errorf("cannot enter component instance: already active")
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for {
+		i.mu.Lock()
+		if i.closed {
+			i.mu.Unlock()
+			return fmt.Errorf("cannot enter component instance: instance is closed")
+		}
+		if isInstanceOnTaskChain(ctx, i) || (i.active && taskFromContext(ctx) == nil) {
+			i.mu.Unlock()
+			return fmt.Errorf("cannot enter component instance: already active")
+		}
+		if !i.active && i.backpressure == 0 {
+			i.active = true
+			i.currentContext = ctx
+			i.mu.Unlock()
+			return nil
+		}
+		released := i.released
+		i.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return &InterruptedError{cause: ctx.Err()}
+		}
 	}
-	i.active = true
-	i.currentContext = ctx
+}
+
+// Close tears the instance down once no task is running in it. It drops the
+// resource handles the instance still owns, running their destructors, then
+// closes its core modules and the instances it created. Calls into the
+// instance fail afterwards.
+func (i *Instance) Close(ctx context.Context) error {
+	for {
+		i.mu.Lock()
+		if i.closed {
+			i.mu.Unlock()
+			return nil
+		}
+		if !i.active {
+			// Hold the instance while it is torn down.
+			i.active = true
+			i.mu.Unlock()
+			break
+		}
+		released := i.released
+		i.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return &InterruptedError{cause: ctx.Err()}
+		}
+	}
+
+	err := i.close(ctx)
+
+	i.mu.Lock()
+	i.currentContext = nil
+	i.active = false
+	i.notifyReleased()
+	i.mu.Unlock()
+	return err
+}
+
+// close tears down an instance the caller has entered.
+func (i *Instance) close(ctx context.Context) error {
+	i.mu.Lock()
+	i.closed = true
+	// Destructors run as a task of the instance, so that they wait for the
+	// instances they enter.
+	i.currentContext = withTask(ctx, newTask(ctx, i, nil, false))
+	handles := i.loweredHandles
+	i.loweredHandles = newTable[ResourceHandle]()
+	modules, children := i.modules, i.children
+	i.modules, i.children = nil, nil
+	i.mu.Unlock()
+
+	var errs []error
+	for idx, entry := range handles.entries {
+		if entry.set && !entry.value.isBorrowed() {
+			if _, owned := entry.value.(*ownHandle); owned {
+				if err := dropHandle(entry.value); err != nil {
+					errs = append(errs, fmt.Errorf("failed to drop resource handle %d: %w", idx, err))
+				}
+			}
+		}
+	}
+	for _, child := range slices.Backward(children) {
+		errs = append(errs, child.Close(ctx))
+	}
+	for _, mod := range slices.Backward(modules) {
+		errs = append(errs, mod.Close(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// dropHandle drops h, reporting a failing destructor as an error.
+func dropHandle(h ResourceHandle) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("resource destructor failed: %v", r)
+		}
+	}()
+	h.Drop()
 	return nil
 }
 
+func (i *Instance) addModule(mod api.Module) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.modules = append(i.modules, mod)
+	if i.interrupted {
+		mod.CloseWithExitCode(context.Background(), sys.ExitCodeContextCanceled)
+	}
+}
+
+// interruptOnDone closes the core modules of the instance once ctx is done.
+// On a runtime configured by NewRuntimeConfig, this stops guest code running in
+// them even in a loop that never calls the host. The instance traps, and the modules it instantiates afterwards are
+// closed as well. The returned function stops watching ctx, and reports false
+// if the instance was interrupted.
+func (i *Instance) interruptOnDone(ctx context.Context) func() bool {
+	if ctx == nil || ctx.Done() == nil {
+		return func() bool { return true }
+	}
+	return context.AfterFunc(ctx, func() {
+		exitCode := sys.ExitCodeContextCanceled
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			exitCode = sys.ExitCodeDeadlineExceeded
+		}
+		i.mu.Lock()
+		i.trapped = true
+		i.interrupted = true
+		modules := slices.Clone(i.modules)
+		i.mu.Unlock()
+		for _, mod := range modules {
+			mod.CloseWithExitCode(context.Background(), exitCode)
+		}
+	})
+}
+
+func (i *Instance) addChild(child *Instance) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.children = append(i.children, child)
+}
+
 func (i *Instance) exit() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
 	if !i.active {
 		panic("instance is not active")
 	}
@@ -110,9 +300,86 @@ func (i *Instance) exit() error {
 	}
 	i.currentContext = nil
 	i.active = false
+	i.notifyReleased()
 	return nil
 }
 
+// suspend lets other tasks run in the instance while the current one blocks.
+// The returned function waits until the instance is free again and resumes
+// the task.
+func (i *Instance) suspend() func() {
+	i.mu.Lock()
+	ctx := i.currentContext
+	i.currentContext = nil
+	i.active = false
+	i.notifyReleased()
+	i.mu.Unlock()
+
+	return func() {
+		for {
+			i.mu.Lock()
+			if !i.active {
+				i.active = true
+				i.currentContext = ctx
+				i.mu.Unlock()
+				return
+			}
+			released := i.released
+			i.mu.Unlock()
+			<-released
+		}
+	}
+}
+
+// notifyReleased wakes the tasks waiting to enter the instance. i.mu must be
+// held.
+func (i *Instance) notifyReleased() {
+	close(i.released)
+	i.released = make(chan struct{})
+}
+
+// trap records that core code of the instance failed, leaving its state
+// undefined.
+func (i *Instance) trap() {
+	i.mu.Lock()
+	i.trapped = true
+	i.mu.Unlock()
+}
+
+func (i *Instance) hasTrapped() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.trapped
+}
+
+// trapError traps the instance after its core code failed with err. If ctx
+// is done, the failure is reported as an interruption instead.
+func (i *Instance) trapError(ctx context.Context, err error) error {
+	i.trap()
+	if ctx == nil {
+		return err
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return &InterruptedError{cause: ctxErr}
+	}
+	return err
+}
+
+// InterruptedError is returned when a call into a component or its
+// instantiation is stopped because its context is done. It wraps the
+// context's error. The instance the call ran in can't be used afterwards.
+type InterruptedError struct {
+	cause error
+}
+
+func (e *InterruptedError) Error() string {
+	return "component call interrupted: " + e.cause.Error()
+}
+
+func (e *InterruptedError) Unwrap() error {
+	return e.cause
+}
+
 func (i *Instance) preventLeave() func() {
 	i.mayLeave = false
 	return func() {
@@ -135,34 +402,34 @@ func (i *Instance) getExport(name string) (any, error) {
 	return val, nil
 }
 
-type instanceType struct {
+type InstanceType struct {
 	exports map[string]*exportSpec
 	newCopy func() map[string]*exportSpec
 }
 
-func newInstanceType(exports map[string]*exportSpec, newCopy func() map[string]*exportSpec) *instanceType {
-	return &instanceType{
+func newInstanceType(exports map[string]*exportSpec, newCopy func() map[string]*exportSpec) *InstanceType {
+	return &InstanceType{
 		exports: exports,
 		newCopy: newCopy,
 	}
 }
 
-func (it *instanceType) clone() *instanceType {
+func (it *InstanceType) clone() *InstanceType {
 	if it.newCopy != nil {
-		return &instanceType{
+		return &InstanceType{
 			exports: it.newCopy(),
 		}
 	}
 	return it
 }
 
-func (it *instanceType) isType() {}
+func (it *InstanceType) isType() {}
 
-func (it *instanceType) typeName() string {
+func (it *InstanceType) typeName() string {
 	return "instance"
 }
 
-func (it *instanceType) exportType(name string) (Type, bool) {
+func (it *InstanceType) exportType(name string) (Type, bool) {
 	spec, ok := it.exports[name]
 	if !ok {
 		return nil, false
@@ -170,7 +437,7 @@ func (it *instanceType) exportType(name string) (Type, bool) {
 	return spec.typ, ok
 }
 
-func (it *instanceType) checkType(other Type, typeChecker typeChecker) error {
+func (it *InstanceType) checkType(other Type, typeChecker typeChecker) error {
 	oit, err := assertTypeKindIsSame(it, other)
 	if err != nil {
 		return err
@@ -197,7 +464,7 @@ func (it *instanceType) checkType(other Type, typeChecker typeChecker) error {
 	return nil
 }
 
-func (it *instanceType) typeSize() int {
+func (it *InstanceType) typeSize() int {
 	size := 1
 	for _, exportSpec := range it.exports {
 		size += exportSpec.typ.typeSize()
@@ -205,7 +472,7 @@ func (it *instanceType) typeSize() int {
 	return size
 }
 
-func (it *instanceType) typeDepth() int {
+func (it *InstanceType) typeDepth() int {
 	maxDepth := 0
 	for _, exportSpec := range it.exports {
 		if d := exportSpec.typ.typeDepth(); d > maxDepth {
@@ -227,7 +494,7 @@ func newInstantiateDefinition(astDef *ast.Instantiate) *instantiateDefinition {
 
 func (d *instantiateDefinition) isDefinition() {}
 
-func (d *instantiateDefinition) createType(scope *scope) (*instanceType, error) {
+func (d *instantiateDefinition) createType(scope *scope) (*InstanceType, error) {
 	componentType, err := sortScopeFor(scope, sortComponent).getType(d.astDef.ComponentIdx)
 	if err != nil {
 		return nil, fmt.Errorf("unknown component: %w", err)
@@ -276,10 +543,11 @@ func (d *instantiateDefinition) createInstance(ctx context.Context, scope *scope
 		args[astArg.Name] = &instanceArgument{val: val, typ: typ}
 	}
 
-	inst, err := comp.instantiate(ctx, args)
+	inst, err := comp.instantiate(ctx, args, nil)
 	if err != nil {
 		return nil, err
 	}
+	scope.instance.addChild(inst)
 	return inst, nil
 }
 
@@ -295,7 +563,7 @@ func newInlineExportsDefinition(exports []ast.InlineExport) *inlineExportsDefini
 
 func (d *inlineExportsDefinition) isDefinition() {}
 
-func (d *inlineExportsDefinition) createType(scope *scope) (*instanceType, error) {
+func (d *inlineExportsDefinition) createType(scope *scope) (*InstanceType, error) {
 	exportSpecs := make(map[string]*exportSpec)
 	for _, export := range d.exports {
 		typ, err := typeForSortIdx(scope, &export.SortIdx)
@@ -354,7 +622,7 @@ func (r *instanceTypeResolver) resolveType(scope *scope) (Type, error) {
 				return nil, err
 			}
 
-			if it, ok := typ.(*instanceType); ok {
+			if it, ok := typ.(*InstanceType); ok {
 				typ = it.clone()
 			}
 
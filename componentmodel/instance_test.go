@@ -0,0 +1,173 @@
+package componentmodel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/partite-ai/wacogo/ast"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+func TestInstanceClose(t *testing.T) {
+	ctx := context.Background()
+	host := newInstance()
+	var destroyed []any
+	rt := newResourceType(host, func(ctx context.Context, res any) {
+		destroyed = append(destroyed, res)
+	})
+
+	inst := newInstance()
+	owned := NewResourceHandle(inst, rt, "owned")
+	if _, err := inst.loweredHandles.add(owned); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	lent := NewResourceHandle(newInstance(), rt, "lent")
+	if _, err := inst.loweredHandles.add(lent.Borrow()); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+
+	// Close waits for the running task to leave the instance.
+	if err := inst.enter(ctx); err != nil {
+		t.Fatalf("enter failed: %v", err)
+	}
+	closed := make(chan error, 1)
+	go func() {
+		closed <- inst.Close(ctx)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close returned while the instance was active")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if err := inst.exit(); err != nil {
+		t.Fatalf("exit failed: %v", err)
+	}
+	if err := <-closed; err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if len(destroyed) != 1 || destroyed[0] != "owned" {
+		t.Errorf("destroyed %v, want [owned]", destroyed)
+	}
+	if err := inst.enter(ctx); err == nil {
+		t.Error("enter succeeded after Close")
+	}
+	if err := inst.Close(ctx); err != nil {
+		t.Errorf("second Close failed: %v", err)
+	}
+}
+
+// closeRecordingModule is a core module that records the exit code it is
+// closed with.
+type closeRecordingModule struct {
+	api.Module
+	exitCode chan uint32
+}
+
+func (m *closeRecordingModule) CloseWithExitCode(ctx context.Context, exitCode uint32) error {
+	m.exitCode <- exitCode
+	return nil
+}
+
+func TestInstanceInterruptOnDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	inst := newInstance()
+	running := &closeRecordingModule{exitCode: make(chan uint32, 1)}
+	inst.addModule(running)
+
+	stop := inst.interruptOnDone(ctx)
+	cancel()
+	select {
+	case code := <-running.exitCode:
+		if code != sys.ExitCodeContextCanceled {
+			t.Errorf("exit code = %#x, want %#x", code, sys.ExitCodeContextCanceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("module was not closed once the context was cancelled")
+	}
+	if stop() {
+		t.Error("stop() = true after an interruption")
+	}
+	if !inst.hasTrapped() {
+		t.Error("interrupted instance has not trapped")
+	}
+
+	// Modules instantiated after the interruption are closed right away.
+	late := &closeRecordingModule{exitCode: make(chan uint32, 1)}
+	inst.addModule(late)
+	select {
+	case <-late.exitCode:
+	default:
+		t.Error("module added after the interruption was not closed")
+	}
+
+	other := newInstance()
+	if !other.interruptOnDone(context.Background())() {
+		t.Error("stop() = false without an interruption")
+	}
+}
+
+// spinCoreModule is
+//
+//	(module
+//	  (func (export "spin")
+//	    loop
+//	      br 0
+//	    end))
+var spinCoreModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+	0x03, 0x02, 0x01, 0x00,
+	0x07, 0x08, 0x01, 0x04, 's', 'p', 'i', 'n', 0x00, 0x00,
+	0x0a, 0x09, 0x01, 0x07, 0x00, 0x03, 0x40, 0x0c, 0x00, 0x0b, 0x0b,
+}
+
+func TestInstanceInterruptSpinningGuest(t *testing.T) {
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, NewRuntimeConfig())
+	defer runtime.Close(ctx)
+
+	comp, err := NewBuilder(runtime).Build(ctx, &ast.Component{
+		Definitions: []ast.Definition{
+			&ast.CoreModule{Raw: spinCoreModule},
+			&ast.CoreInstance{Expr: &ast.CoreInstantiate{ModuleIdx: 0}},
+			&ast.Alias{Sort: ast.SortCoreFunc, Target: &ast.CoreExportAlias{InstanceIdx: 0, Name: "spin"}},
+			&ast.Type{DefType: &ast.FuncType{}},
+			&ast.Canon{Def: &ast.CanonLift{CoreFuncIdx: 0, FunctionTypeIdx: 0}},
+			&ast.Export{ExportName: "spin", SortIdx: ast.SortIdx{Sort: ast.SortFunc, Idx: 0}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	inst, err := comp.Instantiate(ctx, nil)
+	if err != nil {
+		t.Fatalf("Instantiate failed: %v", err)
+	}
+	defer inst.Close(ctx)
+	spin, ok := inst.Export("spin")
+	if !ok {
+		t.Fatal("spin is not exported")
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := spin.(*Function).Invoke(callCtx)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		var interrupted *InterruptedError
+		if !errors.As(err, &interrupted) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("spin returned %v, want an InterruptedError wrapping %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("spinning guest was not stopped once the context was done")
+	}
+}
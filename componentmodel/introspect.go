@@ -0,0 +1,299 @@
+package componentmodel
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/partite-ai/wacogo/ast"
+)
+
+// Extern describes an import or export of a component or instance. The types of
+// the externs returned by Imports and Exports are copies, so changing them
+// doesn't affect the component, instance or type they describe.
+type Extern struct {
+	Name string
+	Sort ast.Sort
+	// Type is a *FunctionType for a function, a ValueType for a value, the
+	// defined type for a type, and an *InstanceType or *ComponentType for an
+	// instance or component. Core module types are opaque.
+	Type Type
+}
+
+func newExtern(name string, sort genericSort, typ Type) Extern {
+	return Extern{Name: name, Sort: sort.astSort(), Type: typ}
+}
+
+func sortExterns(externs []Extern) []Extern {
+	slices.SortFunc(externs, func(a, b Extern) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return externs
+}
+
+// Imports returns the imports of the component, sorted by name.
+func (c *Component) Imports() []Extern {
+	return cloneExterns(c.importExterns)
+}
+
+// Exports returns the exports of the component, sorted by name.
+func (c *Component) Exports() []Extern {
+	return cloneExterns(c.exportExterns)
+}
+
+// resolveExterns records the types of the imports and exports of a component
+// that has just been built.
+func (c *Component) resolveExterns() error {
+	c.importExterns = make([]Extern, 0, len(c.importTypes))
+	for _, binder := range c.definitions.binders {
+		b, ok := binder.(importBinder)
+		if !ok {
+			continue
+		}
+		name, sort, ok := b.imported()
+		if !ok {
+			continue
+		}
+		typ, err := b.boundType(c.componentScope)
+		if err != nil {
+			return err
+		}
+		c.importExterns = append(c.importExterns, newExtern(name, sort, typ))
+	}
+	c.exportExterns = make([]Extern, 0, len(c.exports))
+	for name, export := range c.exports {
+		typ, err := export.typ(c.componentScope)
+		if err != nil {
+			return err
+		}
+		c.exportExterns = append(c.exportExterns, newExtern(name, export.sort(), typ))
+	}
+	sortExterns(c.importExterns)
+	sortExterns(c.exportExterns)
+	return nil
+}
+
+type importBinder interface {
+	imported() (string, genericSort, bool)
+	boundType(scope *scope) (Type, error)
+}
+
+// importSorts returns the names of the imports of the component in the order
+// they are defined, and the sort of each.
+func (c *Component) importSorts() ([]string, map[string]genericSort) {
+	var names []string
+	sorts := make(map[string]genericSort, len(c.importTypes))
+	for _, binder := range c.definitions.binders {
+		b, ok := binder.(importBinder)
+		if !ok {
+			continue
+		}
+		if name, sort, ok := b.imported(); ok {
+			names = append(names, name)
+			sorts[name] = sort
+		}
+	}
+	return names, sorts
+}
+
+// Exports returns the exports of the instance, sorted by name.
+func (i *Instance) Exports() []Extern {
+	return cloneExterns(exportSpecExterns(i.exportSpecs))
+}
+
+// Exports returns the exports of instances of the type, sorted by name.
+func (it *InstanceType) Exports() []Extern {
+	return cloneExterns(it.externs())
+}
+
+// externs is Exports without copying the types.
+func (it *InstanceType) externs() []Extern {
+	return exportSpecExterns(it.exports)
+}
+
+// NewInstanceType returns the type of instances with the given exports.
+func NewInstanceType(exports []Extern) (*InstanceType, error) {
+	specs := make(map[string]*exportSpec, len(exports))
+	for _, e := range exports {
+		sort := sortForSortID(uint32(e.Sort))
+		if sort == nil {
+			return nil, fmt.Errorf("unsupported sort for export %s: %v", e.Name, e.Sort)
+		}
+		if _, ok := specs[e.Name]; ok {
+			return nil, fmt.Errorf("duplicate export %s", e.Name)
+		}
+		specs[e.Name] = &exportSpec{typ: e.Type, sort: sort}
+	}
+	return newInstanceType(specs, nil), nil
+}
+
+func exportSpecExterns(specs map[string]*exportSpec) []Extern {
+	externs := make([]Extern, 0, len(specs))
+	for name, spec := range specs {
+		externs = append(externs, newExtern(name, spec.sort, spec.typ))
+	}
+	return sortExterns(externs)
+}
+
+// Imports returns the imports of components of the type, sorted by name.
+func (ct *ComponentType) Imports() []Extern {
+	_, sorts := ct.component.importSorts()
+	externs := make([]Extern, 0, len(ct.imports))
+	for name, typ := range ct.imports {
+		externs = append(externs, newExtern(name, sorts[name], cloneType(typ)))
+	}
+	return sortExterns(externs)
+}
+
+// Exports returns the exports of components of the type, sorted by name.
+func (ct *ComponentType) Exports() []Extern {
+	externs := make([]Extern, 0, len(ct.exports))
+	for name, typ := range ct.exports {
+		externs = append(externs, newExtern(name, ct.component.exports[name].sort(), cloneType(typ)))
+	}
+	return sortExterns(externs)
+}
+
+func cloneExterns(externs []Extern) []Extern {
+	clones := make([]Extern, len(externs))
+	for i, e := range externs {
+		clones[i] = Extern{Name: e.Name, Sort: e.Sort, Type: cloneType(e.Type)}
+	}
+	return clones
+}
+
+// cloneType copies the parts of t that can be changed through exported fields
+// or reached through accessors. Resource, instance and component types are
+// shared, as they are compared by identity and can't be changed.
+func cloneType(t Type) Type {
+	switch t := t.(type) {
+	case ValueType:
+		return cloneValueType(t)
+	case *FunctionType:
+		params := make([]*FunctionParameter, len(t.Parameters))
+		for i, p := range t.Parameters {
+			params[i] = &FunctionParameter{Name: p.Name, Type: cloneValueType(p.Type)}
+		}
+		return &FunctionType{
+			Parameters:         params,
+			ResultType:         cloneValueType(t.ResultType),
+			skipParamNameCheck: t.skipParamNameCheck,
+		}
+	default:
+		return t
+	}
+}
+
+func cloneValueType(t ValueType) ValueType {
+	switch t := t.(type) {
+	case *RecordType:
+		fields := make([]*RecordField, len(t.Fields))
+		for i, f := range t.Fields {
+			fields[i] = &RecordField{Name: f.Name, Type: cloneValueType(f.Type)}
+		}
+		return &RecordType{Fields: fields}
+	case *VariantType:
+		cases := make([]*VariantCase, len(t.Cases))
+		for i, c := range t.Cases {
+			cases[i] = &VariantCase{Name: c.Name, Type: cloneValueType(c.Type)}
+		}
+		return &VariantType{Cases: cases}
+	case *FlagsType:
+		return &FlagsType{FlagNames: slices.Clone(t.FlagNames)}
+	case *EnumType:
+		return NewEnumType(t.Labels()...)
+	case *TupleType:
+		elems := t.ElementTypes()
+		for i, et := range elems {
+			elems[i] = cloneValueType(et)
+		}
+		return NewTupleType(elems...)
+	case *OptionType:
+		return NewOptionType(cloneValueType(t.ValueType()))
+	case *ResultType:
+		return NewResultType(cloneValueType(t.OkType()), cloneValueType(t.ErrType()))
+	case *ListType:
+		return &ListType{ElementType: cloneValueType(t.ElementType)}
+	case *FixedListType:
+		return &FixedListType{ElementType: cloneValueType(t.ElementType), Length: t.Length}
+	case *StreamType:
+		return &StreamType{ElementType: cloneValueType(t.ElementType)}
+	case *FutureType:
+		return &FutureType{ValueType: cloneValueType(t.ValueType)}
+	default:
+		return t
+	}
+}
+
+// InspectType traverses t in depth-first order. It starts by calling f(t),
+// and if f returns true, inspects each of the types t refers to: the fields,
+// cases and elements of value types, the resource of a handle, the parameters
+// and result of a function, and the imports and exports of instance and
+// component types. Absent types, like the payload of a case without one, are
+// skipped.
+func InspectType(t Type, f func(Type) bool) {
+	if t == nil || !f(t) {
+		return
+	}
+	for _, child := range childTypes(t) {
+		InspectType(child, f)
+	}
+}
+
+func childTypes(t Type) []Type {
+	var children []Type
+	add := func(ts ...Type) {
+		for _, t := range ts {
+			if t != nil {
+				children = append(children, t)
+			}
+		}
+	}
+	switch t := t.(type) {
+	case *RecordType:
+		for _, f := range t.Fields {
+			add(f.Type)
+		}
+	case *VariantType:
+		for _, c := range t.Cases {
+			add(c.Type)
+		}
+	case *TupleType:
+		for _, et := range t.ElementTypes() {
+			add(et)
+		}
+	case *OptionType:
+		add(t.ValueType())
+	case *ResultType:
+		add(t.OkType(), t.ErrType())
+	case *ListType:
+		add(t.ElementType)
+	case *FixedListType:
+		add(t.ElementType)
+	case *StreamType:
+		add(t.ElementType)
+	case *FutureType:
+		add(t.ValueType)
+	case OwnType:
+		add(t.ResourceType)
+	case BorrowType:
+		add(t.ResourceType)
+	case *FunctionType:
+		for _, p := range t.Parameters {
+			add(p.Type)
+		}
+		add(t.ResultType)
+	case *InstanceType:
+		for _, e := range t.Exports() {
+			add(e.Type)
+		}
+	case *ComponentType:
+		for _, e := range t.Imports() {
+			add(e.Type)
+		}
+		for _, e := range t.Exports() {
+			add(e.Type)
+		}
+	}
+	return children
+}
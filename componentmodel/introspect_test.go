@@ -0,0 +1,138 @@
+package componentmodel
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/partite-ai/wacogo/ast"
+)
+
+func externNames(externs []Extern) []string {
+	names := make([]string, len(externs))
+	for i, e := range externs {
+		names[i] = e.Name + ":" + e.Sort.String()
+	}
+	return names
+}
+
+func TestComponentExterns(t *testing.T) {
+	ctx := context.Background()
+	comp, err := NewBuilder(nil).Build(ctx, &ast.Component{
+		Definitions: []ast.Definition{
+			&ast.Type{DefType: &ast.RecordType{Fields: []ast.RecordField{
+				{Label: "x", Type: &ast.U32Type{}},
+				{Label: "tags", Type: &ast.ListType{Element: &ast.StringType{}}},
+			}}},
+			&ast.Import{ImportName: "point", Desc: &ast.TypeExternDesc{Bound: &ast.EqBound{TypeIdx: 0}}},
+			&ast.Type{DefType: &ast.FuncType{
+				Params:  []ast.FuncParam{{Label: "p", Type: &ast.TypeIdx{Idx: 1}}},
+				Results: &ast.OptionType{Type: &ast.U32Type{}},
+			}},
+			&ast.Import{ImportName: "f", Desc: &ast.SortExternDesc{Sort: ast.SortFunc, TypeIdx: 2}},
+			&ast.Export{ExportName: "g", SortIdx: ast.SortIdx{Sort: ast.SortFunc, Idx: 0}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	imports := comp.Imports()
+	if got, want := externNames(imports), []string{"f:" + ast.SortFunc.String(), "point:" + ast.SortType.String()}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Imports() = %v, want %v", got, want)
+	}
+	exports := comp.Exports()
+	if got, want := externNames(exports), []string{"g:" + ast.SortFunc.String()}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Exports() = %v, want %v", got, want)
+	}
+
+	ft, ok := exports[0].Type.(*FunctionType)
+	if !ok {
+		t.Fatalf("export g has type %T, want *FunctionType", exports[0].Type)
+	}
+	var names []string
+	InspectType(ft, func(t Type) bool {
+		names = append(names, t.typeName())
+		return true
+	})
+	if want := []string{"func", "record", "u32", "list", "string", "option", "u32"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("InspectType visited %v, want %v", names, want)
+	}
+
+	names = nil
+	InspectType(ft, func(t Type) bool {
+		names = append(names, t.typeName())
+		_, isFunc := t.(*FunctionType)
+		return isFunc
+	})
+	if want := []string{"func", "record", "option"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("InspectType without descending visited %v, want %v", names, want)
+	}
+}
+
+func TestInstanceExterns(t *testing.T) {
+	fnType := &FunctionType{ResultType: NewResultType(U32Type{}, nil)}
+	inner := NewInstanceBuilder().AddTypeExport("e", NewEnumType("a", "b")).Build()
+	inst := NewInstanceBuilder().
+		AddFunctionExport("run", func(instance *Instance) *Function {
+			return NewFunction(fnType, func(ctx context.Context, params []Value) (Value, error) {
+				return nil, nil
+			})
+		}).
+		AddInstanceExport("inner", inner).
+		Build()
+
+	exports := inst.Exports()
+	if got, want := externNames(exports), []string{"inner:" + ast.SortInstance.String(), "run:" + ast.SortFunc.String()}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Exports() = %v, want %v", got, want)
+	}
+
+	it, ok := exports[0].Type.(*InstanceType)
+	if !ok {
+		t.Fatalf("export inner has type %T, want *InstanceType", exports[0].Type)
+	}
+	innerExports := it.Exports()
+	if len(innerExports) != 1 {
+		t.Fatalf("inner instance type has %d exports, want 1", len(innerExports))
+	}
+	if et, ok := innerExports[0].Type.(*EnumType); !ok || !reflect.DeepEqual(et.Labels(), []string{"a", "b"}) {
+		t.Errorf("inner export e = %v, want enum {a, b}", innerExports[0].Type)
+	}
+
+	rt := exports[1].Type.(*FunctionType).ResultType.(*ResultType)
+	if rt.OkType() != (U32Type{}) || rt.ErrType() != nil {
+		t.Errorf("result type has ok %v and error %v, want u32 and none", rt.OkType(), rt.ErrType())
+	}
+}
+
+func TestExternTypesAreCopies(t *testing.T) {
+	point := &RecordType{Fields: []*RecordField{{Name: "x", Type: U32Type{}}}}
+	mode := &FlagsType{FlagNames: []string{"read", "write"}}
+	inst := NewInstanceBuilder().
+		AddTypeExport("mode", mode).
+		AddTypeExport("point", point).
+		Build()
+
+	exports := inst.Exports()
+	exports[0].Type.(*FlagsType).FlagNames[0] = "changed"
+	exports[1].Type.(*RecordType).Fields[0].Name = "changed"
+	exports[1].Type.(*RecordType).Fields = nil
+
+	if point.Fields[0].Name != "x" || mode.FlagNames[0] != "read" {
+		t.Errorf("changing the exported types changed the instance's: %v, %v", point.Fields, mode.FlagNames)
+	}
+	again := inst.Exports()
+	if got := again[1].Type.(*RecordType); len(got.Fields) != 1 || got.Fields[0].Name != "x" {
+		t.Errorf("point = %v after changing an earlier copy", got.Fields)
+	}
+}
+
+func TestValueTypeAccessors(t *testing.T) {
+	tt := NewTupleType(U8Type{}, StringType{})
+	if got, want := tt.ElementTypes(), []ValueType{U8Type{}, StringType{}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ElementTypes() = %v, want %v", got, want)
+	}
+	if got := NewOptionType(BoolType{}).ValueType(); got != (BoolType{}) {
+		t.Errorf("ValueType() = %v, want bool", got)
+	}
+}
@@ -0,0 +1,108 @@
+package componentmodel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/partite-ai/wacogo/wasm"
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+const wasmPageSize = 65536
+
+// Limits bounds the resources each instance of a component can use. A zero
+// field means no limit.
+type Limits struct {
+	// MemoryPages is the maximum size of a linear memory, in 64KiB pages.
+	// Growing a memory past it fails, and a module whose memory starts out
+	// larger is refused before its memory is allocated.
+	MemoryPages uint32
+	// Handles is the maximum number of resource handles an instance holds
+	// at once. It separately bounds the streams, futures and subtasks, the
+	// waitable sets and the error contexts of the instance.
+	Handles uint32
+	// ListBytes is the maximum size of a list lifted from linear memory, in
+	// bytes. Elements of zero size count as one byte.
+	ListBytes uint32
+	// StringBytes is the maximum size of a string lifted from linear memory,
+	// in encoded bytes.
+	StringBytes uint32
+}
+
+// LimitError is the trap raised when an instance exceeds one of its Limits.
+type LimitError struct {
+	Resource string
+	Size     uint64
+	Limit    uint64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s %d exceeds the limit of %d", e.Resource, e.Size, e.Limit)
+}
+
+func checkLimit(resource string, size uint64, limit uint32) error {
+	if limit != 0 && size > uint64(limit) {
+		return &LimitError{Resource: resource, Size: size, Limit: uint64(limit)}
+	}
+	return nil
+}
+
+// checkMemoryLimit refuses a module whose memories start out larger than the
+// page limit, before instantiating it would allocate them.
+func checkMemoryLimit(externs *wasm.Externs, limits Limits) error {
+	for _, mem := range externs.Memories {
+		if err := checkLimit("memory pages", uint64(mem.Min), limits.MemoryPages); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// memoryLimiter allocates the linear memories of a core module instance,
+// refusing to grow them past the page limit.
+type memoryLimiter struct {
+	maxPages uint32
+}
+
+// withMemoryLimiter returns a context under which core modules are
+// instantiated with memories allocated by a limiter, unless limits doesn't
+// bound memory.
+func withMemoryLimiter(ctx context.Context, limits Limits) context.Context {
+	if limits.MemoryPages == 0 {
+		return ctx
+	}
+	return experimental.WithMemoryAllocator(ctx, &memoryLimiter{maxPages: limits.MemoryPages})
+}
+
+func (l *memoryLimiter) Allocate(capacity, max uint64) experimental.LinearMemory {
+	maxBytes := uint64(l.maxPages) * wasmPageSize
+	return &limitedMemory{
+		maxBytes: maxBytes,
+		buf:      make([]byte, 0, min(capacity, maxBytes)),
+	}
+}
+
+type limitedMemory struct {
+	maxBytes uint64
+	buf      []byte
+}
+
+// Reallocate never allocates past the limit. Modules whose memories start out
+// larger are refused by checkMemoryLimit before they are instantiated.
+func (m *limitedMemory) Reallocate(size uint64) []byte {
+	if size > m.maxBytes {
+		return nil
+	}
+	if uint64(cap(m.buf)) >= size {
+		m.buf = m.buf[:size]
+		return m.buf
+	}
+	buf := make([]byte, size, max(size, min(2*uint64(cap(m.buf)), m.maxBytes)))
+	copy(buf, m.buf)
+	m.buf = buf
+	return m.buf
+}
+
+func (m *limitedMemory) Free() {
+	m.buf = nil
+}
@@ -0,0 +1,141 @@
+package componentmodel
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/partite-ai/wacogo/wasm"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// sliceMemory is a linear memory backed by a byte slice, implementing just
+// what lifting lists and strings needs.
+type sliceMemory struct {
+	api.Memory
+	buf []byte
+}
+
+func (m *sliceMemory) Size() uint32 {
+	return uint32(len(m.buf))
+}
+
+func (m *sliceMemory) Read(offset, count uint32) ([]byte, bool) {
+	if uint64(offset)+uint64(count) > uint64(len(m.buf)) {
+		return nil, false
+	}
+	return m.buf[offset : offset+count], true
+}
+
+func flatValues(vals ...uint64) func() uint64 {
+	return func() uint64 {
+		v := vals[0]
+		vals = vals[1:]
+		return v
+	}
+}
+
+func TestLiftLimits(t *testing.T) {
+	inst := newInstance()
+	inst.setLimits(Limits{ListBytes: 16, StringBytes: 8})
+	llc := &LiftLoadContext{instance: inst, memory: &sliceMemory{buf: make([]byte, 64)}}
+
+	tests := []struct {
+		name    string
+		typ     ValueType
+		ptr     uint32
+		length  uint32
+		wantErr bool
+	}{
+		{"list over limit", &ListType{ElementType: U32Type{}}, 0, 5, true},
+		{"list out of bounds", &ListType{ElementType: U8Type{}}, 60, 8, true},
+		{"byte array within limit", ByteArrayType{}, 0, 16, false},
+		{"byte array over limit", ByteArrayType{}, 0, 17, true},
+		{"string within limit", StringType{}, 0, 8, false},
+		{"string over limit", StringType{}, 0, 9, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.typ.liftFlat(llc, flatValues(uint64(tt.ptr), uint64(tt.length)))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("liftFlat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	var limitErr *LimitError
+	_, err := StringType{}.liftFlat(llc, flatValues(0, 1<<30))
+	if !errors.As(err, &limitErr) {
+		t.Errorf("lifting a huge string returned %v, want a LimitError", err)
+	}
+}
+
+func TestHandleLimit(t *testing.T) {
+	inst := newInstance()
+	inst.setLimits(Limits{Handles: 2})
+	rt := newResourceType(inst, nil)
+
+	var idxs []uint32
+	for range 2 {
+		idx, err := inst.loweredHandles.add(NewResourceHandle(inst, rt, 0))
+		if err != nil {
+			t.Fatalf("add failed: %v", err)
+		}
+		idxs = append(idxs, idx)
+	}
+	var limitErr *LimitError
+	if _, err := inst.loweredHandles.add(NewResourceHandle(inst, rt, 0)); !errors.As(err, &limitErr) {
+		t.Fatalf("add over the limit returned %v, want a LimitError", err)
+	}
+
+	inst.loweredHandles.remove(idxs[0])
+	if _, err := inst.loweredHandles.add(NewResourceHandle(inst, rt, 0)); err != nil {
+		t.Errorf("add after remove failed: %v", err)
+	}
+
+	for range 2 {
+		if _, err := inst.waitableSets.add(newWaitableSet()); err != nil {
+			t.Fatalf("add waitable set failed: %v", err)
+		}
+	}
+	if _, err := inst.waitableSets.add(newWaitableSet()); !errors.As(err, &limitErr) {
+		t.Errorf("add waitable set over the limit returned %v, want a LimitError", err)
+	}
+	for range 2 {
+		if _, err := inst.errorContexts.add(&ErrorContext{}); err != nil {
+			t.Fatalf("add error context failed: %v", err)
+		}
+	}
+	if _, err := inst.errorContexts.add(&ErrorContext{}); !errors.As(err, &limitErr) {
+		t.Errorf("add error context over the limit returned %v, want a LimitError", err)
+	}
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	l := &memoryLimiter{maxPages: 2}
+	mem := l.Allocate(wasmPageSize, 4*wasmPageSize)
+
+	if buf := mem.Reallocate(wasmPageSize); len(buf) != wasmPageSize {
+		t.Fatalf("initial allocation has %d bytes, want %d", len(buf), wasmPageSize)
+	}
+	buf := mem.Reallocate(2 * wasmPageSize)
+	if len(buf) != 2*wasmPageSize {
+		t.Fatalf("growing to the limit gave %d bytes, want %d", len(buf), 2*wasmPageSize)
+	}
+	if buf := mem.Reallocate(3 * wasmPageSize); buf != nil {
+		t.Error("growing past the limit succeeded")
+	}
+}
+
+func TestMemoryLimitBeforeInstantiate(t *testing.T) {
+	limits := Limits{MemoryPages: 2}
+	externs := &wasm.Externs{Memories: []*wasm.MemoryType{{Min: 1}, {Min: 2}}}
+	if err := checkMemoryLimit(externs, limits); err != nil {
+		t.Errorf("checkMemoryLimit() = %v, want nil", err)
+	}
+
+	externs.Memories = append(externs.Memories, &wasm.MemoryType{Min: 65536})
+	var limitErr *LimitError
+	if err := checkMemoryLimit(externs, limits); !errors.As(err, &limitErr) {
+		t.Errorf("checkMemoryLimit() = %v, want a LimitError", err)
+	}
+}
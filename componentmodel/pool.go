@@ -0,0 +1,177 @@
+package componentmodel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// PoolConfig bounds the instances handed out by a Pool.
+type PoolConfig struct {
+	// MaxInstances is the maximum number of instances in use at once. Get
+	// blocks while the limit is reached. Zero means no limit.
+	MaxInstances int
+	// MaxIdle is the maximum number of released instances kept for reuse.
+	// Zero means no limit.
+	MaxIdle int
+	// MaxUses is the number of times an instance is handed out before it is
+	// discarded. Zero means no limit. One gives every caller a new instance.
+	MaxUses int
+	// Reset, if set, is called by Put on an instance before it is kept for
+	// reuse, to restore whatever state the next caller must not see. The
+	// instance is closed instead if Reset returns an error.
+	Reset func(ctx context.Context, inst *Instance) error
+}
+
+// Pool hands out instances of a prepared component and recycles them. An
+// instance is given to one caller at a time, so callers never contend on the
+// same instance. Instances that trapped are discarded rather than reused.
+//
+// A reused instance is not reset: its linear memories, globals, tables and
+// handles are left as the previous caller left them. Components that keep
+// state between calls must be reset with PoolConfig.Reset, or not be reused
+// at all with a MaxUses of one.
+type Pool struct {
+	pre    *InstancePre
+	config PoolConfig
+	slots  chan struct{}
+
+	mu     sync.Mutex
+	idle   []*Instance
+	uses   map[*Instance]int
+	inUse  map[*Instance]struct{}
+	closed bool
+}
+
+func NewPool(pre *InstancePre, config PoolConfig) *Pool {
+	p := &Pool{
+		pre:    pre,
+		config: config,
+		uses:   make(map[*Instance]int),
+		inUse:  make(map[*Instance]struct{}),
+	}
+	if config.MaxInstances > 0 {
+		p.slots = make(chan struct{}, config.MaxInstances)
+	}
+	return p
+}
+
+// Get returns an instance for the exclusive use of the caller, which must
+// return it with Put. It reuses an idle instance if there is one, and
+// otherwise instantiates a new one.
+func (p *Pool) Get(ctx context.Context) (*Instance, error) {
+	if p.slots != nil {
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	inst, err := p.get(ctx)
+	if err != nil {
+		p.releaseSlot()
+		return nil, err
+	}
+	return inst, nil
+}
+
+func (p *Pool) get(ctx context.Context) (*Instance, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("instance pool is closed")
+	}
+	if n := len(p.idle); n > 0 {
+		inst := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.uses[inst]++
+		p.inUse[inst] = struct{}{}
+		p.mu.Unlock()
+		return inst, nil
+	}
+	p.mu.Unlock()
+
+	inst, err := p.pre.Instantiate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.uses[inst] = 1
+	p.inUse[inst] = struct{}{}
+	return inst, nil
+}
+
+// Put returns an instance obtained from Get to the pool. The instance is
+// closed if it trapped, has been used MaxUses times, fails to reset, or the
+// pool is full or closed.
+func (p *Pool) Put(inst *Instance) {
+	p.mu.Lock()
+	if _, ok := p.inUse[inst]; !ok {
+		p.mu.Unlock()
+		panic("instance was not obtained from the pool")
+	}
+	p.mu.Unlock()
+
+	reset := true
+	if p.config.Reset != nil && !inst.hasTrapped() {
+		reset = p.config.Reset(context.Background(), inst) == nil
+	}
+
+	p.mu.Lock()
+	delete(p.inUse, inst)
+	keep := reset && !p.closed &&
+		!inst.hasTrapped() &&
+		(p.config.MaxUses <= 0 || p.uses[inst] < p.config.MaxUses) &&
+		(p.config.MaxIdle <= 0 || len(p.idle) < p.config.MaxIdle)
+	if keep {
+		p.idle = append(p.idle, inst)
+	} else {
+		delete(p.uses, inst)
+	}
+	p.mu.Unlock()
+
+	if !keep {
+		inst.Close(context.Background())
+	}
+	p.releaseSlot()
+}
+
+// Do runs fn with an instance from the pool, returning the instance once fn
+// is done.
+func (p *Pool) Do(ctx context.Context, fn func(inst *Instance) error) error {
+	inst, err := p.Get(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.Put(inst)
+	return fn(inst)
+}
+
+// Close closes the idle instances. Instances in use are closed when they are
+// returned, and later calls to Get fail.
+func (p *Pool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	for _, inst := range idle {
+		delete(p.uses, inst)
+	}
+	p.mu.Unlock()
+
+	var errs []error
+	for _, inst := range idle {
+		errs = append(errs, inst.Close(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Pool) releaseSlot() {
+	if p.slots != nil {
+		<-p.slots
+	}
+}
@@ -0,0 +1,190 @@
+package componentmodel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestPool(t *testing.T, config PoolConfig) (*Pool, *atomic.Int32) {
+	var instantiations atomic.Int32
+	double := NewFunction(&FunctionType{
+		Parameters: []*FunctionParameter{{Name: "x", Type: U32Type{}}},
+		ResultType: U32Type{},
+	}, func(ctx context.Context, params []Value) (Value, error) {
+		instantiations.Add(1)
+		return params[0].(U32) * 2, nil
+	})
+	pre, err := newStartComponent(t).Prepare(context.Background(), map[string]any{"n": U32(21), "double": double})
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	return NewPool(pre, config), &instantiations
+}
+
+func TestPoolReuse(t *testing.T) {
+	ctx := context.Background()
+	pool, instantiations := newTestPool(t, PoolConfig{MaxUses: 2})
+
+	var first *Instance
+	for i := range 3 {
+		inst, err := pool.Get(ctx)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		switch i {
+		case 0:
+			first = inst
+		case 1:
+			if inst != first {
+				t.Error("Get did not reuse the idle instance")
+			}
+		case 2:
+			if inst == first {
+				t.Error("Get reused an instance past MaxUses")
+			}
+		}
+		pool.Put(inst)
+	}
+	if n := instantiations.Load(); n != 2 {
+		t.Errorf("instantiated %d times, want 2", n)
+	}
+}
+
+func TestPoolDiscardsTrappedInstances(t *testing.T) {
+	ctx := context.Background()
+	pool, _ := newTestPool(t, PoolConfig{})
+
+	inst, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	inst.trap()
+	pool.Put(inst)
+
+	next, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if next == inst {
+		t.Error("Get reused a trapped instance")
+	}
+	pool.Put(next)
+}
+
+func TestPoolReset(t *testing.T) {
+	ctx := context.Background()
+	var resets []*Instance
+	failReset := false
+	pool, _ := newTestPool(t, PoolConfig{
+		Reset: func(ctx context.Context, inst *Instance) error {
+			resets = append(resets, inst)
+			if failReset {
+				return errors.New("reset failed")
+			}
+			return nil
+		},
+	})
+
+	inst, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	pool.Put(inst)
+	if len(resets) != 1 || resets[0] != inst {
+		t.Fatalf("reset %v, want the returned instance", resets)
+	}
+
+	// An instance that fails to reset is not reused.
+	inst, err = pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	failReset = true
+	pool.Put(inst)
+	next, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if next == inst {
+		t.Error("Get reused an instance that failed to reset")
+	}
+	pool.Put(next)
+}
+
+func TestPoolMaxInstances(t *testing.T) {
+	ctx := context.Background()
+	pool, _ := newTestPool(t, PoolConfig{MaxInstances: 2})
+
+	var (
+		mu     sync.Mutex
+		active = make(map[*Instance]bool)
+		peak   int
+		wg     sync.WaitGroup
+	)
+	for range 8 {
+		wg.Go(func() {
+			err := pool.Do(ctx, func(inst *Instance) error {
+				mu.Lock()
+				if active[inst] {
+					t.Error("instance handed out to two callers at once")
+				}
+				active[inst] = true
+				peak = max(peak, len(active))
+				mu.Unlock()
+
+				time.Sleep(time.Millisecond)
+
+				mu.Lock()
+				delete(active, inst)
+				mu.Unlock()
+				return nil
+			})
+			if err != nil {
+				t.Errorf("Do failed: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	if peak > 2 {
+		t.Errorf("%d instances in use at once, want at most 2", peak)
+	}
+
+	inst, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	defer pool.Put(inst)
+	inst2, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	defer pool.Put(inst2)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Get(timeoutCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get on an exhausted pool returned %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestPoolClose(t *testing.T) {
+	ctx := context.Background()
+	pool, _ := newTestPool(t, PoolConfig{})
+
+	inst, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if err := pool.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	pool.Put(inst)
+
+	if _, err := pool.Get(ctx); err == nil {
+		t.Error("Get on a closed pool succeeded")
+	}
+}
@@ -1,6 +1,7 @@
 package componentmodel
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"strconv"
@@ -121,48 +122,122 @@ type instanceArgument struct {
 	typ Type
 }
 
+// resolveArgumentValue looks up the argument satisfying the import name. Besides
+// an exact match, an import of a versioned interface is satisfied by any
+// argument for the same interface whose version is semver-compatible with it,
+// preferring the highest such version. Following the component model, versions
+// are compatible when they share a non-zero major version, or a zero major and
+// the same non-zero minor version. Other versions, including prereleases, only
+// match exactly.
 func resolveArgumentValue[T any](args map[string]T, name string) (T, bool) {
 	// Exact match check
 	val, ok := args[name]
 	if ok {
 		return val, true
 	}
+	return highestCompatible(args, name)
+}
+
+// highestCompatible returns the argument for the highest version of the
+// interface of name that is semver-compatible with its version.
+func highestCompatible[T any](args map[string]T, name string) (T, bool) {
+	iface, version, ok := strings.Cut(name, "@")
+	if !ok {
+		return zero[T](), false
+	}
+	want, ok := parseSemver(version)
+	if !ok {
+		return zero[T](), false
+	}
+	wantKey, ok := want.compatibilityKey()
+	if !ok {
+		return zero[T](), false
+	}
 
-	var matchPrefix string
-	if iface, version, ok := strings.Cut(name, "@"); ok {
-		versionParts := strings.Split(version, ".")
-		if len(versionParts) == 3 {
-			major := versionParts[0]
-			minor := versionParts[1]
-			patch := versionParts[2]
-			majorNum, err := strconv.ParseUint(major, 10, 64)
-			if err == nil {
-				if majorNum > 0 {
-					matchPrefix = fmt.Sprintf("%s@%d.", iface, majorNum)
-				} else {
-					minorNum, err := strconv.ParseUint(minor, 10, 64)
-					if err == nil {
-						if minorNum > 0 {
-							matchPrefix = fmt.Sprintf("%s@%d.%d.", iface, majorNum, minorNum)
-						} else {
-							if patchNum, _, ok := strings.Cut(patch, "-"); ok {
-								matchPrefix = fmt.Sprintf("%s@%d.%d.%s", iface, majorNum, minorNum, patchNum)
-							}
-						}
-					}
-				}
-			}
+	var best semver
+	var bestVal T
+	found := false
+	for argName, argVal := range args {
+		argIface, argVersion, ok := strings.Cut(argName, "@")
+		if !ok || argIface != iface {
+			continue
+		}
+		v, ok := parseSemver(argVersion)
+		if !ok {
+			continue
+		}
+		if key, ok := v.compatibilityKey(); !ok || key != wantKey {
+			continue
+		}
+		if !found || v.compare(best) > 0 {
+			best, bestVal, found = v, argVal, true
 		}
 	}
+	return bestVal, found
+}
 
-	if matchPrefix != "" {
-		for argName, argVal := range args {
-			if strings.HasPrefix(argName, matchPrefix) {
-				return argVal, true
-			}
+// HighestCompatibleName returns the name among names with the highest version
+// of the interface of name that is semver-compatible with its version, even if
+// name itself is among them.
+func HighestCompatibleName(names []string, name string) (string, bool) {
+	args := make(map[string]string, len(names))
+	for _, n := range names {
+		args[n] = n
+	}
+	return highestCompatible(args, name)
+}
+
+type semver struct {
+	major, minor, patch uint64
+	prerelease          string
+}
+
+// parseSemver parses a semantic version. Build metadata is ignored.
+func parseSemver(s string) (semver, bool) {
+	s, _, _ = strings.Cut(s, "+")
+	s, pre, _ := strings.Cut(s, "-")
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return semver{}, false
+	}
+	var nums [3]uint64
+	for i, part := range parts {
+		if part == "" || (len(part) > 1 && part[0] == '0') {
+			return semver{}, false
 		}
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return semver{}, false
+		}
+		nums[i] = n
+	}
+	return semver{major: nums[0], minor: nums[1], patch: nums[2], prerelease: pre}, true
+}
+
+// compatibilityKey returns the part of the version that compatible versions
+// share, or false if the version is only compatible with itself.
+func (v semver) compatibilityKey() (string, bool) {
+	switch {
+	case v.prerelease != "":
+		return "", false
+	case v.major > 0:
+		return strconv.FormatUint(v.major, 10), true
+	case v.minor > 0:
+		return fmt.Sprintf("0.%d", v.minor), true
+	default:
+		return "", false
+	}
+}
+
+func (v semver) compare(o semver) int {
+	switch {
+	case v.major != o.major:
+		return cmp.Compare(v.major, o.major)
+	case v.minor != o.minor:
+		return cmp.Compare(v.minor, o.minor)
+	default:
+		return cmp.Compare(v.patch, o.patch)
 	}
-	return zero[T](), false
 }
 
 func sortForSortID(id uint32) genericSort {
@@ -189,6 +264,8 @@ func sortForSortID(id uint32) genericSort {
 		return sortComponent
 	case ast.SortInstance:
 		return sortInstance
+	case ast.SortValue:
+		return sortValue
 	default:
 		return nil
 	}
@@ -230,6 +307,8 @@ func typeForSortIdx(scope *scope, sortIdx *ast.SortIdx) (Type, error) {
 		return sortScopeFor(scope, sortComponent).getType(sortIdx.Idx)
 	case ast.SortInstance:
 		return sortScopeFor(scope, sortInstance).getType(sortIdx.Idx)
+	case ast.SortValue:
+		return sortScopeFor(scope, sortValue).getType(sortIdx.Idx)
 	default:
 		return nil, fmt.Errorf("unsupported sort: %v", sortIdx.Sort)
 	}
@@ -259,6 +338,8 @@ func instanceForSortIdx(scope *scope, sortIdx *ast.SortIdx) (any, error) {
 		return sortScopeFor(scope, sortComponent).getInstance(sortIdx.Idx)
 	case ast.SortInstance:
 		return sortScopeFor(scope, sortInstance).getInstance(sortIdx.Idx)
+	case ast.SortValue:
+		return sortScopeFor(scope, sortValue).getInstance(sortIdx.Idx)
 	default:
 		return nil, fmt.Errorf("unsupported sort: %v", sortIdx.Sort)
 	}
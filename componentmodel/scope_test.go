@@ -0,0 +1,58 @@
+package componentmodel
+
+import "testing"
+
+func TestResolveArgumentValue(t *testing.T) {
+	args := map[string]string{
+		"wasi:io/streams@0.2.0":      "streams 0.2.0",
+		"wasi:io/streams@0.2.6":      "streams 0.2.6",
+		"wasi:io/streams@0.3.0":      "streams 0.3.0",
+		"example:kv/store@1.0.0":     "store 1.0.0",
+		"example:kv/store@1.2.0":     "store 1.2.0",
+		"example:kv/store@2.0.0":     "store 2.0.0",
+		"example:pre/api@1.0.0-rc.1": "api 1.0.0-rc.1",
+		"example:zero/api@0.0.1":     "zero 0.0.1",
+		"plain":                      "plain",
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"plain", "plain"},
+		{"wasi:io/streams@0.2.0", "streams 0.2.0"},
+		{"wasi:io/streams@0.2.3", "streams 0.2.6"},
+		{"wasi:io/streams@0.2.9", "streams 0.2.6"},
+		{"wasi:io/streams@0.3.1", "streams 0.3.0"},
+		{"example:kv/store@1.1.0", "store 1.2.0"},
+		{"example:kv/store@2.5.1", "store 2.0.0"},
+		{"example:pre/api@1.0.0-rc.1", "api 1.0.0-rc.1"},
+		{"example:pre/api@1.0.0-rc.2", ""},
+		{"example:pre/api@1.0.0", ""},
+		{"example:zero/api@0.0.2", ""},
+		{"example:kv/store@3.0.0", ""},
+		{"example:kv/other@1.0.0", ""},
+	}
+	for _, tt := range tests {
+		got, ok := resolveArgumentValue(args, tt.name)
+		if tt.want == "" {
+			if ok {
+				t.Errorf("resolveArgumentValue(%q) = %q, want no match", tt.name, got)
+			}
+			continue
+		}
+		if !ok || got != tt.want {
+			t.Errorf("resolveArgumentValue(%q) = %q, %v, want %q", tt.name, got, ok, tt.want)
+		}
+	}
+}
+
+func TestHighestCompatibleName(t *testing.T) {
+	names := []string{"wasi:cli/run@0.2.0", "wasi:cli/run@0.2.6", "wasi:cli/run@0.3.0", "wasi:cli/environment@0.2.9"}
+	if got, ok := HighestCompatibleName(names, "wasi:cli/run@0.2.0"); !ok || got != "wasi:cli/run@0.2.6" {
+		t.Errorf("HighestCompatibleName() = %q, %v, want %q", got, ok, "wasi:cli/run@0.2.6")
+	}
+	if got, ok := HighestCompatibleName(names, "wasi:http/incoming-handler@0.2.0"); ok {
+		t.Errorf("HighestCompatibleName() = %q, want no match", got)
+	}
+}
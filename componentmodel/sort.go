@@ -30,11 +30,17 @@ func (s sort[V, T]) typeName() string {
 		return "component"
 	case ast.SortInstance:
 		return "instance"
+	case ast.SortValue:
+		return "value"
 	default:
 		return "unknown"
 	}
 }
 
+func (s sort[V, T]) astSort() ast.Sort {
+	return ast.Sort(s)
+}
+
 func (s sort[V, T]) addImport(defs *definitions, name string, typ typeResolver) (typeResolver, error) {
 	idx := sortDefsFor(defs, s).add(newImportDefinition(s, name, typ))
 	return newIndexTypeResolverOf[T](s, idx, ""), nil
@@ -54,13 +60,15 @@ var sortCoreModule sort[*coreModule, *coreModuleType] = sort[*coreModule, *coreM
 var sortCoreInstance sort[*coreInstance, *coreInstanceType] = sort[*coreInstance, *coreInstanceType](ast.SortCoreInstance)
 var sortFunction sort[*Function, *FunctionType] = sort[*Function, *FunctionType](ast.SortFunc)
 var sortType sort[Type, Type] = sort[Type, Type](ast.SortType)
-var sortComponent sort[*Component, *componentType] = sort[*Component, *componentType](ast.SortComponent)
-var sortInstance sort[*Instance, *instanceType] = sort[*Instance, *instanceType](ast.SortInstance)
+var sortComponent sort[*Component, *ComponentType] = sort[*Component, *ComponentType](ast.SortComponent)
+var sortInstance sort[*Instance, *InstanceType] = sort[*Instance, *InstanceType](ast.SortInstance)
+var sortValue sort[Value, ValueType] = sort[Value, ValueType](ast.SortValue)
 
-const numSorts = 11
+const numSorts = 12
 
 type genericSort interface {
 	typeName() string
+	astSort() ast.Sort
 	addImport(defs *definitions, name string, typ typeResolver) (typeResolver, error)
 	addTypeOnlyExportDefinition(defs *definitions, typResolver typeResolver) (componentExport, error)
 }
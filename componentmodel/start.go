@@ -0,0 +1,119 @@
+package componentmodel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/partite-ai/wacogo/ast"
+)
+
+// startDefinition calls a function while an instance is being bound and
+// appends its results to the value index space. It binds directly rather than
+// through a sort, since a start function need not produce any values.
+type startDefinition struct {
+	funcIdx uint32
+	args    []uint32
+	results uint32
+}
+
+func newStartDefinition(astDef *ast.Start) *startDefinition {
+	return &startDefinition{
+		funcIdx: astDef.FuncIdx,
+		args:    astDef.Args,
+		results: astDef.Results,
+	}
+}
+
+func (d *startDefinition) functionType(scope *scope) (*FunctionType, error) {
+	fnType, err := sortScopeFor(scope, sortFunction).getType(d.funcIdx)
+	if err != nil {
+		return nil, err
+	}
+	if len(fnType.Parameters) != len(d.args) {
+		return nil, fmt.Errorf("start function requires %d arguments, found %d", len(fnType.Parameters), len(d.args))
+	}
+	typeChecker := newTypeChecker()
+	for i, param := range fnType.Parameters {
+		argType, err := sortScopeFor(scope, sortValue).getType(d.args[i])
+		if err != nil {
+			return nil, err
+		}
+		if err := typeChecker.checkTypeCompatible(param.Type, argType); err != nil {
+			return nil, fmt.Errorf("type mismatch in start function argument `%s`: %w", param.Name, err)
+		}
+	}
+	var results uint32
+	if fnType.ResultType != nil {
+		results = 1
+	}
+	if results != d.results {
+		return nil, fmt.Errorf("start function returns %d results, found %d", results, d.results)
+	}
+	return fnType, nil
+}
+
+func (d *startDefinition) bindType(scope *scope) error {
+	fnType, err := d.functionType(scope)
+	if err != nil {
+		return err
+	}
+	if fnType.ResultType != nil {
+		sortScopeFor(scope, sortValue).add(&boundDefinition[Value, ValueType]{
+			scope: scope,
+			typ:   fnType.ResultType,
+		})
+	}
+	return nil
+}
+
+func (d *startDefinition) bindInstance(ctx context.Context, scope *scope, prepared Type) error {
+	fnType, err := d.functionType(scope)
+	if err != nil {
+		return err
+	}
+	fn, err := sortScopeFor(scope, sortFunction).getInstance(d.funcIdx)
+	if err != nil {
+		return err
+	}
+	args := make([]Value, len(d.args))
+	for i, idx := range d.args {
+		args[i], err = sortScopeFor(scope, sortValue).getInstance(idx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The start function may be lifted from the instance being bound, so let
+	// it enter the instance.
+	resume := scope.instance.suspend()
+	result, err := fn.invoke(ctx, args)
+	resume()
+	if err != nil {
+		return fmt.Errorf("start function failed: %w", err)
+	}
+
+	if fnType.ResultType != nil {
+		sortScopeFor(scope, sortValue).add(&boundDefinition[Value, ValueType]{
+			scope:       scope,
+			typ:         fnType.ResultType,
+			val:         result,
+			valResolved: true,
+		})
+	}
+	return nil
+}
+
+// startResultDefinition stands in for a start function result in the value
+// definitions, so that later value indices line up. The value itself is bound
+// by the start definition.
+type startResultDefinition struct{}
+
+func (d startResultDefinition) isDefinition() {}
+
+func (d startResultDefinition) createType(scope *scope) (ValueType, error) {
+	return nil, fmt.Errorf("start function results are bound by the start definition")
+}
+
+func (d startResultDefinition) createInstance(ctx context.Context, scope *scope) (Value, error) {
+	return nil, fmt.Errorf("start function results are bound by the start definition")
+}
@@ -0,0 +1,58 @@
+package componentmodel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/partite-ai/wacogo/ast"
+)
+
+// newStartComponent builds a component that runs its "double" import on its
+// "n" import at start and exports the result as "r".
+func newStartComponent(t testing.TB) *Component {
+	comp, err := NewBuilder(nil).Build(context.Background(), &ast.Component{
+		Definitions: []ast.Definition{
+			&ast.Type{DefType: &ast.FuncType{
+				Params:  []ast.FuncParam{{Label: "x", Type: &ast.U32Type{}}},
+				Results: &ast.U32Type{},
+			}},
+			&ast.Import{ImportName: "n", Desc: &ast.ValueExternDesc{Bound: &ast.ValueTypeBound{Type: &ast.U32Type{}}}},
+			&ast.Import{ImportName: "double", Desc: &ast.SortExternDesc{Sort: ast.SortFunc, TypeIdx: 0}},
+			&ast.Start{FuncIdx: 0, Args: []uint32{0}, Results: 1},
+			&ast.Export{ExportName: "r", SortIdx: ast.SortIdx{Sort: ast.SortValue, Idx: 1}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	return comp
+}
+
+func TestStartWithValues(t *testing.T) {
+	ctx := context.Background()
+	comp := newStartComponent(t)
+
+	calls := 0
+	double := NewFunction(&FunctionType{
+		Parameters: []*FunctionParameter{{Name: "x", Type: U32Type{}}},
+		ResultType: U32Type{},
+	}, func(ctx context.Context, params []Value) (Value, error) {
+		calls++
+		return params[0].(U32) * 2, nil
+	})
+
+	inst, err := comp.Instantiate(ctx, map[string]any{"n": U32(21), "double": double})
+	if err != nil {
+		t.Fatalf("Instantiate failed: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("start function called %d times, want 1", calls)
+	}
+	if r, ok := inst.Export("r"); !ok || r != U32(42) {
+		t.Errorf("Export(\"r\") = %v, %v, want 42", r, ok)
+	}
+
+	if _, err := comp.Instantiate(ctx, map[string]any{"n": String("21"), "double": double}); err == nil {
+		t.Error("Instantiate with a mistyped value import succeeded")
+	}
+}
@@ -0,0 +1,676 @@
+package componentmodel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// streamBufferSize is the number of elements a stream buffers between its
+// writer and its reader.
+const streamBufferSize = 16384
+
+var (
+	errStreamClosed   = errors.New("stream closed")
+	errFutureConsumed = errors.New("future has already been read")
+)
+
+type StreamType struct {
+	// ElementType is nil for a stream that only signals.
+	ElementType ValueType
+}
+
+func NewStreamType(elementType ValueType) *StreamType {
+	return &StreamType{ElementType: elementType}
+}
+
+func (t *StreamType) isType()      {}
+func (t *StreamType) isValueType() {}
+func (t *StreamType) supportsValue(v Value) bool {
+	_, ok := v.(*Stream)
+	return ok
+}
+
+func (t *StreamType) typeName() string {
+	return "stream"
+}
+
+func (t *StreamType) checkType(other Type, typeChecker typeChecker) error {
+	ot, err := assertTypeKindIsSame(t, other)
+	if err != nil {
+		return err
+	}
+	return checkOptionalTypesCompatible(t.ElementType, ot.ElementType, typeChecker)
+}
+
+func (t *StreamType) alignment() uint32   { return 4 }
+func (t *StreamType) elementSize() uint32 { return 4 }
+func (t *StreamType) flatTypes() []api.ValueType {
+	return []api.ValueType{api.ValueTypeI32}
+}
+
+func (t *StreamType) liftFlat(llc *LiftLoadContext, itr func() uint64) (Value, error) {
+	return t.lift(llc, api.DecodeU32(itr()))
+}
+
+func (t *StreamType) load(llc *LiftLoadContext, offset uint32) (Value, error) {
+	v, ok := llc.memory.ReadUint32Le(offset)
+	if !ok {
+		return nil, fmt.Errorf("failed to read stream handle index at offset %d", offset)
+	}
+	return t.lift(llc, v)
+}
+
+func (t *StreamType) lift(llc *LiftLoadContext, idx uint32) (Value, error) {
+	end, err := takeStreamEnd(llc.instance, idx)
+	if err != nil {
+		return nil, err
+	}
+	return &Stream{state: end.stream}, nil
+}
+
+func (t *StreamType) lowerFlat(llc *LiftLoadContext, val Value) ([]uint64, error) {
+	idx, err := t.lower(llc, val)
+	if err != nil {
+		return nil, err
+	}
+	return []uint64{uint64(idx)}, nil
+}
+
+func (t *StreamType) store(llc *LiftLoadContext, offset uint32, val Value) error {
+	idx, err := t.lower(llc, val)
+	if err != nil {
+		return err
+	}
+	if !llc.memory.WriteUint32Le(offset, idx) {
+		return fmt.Errorf("failed to write stream handle index at offset %d", offset)
+	}
+	return nil
+}
+
+func (t *StreamType) lower(llc *LiftLoadContext, v Value) (uint32, error) {
+	s := v.(*Stream)
+	state, err := s.take()
+	if err != nil {
+		return 0, err
+	}
+	inst := llc.instance
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	return inst.waitables.add(&streamEnd{stream: state})
+}
+
+func (t *StreamType) typeDepth() int {
+	if t.ElementType == nil {
+		return 1
+	}
+	return 1 + t.ElementType.typeDepth()
+}
+
+func (t *StreamType) typeSize() int {
+	if t.ElementType == nil {
+		return 1
+	}
+	return 1 + t.ElementType.typeSize()
+}
+
+func checkOptionalTypesCompatible(a, b ValueType, typeChecker typeChecker) error {
+	if a == nil && b == nil {
+		return nil
+	}
+	if a == nil || b == nil {
+		return fmt.Errorf("type mismatch: expected %s, found %s", optionalTypeName(a), optionalTypeName(b))
+	}
+	return typeChecker.checkTypeCompatible(a, b)
+}
+
+func optionalTypeName(t ValueType) string {
+	if t == nil {
+		return "no type"
+	}
+	return t.typeName()
+}
+
+// checkOptionalValue fails if v is not a value of t, or not nil if t is nil.
+func checkOptionalValue(t ValueType, v Value) error {
+	if t == nil {
+		if v != nil {
+			return fmt.Errorf("value %T given for no type", v)
+		}
+		return nil
+	}
+	if v == nil || !t.supportsValue(v) {
+		return fmt.Errorf("value %T is not of type %s", v, t.typeName())
+	}
+	return nil
+}
+
+// streamState is shared by the readable and writable ends of a stream.
+type streamState struct {
+	elementType  ValueType
+	mu           sync.Mutex
+	buf          []Value
+	readDropped  bool
+	writeDropped bool
+	changed      chan struct{}
+}
+
+func newStreamState(elementType ValueType) *streamState {
+	return &streamState{
+		elementType: elementType,
+		changed:     make(chan struct{}),
+	}
+}
+
+// notify wakes up anyone waiting for the stream to change. s.mu must be held.
+func (s *streamState) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// readReady returns nil if a read can make progress, and otherwise a channel
+// that is closed when the stream changes.
+func (s *streamState) readReady() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.buf) > 0 || s.writeDropped {
+		return nil
+	}
+	return s.changed
+}
+
+// writeReady is readReady for writes.
+func (s *streamState) writeReady() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.buf) < streamBufferSize || s.readDropped {
+		return nil
+	}
+	return s.changed
+}
+
+// read takes up to n buffered elements. It returns io.EOF once the writer is
+// dropped and the buffer is empty, and no elements if it would block.
+func (s *streamState) read(n int) ([]Value, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.buf) == 0 {
+		if s.writeDropped {
+			return nil, io.EOF
+		}
+		return nil, nil
+	}
+	n = min(n, len(s.buf))
+	vals := s.buf[:n:n]
+	s.buf = s.buf[n:]
+	s.notify()
+	return vals, nil
+}
+
+// writeSpace returns how many elements can be written without blocking,
+// or errStreamClosed if the reader was dropped.
+func (s *streamState) writeSpace() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.readDropped {
+		return 0, errStreamClosed
+	}
+	return streamBufferSize - len(s.buf), nil
+}
+
+func (s *streamState) write(vals []Value) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buf = append(s.buf, vals...)
+	s.notify()
+}
+
+func (s *streamState) dropReader() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readDropped = true
+	s.buf = nil
+	s.notify()
+}
+
+func (s *streamState) dropWriter() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writeDropped = true
+	s.notify()
+}
+
+func waitForChange(ctx context.Context, changed <-chan struct{}) error {
+	select {
+	case <-changed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stream is the readable end of a stream passed between the host and a
+// component. A Stream lowered into a component can't be used by the host
+// anymore.
+type Stream struct {
+	mu    sync.Mutex
+	state *streamState
+}
+
+func (s *Stream) isValue() {}
+
+// StreamWriter is the writable end of a stream created by the host.
+type StreamWriter struct {
+	state *streamState
+}
+
+// NewStream creates a stream of elementType, returning its readable end to
+// pass to a component and the writable end that feeds it.
+func NewStream(elementType ValueType) (*Stream, *StreamWriter) {
+	state := newStreamState(elementType)
+	return &Stream{state: state}, &StreamWriter{state: state}
+}
+
+// NewReaderStream returns a stream<u8> fed from r by its own goroutine. The
+// stream ends when r returns an error, including io.EOF.
+func NewReaderStream(r io.Reader) *Stream {
+	s, w := NewStream(U8Type{})
+	go func() {
+		defer w.Close()
+		io.Copy(w, r)
+	}()
+	return s
+}
+
+func (s *Stream) take() (*streamState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == nil {
+		return nil, fmt.Errorf("stream has already been passed on or closed")
+	}
+	state := s.state
+	s.state = nil
+	return state, nil
+}
+
+func (s *Stream) current() (*streamState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == nil {
+		return nil, errStreamClosed
+	}
+	return s.state, nil
+}
+
+// ReadValues waits until elements are available and returns up to n of them.
+// It returns io.EOF once the writer is dropped and everything was read.
+func (s *Stream) ReadValues(ctx context.Context, n int) ([]Value, error) {
+	state, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		vals, err := state.read(n)
+		if len(vals) > 0 || err != nil {
+			return vals, err
+		}
+		if err := waitForChange(ctx, state.readReady()); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Read implements io.Reader for a stream<u8>. It fails for other streams.
+func (s *Stream) Read(p []byte) (int, error) {
+	state, err := s.current()
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := state.elementType.(U8Type); !ok {
+		return 0, fmt.Errorf("stream of %s is not a byte stream", optionalTypeName(state.elementType))
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	vals, err := s.ReadValues(context.Background(), len(p))
+	for i, v := range vals {
+		p[i] = byte(v.(U8))
+	}
+	return len(vals), err
+}
+
+// WriteTo implements io.WriterTo for a stream<u8>, consuming the stream into
+// w until its writer is dropped.
+func (s *Stream) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := s.Read(buf)
+		if n > 0 {
+			written, werr := w.Write(buf[:n])
+			total += int64(written)
+			if werr != nil {
+				return total, werr
+			}
+		}
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// Close drops the readable end, after which writes fail.
+func (s *Stream) Close() error {
+	state, err := s.take()
+	if err != nil {
+		return nil
+	}
+	state.dropReader()
+	return nil
+}
+
+// WriteValues writes all of vals, waiting while the stream's buffer is full.
+// It fails once the reader is dropped, and writes nothing if a value is not of
+// the element type.
+func (w *StreamWriter) WriteValues(ctx context.Context, vals []Value) (int, error) {
+	for _, v := range vals {
+		if err := checkOptionalValue(w.state.elementType, v); err != nil {
+			return 0, err
+		}
+	}
+	written := 0
+	for written < len(vals) {
+		space, err := w.state.writeSpace()
+		if err != nil {
+			return written, err
+		}
+		if space == 0 {
+			if err := waitForChange(ctx, w.state.writeReady()); err != nil {
+				return written, err
+			}
+			continue
+		}
+		n := min(space, len(vals)-written)
+		w.state.write(vals[written : written+n])
+		written += n
+	}
+	return written, nil
+}
+
+// Write implements io.Writer for a stream<u8>.
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	vals := make([]Value, len(p))
+	for i, b := range p {
+		vals[i] = U8(b)
+	}
+	return w.WriteValues(context.Background(), vals)
+}
+
+// Close drops the writable end, ending the stream once the reader has read
+// what is buffered.
+func (w *StreamWriter) Close() error {
+	w.state.dropWriter()
+	return nil
+}
+
+type FutureType struct {
+	// ValueType is nil for a future that only signals.
+	ValueType ValueType
+}
+
+func NewFutureType(valueType ValueType) *FutureType {
+	return &FutureType{ValueType: valueType}
+}
+
+func (t *FutureType) isType()      {}
+func (t *FutureType) isValueType() {}
+func (t *FutureType) supportsValue(v Value) bool {
+	_, ok := v.(*Future)
+	return ok
+}
+
+func (t *FutureType) typeName() string {
+	return "future"
+}
+
+func (t *FutureType) checkType(other Type, typeChecker typeChecker) error {
+	ot, err := assertTypeKindIsSame(t, other)
+	if err != nil {
+		return err
+	}
+	return checkOptionalTypesCompatible(t.ValueType, ot.ValueType, typeChecker)
+}
+
+func (t *FutureType) alignment() uint32   { return 4 }
+func (t *FutureType) elementSize() uint32 { return 4 }
+func (t *FutureType) flatTypes() []api.ValueType {
+	return []api.ValueType{api.ValueTypeI32}
+}
+
+func (t *FutureType) liftFlat(llc *LiftLoadContext, itr func() uint64) (Value, error) {
+	return t.lift(llc, api.DecodeU32(itr()))
+}
+
+func (t *FutureType) load(llc *LiftLoadContext, offset uint32) (Value, error) {
+	v, ok := llc.memory.ReadUint32Le(offset)
+	if !ok {
+		return nil, fmt.Errorf("failed to read future handle index at offset %d", offset)
+	}
+	return t.lift(llc, v)
+}
+
+func (t *FutureType) lift(llc *LiftLoadContext, idx uint32) (Value, error) {
+	end, err := takeFutureEnd(llc.instance, idx)
+	if err != nil {
+		return nil, err
+	}
+	return &Future{state: end.future}, nil
+}
+
+func (t *FutureType) lowerFlat(llc *LiftLoadContext, val Value) ([]uint64, error) {
+	idx, err := t.lower(llc, val)
+	if err != nil {
+		return nil, err
+	}
+	return []uint64{uint64(idx)}, nil
+}
+
+func (t *FutureType) store(llc *LiftLoadContext, offset uint32, val Value) error {
+	idx, err := t.lower(llc, val)
+	if err != nil {
+		return err
+	}
+	if !llc.memory.WriteUint32Le(offset, idx) {
+		return fmt.Errorf("failed to write future handle index at offset %d", offset)
+	}
+	return nil
+}
+
+func (t *FutureType) lower(llc *LiftLoadContext, v Value) (uint32, error) {
+	f := v.(*Future)
+	state, err := f.take()
+	if err != nil {
+		return 0, err
+	}
+	inst := llc.instance
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	return inst.waitables.add(&futureEnd{future: state})
+}
+
+func (t *FutureType) typeDepth() int {
+	if t.ValueType == nil {
+		return 1
+	}
+	return 1 + t.ValueType.typeDepth()
+}
+
+func (t *FutureType) typeSize() int {
+	if t.ValueType == nil {
+		return 1
+	}
+	return 1 + t.ValueType.typeSize()
+}
+
+// futureState is shared by the readable and writable ends of a future.
+type futureState struct {
+	valueType    ValueType
+	mu           sync.Mutex
+	value        Value
+	written      bool
+	consumed     bool
+	readDropped  bool
+	writeDropped bool
+	changed      chan struct{}
+}
+
+func newFutureState(valueType ValueType) *futureState {
+	return &futureState{
+		valueType: valueType,
+		changed:   make(chan struct{}),
+	}
+}
+
+func (f *futureState) readReady() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.written || f.writeDropped {
+		return nil
+	}
+	return f.changed
+}
+
+// read returns the value once it is written, io.EOF if the writer was
+// dropped without writing one, and ok false if it would block. The value is
+// read only once; later reads fail with errFutureConsumed.
+func (f *futureState) read() (Value, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.consumed {
+		return nil, true, errFutureConsumed
+	}
+	if f.written {
+		f.consumed = true
+		return f.value, true, nil
+	}
+	if f.writeDropped {
+		return nil, true, io.EOF
+	}
+	return nil, false, nil
+}
+
+func (f *futureState) write(v Value) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.written {
+		return fmt.Errorf("future has already been written")
+	}
+	if f.readDropped {
+		return errStreamClosed
+	}
+	f.value = v
+	f.written = true
+	close(f.changed)
+	f.changed = make(chan struct{})
+	return nil
+}
+
+func (f *futureState) dropReader() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.readDropped = true
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *futureState) dropWriter() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writeDropped = true
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// Future is the readable end of a future passed between the host and a
+// component.
+type Future struct {
+	mu    sync.Mutex
+	state *futureState
+}
+
+func (f *Future) isValue() {}
+
+// FutureWriter is the writable end of a future created by the host.
+type FutureWriter struct {
+	state *futureState
+}
+
+// NewFuture creates a future of valueType, returning its readable end to pass
+// to a component and the writable end that resolves it.
+func NewFuture(valueType ValueType) (*Future, *FutureWriter) {
+	state := newFutureState(valueType)
+	return &Future{state: state}, &FutureWriter{state: state}
+}
+
+func (f *Future) take() (*futureState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.state == nil {
+		return nil, fmt.Errorf("future has already been passed on or closed")
+	}
+	state := f.state
+	f.state = nil
+	return state, nil
+}
+
+// Get waits for the value of the future. It returns io.EOF if the writer is
+// dropped without writing a value, and fails if the value was already read.
+func (f *Future) Get(ctx context.Context) (Value, error) {
+	f.mu.Lock()
+	state := f.state
+	f.mu.Unlock()
+	if state == nil {
+		return nil, errStreamClosed
+	}
+	for {
+		v, ok, err := state.read()
+		if ok {
+			return v, err
+		}
+		if err := waitForChange(ctx, state.readReady()); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Close drops the readable end of the future.
+func (f *Future) Close() error {
+	state, err := f.take()
+	if err != nil {
+		return nil
+	}
+	state.dropReader()
+	return nil
+}
+
+// Set resolves the future with v. It fails if the reader was dropped or v is
+// not of the future's type.
+func (w *FutureWriter) Set(v Value) error {
+	if err := checkOptionalValue(w.state.valueType, v); err != nil {
+		return err
+	}
+	return w.state.write(v)
+}
+
+// Close drops the writable end of the future.
+func (w *FutureWriter) Close() error {
+	w.state.dropWriter()
+	return nil
+}
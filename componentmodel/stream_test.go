@@ -0,0 +1,177 @@
+package componentmodel
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReaderStream(t *testing.T) {
+	data := strings.Repeat("wacogo streams ", streamBufferSize/4)
+	s := NewReaderStream(strings.NewReader(data))
+
+	var out bytes.Buffer
+	n, err := s.WriteTo(&out)
+	if err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	if n != int64(len(data)) || out.String() != data {
+		t.Errorf("WriteTo copied %d bytes, want %d", n, len(data))
+	}
+}
+
+func TestStreamWriterBlocksWhenFull(t *testing.T) {
+	s, w := NewStream(U32Type{})
+	vals := make([]Value, streamBufferSize+1)
+	for i := range vals {
+		vals[i] = U32(i)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	n, err := w.WriteValues(ctx, vals)
+	if err != context.DeadlineExceeded || n != streamBufferSize {
+		t.Fatalf("WriteValues() = %d, %v, want %d, %v", n, err, streamBufferSize, context.DeadlineExceeded)
+	}
+
+	got, err := s.ReadValues(context.Background(), 2)
+	if err != nil || len(got) != 2 || got[1] != U32(1) {
+		t.Fatalf("ReadValues() = %v, %v", got, err)
+	}
+	if _, err := w.WriteValues(context.Background(), vals[n:]); err != nil {
+		t.Fatalf("WriteValues failed: %v", err)
+	}
+	w.Close()
+
+	total := 2
+	for {
+		got, err := s.ReadValues(context.Background(), streamBufferSize)
+		total += len(got)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ReadValues failed: %v", err)
+		}
+	}
+	if total != len(vals) {
+		t.Errorf("read %d values, want %d", total, len(vals))
+	}
+
+	s.Close()
+	if _, err := w.WriteValues(context.Background(), vals[:1]); err != errStreamClosed {
+		t.Errorf("WriteValues() after the reader closed = %v, want %v", err, errStreamClosed)
+	}
+}
+
+func TestStreamElementTypes(t *testing.T) {
+	s, w := NewStream(U32Type{})
+	if n, err := w.WriteValues(context.Background(), []Value{U32(1), String("x")}); err == nil || n != 0 {
+		t.Errorf("WriteValues() of a mistyped value = %d, %v, want an error", n, err)
+	}
+	if _, err := w.WriteValues(context.Background(), []Value{U32(1)}); err != nil {
+		t.Fatalf("WriteValues failed: %v", err)
+	}
+	if _, err := s.Read(make([]byte, 1)); err == nil {
+		t.Error("Read() of a stream<u32> succeeded")
+	}
+	if _, err := s.WriteTo(io.Discard); err == nil {
+		t.Error("WriteTo() of a stream<u32> succeeded")
+	}
+}
+
+func TestFuture(t *testing.T) {
+	f, w := NewFuture(StringType{})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		w.Set(String("done"))
+	}()
+	v, err := f.Get(context.Background())
+	if err != nil || v != String("done") {
+		t.Errorf("Get() = %v, %v, want done", v, err)
+	}
+	if err := w.Set(String("again")); err == nil {
+		t.Error("Set() succeeded twice")
+	}
+	if _, err := f.Get(context.Background()); err != errFutureConsumed {
+		t.Errorf("second Get() = %v, want %v", err, errFutureConsumed)
+	}
+
+	_, w = NewFuture(U32Type{})
+	if err := w.Set(String("mistyped")); err == nil {
+		t.Error("Set() of a mistyped value succeeded")
+	}
+
+	f, w = NewFuture(nil)
+	w.Close()
+	if _, err := f.Get(context.Background()); err != io.EOF {
+		t.Errorf("Get() of a dropped future = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestAsyncStreamCopy(t *testing.T) {
+	inst := newInstance()
+	if err := inst.enter(context.Background()); err != nil {
+		t.Fatalf("enter failed: %v", err)
+	}
+	defer inst.exit()
+
+	state := newStreamState(U8Type{})
+	end := &streamEnd{stream: state}
+	idx, err := inst.waitables.add(end)
+	if err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	s := newWaitableSet()
+	end.join(s)
+
+	var got []Value
+	op := copyOp{
+		try: func() (uint32, bool, error) {
+			vals, err := state.read(8)
+			if err == io.EOF {
+				return copyResult(copyDropped, 0), true, nil
+			}
+			if len(vals) == 0 {
+				return 0, false, nil
+			}
+			got = append(got, vals...)
+			return copyResult(copyCompleted, len(vals)), true, nil
+		},
+		ready: state.readReady,
+	}
+	result, err := inst.runCopy(context.Background(), &end.copyEnd, idx, true, eventStreamRead, op)
+	if err != nil || result != copyResultBlocked {
+		t.Fatalf("runCopy() = %#x, %v, want blocked", result, err)
+	}
+	if _, err := inst.runCopy(context.Background(), &end.copyEnd, idx, true, eventStreamRead, op); err == nil {
+		t.Error("runCopy() succeeded while a copy was in progress")
+	}
+
+	state.write([]Value{U8(1), U8(2), U8(3)})
+	e, err := inst.waitForEvent(context.Background(), s, false)
+	if err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+	if e.code != eventStreamRead || e.index != idx || e.payload != copyResult(copyCompleted, 3) {
+		t.Errorf("wait() = %+v, want a completed read of 3 elements", e)
+	}
+	if len(got) != 3 {
+		t.Errorf("read %d elements, want 3", len(got))
+	}
+
+	result, err = inst.runCopy(context.Background(), &end.copyEnd, idx, true, eventStreamRead, op)
+	if err != nil || result != copyResultBlocked {
+		t.Fatalf("runCopy() = %#x, %v, want blocked", result, err)
+	}
+	result, err = inst.cancelCopy(&end.copyEnd)
+	if err != nil || result != uint32(copyCancelled) {
+		t.Errorf("cancelCopy() = %d, %v, want cancelled", result, err)
+	}
+	if _, err := inst.cancelCopy(&end.copyEnd); err == nil {
+		t.Error("cancelCopy() succeeded without a copy in progress")
+	}
+}
@@ -0,0 +1,77 @@
+package componentmodel
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// stubModule is the host module behind a canon built-in or lowered function.
+// Compiling a host module is costly, so it is compiled once and shared by
+// every instance of the definition that owns it. Each instance registers its
+// own implementation, which the shared function finds through the instance
+// recorded in the calling context.
+type stubModule struct {
+	mu       sync.Mutex
+	compiled map[string]wazero.CompiledModule
+}
+
+func (s *stubModule) compile(ctx context.Context, runtime wazero.Runtime, id string, params, results []api.ValueType) (wazero.CompiledModule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := fmt.Sprint(params, results)
+	if mod, ok := s.compiled[key]; ok {
+		return mod, nil
+	}
+
+	mod, err := runtime.NewHostModuleBuilder(id).NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			// Stop guest code at its next call into the host once the call
+			// is cancelled, even if the runtime doesn't close modules itself.
+			if err := ctx.Err(); err != nil {
+				panic(err)
+			}
+			inst := instanceFromContext(ctx)
+			if inst == nil {
+				panic(fmt.Errorf("%s called outside of a component instance", id))
+			}
+			fn, ok := inst.stubs.Load(s)
+			if !ok {
+				panic(fmt.Errorf("%s is not defined in the calling component instance", id))
+			}
+			fn.(api.GoModuleFunc)(ctx, mod, stack)
+		}), params, results).
+		Export("stub_function").
+		Compile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if s.compiled == nil {
+		s.compiled = make(map[string]wazero.CompiledModule)
+	}
+	s.compiled[key] = mod
+	return mod, nil
+}
+
+// newStubCoreFunction instantiates the host module of stub, which exports fn
+// as a core function of the scope's instance.
+func newStubCoreFunction(ctx context.Context, scope *scope, id string, stub *stubModule, fn api.GoModuleFunc, params, results []api.ValueType) (*coreFunction, error) {
+	mod, err := stub.compile(ctx, scope.runtime, id, params, results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s stub module: %w", id, err)
+	}
+	scope.instance.stubs.Store(stub, fn)
+
+	modInst, err := scope.runtime.InstantiateModule(ctx, mod, wazero.NewModuleConfig().WithName(""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate %s stub module: %w", id, err)
+	}
+	scope.instance.addModule(modInst)
+
+	return newCoreFunction(
+		modInst, "stub_function", mod.ExportedFunctions()["stub_function"],
+	), nil
+}
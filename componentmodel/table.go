@@ -7,6 +7,11 @@ const maxTableSize = 1 << 28
 type table[T any] struct {
 	entries []tableEntry[T]
 	free    []uint32
+	// limit bounds the number of entries in use, if it isn't zero. resource
+	// names the entries in the error raised past it.
+	limit    uint32
+	resource string
+	used     uint32
 }
 
 func newTable[T any]() *table[T] {
@@ -19,7 +24,15 @@ func newTable[T any]() *table[T] {
 	}
 }
 
-func (t *table[T]) add(entry T) uint32 {
+func (t *table[T]) setLimit(resource string, limit uint32) {
+	t.resource = resource
+	t.limit = limit
+}
+
+func (t *table[T]) add(entry T) (uint32, error) {
+	if err := checkLimit(t.resource, uint64(t.used)+1, t.limit); err != nil {
+		return 0, err
+	}
 	if len(t.free) > 0 {
 		idx := t.free[len(t.free)-1]
 		t.free = t.free[:len(t.free)-1]
@@ -27,17 +40,19 @@ func (t *table[T]) add(entry T) uint32 {
 			value: entry,
 			set:   true,
 		}
-		return idx
+		t.used++
+		return idx, nil
 	}
 	idx := uint32(len(t.entries))
 	if idx >= maxTableSize {
-		panic("table size exceeded")
+		return 0, fmt.Errorf("table size exceeds the limit of %d entries", maxTableSize)
 	}
 	t.entries = append(t.entries, tableEntry[T]{
 		value: entry,
 		set:   true,
 	})
-	return uint32(idx)
+	t.used++
+	return uint32(idx), nil
 }
 
 func (t *table[T]) get(idx uint32) T {
@@ -51,6 +66,16 @@ func (t *table[T]) get(idx uint32) T {
 	return entry.value
 }
 
+// lookup is like get, but reports an unknown index instead of panicking, for
+// callers that aren't running inside a core function.
+func (t *table[T]) lookup(idx uint32) (T, bool) {
+	if idx >= uint32(len(t.entries)) || !t.entries[idx].set {
+		var zero T
+		return zero, false
+	}
+	return t.entries[idx].value, true
+}
+
 func (t *table[T]) remove(idx uint32) T {
 	if idx >= uint32(len(t.entries)) {
 		panic("invalid table index")
@@ -63,6 +88,7 @@ func (t *table[T]) remove(idx uint32) T {
 	var zero T
 	t.entries[idx] = tableEntry[T]{set: false, value: zero}
 	t.free = append(t.free, idx)
+	t.used--
 
 	return v
 }
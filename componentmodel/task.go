@@ -0,0 +1,261 @@
+package componentmodel
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+)
+
+type eventCode uint32
+
+const (
+	eventNone eventCode = iota
+	eventSubtask
+	eventStreamRead
+	eventStreamWrite
+	eventFutureRead
+	eventFutureWrite
+	eventTaskCancelled
+)
+
+type subtaskState uint32
+
+const (
+	subtaskStarting subtaskState = iota
+	subtaskStarted
+	subtaskReturned
+)
+
+const (
+	callbackCodeExit = iota
+	callbackCodeYield
+	callbackCodeWait
+	callbackCodePoll
+)
+
+const maxContextSlots = 2
+
+// task is a single call of a lifted function. The task running in an instance
+// is carried in the context passed to its core functions, so canon built-ins
+// can find it and calls out of the instance can tell who their caller is.
+type task struct {
+	instance       *Instance
+	parent         *task
+	usesTaskReturn bool
+	resultType     ValueType
+	returned       bool
+	result         Value
+	context        [maxContextSlots]uint32
+}
+
+type taskContextKey struct{}
+
+func withTask(ctx context.Context, t *task) context.Context {
+	return context.WithValue(ctx, taskContextKey{}, t)
+}
+
+func taskFromContext(ctx context.Context) *task {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(taskContextKey{}).(*task)
+	return t
+}
+
+func newTask(ctx context.Context, inst *Instance, resultType ValueType, usesTaskReturn bool) *task {
+	return &task{
+		instance:       inst,
+		parent:         taskFromContext(ctx),
+		usesTaskReturn: usesTaskReturn,
+		resultType:     resultType,
+	}
+}
+
+// isInstanceOnTaskChain reports whether a task of inst is waiting, directly or
+// indirectly, on the task in ctx.
+func isInstanceOnTaskChain(ctx context.Context, inst *Instance) bool {
+	for t := taskFromContext(ctx); t != nil; t = t.parent {
+		if t.instance == inst {
+			return true
+		}
+	}
+	return false
+}
+
+// currentTask returns the task running in inst, for canon built-ins that act
+// on it.
+func currentTask(ctx context.Context, inst *Instance) (*task, error) {
+	t := taskFromContext(ctx)
+	if t == nil || t.instance != inst {
+		return nil, fmt.Errorf("no task is running in the component instance")
+	}
+	return t, nil
+}
+
+func (t *task) taskReturn(resultType ValueType, result Value) error {
+	if !t.usesTaskReturn {
+		return fmt.Errorf("task.return called by a task that returns its result directly")
+	}
+	if t.returned {
+		return fmt.Errorf("task.return called more than once")
+	}
+	if (t.resultType == nil) != (resultType == nil) {
+		return fmt.Errorf("task.return result does not match the function result")
+	}
+	if resultType != nil {
+		if err := newTypeChecker().checkTypeCompatible(t.resultType, resultType); err != nil {
+			return fmt.Errorf("task.return result does not match the function result: %w", err)
+		}
+	}
+	t.returned = true
+	t.result = result
+	return nil
+}
+
+// event is reported to a task waiting on a waitable set.
+type event struct {
+	code    eventCode
+	index   uint32
+	payload uint32
+	// deliver runs in the waiting task right before the event is reported,
+	// to finish any work that has to happen inside the instance.
+	deliver func() error
+}
+
+type waitable interface {
+	state() *waitableState
+}
+
+// waitableState is the part of a waitable guarded by the mutex of the
+// instance that owns it.
+type waitableState struct {
+	set     *waitableSet
+	pending *event
+}
+
+func (w *waitableState) state() *waitableState {
+	return w
+}
+
+// setEvent records the pending event of w and wakes up tasks waiting on its
+// set. The instance mutex must be held.
+func (w *waitableState) setEvent(e *event) {
+	w.pending = e
+	if w.set != nil {
+		w.set.notify()
+	}
+}
+
+func (w *waitableState) join(s *waitableSet) {
+	if w.set != nil {
+		w.set.remove(w)
+	}
+	w.set = s
+	if s == nil {
+		return
+	}
+	s.members = append(s.members, w)
+	if w.pending != nil {
+		s.notify()
+	}
+}
+
+type waitableSet struct {
+	members []*waitableState
+	waiters int
+	changed chan struct{}
+}
+
+func newWaitableSet() *waitableSet {
+	return &waitableSet{
+		changed: make(chan struct{}),
+	}
+}
+
+func (s *waitableSet) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *waitableSet) remove(w *waitableState) {
+	for i, m := range s.members {
+		if m == w {
+			s.members = append(s.members[:i], s.members[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *waitableSet) takeEvent() *event {
+	for _, m := range s.members {
+		if e := m.pending; e != nil {
+			m.pending = nil
+			return e
+		}
+	}
+	return nil
+}
+
+// subtask is a call made through an async lowered function that didn't
+// complete before returning to the caller.
+type subtask struct {
+	waitableState
+	returned bool
+}
+
+// waitForEvent takes the next event of s, blocking until there is one unless
+// poll is set. Other tasks may run in the instance while it blocks.
+func (i *Instance) waitForEvent(ctx context.Context, s *waitableSet, poll bool) (*event, error) {
+	for {
+		i.mu.Lock()
+		if e := s.takeEvent(); e != nil {
+			i.mu.Unlock()
+			if e.deliver != nil {
+				if err := e.deliver(); err != nil {
+					return nil, err
+				}
+			}
+			return e, nil
+		}
+		if poll {
+			i.mu.Unlock()
+			return &event{code: eventNone}, nil
+		}
+		changed := s.changed
+		s.waiters++
+		i.mu.Unlock()
+
+		resume := i.suspend()
+		var err error
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		resume()
+
+		i.mu.Lock()
+		s.waiters--
+		i.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// yield lets other tasks run in the instance before continuing.
+func (i *Instance) yield() {
+	resume := i.suspend()
+	runtime.Gosched()
+	resume()
+}
+
+func (i *Instance) waitableSet(idx uint32) (*waitableSet, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	s, ok := i.waitableSets.lookup(idx)
+	if !ok {
+		return nil, fmt.Errorf("unknown waitable set %d", idx)
+	}
+	return s, nil
+}
@@ -0,0 +1,95 @@
+package componentmodel
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInstanceEnter(t *testing.T) {
+	inst := newInstance()
+	ctx := withTask(context.Background(), newTask(context.Background(), inst, nil, false))
+	if err := inst.enter(context.Background()); err != nil {
+		t.Fatalf("enter failed: %v", err)
+	}
+
+	// Reentering from a task of the instance is an error, while a task of
+	// another caller waits for the instance to be free.
+	if err := inst.enter(ctx); err == nil {
+		t.Error("enter() from a task of the instance succeeded")
+	}
+	other := withTask(context.Background(), newTask(context.Background(), newInstance(), nil, false))
+	entered := make(chan error, 1)
+	go func() {
+		entered <- inst.enter(other)
+	}()
+	select {
+	case <-entered:
+		t.Fatal("enter() succeeded while the instance was active")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	resume := inst.suspend()
+	if err := <-entered; err != nil {
+		t.Fatalf("enter failed: %v", err)
+	}
+	resumed := make(chan struct{})
+	go func() {
+		resume()
+		close(resumed)
+	}()
+	if err := inst.exit(); err != nil {
+		t.Fatalf("exit failed: %v", err)
+	}
+	<-resumed
+	if err := inst.exit(); err != nil {
+		t.Fatalf("exit failed: %v", err)
+	}
+}
+
+func TestWaitForEvent(t *testing.T) {
+	inst := newInstance()
+	if err := inst.enter(context.Background()); err != nil {
+		t.Fatalf("enter failed: %v", err)
+	}
+	defer inst.exit()
+
+	s := newWaitableSet()
+	st := &subtask{}
+	idx, err := inst.waitables.add(st)
+	if err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	st.join(s)
+
+	e, err := inst.waitForEvent(context.Background(), s, true)
+	if err != nil || e.code != eventNone {
+		t.Fatalf("poll() = %v, %v, want no event", e, err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		inst.mu.Lock()
+		defer inst.mu.Unlock()
+		st.setEvent(&event{code: eventSubtask, index: idx, payload: uint32(subtaskReturned), deliver: func() error {
+			st.returned = true
+			return nil
+		}})
+	}()
+	e, err = inst.waitForEvent(context.Background(), s, false)
+	if err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+	if e.code != eventSubtask || e.index != idx || e.payload != uint32(subtaskReturned) {
+		t.Errorf("wait() = %+v, want a returned subtask %d", e, idx)
+	}
+	if !st.returned {
+		t.Error("event wasn't delivered before it was reported")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := inst.waitForEvent(ctx, s, false); err != context.DeadlineExceeded {
+		t.Errorf("wait() on a cancelled context = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
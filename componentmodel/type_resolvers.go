@@ -190,6 +190,127 @@ func (d *listTypeResolver) typeInfo(scope *scope) *typeInfo {
 	}
 }
 
+type fixedListTypeResolver struct {
+	elementTypeResolver typeResolver
+	length              uint32
+}
+
+func newFixedListTypeResolver(
+	elementTypeResolver typeResolver,
+	length uint32,
+) *fixedListTypeResolver {
+	return &fixedListTypeResolver{
+		elementTypeResolver: elementTypeResolver,
+		length:              length,
+	}
+}
+
+func (d *fixedListTypeResolver) resolveType(scope *scope) (Type, error) {
+	elementType, err := d.elementTypeResolver.resolveType(scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve list element type: %w", err)
+	}
+	if _, ok := elementType.(ValueType); !ok {
+		return nil, fmt.Errorf("list element type is not a value type: %T", elementType)
+	}
+	t := &FixedListType{
+		ElementType: elementType.(ValueType),
+		Length:      d.length,
+	}
+	if t.typeSize() > maxTypeSize {
+		return nil, fmt.Errorf("effective type size exceeds the limit")
+	}
+	return t, nil
+}
+
+func (d *fixedListTypeResolver) typeInfo(scope *scope) *typeInfo {
+	et := d.elementTypeResolver.typeInfo(scope)
+	return &typeInfo{
+		isValue:  true,
+		typeName: "list",
+		depth:    1 + et.depth,
+		size:     fixedListTypeSize(d.length, et.size),
+	}
+}
+
+type streamTypeResolver struct {
+	elementTypeResolver typeResolver
+}
+
+func newStreamTypeResolver(
+	elementTypeResolver typeResolver,
+) *streamTypeResolver {
+	return &streamTypeResolver{
+		elementTypeResolver: elementTypeResolver,
+	}
+}
+
+func (d *streamTypeResolver) resolveType(scope *scope) (Type, error) {
+	elementType, err := resolveOptionalValueType(scope, d.elementTypeResolver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve stream element type: %w", err)
+	}
+	return NewStreamType(elementType), nil
+}
+
+func (d *streamTypeResolver) typeInfo(scope *scope) *typeInfo {
+	return optionalElementTypeInfo(scope, "stream", d.elementTypeResolver)
+}
+
+type futureTypeResolver struct {
+	valueTypeResolver typeResolver
+}
+
+func newFutureTypeResolver(
+	valueTypeResolver typeResolver,
+) *futureTypeResolver {
+	return &futureTypeResolver{
+		valueTypeResolver: valueTypeResolver,
+	}
+}
+
+func (d *futureTypeResolver) resolveType(scope *scope) (Type, error) {
+	valueType, err := resolveOptionalValueType(scope, d.valueTypeResolver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve future value type: %w", err)
+	}
+	return NewFutureType(valueType), nil
+}
+
+func (d *futureTypeResolver) typeInfo(scope *scope) *typeInfo {
+	return optionalElementTypeInfo(scope, "future", d.valueTypeResolver)
+}
+
+func resolveOptionalValueType(scope *scope, resolver typeResolver) (ValueType, error) {
+	if resolver == nil {
+		return nil, nil
+	}
+	t, err := resolver.resolveType(scope)
+	if err != nil {
+		return nil, err
+	}
+	vt, ok := t.(ValueType)
+	if !ok {
+		return nil, fmt.Errorf("type is not a value type: %T", t)
+	}
+	return vt, nil
+}
+
+func optionalElementTypeInfo(scope *scope, typeName string, resolver typeResolver) *typeInfo {
+	ti := &typeInfo{
+		isValue:  true,
+		typeName: typeName,
+		depth:    1,
+		size:     1,
+	}
+	if resolver != nil {
+		et := resolver.typeInfo(scope)
+		ti.depth += et.depth
+		ti.size += et.size
+	}
+	return ti
+}
+
 type recordTypeResolver struct {
 	labels               []string
 	elementTypeResolvers []typeResolver
@@ -544,7 +665,10 @@ func (d *resourceTypeResolver) resolveType(scope *scope) (Type, error) {
 				// TODO: what do if destructor fails?
 				coreFn, _ := sortScopeFor(scope, sortCoreFunction).getInstance(*d.destructorFnIndex)
 				fn := coreFn.module.ExportedFunction(coreFn.name)
-				fn.Call(ctx, uint64(res.(uint32)))
+				if ctx == nil {
+					ctx = context.Background()
+				}
+				fn.Call(withInstance(ctx, instance), uint64(res.(uint32)))
 			}
 		},
 	), nil
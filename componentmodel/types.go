@@ -100,6 +100,8 @@ func astDefTypeToTypeResolver(defs *definitions, defType ast.DefType, allowResou
 		return newStaticTypeResolver(CharType{}), nil
 	case *ast.StringType:
 		return newStaticTypeResolver(StringType{}), nil
+	case *ast.ErrorContextType:
+		return newStaticTypeResolver(ErrorContextType{}), nil
 	case *ast.RecordType:
 		labels := make([]string, len(def.Fields))
 		elementTypeResolvers := make([]typeResolver, len(def.Fields))
@@ -133,6 +135,15 @@ func astDefTypeToTypeResolver(defs *definitions, defType ast.DefType, allowResou
 		}
 
 		return newListTypeResolver(elemTypeDef)
+	case *ast.FixedListType:
+		if def.Length == 0 {
+			return nil, fmt.Errorf("fixed-length list must have at least one element")
+		}
+		elemTypeDef, err := astDefTypeToTypeResolver(defs, def.Element, allowResources)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve list element type: %w", err)
+		}
+		return newFixedListTypeResolver(elemTypeDef, def.Length), nil
 	case *ast.TupleType:
 		labels := make([]string, len(def.Types))
 		elementTypeResolvers := make([]typeResolver, len(def.Types))
@@ -199,6 +210,26 @@ func astDefTypeToTypeResolver(defs *definitions, defType ast.DefType, allowResou
 		return newBorrowTypeResolver(
 			newIndexTypeResolverOf[*ResourceType](sortType, def.TypeIdx, ""),
 		), nil
+	case *ast.StreamType:
+		var elemTypeResolver typeResolver
+		if def.Element != nil {
+			var err error
+			elemTypeResolver, err = astDefTypeToTypeResolver(defs, def.Element, allowResources)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve stream element type: %w", err)
+			}
+		}
+		return newStreamTypeResolver(elemTypeResolver), nil
+	case *ast.FutureType:
+		var valueTypeResolver typeResolver
+		if def.Value != nil {
+			var err error
+			valueTypeResolver, err = astDefTypeToTypeResolver(defs, def.Value, allowResources)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve future value type: %w", err)
+			}
+		}
+		return newFutureTypeResolver(valueTypeResolver), nil
 	case *ast.ResourceType:
 		if !allowResources {
 			return nil, fmt.Errorf("resources can only be defined within a concrete component")
@@ -237,7 +268,7 @@ func astDefTypeToTypeResolver(defs *definitions, defType ast.DefType, allowResou
 			switch decl := decl.(type) {
 			// importdecl
 			case *ast.ImportDecl:
-				gs, typResolver, err := astExternDescToTypeResolver(decl.Desc)
+				gs, typResolver, err := astExternDescToTypeResolver(componentDefs, decl.Desc)
 				if err != nil {
 					return nil, fmt.Errorf("failed to resolve component import declaration: %w", err)
 				}
@@ -272,18 +303,29 @@ func astDefTypeToTypeResolver(defs *definitions, defType ast.DefType, allowResou
 	}
 }
 
-func astExternDescToTypeResolver(desc ast.ExternDesc) (genericSort, typeResolver, error) {
+func astValueBoundToTypeResolver(defs *definitions, bound ast.ValueBound) (typeResolver, error) {
+	switch bound := bound.(type) {
+	case *ast.ValueEqBound:
+		return newIndexTypeResolverOf[ValueType](sortValue, bound.ValueIdx, ""), nil
+	case *ast.ValueTypeBound:
+		return astDefTypeToTypeResolver(defs, bound.Type, false)
+	default:
+		return nil, fmt.Errorf("unsupported value bound: %T", bound)
+	}
+}
+
+func astExternDescToTypeResolver(defs *definitions, desc ast.ExternDesc) (genericSort, typeResolver, error) {
 	switch desc := desc.(type) {
 	case *ast.SortExternDesc:
 		switch desc.Sort {
 		case ast.SortCoreModule:
 			return sortCoreModule, newIndexTypeResolverOf[*coreModuleType](sortCoreType, desc.TypeIdx, ""), nil
 		case ast.SortComponent:
-			return sortComponent, newIndexTypeResolverOf[*componentType](sortType, desc.TypeIdx, ""), nil
+			return sortComponent, newIndexTypeResolverOf[*ComponentType](sortType, desc.TypeIdx, ""), nil
 		case ast.SortFunc:
 			return sortFunction, newIndexTypeResolverOf[*FunctionType](sortType, desc.TypeIdx, fmt.Sprintf("type index %d is not a function type", desc.TypeIdx)), nil
 		case ast.SortInstance:
-			return sortInstance, newIndexTypeResolverOf[*instanceType](sortType, desc.TypeIdx, ""), nil
+			return sortInstance, newIndexTypeResolverOf[*InstanceType](sortType, desc.TypeIdx, ""), nil
 		default:
 			return nil, nil, fmt.Errorf("unsupported import sort in type declaration: %v", desc.Sort)
 		}
@@ -296,6 +338,12 @@ func astExternDescToTypeResolver(desc ast.ExternDesc) (genericSort, typeResolver
 		default:
 			return nil, nil, fmt.Errorf("unsupported type extern desc bound in type declaration: %T", bound)
 		}
+	case *ast.ValueExternDesc:
+		tr, err := astValueBoundToTypeResolver(defs, desc.Bound)
+		if err != nil {
+			return nil, nil, err
+		}
+		return sortValue, tr, nil
 	default:
 		return nil, nil, fmt.Errorf("unsupported extern desc in type declaration: %T", desc)
 	}
@@ -368,7 +416,7 @@ func addInstanceDeclToTypeScope(defs *definitions, decl ast.InstanceDecl, export
 			return fmt.Errorf("unsupported component alias target: %T", target)
 		}
 	case *ast.ExportDecl:
-		gs, typResolver, err := astExternDescToTypeResolver(decl.Desc)
+		gs, typResolver, err := astExternDescToTypeResolver(defs, decl.Desc)
 		if err != nil {
 			return fmt.Errorf("failed to resolve instance export declaration: %w", err)
 		}
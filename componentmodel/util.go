@@ -89,7 +89,7 @@ func walkImportTypes(imports map[string]Type, fn func(Type) error) error {
 			return err
 		}
 		switch it := importType.(type) {
-		case *instanceType:
+		case *InstanceType:
 			if err := walkExportTypes(it.exports, fn); err != nil {
 				return err
 			}
@@ -107,7 +107,7 @@ func walkExportTypes(exports map[string]*exportSpec, fn func(Type) error) error
 			return err
 		}
 		switch et := exportSpec.typ.(type) {
-		case *instanceType:
+		case *InstanceType:
 			if err := walkExportTypes(et.exports, fn); err != nil {
 				return err
 			}
@@ -162,7 +162,7 @@ func walkTypes(t Type, fn func(Type) error) error {
 		if err := walkTypes(tt.elementType, fn); err != nil {
 			return err
 		}
-	case *componentType:
+	case *ComponentType:
 		for _, importType := range tt.imports {
 			if err := walkTypes(importType, fn); err != nil {
 				return err
@@ -173,7 +173,7 @@ func walkTypes(t Type, fn func(Type) error) error {
 				return err
 			}
 		}
-	case *instanceType:
+	case *InstanceType:
 		for _, exportSpec := range tt.exports {
 			if err := walkTypes(exportSpec.typ, fn); err != nil {
 				return err
@@ -209,6 +209,22 @@ func walkTypes(t Type, fn func(Type) error) error {
 		if err := walkTypes(tt.ElementType, fn); err != nil {
 			return err
 		}
+	case *FixedListType:
+		if err := walkTypes(tt.ElementType, fn); err != nil {
+			return err
+		}
+	case *StreamType:
+		if tt.ElementType != nil {
+			if err := walkTypes(tt.ElementType, fn); err != nil {
+				return err
+			}
+		}
+	case *FutureType:
+		if tt.ValueType != nil {
+			if err := walkTypes(tt.ValueType, fn); err != nil {
+				return err
+			}
+		}
 	case OwnType:
 		if err := walkTypes(tt.ResourceType, fn); err != nil {
 			return err
@@ -595,6 +595,9 @@ func (t StringType) readString(llc *LiftLoadContext, ptr uint32, length uint32)
 	if ptr != alignTo(ptr, t.alignment()) {
 		return "", fmt.Errorf("unaligned pointer: string pointer %d is not aligned to %d", ptr, t.alignment())
 	}
+	if err := checkLimit("string bytes", stringByteLength(llc.stringEncoding, length), llc.limits().StringBytes); err != nil {
+		return "", err
+	}
 	switch llc.stringEncoding {
 	case stringEncodingUTF8:
 		bytes, ok := llc.memory.Read(ptr, length)
@@ -644,6 +647,20 @@ func (t StringType) readString(llc *LiftLoadContext, ptr uint32, length uint32)
 	}
 }
 
+// stringByteLength returns the size in memory of a string of the given
+// length in code units.
+func stringByteLength(encoding stringEncoding, length uint32) uint64 {
+	switch encoding {
+	case stringEncodingUTF16:
+		return 2 * uint64(length)
+	case stringEncodingLatin1UTF16:
+		if (length & (1 << 31)) != 0 {
+			return 2 * uint64(length&0x7FFFFFFF)
+		}
+	}
+	return uint64(length)
+}
+
 func (t StringType) lowerFlat(llc *LiftLoadContext, val Value) ([]uint64, error) {
 	ptr, len, err := t.writeString(llc, val.(String))
 	if err != nil {
@@ -1242,9 +1259,12 @@ func (t *ListType) flatTypes() []api.ValueType {
 }
 
 func (t *ListType) loadListValues(llc *LiftLoadContext, ptr uint32, length uint32) (Value, error) {
+	elementSize := t.ElementType.elementSize()
+	if err := checkListSize(llc, ptr, length, elementSize); err != nil {
+		return nil, err
+	}
 	elements := make(List, length)
 	currentOffset := ptr
-	elementSize := t.ElementType.elementSize()
 	for i := range length {
 		val, err := t.ElementType.load(llc, currentOffset)
 		if err != nil {
@@ -1256,6 +1276,16 @@ func (t *ListType) loadListValues(llc *LiftLoadContext, ptr uint32, length uint3
 	return elements, nil
 }
 
+// checkListSize fails if a list of length elements at ptr doesn't fit in
+// memory or exceeds the list size limit, before any of it is allocated.
+func checkListSize(llc *LiftLoadContext, ptr uint32, length uint32, elementSize uint32) error {
+	size := uint64(length) * uint64(elementSize)
+	if uint64(ptr)+size > uint64(llc.memory.Size()) {
+		return fmt.Errorf("list pointer/length out of bounds of memory at ptr %d with length %d", ptr, length)
+	}
+	return checkLimit("list bytes", uint64(length)*uint64(max(elementSize, 1)), llc.limits().ListBytes)
+}
+
 func (t *ListType) liftFlat(llc *LiftLoadContext, itr func() uint64) (Value, error) {
 	ptr := uint32(itr())
 	length := uint32(itr())
@@ -1324,6 +1354,130 @@ func (t *ListType) typeSize() int {
 	return 1 + t.ElementType.typeSize()
 }
 
+// FixedListType is a list with a fixed number of elements. Unlike ListType,
+// its elements are stored inline and flattened one after another.
+type FixedListType struct {
+	ElementType ValueType
+	Length      uint32
+}
+
+func (t *FixedListType) isType() {}
+
+func (t *FixedListType) typeName() string {
+	return "list"
+}
+
+func (t *FixedListType) isValueType() {}
+
+func (t *FixedListType) supportsValue(v Value) bool {
+	listVal, ok := v.(List)
+	if !ok || uint32(len(listVal)) != t.Length {
+		return false
+	}
+	for i := 0; i < len(listVal); i++ {
+		if !t.ElementType.supportsValue(listVal[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *FixedListType) checkType(other Type, typeChecker typeChecker) error {
+	ot, err := assertTypeKindIsSame(t, other)
+	if err != nil {
+		return err
+	}
+	if t.Length != ot.Length {
+		return fmt.Errorf("list length mismatch: expected %d, found %d", t.Length, ot.Length)
+	}
+	return typeChecker.checkTypeCompatible(t.ElementType, ot.ElementType)
+}
+
+func (t *FixedListType) alignment() uint32 { return t.ElementType.alignment() }
+func (t *FixedListType) elementSize() uint32 {
+	return t.Length * t.ElementType.elementSize()
+}
+
+func (t *FixedListType) flatTypes() []api.ValueType {
+	elemFlats := t.ElementType.flatTypes()
+	flats := make([]api.ValueType, 0, int(t.Length)*len(elemFlats))
+	for range t.Length {
+		flats = append(flats, elemFlats...)
+	}
+	return flats
+}
+
+func (t *FixedListType) liftFlat(llc *LiftLoadContext, itr func() uint64) (Value, error) {
+	elements := make(List, t.Length)
+	for i := range elements {
+		val, err := t.ElementType.liftFlat(llc, itr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lift list element %d: %w", i, err)
+		}
+		elements[i] = val
+	}
+	return elements, nil
+}
+
+func (t *FixedListType) load(llc *LiftLoadContext, offset uint32) (Value, error) {
+	elements := make(List, t.Length)
+	elementSize := t.ElementType.elementSize()
+	for i := range elements {
+		val, err := t.ElementType.load(llc, offset+uint32(i)*elementSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load list element %d: %w", i, err)
+		}
+		elements[i] = val
+	}
+	return elements, nil
+}
+
+func (t *FixedListType) lowerFlat(llc *LiftLoadContext, val Value) ([]uint64, error) {
+	listVal := val.(List)
+	if uint32(len(listVal)) != t.Length {
+		return nil, fmt.Errorf("list length mismatch: expected %d, found %d", t.Length, len(listVal))
+	}
+	var flats []uint64
+	for i := range listVal {
+		elemFlats, err := t.ElementType.lowerFlat(llc, listVal[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to lower list element %d: %w", i, err)
+		}
+		flats = append(flats, elemFlats...)
+	}
+	return flats, nil
+}
+
+func (t *FixedListType) store(llc *LiftLoadContext, offset uint32, val Value) error {
+	listVal := val.(List)
+	if uint32(len(listVal)) != t.Length {
+		return fmt.Errorf("list length mismatch: expected %d, found %d", t.Length, len(listVal))
+	}
+	elementSize := t.ElementType.elementSize()
+	for i := range listVal {
+		if err := t.ElementType.store(llc, offset+uint32(i)*elementSize, listVal[i]); err != nil {
+			return fmt.Errorf("failed to store list element %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (t *FixedListType) typeDepth() int {
+	return t.ElementType.typeDepth() + 1
+}
+
+func (t *FixedListType) typeSize() int {
+	return fixedListTypeSize(t.Length, t.ElementType.typeSize())
+}
+
+// fixedListTypeSize counts every element of a fixed-length list in its type
+// size. The size is capped just past maxTypeSize so that nested lists can't
+// overflow it, and the list's byte size and flattening stay in bounds once
+// the size is checked.
+func fixedListTypeSize(length uint32, elementSize int) int {
+	return int(min(1+int64(length)*int64(elementSize), maxTypeSize+1))
+}
+
 type Flags map[string]bool
 
 func (f Flags) isValue() {}
@@ -1446,6 +1600,13 @@ func newResourceType(instance *Instance, destructor func(ctx context.Context, re
 	}
 }
 
+// NewResourceTypeBound returns a resource type standing for any resource, like
+// an import bounded by (sub resource). It takes on the identity of the first
+// resource type it is checked against.
+func NewResourceTypeBound() *ResourceType {
+	return &ResourceType{instance: resourceTypeBoundMarker}
+}
+
 func (t *ResourceType) isType() {}
 
 func (t *ResourceType) typeName() string {
@@ -1650,8 +1811,7 @@ func (t OwnType) lower(llc *LiftLoadContext, v Value) (uint32, error) {
 		return 0, fmt.Errorf("resource handle type mismatch during lower: expected %p, found %p", t.ResourceType, tgtHandle.resourceType())
 	}
 
-	idx := llc.instance.loweredHandles.add(tgtHandle)
-	return idx, nil
+	return llc.instance.loweredHandles.add(tgtHandle)
 }
 
 func (t OwnType) typeDepth() int {
@@ -1798,8 +1958,7 @@ func (t BorrowType) lower(llc *LiftLoadContext, v Value) (uint32, error) {
 			return u32, nil
 		}
 	}
-	idx := llc.instance.loweredHandles.add(borrowVal.Borrow())
-	return idx, nil
+	return llc.instance.loweredHandles.add(borrowVal.Borrow())
 }
 
 func (t BorrowType) typeSize() int {
@@ -1851,6 +2010,9 @@ func (t ByteArrayType) flatTypes() []api.ValueType {
 func (t ByteArrayType) liftFlat(llc *LiftLoadContext, itr func() uint64) (Value, error) {
 	ptr := uint32(itr())
 	length := uint32(itr())
+	if err := checkListSize(llc, ptr, length, 1); err != nil {
+		return nil, err
+	}
 	bytes, ok := llc.memory.Read(ptr, length)
 	if !ok {
 		return nil, fmt.Errorf("failed to read byte array at pointer %d with length %d", ptr, length)
@@ -1867,6 +2029,9 @@ func (t ByteArrayType) load(llc *LiftLoadContext, offset uint32) (Value, error)
 	if !ok {
 		return nil, fmt.Errorf("failed to read list length at offset %d", offset+4)
 	}
+	if err := checkListSize(llc, ptr, length, 1); err != nil {
+		return nil, err
+	}
 	bytes, ok := llc.memory.Read(ptr, length)
 	if !ok {
 		return nil, fmt.Errorf("failed to read byte array at pointer %d with length %d", ptr, length)
@@ -1986,6 +2151,15 @@ func (t *TupleType) typeName() string {
 	return "tuple"
 }
 
+// ElementTypes returns the types of the elements of the tuple.
+func (t *TupleType) ElementTypes() []ValueType {
+	types := make([]ValueType, len(t.underlying.Fields))
+	for i, f := range t.underlying.Fields {
+		types[i] = f.Type
+	}
+	return types
+}
+
 func (t *TupleType) checkType(other Type, typeChecker typeChecker) error {
 	ot, err := assertTypeKindIsSame(t, other)
 	if err != nil {
@@ -2011,6 +2185,15 @@ func (t *EnumType) typeName() string {
 	return "enum"
 }
 
+// Labels returns the names of the cases of the enum.
+func (t *EnumType) Labels() []string {
+	labels := make([]string, len(t.underlying.Cases))
+	for i, c := range t.underlying.Cases {
+		labels[i] = c.Name
+	}
+	return labels
+}
+
 func (t *EnumType) checkType(other Type, typeChecker typeChecker) error {
 	ot, err := assertTypeKindIsSame(t, other)
 	if err != nil {
@@ -2047,6 +2230,11 @@ func (t *OptionType) typeName() string {
 	return "option"
 }
 
+// ValueType returns the type of the value of some.
+func (t *OptionType) ValueType() ValueType {
+	return t.underlying.Cases[1].Type
+}
+
 func (t *OptionType) checkType(other Type, typeChecker typeChecker) error {
 	ot, err := assertTypeKindIsSame(t, other)
 	if err != nil {
@@ -2070,6 +2258,16 @@ func (t *ResultType) typeName() string {
 	return "result"
 }
 
+// OkType returns the type of the value of ok, or nil if it has none.
+func (t *ResultType) OkType() ValueType {
+	return t.underlying.Cases[0].Type
+}
+
+// ErrType returns the type of the value of error, or nil if it has none.
+func (t *ResultType) ErrType() ValueType {
+	return t.underlying.Cases[1].Type
+}
+
 func (t *ResultType) checkType(other Type, typeChecker typeChecker) error {
 	ot, err := assertTypeKindIsSame(t, other)
 	if err != nil {
@@ -0,0 +1,76 @@
+package componentmodel
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/partite-ai/wacogo/ast"
+	"github.com/tetratelabs/wazero/api"
+)
+
+func TestFixedListFlat(t *testing.T) {
+	lt := &FixedListType{ElementType: F32Type{}, Length: 4}
+	flatTypes := lt.flatTypes()
+	if len(flatTypes) != 4 {
+		t.Fatalf("flatTypes() has %d types, want 4", len(flatTypes))
+	}
+	for i, ft := range flatTypes {
+		if ft != api.ValueTypeF32 {
+			t.Errorf("flatTypes()[%d] = %v, want f32", i, ft)
+		}
+	}
+	if lt.elementSize() != 16 || lt.alignment() != 4 {
+		t.Errorf("size and alignment = %d, %d, want 16, 4", lt.elementSize(), lt.alignment())
+	}
+
+	llc := &LiftLoadContext{instance: newInstance()}
+	val := List{F32(1), F32(2), F32(3), F32(4)}
+	flat, err := lt.lowerFlat(llc, val)
+	if err != nil {
+		t.Fatalf("lowerFlat failed: %v", err)
+	}
+	if len(flat) != 4 {
+		t.Fatalf("lowerFlat() returned %d values, want 4", len(flat))
+	}
+	lifted, err := lt.liftFlat(llc, func() uint64 {
+		v := flat[0]
+		flat = flat[1:]
+		return v
+	})
+	if err != nil {
+		t.Fatalf("liftFlat failed: %v", err)
+	}
+	for i, v := range lifted.(List) {
+		if v != val[i] {
+			t.Errorf("element %d = %v, want %v", i, v, val[i])
+		}
+	}
+
+	if _, err := lt.lowerFlat(llc, val[:3]); err == nil {
+		t.Error("lowerFlat() of a list with the wrong length succeeded")
+	}
+}
+
+func TestFixedListTypeSize(t *testing.T) {
+	// Nested lists would overflow their byte size if their length weren't
+	// counted in the type size.
+	_, err := NewBuilder(nil).Build(context.Background(), &ast.Component{
+		Definitions: []ast.Definition{
+			&ast.Type{DefType: &ast.FixedListType{Element: &ast.U64Type{}, Length: 1 << 16}},
+			&ast.Type{DefType: &ast.FixedListType{Element: &ast.TypeIdx{Idx: 0}, Length: 1 << 16}},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "effective type size exceeds the limit") {
+		t.Errorf("Build returned %v, want a type size error", err)
+	}
+
+	_, err = NewBuilder(nil).Build(context.Background(), &ast.Component{
+		Definitions: []ast.Definition{
+			&ast.Type{DefType: &ast.FixedListType{Element: &ast.U8Type{}, Length: 0}},
+		},
+	})
+	if err == nil {
+		t.Error("Build of an empty fixed-length list succeeded")
+	}
+}
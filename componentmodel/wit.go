@@ -0,0 +1,786 @@
+package componentmodel
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/partite-ai/wacogo/ast"
+)
+
+// ToWIT renders the imports and exports of the component as a WIT world.
+// Interfaces with qualified names, like wasi:io/streams@0.2.0, are printed
+// after the world in packages of their own. Imports, exports and types WIT
+// can't express, like core modules or names that aren't WIT identifiers, are
+// printed as comments.
+func (c *Component) ToWIT() string {
+	byName := make(map[string]Extern, len(c.importExterns))
+	for _, e := range c.importExterns {
+		byName[e.Name] = e
+	}
+	// Print imports in the order they are defined, which puts interfaces
+	// before the ones using their types.
+	names, _ := c.importSorts()
+	imports := make([]Extern, 0, len(names))
+	for _, name := range names {
+		imports = append(imports, byName[name])
+	}
+	return newWITPrinter().world("root", imports, c.exportExterns)
+}
+
+// ToWIT renders the exports of the instance as a WIT interface. If name is a
+// qualified interface name, the interface is printed in its package.
+func (i *Instance) ToWIT(name string) string {
+	return newWITPrinter().standaloneInterface(name, exportSpecExterns(i.exportSpecs))
+}
+
+type witTypeName struct {
+	name string
+	typ  Type
+	// owner is the qualified name of the interface that defines the type.
+	owner string
+}
+
+type witPrinter struct {
+	// named holds the named types of every qualified interface printed.
+	named []witTypeName
+}
+
+func newWITPrinter() *witPrinter {
+	return &witPrinter{}
+}
+
+func (p *witPrinter) world(name string, imports, exports []Extern) string {
+	// Name the types of all interfaces up front, so an interface can use the
+	// types of one printed after it.
+	for _, e := range imports {
+		p.register(e)
+	}
+	for _, e := range exports {
+		p.register(e)
+	}
+
+	s := &witScope{p: p}
+	var types []Extern
+	for _, e := range imports {
+		if e.Sort == ast.SortType {
+			types = append(types, e)
+		}
+	}
+	for _, e := range exports {
+		if e.Sort == ast.SortType {
+			types = append(types, e)
+		}
+	}
+	s.declare(types)
+	s.prune(types, nil)
+
+	var body []string
+	for _, e := range types {
+		if !s.used(e.Name) {
+			body = append(body, s.checked("  ", func() []string {
+				return s.typeDef(e.Name, e.Type, nil, "  ")
+			})...)
+		}
+	}
+	var interfaces []Extern
+	seen := make(map[string]bool)
+	externs := func(direction string, externs []Extern) {
+		for _, e := range externs {
+			switch e.Sort {
+			case ast.SortType:
+			case ast.SortFunc:
+				body = append(body, s.checked("  ", func() []string {
+					return []string{fmt.Sprintf("  %s %s: %s;", direction, s.ident(e.Name), s.funcTypeExpr(e.Type))}
+				})...)
+			case ast.SortInstance:
+				if _, _, ok := splitWITInterfaceName(e.Name); ok {
+					body = append(body, fmt.Sprintf("  %s %s;", direction, e.Name))
+					if !seen[e.Name] {
+						seen[e.Name] = true
+						interfaces = append(interfaces, e)
+					}
+					continue
+				}
+				inline := &witScope{p: p}
+				body = append(body, inline.checked("  ", func() []string {
+					lines := []string{fmt.Sprintf("  %s %s: interface {", direction, inline.ident(e.Name))}
+					lines = append(lines, inline.interfaceBody(e.Type.(*InstanceType).externs(), "    ")...)
+					return append(lines, "  }")
+				})...)
+			default:
+				body = append(body, fmt.Sprintf("  // %s %s: %s", direction, e.Name, e.Sort))
+			}
+		}
+	}
+	externs("import", imports)
+	externs("export", exports)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "package root:component;\n\nworld %s {\n", witIdent(name))
+	writeWITLines(&sb, s.useLines("  "))
+	writeWITLines(&sb, body)
+	sb.WriteString("}\n")
+
+	var packages []string
+	byPackage := make(map[string][]Extern)
+	for _, e := range interfaces {
+		pkg, _, _ := splitWITInterfaceName(e.Name)
+		if _, ok := byPackage[pkg]; !ok {
+			packages = append(packages, pkg)
+		}
+		byPackage[pkg] = append(byPackage[pkg], e)
+	}
+	for _, pkg := range packages {
+		fmt.Fprintf(&sb, "\npackage %s {\n", pkg)
+		for _, e := range byPackage[pkg] {
+			_, iface, _ := splitWITInterfaceName(e.Name)
+			s := &witScope{p: p, iface: e.Name, pkg: pkg}
+			fmt.Fprintf(&sb, "  interface %s {\n", witIdent(iface))
+			writeWITLines(&sb, s.interfaceBody(e.Type.(*InstanceType).externs(), "    "))
+			sb.WriteString("  }\n")
+		}
+		sb.WriteString("}\n")
+	}
+	return sb.String()
+}
+
+func (p *witPrinter) standaloneInterface(name string, externs []Extern) string {
+	var sb strings.Builder
+	pkg, iface, ok := splitWITInterfaceName(name)
+	if ok {
+		fmt.Fprintf(&sb, "package %s;\n\n", pkg)
+	} else {
+		iface = name
+	}
+	s := &witScope{p: p, iface: name, pkg: pkg}
+	fmt.Fprintf(&sb, "interface %s {\n", witIdent(iface))
+	writeWITLines(&sb, s.interfaceBody(externs, "  "))
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// register names the types exported by a qualified interface.
+func (p *witPrinter) register(e Extern) {
+	it, ok := e.Type.(*InstanceType)
+	if !ok {
+		return
+	}
+	if _, _, ok := splitWITInterfaceName(e.Name); !ok {
+		return
+	}
+	for _, te := range it.externs() {
+		if te.Sort != ast.SortType || !isWITNamedType(te.Type) || !isWITLabel(te.Name) {
+			continue
+		}
+		if _, ok := p.owner(te.Type); !ok {
+			p.named = append(p.named, witTypeName{name: te.Name, typ: te.Type, owner: e.Name})
+		}
+	}
+}
+
+func (p *witPrinter) owner(t Type) (witTypeName, bool) {
+	for _, n := range p.named {
+		if n.typ == t {
+			return n, true
+		}
+	}
+	return witTypeName{}, false
+}
+
+type witUse struct {
+	from, name, as string
+}
+
+// witScope tracks the type names visible in a world or interface.
+type witScope struct {
+	p *witPrinter
+	// iface and pkg are the qualified name and package of the interface
+	// printed, if it has one.
+	iface string
+	pkg   string
+	local []witTypeName
+	uses  []witUse
+	// inexpressible is set when something WIT can't express is rendered.
+	inexpressible bool
+}
+
+// declare makes the types in externs visible in the scope, using the ones
+// defined by other interfaces.
+func (s *witScope) declare(types []Extern) {
+	for _, e := range types {
+		if n, ok := s.p.owner(e.Type); ok && n.owner != s.iface && isWITLabel(e.Name) {
+			s.use(n, e.Name)
+			continue
+		}
+		s.local = append(s.local, witTypeName{name: e.Name, typ: e.Type})
+	}
+}
+
+func (s *witScope) use(n witTypeName, as string) {
+	for _, u := range s.uses {
+		if u.from == n.owner && u.name == n.name {
+			return
+		}
+	}
+	s.uses = append(s.uses, witUse{from: n.owner, name: n.name, as: as})
+	s.local = append(s.local, witTypeName{name: as, typ: n.typ})
+}
+
+func (s *witScope) used(name string) bool {
+	for _, u := range s.uses {
+		if u.as == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checked renders lines with render, commenting them out if they use names or
+// types WIT can't express.
+func (s *witScope) checked(indent string, render func() []string) []string {
+	outer := s.inexpressible
+	s.inexpressible = false
+	lines := render()
+	if s.inexpressible {
+		for i, line := range lines {
+			lines[i] = indent + "// " + strings.TrimPrefix(line, indent)
+		}
+	}
+	s.inexpressible = outer
+	return lines
+}
+
+// prune removes the types WIT can't express from the scope, so they are
+// written out, and commented out, wherever they are used.
+func (s *witScope) prune(types []Extern, methods map[string][]Extern) {
+	outer := s.inexpressible
+	for pruned := true; pruned; {
+		pruned = false
+		for _, e := range types {
+			if s.used(e.Name) {
+				continue
+			}
+			i := slices.IndexFunc(s.local, func(n witTypeName) bool { return n.name == e.Name })
+			if i < 0 {
+				continue
+			}
+			s.inexpressible = false
+			s.typeDef(e.Name, e.Type, methods[e.Name], "")
+			if s.inexpressible {
+				s.local = slices.Delete(s.local, i, i+1)
+				pruned = true
+			}
+		}
+	}
+	s.inexpressible = outer
+}
+
+// ident renders name as a WIT identifier, which it may not be.
+func (s *witScope) ident(name string) string {
+	if !isWITLabel(name) {
+		s.inexpressible = true
+	}
+	return witIdent(name)
+}
+
+// lookup returns the name t is known by in the scope. Only types WIT gives
+// names to are looked up; others are always written out.
+func (s *witScope) lookup(t Type) (string, bool) {
+	if !isWITNamedType(t) {
+		return "", false
+	}
+	for _, n := range s.local {
+		if n.typ == t {
+			return n.name, true
+		}
+	}
+	// Host instances create a new type each time a Go type is used, so
+	// value types are also matched by structure.
+	if _, isResource := t.(*ResourceType); !isResource {
+		for _, n := range s.local {
+			if reflect.DeepEqual(n.typ, t) {
+				return n.name, true
+			}
+		}
+	}
+	if n, ok := s.p.owner(t); ok && n.owner != s.iface {
+		s.use(n, n.name)
+		return n.name, true
+	}
+	return "", false
+}
+
+func (s *witScope) useLines(indent string) []string {
+	var lines []string
+	var from []string
+	names := make(map[string][]string)
+	for _, u := range s.uses {
+		if _, ok := names[u.from]; !ok {
+			from = append(from, u.from)
+		}
+		name := witIdent(u.name)
+		if u.as != u.name {
+			name += " as " + witIdent(u.as)
+		}
+		names[u.from] = append(names[u.from], name)
+	}
+	for _, f := range from {
+		path := f
+		if pkg, iface, _ := splitWITInterfaceName(f); s.pkg != "" && pkg == s.pkg {
+			path = witIdent(iface)
+		}
+		lines = append(lines, fmt.Sprintf("%suse %s.{%s};", indent, path, strings.Join(names[f], ", ")))
+	}
+	return lines
+}
+
+// interfaceBody renders the items of an interface exporting externs: its
+// types, with the functions of resources inside them, then its functions.
+func (s *witScope) interfaceBody(externs []Extern, indent string) []string {
+	var types, funcs, others []Extern
+	var resources []string
+	methods := make(map[string][]Extern)
+	for _, e := range externs {
+		switch e.Sort {
+		case ast.SortType:
+			types = append(types, e)
+		case ast.SortFunc:
+			if res, _, _, ok := splitWITResourceFunc(e.Name); ok {
+				if _, ok := methods[res]; !ok {
+					resources = append(resources, res)
+				}
+				methods[res] = append(methods[res], e)
+				continue
+			}
+			funcs = append(funcs, e)
+		default:
+			others = append(others, e)
+		}
+	}
+
+	// Resources that aren't exported as types are named after the functions
+	// operating on them.
+	for _, res := range resources {
+		if hasExtern(types, res) {
+			continue
+		}
+		if rt := witResourceOf(methods[res]); rt != nil {
+			types = append(types, Extern{Name: res, Sort: ast.SortType, Type: rt})
+			continue
+		}
+		funcs = append(funcs, methods[res]...)
+		delete(methods, res)
+	}
+	sortExterns(types)
+	s.declare(types)
+	s.prune(types, methods)
+
+	var body []string
+	for _, e := range types {
+		if s.used(e.Name) {
+			continue
+		}
+		body = append(body, s.checked(indent, func() []string {
+			return s.typeDef(e.Name, e.Type, methods[e.Name], indent)
+		})...)
+	}
+	for _, e := range funcs {
+		body = append(body, s.checked(indent, func() []string {
+			return []string{fmt.Sprintf("%s%s: %s;", indent, s.ident(e.Name), s.funcTypeExpr(e.Type))}
+		})...)
+	}
+	for _, e := range others {
+		body = append(body, fmt.Sprintf("%s// %s: %s", indent, e.Name, e.Sort))
+	}
+
+	uses := s.useLines(indent)
+	if len(uses) > 0 && len(body) > 0 {
+		uses = append(uses, "")
+	}
+	return append(uses, body...)
+}
+
+// typeDef renders the definition of the type named name, which is given
+// methods if it is a resource.
+func (s *witScope) typeDef(name string, t Type, methods []Extern, indent string) []string {
+	ident := s.ident(name)
+	if first, ok := s.lookup(t); ok && first != name {
+		return []string{fmt.Sprintf("%stype %s = %s;", indent, ident, s.ident(first))}
+	}
+	block := func(kind string, items []string) []string {
+		// WIT has no empty records, variants, enums or flags.
+		if len(items) == 0 && kind != "resource" {
+			s.inexpressible = true
+		}
+		lines := []string{fmt.Sprintf("%s%s %s {", indent, kind, ident)}
+		for _, item := range items {
+			lines = append(lines, indent+"  "+item)
+		}
+		return append(lines, indent+"}")
+	}
+	switch t := t.(type) {
+	case *RecordType:
+		var items []string
+		for _, f := range t.Fields {
+			items = append(items, fmt.Sprintf("%s: %s,", s.ident(f.Name), s.typeExpr(f.Type)))
+		}
+		return block("record", items)
+	case *VariantType:
+		var items []string
+		for _, c := range t.Cases {
+			items = append(items, s.caseExpr(c)+",")
+		}
+		return block("variant", items)
+	case *EnumType:
+		var items []string
+		for _, label := range t.Labels() {
+			items = append(items, s.ident(label)+",")
+		}
+		return block("enum", items)
+	case *FlagsType:
+		var items []string
+		for _, flag := range t.FlagNames {
+			items = append(items, s.ident(flag)+",")
+		}
+		return block("flags", items)
+	case *ResourceType:
+		if len(methods) == 0 {
+			return []string{fmt.Sprintf("%sresource %s;", indent, ident)}
+		}
+		// Functions WIT can't express are commented out on their own, as
+		// the resource doesn't depend on them.
+		var items []string
+		for _, m := range methods {
+			items = append(items, s.checked("", func() []string {
+				return []string{s.resourceFunc(m)}
+			})...)
+		}
+		return block("resource", items)
+	default:
+		return []string{fmt.Sprintf("%stype %s = %s;", indent, ident, s.typeExpr(t))}
+	}
+}
+
+// resourceFunc renders a function of a resource inside its definition.
+func (s *witScope) resourceFunc(m Extern) string {
+	_, kind, fn, _ := splitWITResourceFunc(m.Name)
+	ft, ok := m.Type.(*FunctionType)
+	if !ok {
+		s.inexpressible = true
+		return fmt.Sprintf("%s: %s", m.Name, m.Sort)
+	}
+	switch kind {
+	case "constructor":
+		item := "constructor(" + s.paramsExpr(ft.Parameters) + ")"
+		if _, isOwn := ft.ResultType.(OwnType); ft.ResultType != nil && !isOwn {
+			item += " -> " + s.typeExpr(ft.ResultType)
+		}
+		return item + ";"
+	case "method":
+		params := ft.Parameters
+		if len(params) > 0 {
+			params = params[1:]
+		}
+		return fmt.Sprintf("%s: %s;", s.ident(fn), s.funcExpr(params, ft.ResultType))
+	default:
+		return fmt.Sprintf("%s: static %s;", s.ident(fn), s.funcExpr(ft.Parameters, ft.ResultType))
+	}
+}
+
+// typeExpr renders a reference to t. Types WIT requires to be named are
+// written out in full if they have no name in the scope, which WIT can't
+// express.
+func (s *witScope) typeExpr(t Type) string {
+	if name, ok := s.lookup(t); ok {
+		return s.ident(name)
+	}
+	switch t := t.(type) {
+	case BoolType, U8Type, U16Type, U32Type, U64Type, S8Type, S16Type, S32Type, S64Type,
+		F32Type, F64Type, CharType, StringType, ErrorContextType:
+		return t.typeName()
+	case ByteArrayType:
+		return "list<u8>"
+	case *ListType:
+		return "list<" + s.typeExpr(t.ElementType) + ">"
+	case *FixedListType:
+		return fmt.Sprintf("list<%s, %d>", s.typeExpr(t.ElementType), t.Length)
+	case *TupleType:
+		var elems []string
+		for _, et := range t.ElementTypes() {
+			elems = append(elems, s.typeExpr(et))
+		}
+		if len(elems) == 0 {
+			s.inexpressible = true
+		}
+		return "tuple<" + strings.Join(elems, ", ") + ">"
+	case *OptionType:
+		return "option<" + s.typeExpr(t.ValueType()) + ">"
+	case *ResultType:
+		ok, err := t.OkType(), t.ErrType()
+		switch {
+		case ok == nil && err == nil:
+			return "result"
+		case err == nil:
+			return "result<" + s.typeExpr(ok) + ">"
+		case ok == nil:
+			return "result<_, " + s.typeExpr(err) + ">"
+		default:
+			return "result<" + s.typeExpr(ok) + ", " + s.typeExpr(err) + ">"
+		}
+	case OwnType:
+		if name, ok := s.lookup(t.ResourceType); ok {
+			return s.ident(name)
+		}
+		return "own<" + s.typeExpr(t.ResourceType) + ">"
+	case BorrowType:
+		return "borrow<" + s.typeExpr(t.ResourceType) + ">"
+	case *StreamType:
+		if t.ElementType == nil {
+			return "stream"
+		}
+		return "stream<" + s.typeExpr(t.ElementType) + ">"
+	case *FutureType:
+		if t.ValueType == nil {
+			return "future"
+		}
+		return "future<" + s.typeExpr(t.ValueType) + ">"
+	}
+
+	s.inexpressible = true
+	switch t := t.(type) {
+	case *RecordType:
+		var fields []string
+		for _, f := range t.Fields {
+			fields = append(fields, witIdent(f.Name)+": "+s.typeExpr(f.Type))
+		}
+		return "record { " + strings.Join(fields, ", ") + " }"
+	case *VariantType:
+		var cases []string
+		for _, c := range t.Cases {
+			cases = append(cases, s.caseExpr(c))
+		}
+		return "variant { " + strings.Join(cases, ", ") + " }"
+	case *EnumType:
+		return "enum { " + strings.Join(witIdents(t.Labels()), ", ") + " }"
+	case *FlagsType:
+		return "flags { " + strings.Join(witIdents(t.FlagNames), ", ") + " }"
+	case *FunctionType:
+		return s.funcExpr(t.Parameters, t.ResultType)
+	default:
+		return t.typeName()
+	}
+}
+
+// funcTypeExpr renders the type of a function import or export.
+func (s *witScope) funcTypeExpr(t Type) string {
+	if ft, ok := t.(*FunctionType); ok {
+		return s.funcExpr(ft.Parameters, ft.ResultType)
+	}
+	return s.typeExpr(t)
+}
+
+func (s *witScope) caseExpr(c *VariantCase) string {
+	if c.Type == nil {
+		return s.ident(c.Name)
+	}
+	return s.ident(c.Name) + "(" + s.typeExpr(c.Type) + ")"
+}
+
+func (s *witScope) funcExpr(params []*FunctionParameter, result ValueType) string {
+	expr := "func(" + s.paramsExpr(params) + ")"
+	if result != nil {
+		expr += " -> " + s.typeExpr(result)
+	}
+	return expr
+}
+
+func (s *witScope) paramsExpr(params []*FunctionParameter) string {
+	var exprs []string
+	for _, p := range params {
+		exprs = append(exprs, s.ident(p.Name)+": "+s.typeExpr(p.Type))
+	}
+	return strings.Join(exprs, ", ")
+}
+
+// isWITNamedType reports whether WIT requires t to be defined with a name
+// before it is used.
+func isWITNamedType(t Type) bool {
+	switch t.(type) {
+	case *RecordType, *VariantType, *EnumType, *FlagsType, *ResourceType:
+		return true
+	}
+	return false
+}
+
+// splitWITInterfaceName splits a qualified interface name, like
+// wasi:io/streams@0.2.0, into its package, wasi:io@0.2.0, and interface,
+// streams.
+func splitWITInterfaceName(name string) (pkg, iface string, ok bool) {
+	ns, rest, ok := strings.Cut(name, ":")
+	if !ok {
+		return "", "", false
+	}
+	pkgName, rest, ok := strings.Cut(rest, "/")
+	if !ok {
+		return "", "", false
+	}
+	iface, version, versioned := strings.Cut(rest, "@")
+	if !isWITLabel(ns) || !isWITLabel(pkgName) || !isWITLabel(iface) || versioned && !isWITVersion(version) {
+		return "", "", false
+	}
+	pkg = ns + ":" + pkgName
+	if versioned {
+		pkg += "@" + version
+	}
+	return pkg, iface, true
+}
+
+// isWITLabel reports whether name can be written as a WIT identifier: words
+// of letters and digits, each starting with a letter, separated by hyphens.
+func isWITLabel(name string) bool {
+	if name == "" {
+		return false
+	}
+	for word := range strings.SplitSeq(name, "-") {
+		if word == "" || !isWITLetter(word[0]) {
+			return false
+		}
+		for i := range len(word) {
+			if !isWITLetter(word[i]) && !isWITDigit(word[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isWITLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isWITDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isWITVersion reports whether version is a semantic version WIT accepts,
+// like 0.2.0 or 1.0.0-rc.1+build.
+func isWITVersion(version string) bool {
+	version, build, _ := strings.Cut(version, "+")
+	core, pre, _ := strings.Cut(version, "-")
+	numbers := strings.Split(core, ".")
+	if len(numbers) != 3 {
+		return false
+	}
+	for _, n := range numbers {
+		if n == "" || strings.ContainsFunc(n, func(r rune) bool { return r < '0' || r > '9' }) {
+			return false
+		}
+	}
+	for _, ids := range []string{pre, build} {
+		if ids == "" {
+			continue
+		}
+		for id := range strings.SplitSeq(ids, ".") {
+			if id == "" || strings.ContainsFunc(id, func(r rune) bool {
+				return r != '-' && (r < '0' || r > '9') && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z')
+			}) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// splitWITResourceFunc splits the name of a resource function, like
+// [method]stream.read, into its resource, kind and function name.
+func splitWITResourceFunc(name string) (resource, kind, fn string, ok bool) {
+	for _, k := range []string{"constructor", "method", "static"} {
+		rest, found := strings.CutPrefix(name, "["+k+"]")
+		if !found {
+			continue
+		}
+		if k == "constructor" {
+			return rest, k, "", true
+		}
+		resource, fn, found := strings.Cut(rest, ".")
+		return resource, k, fn, found
+	}
+	return "", "", "", false
+}
+
+// witResourceOf finds the resource the functions of a resource operate on.
+func witResourceOf(fns []Extern) *ResourceType {
+	for _, e := range fns {
+		ft, ok := e.Type.(*FunctionType)
+		if !ok {
+			continue
+		}
+		_, kind, _, _ := splitWITResourceFunc(e.Name)
+		var handle Type
+		switch kind {
+		case "constructor":
+			handle = ft.ResultType
+		case "method":
+			if len(ft.Parameters) > 0 {
+				handle = ft.Parameters[0].Type
+			}
+		}
+		switch h := handle.(type) {
+		case OwnType:
+			if rt, ok := h.ResourceType.(*ResourceType); ok {
+				return rt
+			}
+		case BorrowType:
+			if rt, ok := h.ResourceType.(*ResourceType); ok {
+				return rt
+			}
+		}
+	}
+	return nil
+}
+
+func hasExtern(externs []Extern, name string) bool {
+	for _, e := range externs {
+		if e.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+var witKeywords = map[string]bool{
+	"as": true, "async": true, "bool": true, "borrow": true, "char": true,
+	"constructor": true, "enum": true, "error-context": true, "export": true,
+	"f32": true, "f64": true, "flags": true, "from": true, "func": true,
+	"future": true, "import": true, "include": true, "interface": true,
+	"list": true, "option": true, "own": true, "package": true, "record": true,
+	"resource": true, "result": true, "s8": true, "s16": true, "s32": true,
+	"s64": true, "static": true, "stream": true, "string": true, "tuple": true,
+	"type": true, "u8": true, "u16": true, "u32": true, "u64": true, "use": true,
+	"variant": true, "with": true, "world": true,
+}
+
+// witIdent escapes name if it is a WIT keyword.
+func witIdent(name string) string {
+	if witKeywords[name] {
+		return "%" + name
+	}
+	return name
+}
+
+func witIdents(names []string) []string {
+	idents := make([]string, len(names))
+	for i, name := range names {
+		idents[i] = witIdent(name)
+	}
+	return idents
+}
+
+func writeWITLines(sb *strings.Builder, lines []string) {
+	for _, line := range lines {
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+}
@@ -0,0 +1,136 @@
+package componentmodel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/partite-ai/wacogo/ast"
+)
+
+func TestComponentToWIT(t *testing.T) {
+	comp, err := NewBuilder(nil).Build(context.Background(), &ast.Component{
+		Definitions: []ast.Definition{
+			&ast.Type{DefType: &ast.InstanceType{Declarations: []ast.InstanceDecl{
+				&ast.TypeDecl{Type: &ast.Type{DefType: &ast.RecordType{Fields: []ast.RecordField{
+					{Label: "x", Type: &ast.U32Type{}},
+					{Label: "y", Type: &ast.U32Type{}},
+				}}}},
+				&ast.ExportDecl{ExportName: "point", Desc: &ast.TypeExternDesc{Bound: &ast.EqBound{TypeIdx: 0}}},
+				&ast.TypeDecl{Type: &ast.Type{DefType: &ast.FuncType{
+					Params:  []ast.FuncParam{{Label: "p", Type: &ast.TypeIdx{Idx: 1}}},
+					Results: &ast.U32Type{},
+				}}},
+				&ast.ExportDecl{ExportName: "norm", Desc: &ast.SortExternDesc{Sort: ast.SortFunc, TypeIdx: 2}},
+			}}},
+			&ast.Import{ImportName: "test:geo/types@0.1.0", Desc: &ast.SortExternDesc{Sort: ast.SortInstance, TypeIdx: 0}},
+			&ast.Alias{Sort: ast.SortType, Target: &ast.ExportAlias{InstanceIdx: 0, Name: "point"}},
+			&ast.Type{DefType: &ast.FuncType{
+				Params:  []ast.FuncParam{{Label: "p", Type: &ast.TypeIdx{Idx: 1}}, {Label: "type", Type: &ast.StringType{}}},
+				Results: &ast.ResultType{Error: &ast.StringType{}},
+			}},
+			&ast.Import{ImportName: "scale", Desc: &ast.SortExternDesc{Sort: ast.SortFunc, TypeIdx: 2}},
+			&ast.Export{ExportName: "run", SortIdx: ast.SortIdx{Sort: ast.SortFunc, Idx: 0}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	want := `package root:component;
+
+world root {
+  use test:geo/types@0.1.0.{point};
+  import test:geo/types@0.1.0;
+  import scale: func(p: point, %type: string) -> result<_, string>;
+  export run: func(p: point, %type: string) -> result<_, string>;
+}
+
+package test:geo@0.1.0 {
+  interface types {
+    record point {
+      x: u32,
+      y: u32,
+    }
+    norm: func(p: point) -> u32;
+  }
+}
+`
+	if got := comp.ToWIT(); got != want {
+		t.Errorf("ToWIT() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestInstanceToWIT(t *testing.T) {
+	inst := newInstance()
+	counter := newResourceType(inst, nil)
+	kind := NewEnumType("up", "down")
+	function := func(params []*FunctionParameter, result ValueType) func(*Instance) *Function {
+		return func(*Instance) *Function {
+			return NewFunction(&FunctionType{Parameters: params, ResultType: result}, nil)
+		}
+	}
+	b := NewInstanceBuilder().
+		AddTypeExport("kind", kind).
+		AddFunctionExport("[constructor]counter", function(
+			[]*FunctionParameter{{Name: "start", Type: U32Type{}}},
+			OwnType{ResourceType: counter},
+		)).
+		AddFunctionExport("[method]counter.step", function(
+			[]*FunctionParameter{
+				{Name: "self", Type: BorrowType{ResourceType: counter}},
+				{Name: "k", Type: NewEnumType("up", "down")},
+			},
+			U32Type{},
+		)).
+		AddFunctionExport("reset", function(
+			[]*FunctionParameter{{Name: "c", Type: OwnType{ResourceType: counter}}},
+			NewOptionType(&ListType{ElementType: U8Type{}}),
+		))
+
+	want := `package test:counter;
+
+interface counters {
+  resource counter {
+    constructor(start: u32);
+    step: func(k: kind) -> u32;
+  }
+  enum kind {
+    up,
+    down,
+  }
+  reset: func(c: counter) -> option<list<u8>>;
+}
+`
+	if got := b.Build().ToWIT("test:counter/counters"); got != want {
+		t.Errorf("ToWIT() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestInstanceToWITInexpressible(t *testing.T) {
+	function := func(params []*FunctionParameter) func(*Instance) *Function {
+		return func(*Instance) *Function {
+			return NewFunction(&FunctionType{Parameters: params}, nil)
+		}
+	}
+	kinds := &ListType{ElementType: NewEnumType("a")}
+	b := NewInstanceBuilder().
+		AddTypeExport("kinds", kinds).
+		AddTypeExport("count", U32Type{}).
+		AddFunctionExport("[static]a.a", function(nil)).
+		AddFunctionExport("locked-dep=<a:a@1.2.3>x", function(nil)).
+		AddFunctionExport("f", function([]*FunctionParameter{{Name: "k", Type: kinds}})).
+		AddFunctionExport("g", function([]*FunctionParameter{{Name: "n", Type: U32Type{}}}))
+
+	want := `interface x {
+  type count = u32;
+  // type kinds = list<enum { a }>;
+  // f: func(k: list<enum { a }>);
+  g: func(n: u32);
+  // locked-dep=<a:a@1.2.3>x: func();
+  // [static]a.a: func();
+}
+`
+	if got := b.Build().ToWIT("x"); got != want {
+		t.Errorf("ToWIT() =\n%s\nwant\n%s", got, want)
+	}
+}
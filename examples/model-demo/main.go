@@ -52,7 +52,7 @@ func main() {
 
 	// Create a wazero runtime for loading core modules
 	ctx := context.Background()
-	runtime := wazero.NewRuntime(ctx)
+	runtime := wazero.NewRuntimeWithConfig(ctx, componentmodel.NewRuntimeConfig())
 	defer runtime.Close(ctx)
 
 	// Build the model
@@ -64,14 +64,11 @@ func main() {
 	}
 
 	fmt.Println("Creating WASI instances...")
-	wasiInstances, err := p2.CreateStandardWASIInstances(
-		bytes.NewBuffer(nil),
-		os.Stdout,
-		os.Stderr,
-		os.Args,
-		nil,
-		"",
-	)
+	wasiInstances, err := p2.NewWASIConfig().
+		Stdout(os.Stdout).
+		Stderr(os.Stderr).
+		Args(os.Args...).
+		Instances()
 
 	if err != nil {
 		log.Fatalf("Failed to create WASI instances: %v", err)
@@ -94,6 +91,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to instantiate model: %v", err)
 	}
+	defer compInst.Close(ctx)
 	fmt.Println("Component instantiated successfully:", compInst)
 
 	greetComp, ok := compInst.Export("example:people/greet")
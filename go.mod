@@ -4,6 +4,7 @@ go 1.25.5
 
 require (
 	github.com/tetratelabs/wazero v1.11.0
+	golang.org/x/term v0.37.0
 	golang.org/x/text v0.33.0
 )
 
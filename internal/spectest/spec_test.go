@@ -153,7 +153,7 @@ func runSpecTest(t *testing.T, fsys fs.FS, jsonPath string) {
 		t.Fatal("Failed to unmarshal spec test JSON:", err)
 	}
 
-	runtime := wazero.NewRuntime(t.Context())
+	runtime := wazero.NewRuntimeWithConfig(t.Context(), componentmodel.NewRuntimeConfig())
 	defer runtime.Close(t.Context())
 
 	tc := &testContext{
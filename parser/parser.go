@@ -1409,20 +1409,20 @@ func (p *Parser) parseTypeConstructor() (ast.DefType, error) {
 		return &ast.ListType{Element: elemType}, nil
 
 	case 0x67:
-		return nil, fmt.Errorf("fixed length list type (0x67) is not yet supported")
-		/*
-			// fixed-length list type 🔧
-			elemType, err := p.parseValType()
-			if err != nil {
-				return nil, err
-			}
-			_, err = p.readU32() // length
-			if err != nil {
-				return nil, err
-			}
-			// For now, treat as regular list - TODO: extend AST to support fixed-length lists
-			return &ast.ListType{Element: elemType}, nil
-		*/
+		// fixed-length list type 🔧
+		elemType, err := p.parseValType()
+		if err != nil {
+			return nil, err
+		}
+		length, err := p.readU32()
+		if err != nil {
+			return nil, err
+		}
+		if length == 0 {
+			return nil, fmt.Errorf("fixed-length list must have at least one element")
+		}
+		return &ast.FixedListType{Element: elemType, Length: length}, nil
+
 	case 0x6f:
 		// tuple type
 		var types []ast.DefValType
@@ -1526,15 +1526,23 @@ func (p *Parser) parseTypeConstructor() (ast.DefType, error) {
 
 	case 0x66:
 		// stream type 🔀 (async feature)
-		return nil, fmt.Errorf("stream types (0x66) are not yet supported")
+		elemType, err := p.parseOptionalValType()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.StreamType{Element: elemType}, nil
 
 	case 0x65:
 		// future type 🔀 (async feature)
-		return nil, fmt.Errorf("future types (0x65) are not yet supported")
+		valType, err := p.parseOptionalValType()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.FutureType{Value: valType}, nil
 
 	case 0x64:
 		// error-context type 📝
-		return nil, fmt.Errorf("error-context types (0x64) are not yet supported")
+		return &ast.ErrorContextType{}, nil
 
 	case 0x40:
 		// func type
@@ -1713,7 +1721,7 @@ func (p *Parser) parseValType() (ast.DefValType, error) {
 		case 0x73:
 			return &ast.StringType{}, nil
 		case 0x64:
-			return nil, fmt.Errorf("error-context types (0x64) are not yet supported")
+			return &ast.ErrorContextType{}, nil
 		default:
 			return nil, fmt.Errorf("invalid primitive value type: 0x%02x", discriminator)
 		}
@@ -1939,8 +1947,8 @@ func (p *Parser) parseCanon() (*ast.Canon, error) {
 			TypeIdx: typeIdx,
 		}
 
-	case 0x03:
-		// canon resource.drop
+	case 0x03, 0x07:
+		// canon resource.drop, or resource.drop async for 0x07
 		typeIdx, err := p.readU32()
 		if err != nil {
 			return nil, err
@@ -1948,6 +1956,7 @@ func (p *Parser) parseCanon() (*ast.Canon, error) {
 
 		def = &ast.CanonResourceDrop{
 			TypeIdx: typeIdx,
+			Async:   discriminator == 0x07,
 		}
 
 	case 0x04:
@@ -1961,6 +1970,184 @@ func (p *Parser) parseCanon() (*ast.Canon, error) {
 			TypeIdx: typeIdx,
 		}
 
+	case 0x05:
+		def = &ast.CanonTaskCancel{}
+
+	case 0x06:
+		// canon subtask.cancel
+		async, err := p.parseCanonFlag("async")
+		if err != nil {
+			return nil, err
+		}
+		def = &ast.CanonSubtaskCancel{Async: async}
+
+	case 0x08:
+		def = &ast.CanonBackpressureSet{}
+
+	case 0x09:
+		// canon task.return
+		result, err := p.parseResultList()
+		if err != nil {
+			return nil, err
+		}
+		opts, err := p.parseCanonOpts()
+		if err != nil {
+			return nil, err
+		}
+		def = &ast.CanonTaskReturn{
+			Result:  result,
+			Options: opts,
+		}
+
+	case 0x0a, 0x0b:
+		// canon context.get and context.set, which only support i32 slots
+		valType, err := p.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if valType != 0x7f {
+			return nil, fmt.Errorf("expected i32 context slot type 0x7f, got 0x%02x", valType)
+		}
+		slot, err := p.readU32()
+		if err != nil {
+			return nil, err
+		}
+		if discriminator == 0x0a {
+			def = &ast.CanonContextGet{Slot: slot}
+		} else {
+			def = &ast.CanonContextSet{Slot: slot}
+		}
+
+	case 0x0c:
+		// canon thread.yield
+		cancellable, err := p.parseCanonFlag("cancellable")
+		if err != nil {
+			return nil, err
+		}
+		def = &ast.CanonYield{Cancellable: cancellable}
+
+	case 0x0d:
+		def = &ast.CanonSubtaskDrop{}
+
+	case 0x0e, 0x15:
+		// canon stream.new and future.new
+		typeIdx, err := p.readU32()
+		if err != nil {
+			return nil, err
+		}
+		if discriminator == 0x0e {
+			def = &ast.CanonStreamNew{TypeIdx: typeIdx}
+		} else {
+			def = &ast.CanonFutureNew{TypeIdx: typeIdx}
+		}
+
+	case 0x0f, 0x10, 0x16, 0x17:
+		// canon stream.read, stream.write, future.read and future.write
+		typeIdx, err := p.readU32()
+		if err != nil {
+			return nil, err
+		}
+		opts, err := p.parseCanonOpts()
+		if err != nil {
+			return nil, err
+		}
+		switch discriminator {
+		case 0x0f:
+			def = &ast.CanonStreamRead{TypeIdx: typeIdx, Options: opts}
+		case 0x10:
+			def = &ast.CanonStreamWrite{TypeIdx: typeIdx, Options: opts}
+		case 0x16:
+			def = &ast.CanonFutureRead{TypeIdx: typeIdx, Options: opts}
+		default:
+			def = &ast.CanonFutureWrite{TypeIdx: typeIdx, Options: opts}
+		}
+
+	case 0x11, 0x12, 0x18, 0x19:
+		// canon stream.cancel-read, stream.cancel-write, future.cancel-read
+		// and future.cancel-write
+		typeIdx, err := p.readU32()
+		if err != nil {
+			return nil, err
+		}
+		async, err := p.parseCanonFlag("async")
+		if err != nil {
+			return nil, err
+		}
+		switch discriminator {
+		case 0x11:
+			def = &ast.CanonStreamCancelRead{TypeIdx: typeIdx, Async: async}
+		case 0x12:
+			def = &ast.CanonStreamCancelWrite{TypeIdx: typeIdx, Async: async}
+		case 0x18:
+			def = &ast.CanonFutureCancelRead{TypeIdx: typeIdx, Async: async}
+		default:
+			def = &ast.CanonFutureCancelWrite{TypeIdx: typeIdx, Async: async}
+		}
+
+	case 0x13, 0x14, 0x1a, 0x1b:
+		// canon stream.drop-readable, stream.drop-writable,
+		// future.drop-readable and future.drop-writable
+		typeIdx, err := p.readU32()
+		if err != nil {
+			return nil, err
+		}
+		switch discriminator {
+		case 0x13:
+			def = &ast.CanonStreamDropReadable{TypeIdx: typeIdx}
+		case 0x14:
+			def = &ast.CanonStreamDropWritable{TypeIdx: typeIdx}
+		case 0x1a:
+			def = &ast.CanonFutureDropReadable{TypeIdx: typeIdx}
+		default:
+			def = &ast.CanonFutureDropWritable{TypeIdx: typeIdx}
+		}
+
+	case 0x1c, 0x1d:
+		// canon error-context.new and error-context.debug-message
+		opts, err := p.parseCanonOpts()
+		if err != nil {
+			return nil, err
+		}
+		if discriminator == 0x1c {
+			def = &ast.CanonErrorContextNew{Options: opts}
+		} else {
+			def = &ast.CanonErrorContextDebugMessage{Options: opts}
+		}
+
+	case 0x1e:
+		def = &ast.CanonErrorContextDrop{}
+
+	case 0x1f:
+		def = &ast.CanonWaitableSetNew{}
+
+	case 0x20, 0x21:
+		// canon waitable-set.wait and waitable-set.poll
+		cancellable, err := p.parseCanonFlag("cancellable")
+		if err != nil {
+			return nil, err
+		}
+		memIdx, err := p.readU32()
+		if err != nil {
+			return nil, err
+		}
+		if discriminator == 0x20 {
+			def = &ast.CanonWaitableSetWait{Cancellable: cancellable, MemoryIdx: memIdx}
+		} else {
+			def = &ast.CanonWaitableSetPoll{Cancellable: cancellable, MemoryIdx: memIdx}
+		}
+
+	case 0x22:
+		def = &ast.CanonWaitableSetDrop{}
+
+	case 0x23:
+		def = &ast.CanonWaitableJoin{}
+
+	case 0x24:
+		def = &ast.CanonBackpressureInc{}
+
+	case 0x25:
+		def = &ast.CanonBackpressureDec{}
+
 	default:
 		return nil, fmt.Errorf("invalid canon discriminator: 0x%02x", discriminator)
 	}
@@ -1970,6 +2157,39 @@ func (p *Parser) parseCanon() (*ast.Canon, error) {
 	}, nil
 }
 
+// parseOptionalValType reads a t?:<valtype>? immediate, returning nil when
+// the type is absent.
+func (p *Parser) parseOptionalValType() (ast.DefValType, error) {
+	b, err := p.readByte()
+	if err != nil {
+		return nil, err
+	}
+	switch b {
+	case 0x00:
+		return nil, nil
+	case 0x01:
+		return p.parseValType()
+	default:
+		return nil, fmt.Errorf("invalid optional value type flag: 0x%02x", b)
+	}
+}
+
+// parseCanonFlag reads the async? or cancel? immediate of a canon built-in.
+func (p *Parser) parseCanonFlag(name string) (bool, error) {
+	b, err := p.readByte()
+	if err != nil {
+		return false, err
+	}
+	switch b {
+	case 0x00:
+		return false, nil
+	case 0x01:
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid %s flag: 0x%02x", name, b)
+	}
+}
+
 func (p *Parser) parseCanonOpts() ([]ast.CanonOpt, error) {
 	var opts []ast.CanonOpt
 
@@ -2019,13 +2239,48 @@ func (p *Parser) parseCanonOpt() (ast.CanonOpt, error) {
 			return nil, err
 		}
 		return &ast.PostReturnOpt{FuncIdx: funcIdx}, nil
+	case 0x06:
+		return &ast.AsyncOpt{}, nil
+	case 0x07:
+		// callback
+		funcIdx, err := p.readU32()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.CallbackOpt{FuncIdx: funcIdx}, nil
+	case 0x08:
+		return &ast.AlwaysTaskReturnOpt{}, nil
 	default:
 		return nil, fmt.Errorf("invalid canon option discriminator: 0x%02x", discriminator)
 	}
 }
 
 func (p *Parser) parseStartSection() (ast.Definition, error) {
-	return nil, fmt.Errorf("start section not yet implemented")
+	funcIdx, err := p.readU32()
+	if err != nil {
+		return nil, err
+	}
+	var args []uint32
+	err = p.readVec(func() error {
+		valueIdx, err := p.readU32()
+		if err != nil {
+			return err
+		}
+		args = append(args, valueIdx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	results, err := p.readU32()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Start{
+		FuncIdx: funcIdx,
+		Args:    args,
+		Results: results,
+	}, nil
 }
 
 func (p *Parser) parseImportSection() ([]ast.Definition, error) {
@@ -2161,9 +2416,14 @@ func (p *Parser) parseExternDesc() (ast.ExternDesc, error) {
 		}, nil
 
 	case 0x02:
-		// value - not fully implemented yet
-		// TODO: Implement value extern desc
-		return nil, fmt.Errorf("value extern desc not yet implemented")
+		// value (valuebound)
+		valueBound, err := p.parseValueBound()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ValueExternDesc{
+			Bound: valueBound,
+		}, nil
 
 	case 0x03:
 		// type (typebound)
@@ -2202,6 +2462,32 @@ func (p *Parser) parseExternDesc() (ast.ExternDesc, error) {
 	}
 }
 
+func (p *Parser) parseValueBound() (ast.ValueBound, error) {
+	discriminator, err := p.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch discriminator {
+	case 0x00:
+		// eq i
+		valueIdx, err := p.readU32()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ValueEqBound{ValueIdx: valueIdx}, nil
+	case 0x01:
+		// t
+		valType, err := p.parseValType()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ValueTypeBound{Type: valType}, nil
+	default:
+		return nil, fmt.Errorf("invalid value bound discriminator: 0x%02x", discriminator)
+	}
+}
+
 func (p *Parser) parseTypeBound() (ast.TypeBound, error) {
 	discriminator, err := p.readByte()
 	if err != nil {
@@ -2460,6 +2746,8 @@ func (p *Parser) parseSort() (ast.Sort, error) {
 		}
 	case 0x01:
 		return ast.SortFunc, nil
+	case 0x02:
+		return ast.SortValue, nil
 	case 0x03:
 		return ast.SortType, nil
 	case 0x04:
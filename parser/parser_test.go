@@ -218,6 +218,302 @@ func TestBasicComponent(t *testing.T) {
 	RunParserTests(t, tests)
 }
 
+// TestFixedListType tests parsing of fixed-length list types
+func TestFixedListType(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name: "fixed-length list",
+			WAT: `(component
+				(type (list f32 4))
+			)`,
+			ExpectedMatcher: astmatcher.MatchComponent(
+				func(c *ast.Component) error {
+					dt := c.Definitions[0].(*ast.Type).DefType
+					lt, ok := dt.(*ast.FixedListType)
+					if !ok {
+						return fmt.Errorf("expected fixed-length list type, got %T", dt)
+					}
+					if _, ok := lt.Element.(*ast.F32Type); !ok || lt.Length != 4 {
+						return fmt.Errorf("expected list of 4 f32, got %#v", lt)
+					}
+					return nil
+				},
+			).Match,
+		},
+	}
+
+	RunParserTests(t, tests)
+
+	// wasm-tools refuses to encode an empty fixed-length list, so the binary
+	// is written by hand: a type section holding (list f32 0).
+	empty := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x0d, 0x00, 0x01, 0x00,
+		0x07, 0x04, 0x01, 0x67, 0x76, 0x00,
+	}
+	if _, err := NewParser(bytes.NewReader(empty)).ParseComponent(); err == nil || !contains(err.Error(), "at least one element") {
+		t.Errorf("parsing (list f32 0) returned %v, want an error", err)
+	}
+}
+
+// TestAsyncCanonDefs tests parsing of the async canon options and built-ins
+func TestAsyncCanonDefs(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name: "async lift with callback and task built-ins",
+			WAT: `(component
+				(core module $m
+					(memory (export "mem") 1)
+					(func (export "run") (result i32) i32.const 0)
+					(func (export "cb") (param i32 i32 i32) (result i32) i32.const 0)
+				)
+				(core instance $i (instantiate $m))
+				(alias core export $i "mem" (core memory $mem))
+				(alias core export $i "run" (core func $run))
+				(alias core export $i "cb" (core func $cb))
+				(core func (canon task.return (result u32) (memory $mem)))
+				(core func (canon context.get i32 1))
+				(core func (canon thread.yield cancellable))
+				(core func (canon waitable-set.new))
+				(core func (canon waitable-set.wait (memory $mem)))
+				(core func (canon subtask.drop))
+				(core func (canon backpressure.set))
+				(type $ft (func (result u32)))
+				(func (type $ft) (canon lift (core func $run) async (callback $cb)))
+			)`,
+			ExpectedMatcher: astmatcher.MatchComponent(
+				func(c *ast.Component) error {
+					var defs []ast.CanonDef
+					for _, def := range c.Definitions {
+						if canon, ok := def.(*ast.Canon); ok {
+							defs = append(defs, canon.Def)
+						}
+					}
+					if len(defs) != 8 {
+						return fmt.Errorf("expected 8 canon definitions, got %d", len(defs))
+					}
+					if tr, ok := defs[0].(*ast.CanonTaskReturn); !ok || len(tr.Options) != 1 {
+						return fmt.Errorf("expected task.return with a memory option, got %#v", defs[0])
+					}
+					if cg, ok := defs[1].(*ast.CanonContextGet); !ok || cg.Slot != 1 {
+						return fmt.Errorf("expected context.get of slot 1, got %#v", defs[1])
+					}
+					if y, ok := defs[2].(*ast.CanonYield); !ok || !y.Cancellable {
+						return fmt.Errorf("expected cancellable yield, got %#v", defs[2])
+					}
+					if _, ok := defs[4].(*ast.CanonWaitableSetWait); !ok {
+						return fmt.Errorf("expected waitable-set.wait, got %#v", defs[4])
+					}
+					lift, ok := defs[7].(*ast.CanonLift)
+					if !ok || len(lift.Options) != 2 {
+						return fmt.Errorf("expected lift with two options, got %#v", defs[7])
+					}
+					if _, ok := lift.Options[0].(*ast.AsyncOpt); !ok {
+						return fmt.Errorf("expected async option, got %#v", lift.Options[0])
+					}
+					if _, ok := lift.Options[1].(*ast.CallbackOpt); !ok {
+						return fmt.Errorf("expected callback option, got %#v", lift.Options[1])
+					}
+					return nil
+				},
+			).Match,
+		},
+	}
+
+	RunParserTests(t, tests)
+}
+
+// TestStreamAndFutureCanonDefs tests parsing of stream and future types and
+// their canon built-ins
+func TestStreamAndFutureCanonDefs(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name: "stream and future built-ins",
+			WAT: `(component
+				(core module $m
+					(memory (export "mem") 1)
+				)
+				(core instance $i (instantiate $m))
+				(alias core export $i "mem" (core memory $mem))
+				(type $s (stream u8))
+				(type $f (future))
+				(core func (canon stream.new $s))
+				(core func (canon stream.read $s (memory $mem) async))
+				(core func (canon stream.cancel-write $s async))
+				(core func (canon stream.drop-readable $s))
+				(core func (canon future.new $f))
+				(core func (canon future.write $f (memory $mem)))
+				(core func (canon future.drop-writable $f))
+			)`,
+			ExpectedMatcher: astmatcher.MatchComponent(
+				func(c *ast.Component) error {
+					var types []ast.DefType
+					var defs []ast.CanonDef
+					for _, def := range c.Definitions {
+						switch d := def.(type) {
+						case *ast.Type:
+							types = append(types, d.DefType)
+						case *ast.Canon:
+							defs = append(defs, d.Def)
+						}
+					}
+					if len(types) != 2 {
+						return fmt.Errorf("expected 2 type definitions, got %d", len(types))
+					}
+					if st, ok := types[0].(*ast.StreamType); !ok {
+						return fmt.Errorf("expected stream type, got %#v", types[0])
+					} else if _, ok := st.Element.(*ast.U8Type); !ok {
+						return fmt.Errorf("expected stream of u8, got %#v", st.Element)
+					}
+					if ft, ok := types[1].(*ast.FutureType); !ok || ft.Value != nil {
+						return fmt.Errorf("expected future without a value, got %#v", types[1])
+					}
+					if len(defs) != 7 {
+						return fmt.Errorf("expected 7 canon definitions, got %d", len(defs))
+					}
+					if _, ok := defs[0].(*ast.CanonStreamNew); !ok {
+						return fmt.Errorf("expected stream.new, got %#v", defs[0])
+					}
+					if sr, ok := defs[1].(*ast.CanonStreamRead); !ok || len(sr.Options) != 2 {
+						return fmt.Errorf("expected stream.read with two options, got %#v", defs[1])
+					}
+					if cw, ok := defs[2].(*ast.CanonStreamCancelWrite); !ok || !cw.Async {
+						return fmt.Errorf("expected async stream.cancel-write, got %#v", defs[2])
+					}
+					if _, ok := defs[3].(*ast.CanonStreamDropReadable); !ok {
+						return fmt.Errorf("expected stream.drop-readable, got %#v", defs[3])
+					}
+					if _, ok := defs[5].(*ast.CanonFutureWrite); !ok {
+						return fmt.Errorf("expected future.write, got %#v", defs[5])
+					}
+					if _, ok := defs[6].(*ast.CanonFutureDropWritable); !ok {
+						return fmt.Errorf("expected future.drop-writable, got %#v", defs[6])
+					}
+					return nil
+				},
+			).Match,
+		},
+	}
+
+	RunParserTests(t, tests)
+}
+
+// TestErrorContextCanonDefs tests parsing of the error-context type and its
+// canon built-ins
+func TestErrorContextCanonDefs(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name: "error-context built-ins",
+			WAT: `(component
+				(core module $m
+					(memory (export "mem") 1)
+					(func (export "realloc") (param i32 i32 i32 i32) (result i32) i32.const 0)
+				)
+				(core instance $i (instantiate $m))
+				(alias core export $i "mem" (core memory $mem))
+				(alias core export $i "realloc" (core func $realloc))
+				(type $f (future error-context))
+				(core func (canon error-context.new (memory $mem) string-encoding=utf16))
+				(core func (canon error-context.debug-message (memory $mem) (realloc $realloc)))
+				(core func (canon error-context.drop))
+			)`,
+			ExpectedMatcher: astmatcher.MatchComponent(
+				func(c *ast.Component) error {
+					var types []ast.DefType
+					var defs []ast.CanonDef
+					for _, def := range c.Definitions {
+						switch d := def.(type) {
+						case *ast.Type:
+							types = append(types, d.DefType)
+						case *ast.Canon:
+							defs = append(defs, d.Def)
+						}
+					}
+					if len(types) != 1 {
+						return fmt.Errorf("expected 1 type definition, got %d", len(types))
+					}
+					if ft, ok := types[0].(*ast.FutureType); !ok {
+						return fmt.Errorf("expected future type, got %#v", types[0])
+					} else if _, ok := ft.Value.(*ast.ErrorContextType); !ok {
+						return fmt.Errorf("expected future of error-context, got %#v", ft.Value)
+					}
+					if len(defs) != 3 {
+						return fmt.Errorf("expected 3 canon definitions, got %d", len(defs))
+					}
+					if n, ok := defs[0].(*ast.CanonErrorContextNew); !ok || len(n.Options) != 2 {
+						return fmt.Errorf("expected error-context.new with two options, got %#v", defs[0])
+					}
+					if dm, ok := defs[1].(*ast.CanonErrorContextDebugMessage); !ok || len(dm.Options) != 2 {
+						return fmt.Errorf("expected error-context.debug-message with two options, got %#v", defs[1])
+					}
+					if _, ok := defs[2].(*ast.CanonErrorContextDrop); !ok {
+						return fmt.Errorf("expected error-context.drop, got %#v", defs[2])
+					}
+					return nil
+				},
+			).Match,
+		},
+	}
+
+	RunParserTests(t, tests)
+}
+
+// TestStartAndValues tests parsing of value imports, value exports and the
+// start section
+func TestStartAndValues(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name: "start function with value import and export",
+			WAT: `(component
+				(import "n" (value $n u32))
+				(import "f" (func $f (param "x" u32) (result u32)))
+				(start $f (value $n) (result (value $r)))
+				(export "r" (value $r))
+			)`,
+			ExpectedMatcher: astmatcher.MatchComponent(
+				func(c *ast.Component) error {
+					var imports []*ast.Import
+					var start *ast.Start
+					var export *ast.Export
+					for _, def := range c.Definitions {
+						switch d := def.(type) {
+						case *ast.Import:
+							imports = append(imports, d)
+						case *ast.Start:
+							start = d
+						case *ast.Export:
+							export = d
+						}
+					}
+					if len(imports) != 2 {
+						return fmt.Errorf("expected 2 imports, got %d", len(imports))
+					}
+					desc, ok := imports[0].Desc.(*ast.ValueExternDesc)
+					if !ok {
+						return fmt.Errorf("expected value extern desc, got %#v", imports[0].Desc)
+					}
+					if b, ok := desc.Bound.(*ast.ValueTypeBound); !ok {
+						return fmt.Errorf("expected value type bound, got %#v", desc.Bound)
+					} else if _, ok := b.Type.(*ast.U32Type); !ok {
+						return fmt.Errorf("expected u32 value, got %#v", b.Type)
+					}
+					if start == nil {
+						return fmt.Errorf("expected start definition")
+					}
+					if start.FuncIdx != 0 || len(start.Args) != 1 || start.Args[0] != 0 || start.Results != 1 {
+						return fmt.Errorf("unexpected start definition %#v", start)
+					}
+					if export == nil || export.SortIdx.Sort != ast.SortValue || export.SortIdx.Idx != 1 {
+						return fmt.Errorf("expected export of value 1, got %#v", export)
+					}
+					return nil
+				},
+			).Match,
+		},
+	}
+
+	RunParserTests(t, tests)
+}
+
 // TestParserErrors tests error cases
 func TestParserErrors(t *testing.T) {
 	tests := []TestCase{
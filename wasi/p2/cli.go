@@ -1,6 +1,7 @@
 package p2
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/partite-ai/wacogo/componentmodel"
@@ -98,37 +99,55 @@ func CreateTerminalOutputInstance() *host.Instance {
 	return hi
 }
 
+// CreateTerminalStdinInstance creates wasi:cli/terminal-stdin, which reports
+// stdin as a terminal if isTerminal is set.
 func CreateTerminalStdinInstance(
 	terminalInputInstance *host.Instance,
+	isTerminal bool,
 ) *host.Instance {
 	hi := host.NewInstance()
 	hi.AddTypeExport("terminal-input", host.ResourceTypeFor[TerminalInput](hi, terminalInputInstance))
 
 	hi.MustAddFunction("get-terminal-stdin", func() Option[host.Own[TerminalInput]] {
+		if isTerminal {
+			return OptionSome(host.NewOwn(TerminalInput{}))
+		}
 		return OptionNone[host.Own[TerminalInput]]()
 	})
 	return hi
 }
 
+// CreateTerminalStdoutInstance creates wasi:cli/terminal-stdout, which reports
+// stdout as a terminal if isTerminal is set.
 func CreateTerminalStdoutInstance(
 	terminalOutputInstance *host.Instance,
+	isTerminal bool,
 ) *host.Instance {
 	hi := host.NewInstance()
 	hi.AddTypeExport("terminal-output", host.ResourceTypeFor[TerminalOutput](hi, terminalOutputInstance))
 
 	hi.MustAddFunction("get-terminal-stdout", func() Option[host.Own[TerminalOutput]] {
+		if isTerminal {
+			return OptionSome(host.NewOwn(TerminalOutput{}))
+		}
 		return OptionNone[host.Own[TerminalOutput]]()
 	})
 	return hi
 }
 
+// CreateTerminalStderrInstance creates wasi:cli/terminal-stderr, which reports
+// stderr as a terminal if isTerminal is set.
 func CreateTerminalStderrInstance(
 	terminalOutputInstance *host.Instance,
+	isTerminal bool,
 ) *host.Instance {
 	hi := host.NewInstance()
 	hi.AddTypeExport("terminal-output", host.ResourceTypeFor[TerminalOutput](hi, terminalOutputInstance))
 
 	hi.MustAddFunction("get-terminal-stderr", func() Option[host.Own[TerminalOutput]] {
+		if isTerminal {
+			return OptionSome(host.NewOwn(TerminalOutput{}))
+		}
 		return OptionNone[host.Own[TerminalOutput]]()
 	})
 	return hi
@@ -137,19 +156,29 @@ func CreateTerminalStderrInstance(
 func CreateExitInstance() *host.Instance {
 	hi := host.NewInstance()
 	hi.MustAddFunction("exit", func(status Result[Void, Void]) {
-		panic(&ExitError{Status: status})
+		code := 0
+		if _, ok := status.Err(); ok {
+			code = 1
+		}
+		panic(&ExitError{Status: status, Code: code})
+	})
+	hi.MustAddFunction("exit-with-code", func(statusCode uint8) {
+		status := ResultOk[Void](Void{})
+		if statusCode != 0 {
+			status = ResultErr[Void](Void{})
+		}
+		panic(&ExitError{Status: status, Code: int(statusCode)})
 	})
 	return hi
 }
 
+// ExitError is raised when the guest calls wasi:cli/exit. Code is the exit code
+// passed to exit-with-code, or 0 or 1 for a successful or failed exit.
 type ExitError struct {
 	Status Result[Void, Void]
+	Code   int
 }
 
 func (e *ExitError) Error() string {
-	if _, ok := e.Status.Ok(); ok {
-		return "process exited with success"
-	}
-
-	return "process exited with failure"
+	return fmt.Sprintf("process exited with code %d", e.Code)
 }
@@ -1,6 +1,7 @@
 package p2
 
 import (
+	"math"
 	"time"
 
 	"github.com/partite-ai/wacogo/componentmodel"
@@ -23,43 +24,75 @@ func NewDateTime(seconds uint64, nanoseconds uint32) DateTime {
 	return rec.Record()
 }
 
+// Clock is a source of time for wasi:clocks.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock reads the host's clock.
+var SystemClock Clock = systemClock{}
+
+// saturatedDuration converts nanoseconds to a time.Duration, saturating at the
+// longest duration it can hold.
+func saturatedDuration(ns uint64) time.Duration {
+	return time.Duration(min(ns, math.MaxInt64))
+}
+
+// CreateMonotonicClockInstance creates wasi:clocks/monotonic-clock backed by
+// clock, or by the system clock if clock is nil. Instants are nanoseconds since
+// the instance was created.
 func CreateMonotonicClockInstance(
 	pollInstance *host.Instance,
+	clock Clock,
 ) *host.Instance {
+	if clock == nil {
+		clock = SystemClock
+	}
+	epoch := clock.Now()
+	now := func() time.Duration {
+		return max(clock.Now().Sub(epoch), 0)
+	}
+
 	hi := host.NewInstance()
 	hi.AddTypeExport("instant", host.ValueTypeFor[Instant](hi))
 	hi.AddTypeExport("duration", host.ValueTypeFor[Duration](hi))
 	hi.AddTypeExport("pollable", host.ResourceTypeFor[Pollable](hi, pollInstance))
 
 	hi.MustAddFunction("now", func() componentmodel.U64 {
-		return componentmodel.U64(time.Now().UnixNano())
+		return componentmodel.U64(now())
 	})
 	hi.MustAddFunction("resolution", func() componentmodel.U64 {
 		return componentmodel.U64(1)
 	})
 	hi.MustAddFunction("subscribe-instant", func(d componentmodel.U64) host.Own[Pollable] {
-		target := time.Unix(0, int64(d))
-		delta := time.Until(target)
-		if delta <= 0 {
-			return host.NewOwn[Pollable](AlwaysReadyPollable{})
-		}
-
-		return host.NewOwn[Pollable](NewChanPollable(time.After(delta)))
+		return host.NewOwn[Pollable](NewClockDeadlinePollable(clock, epoch.Add(saturatedDuration(uint64(d)))))
 	})
 
 	hi.MustAddFunction("subscribe-duration", func(d componentmodel.U64) host.Own[Pollable] {
-		return host.NewOwn[Pollable](NewChanPollable(time.After(time.Duration(d))))
+		return host.NewOwn[Pollable](NewClockDeadlinePollable(clock, clock.Now().Add(saturatedDuration(uint64(d)))))
 	})
 
 	return hi
 }
 
-func CreateWallClockInstance() *host.Instance {
+// CreateWallClockInstance creates wasi:clocks/wall-clock backed by clock, or by
+// the system clock if clock is nil.
+func CreateWallClockInstance(clock Clock) *host.Instance {
+	if clock == nil {
+		clock = SystemClock
+	}
+
 	hi := host.NewInstance()
 	hi.AddTypeExport("datetime", host.ValueTypeFor[DateTime](hi))
 
 	hi.MustAddFunction("now", func() DateTime {
-		now := time.Now()
+		now := clock.Now()
 		return NewDateTime(uint64(now.Unix()), uint32(now.Nanosecond()))
 	})
 
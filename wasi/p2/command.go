@@ -0,0 +1,94 @@
+package p2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/partite-ai/wacogo/componentmodel"
+)
+
+// TrapError reports that a command stopped because of a trap, either in the
+// guest or in a host function it called, rather than by returning from run or
+// calling wasi:cli/exit.
+type TrapError struct {
+	Err error
+}
+
+func (e *TrapError) Error() string {
+	return "component trapped: " + e.Err.Error()
+}
+
+func (e *TrapError) Unwrap() error {
+	return e.Err
+}
+
+// RunCommand instantiates a wasi:cli/command component with the WASI host
+// instances described by config and calls its run export. It returns the exit
+// status of the command: 0 if run succeeded, 1 if it failed, or the code passed
+// to wasi:cli/exit. A trap is reported as a *TrapError, and failures to
+// instantiate the component as other errors.
+func RunCommand(ctx context.Context, component *componentmodel.Component, config *WASIConfig) (int, error) {
+	instances, err := config.Instances()
+	if err != nil {
+		return 0, err
+	}
+	args := make(map[string]any, len(instances))
+	for name, inst := range instances {
+		args[name] = inst
+	}
+
+	inst, err := component.Instantiate(ctx, args)
+	if err != nil {
+		return 0, fmt.Errorf("failed to instantiate command: %w", err)
+	}
+	defer inst.Close(context.WithoutCancel(ctx))
+	run, err := exportedFunction(inst, "wasi:cli/run", "run")
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := run.Invoke(ctx)
+	return commandStatus(result, err)
+}
+
+// exportedFunction finds a function of an interface the component exports,
+// preferring the highest 0.2.x version of the interface it exports.
+func exportedFunction(inst *componentmodel.Instance, iface, name string) (*componentmodel.Function, error) {
+	var names []string
+	for _, e := range inst.Exports() {
+		names = append(names, e.Name)
+	}
+	exportName, ok := componentmodel.HighestCompatibleName(names, iface+"@0.2.0")
+	if !ok {
+		return nil, fmt.Errorf("component does not export %s", iface)
+	}
+	export, _ := inst.Export(exportName)
+	ifaceInstance, ok := export.(*componentmodel.Instance)
+	if !ok {
+		return nil, fmt.Errorf("%s export is a %T, not an instance", iface, export)
+	}
+	fn, ok := ifaceInstance.Export(name)
+	if !ok {
+		return nil, fmt.Errorf("%s export has no %s function", iface, name)
+	}
+	f, ok := fn.(*componentmodel.Function)
+	if !ok {
+		return nil, fmt.Errorf("%s#%s is a %T, not a function", iface, name, fn)
+	}
+	return f, nil
+}
+
+func commandStatus(result componentmodel.Value, err error) (int, error) {
+	if err != nil {
+		var exitErr *ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.Code, nil
+		}
+		return 0, &TrapError{Err: err}
+	}
+	if v, ok := result.(*componentmodel.Variant); ok && v.CaseLabel == "ok" {
+		return 0, nil
+	}
+	return 1, nil
+}
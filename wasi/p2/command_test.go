@@ -0,0 +1,108 @@
+package p2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/partite-ai/wacogo/ast"
+	"github.com/partite-ai/wacogo/componentmodel"
+	"github.com/tetratelabs/wazero"
+)
+
+func TestCommandStatus(t *testing.T) {
+	trap := errors.New("unreachable executed")
+	tests := []struct {
+		name     string
+		result   componentmodel.Value
+		err      error
+		wantCode int
+		wantTrap bool
+	}{
+		{"ok", &componentmodel.Variant{CaseLabel: "ok"}, nil, 0, false},
+		{"error", &componentmodel.Variant{CaseLabel: "error"}, nil, 1, false},
+		{"exit", nil, fmt.Errorf("wrapped: %w", &ExitError{Code: 1}), 1, false},
+		{"exit-with-code", nil, fmt.Errorf("wrapped: %w", &ExitError{Code: 42}), 42, false},
+		{"trap", nil, fmt.Errorf("wrapped: %w", trap), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := commandStatus(tt.result, tt.err)
+			if code != tt.wantCode {
+				t.Errorf("code = %d, want %d", code, tt.wantCode)
+			}
+			var trapErr *TrapError
+			if got := errors.As(err, &trapErr); got != tt.wantTrap {
+				t.Fatalf("error = %v, want trap %v", err, tt.wantTrap)
+			}
+			if tt.wantTrap && !errors.Is(err, trap) {
+				t.Errorf("error = %v, want it to wrap %v", err, trap)
+			}
+		})
+	}
+}
+
+// statusCoreModule is
+//
+//	(module
+//	  (func (export "ok") (result i32) i32.const 0)
+//	  (func (export "err") (result i32) i32.const 1))
+var statusCoreModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	0x01, 0x05, 0x01, 0x60, 0x00, 0x01, 0x7f,
+	0x03, 0x03, 0x02, 0x00, 0x00,
+	0x07, 0x0c, 0x02, 0x02, 'o', 'k', 0x00, 0x00, 0x03, 'e', 'r', 'r', 0x00, 0x01,
+	0x0a, 0x0b, 0x02, 0x04, 0x00, 0x41, 0x00, 0x0b, 0x04, 0x00, 0x41, 0x01, 0x0b,
+}
+
+func TestExportedFunctionPrefersHighestVersion(t *testing.T) {
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, componentmodel.NewRuntimeConfig())
+	defer runtime.Close(ctx)
+
+	// wasi:cli/run@0.2.0 succeeds and wasi:cli/run@0.2.6 fails.
+	comp, err := componentmodel.NewBuilder(runtime).Build(ctx, &ast.Component{
+		Definitions: []ast.Definition{
+			&ast.CoreModule{Raw: statusCoreModule},
+			&ast.CoreInstance{Expr: &ast.CoreInstantiate{ModuleIdx: 0}},
+			&ast.Alias{Sort: ast.SortCoreFunc, Target: &ast.CoreExportAlias{InstanceIdx: 0, Name: "ok"}},
+			&ast.Alias{Sort: ast.SortCoreFunc, Target: &ast.CoreExportAlias{InstanceIdx: 0, Name: "err"}},
+			&ast.Type{DefType: &ast.FuncType{Results: &ast.ResultType{}}},
+			&ast.Canon{Def: &ast.CanonLift{CoreFuncIdx: 0, FunctionTypeIdx: 0}},
+			&ast.Canon{Def: &ast.CanonLift{CoreFuncIdx: 1, FunctionTypeIdx: 0}},
+			&ast.Instance{Expr: &ast.InlineExports{Exports: []ast.InlineExport{
+				{Name: "run", SortIdx: ast.SortIdx{Sort: ast.SortFunc, Idx: 0}},
+			}}},
+			&ast.Instance{Expr: &ast.InlineExports{Exports: []ast.InlineExport{
+				{Name: "run", SortIdx: ast.SortIdx{Sort: ast.SortFunc, Idx: 1}},
+			}}},
+			&ast.Export{ExportName: "wasi:cli/run@0.2.0", SortIdx: ast.SortIdx{Sort: ast.SortInstance, Idx: 0}},
+			&ast.Export{ExportName: "wasi:cli/run@0.2.6", SortIdx: ast.SortIdx{Sort: ast.SortInstance, Idx: 1}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	inst, err := comp.Instantiate(ctx, nil)
+	if err != nil {
+		t.Fatalf("Instantiate failed: %v", err)
+	}
+	defer inst.Close(ctx)
+
+	run, err := exportedFunction(inst, "wasi:cli/run", "run")
+	if err != nil {
+		t.Fatalf("exportedFunction failed: %v", err)
+	}
+	code, err := commandStatus(run.Invoke(ctx))
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if code != 1 {
+		t.Errorf("run exited with %d, want 1 from wasi:cli/run@0.2.6", code)
+	}
+
+	if _, err := exportedFunction(inst, "wasi:http/incoming-handler", "handle"); err == nil {
+		t.Error("exportedFunction found an interface the component does not export")
+	}
+}
@@ -0,0 +1,441 @@
+package p2
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/partite-ai/wacogo/componentmodel"
+	"github.com/partite-ai/wacogo/componentmodel/host"
+	"golang.org/x/term"
+)
+
+// WASIConfig describes the environment a component sees through WASI 0.2. The
+// zero configuration grants nothing: stdin is empty, output is discarded, there
+// are no arguments, environment variables or preopened directories, and network
+// access and outgoing HTTP requests are denied.
+//
+// Methods return the config so calls can be chained. Errors, such as a preopened
+// directory that doesn't exist, are reported by Instances. Close releases the
+// directories opened by PreopenDir once the instances are no longer used.
+type WASIConfig struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	terminalStdin  bool
+	terminalStdout bool
+	terminalStderr bool
+
+	args       []string
+	env        []*EnvVar
+	inheritEnv bool
+	envFilter  func(key string) bool
+	cwd        string
+
+	preopens []*Preopen
+	// opened holds the preopens created by PreopenDir, which Close closes.
+	opened []*Preopen
+
+	allowNetwork bool
+	resolver     Resolver
+	httpClient   *HttpClient
+
+	clock          Clock
+	random         io.Reader
+	insecureRandom io.Reader
+
+	err error
+}
+
+func NewWASIConfig() *WASIConfig {
+	return &WASIConfig{}
+}
+
+// nopCloser hides the Close method of inherited stdio, which would otherwise be
+// called when the guest drops the stream.
+type nopCloser struct {
+	io.Reader
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// InheritStdio connects the guest's stdin, stdout and stderr to the host's, and
+// reports those that are terminals as terminals to the guest.
+func (c *WASIConfig) InheritStdio() *WASIConfig {
+	c.stdin = nopCloser{os.Stdin}
+	c.stdout = nopWriteCloser{os.Stdout}
+	c.stderr = nopWriteCloser{os.Stderr}
+	return c.Terminal(isTerminal(os.Stdin), isTerminal(os.Stdout), isTerminal(os.Stderr))
+}
+
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// Terminal sets whether wasi:cli/terminal-stdin, terminal-stdout and
+// terminal-stderr report the guest's stdin, stdout and stderr as terminals.
+func (c *WASIConfig) Terminal(stdin, stdout, stderr bool) *WASIConfig {
+	c.terminalStdin = stdin
+	c.terminalStdout = stdout
+	c.terminalStderr = stderr
+	return c
+}
+
+func (c *WASIConfig) Stdin(r io.Reader) *WASIConfig {
+	c.stdin = r
+	return c
+}
+
+func (c *WASIConfig) Stdout(w io.Writer) *WASIConfig {
+	c.stdout = w
+	return c
+}
+
+func (c *WASIConfig) Stderr(w io.Writer) *WASIConfig {
+	c.stderr = w
+	return c
+}
+
+// CaptureStdout collects the guest's stdout in the returned buffer, keeping at
+// most limit bytes. Unlike the other methods it doesn't return the config.
+func (c *WASIConfig) CaptureStdout(limit int) *LimitedBuffer {
+	buf := NewLimitedBuffer(limit)
+	c.stdout = buf
+	return buf
+}
+
+// Args sets the arguments returned by wasi:cli/environment. By convention the
+// first one is the program name.
+func (c *WASIConfig) Args(args ...string) *WASIConfig {
+	c.args = args
+	return c
+}
+
+// Env adds an environment variable. Variables added with Env take precedence
+// over inherited ones with the same name.
+func (c *WASIConfig) Env(key, value string) *WASIConfig {
+	c.env = append(c.env, &EnvVar{Key: key, Value: value})
+	return c
+}
+
+// InheritEnv passes the host's environment variables through to the guest.
+func (c *WASIConfig) InheritEnv() *WASIConfig {
+	c.inheritEnv = true
+	return c
+}
+
+// FilterEnv limits the environment to the variables for which keep returns
+// true. It applies to both inherited variables and those added with Env.
+func (c *WASIConfig) FilterEnv(keep func(key string) bool) *WASIConfig {
+	c.envFilter = keep
+	return c
+}
+
+func (c *WASIConfig) Cwd(dir string) *WASIConfig {
+	c.cwd = dir
+	return c
+}
+
+// PreopenDir gives the guest access to the host directory hostPath under the
+// name guestPath. The directory stays open until Close is called.
+func (c *WASIConfig) PreopenDir(hostPath, guestPath string, readOnly bool) *WASIConfig {
+	p, err := NewPreopen(hostPath, guestPath, readOnly)
+	if err != nil {
+		if c.err == nil {
+			c.err = err
+		}
+		return c
+	}
+	c.preopens = append(c.preopens, p)
+	c.opened = append(c.opened, p)
+	return c
+}
+
+// Preopen gives the guest access to an already opened preopen, such as one backed
+// by a MemFileSystem. The caller remains responsible for closing it.
+func (c *WASIConfig) Preopen(p *Preopen) *WASIConfig {
+	c.preopens = append(c.preopens, p)
+	return c
+}
+
+// AllowNetwork lets the guest create sockets and resolve names.
+func (c *WASIConfig) AllowNetwork() *WASIConfig {
+	c.allowNetwork = true
+	return c
+}
+
+// Resolver sets the resolver used by wasi:sockets/ip-name-lookup. The default is
+// net.DefaultResolver.
+func (c *WASIConfig) Resolver(r Resolver) *WASIConfig {
+	c.resolver = r
+	return c
+}
+
+// HttpClient lets the guest make requests with wasi:http/outgoing-handler,
+// sent and limited by client. Without it every request is denied.
+func (c *WASIConfig) HttpClient(client *HttpClient) *WASIConfig {
+	c.httpClient = client
+	return c
+}
+
+// Clock sets the clock behind wasi:clocks. The default is the system clock.
+func (c *WASIConfig) Clock(clock Clock) *WASIConfig {
+	c.clock = clock
+	return c
+}
+
+// Random sets the source of wasi:random/random. The default is crypto/rand.
+func (c *WASIConfig) Random(r io.Reader) *WASIConfig {
+	c.random = r
+	return c
+}
+
+// InsecureRandom sets the source of wasi:random/insecure and
+// wasi:random/insecure-seed. The default is crypto/rand.
+func (c *WASIConfig) InsecureRandom(r io.Reader) *WASIConfig {
+	c.insecureRandom = r
+	return c
+}
+
+// Close closes the directories opened by PreopenDir. Instances created from the
+// config must not be used afterwards.
+func (c *WASIConfig) Close() error {
+	var errs []error
+	for _, p := range c.opened {
+		errs = append(errs, p.Close())
+	}
+	c.opened = nil
+	return errors.Join(errs...)
+}
+
+func (c *WASIConfig) environment() []*EnvVar {
+	var vars []*EnvVar
+	seen := make(map[string]bool)
+	for _, v := range c.env {
+		seen[v.Key] = true
+	}
+	if c.inheritEnv {
+		for _, kv := range os.Environ() {
+			key, value, _ := strings.Cut(kv, "=")
+			if key == "" || seen[key] {
+				continue
+			}
+			vars = append(vars, &EnvVar{Key: key, Value: value})
+		}
+	}
+	vars = append(vars, c.env...)
+
+	if c.envFilter == nil {
+		return vars
+	}
+	filtered := vars[:0]
+	for _, v := range vars {
+		if c.envFilter(v.Key) {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered
+}
+
+// Instances creates the WASI 0.2 host instances described by the config, keyed
+// by the name components import them under. Each instance is registered under
+// its 0.2.0 name, which components importing any later 0.2.x version resolve to
+// by semver compatibility.
+func (c *WASIConfig) Instances() (map[string]*componentmodel.Instance, error) {
+	instances, _, err := c.instances()
+	return instances, err
+}
+
+// instances is Instances, also returning the wasi:http/types host instance,
+// whose resource types are needed to pass requests to an incoming-handler.
+func (c *WASIConfig) instances() (map[string]*componentmodel.Instance, *host.Instance, error) {
+	if c.err != nil {
+		return nil, nil, c.err
+	}
+
+	stdin := c.stdin
+	if stdin == nil {
+		stdin = bytes.NewReader(nil)
+	}
+	stdout := c.stdout
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	stderr := c.stderr
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	instances := make(map[string]*componentmodel.Instance)
+
+	cliInstance := CreateEnvironmentInstance(c.environment(), c.args, c.cwd)
+	instances["wasi:cli/environment@0.2.0"] = cliInstance.Instance()
+
+	exitInstance := CreateExitInstance()
+	instances["wasi:cli/exit@0.2.0"] = exitInstance.Instance()
+
+	errorInstance := CreateErrorInstance()
+	instances["wasi:io/error@0.2.0"] = errorInstance.Instance()
+
+	pollInstance := CreatePollInstance()
+	instances["wasi:io/poll@0.2.0"] = pollInstance.Instance()
+
+	streamsInstance := CreateStreamsInstance(
+		errorInstance,
+		pollInstance,
+	)
+	instances["wasi:io/streams@0.2.0"] = streamsInstance.Instance()
+
+	stdinInstance := CreateStdinInstance(stdin, streamsInstance)
+	instances["wasi:cli/stdin@0.2.0"] = stdinInstance.Instance()
+
+	stdoutInstance := CreateStdoutInstance(stdout, streamsInstance)
+	instances["wasi:cli/stdout@0.2.0"] = stdoutInstance.Instance()
+
+	stderrInstance := CreateStderrInstance(stderr, streamsInstance)
+	instances["wasi:cli/stderr@0.2.0"] = stderrInstance.Instance()
+
+	randomInstance := CreateRandomInstance(c.random)
+	instances["wasi:random/random@0.2.0"] = randomInstance.Instance()
+
+	insecureRandomInstance := CreateInsecureRandomInstance(c.insecureRandom)
+	instances["wasi:random/insecure@0.2.0"] = insecureRandomInstance.Instance()
+
+	insecureSeedInstance := CreateInsecureSeedInstance(c.insecureRandom)
+	instances["wasi:random/insecure-seed@0.2.0"] = insecureSeedInstance.Instance()
+
+	monotonicClockInstance := CreateMonotonicClockInstance(pollInstance, c.clock)
+	instances["wasi:clocks/monotonic-clock@0.2.0"] = monotonicClockInstance.Instance()
+
+	wallClockInstance := CreateWallClockInstance(c.clock)
+	instances["wasi:clocks/wall-clock@0.2.0"] = wallClockInstance.Instance()
+
+	fsTypes := CreateFilesystemTypesInstance(
+		streamsInstance,
+		errorInstance,
+	)
+	instances["wasi:filesystem/types@0.2.0"] = fsTypes.Instance()
+
+	preopensInstance := CreateFilesystemPreopensInstance(fsTypes, c.preopens)
+	instances["wasi:filesystem/preopens@0.2.0"] = preopensInstance.Instance()
+
+	terminalInput := CreateTerminalInputInstance()
+	instances["wasi:cli/terminal-input@0.2.0"] = terminalInput.Instance()
+
+	terminalOutput := CreateTerminalOutputInstance()
+	instances["wasi:cli/terminal-output@0.2.0"] = terminalOutput.Instance()
+
+	terminalStdin := CreateTerminalStdinInstance(terminalInput, c.terminalStdin)
+	instances["wasi:cli/terminal-stdin@0.2.0"] = terminalStdin.Instance()
+
+	terminalStdout := CreateTerminalStdoutInstance(terminalOutput, c.terminalStdout)
+	instances["wasi:cli/terminal-stdout@0.2.0"] = terminalStdout.Instance()
+
+	terminalStderr := CreateTerminalStderrInstance(terminalOutput, c.terminalStderr)
+	instances["wasi:cli/terminal-stderr@0.2.0"] = terminalStderr.Instance()
+
+	networkInstance := CreateNetworkInstance()
+	instances["wasi:sockets/network@0.2.0"] = networkInstance.Instance()
+
+	ipNameLookupInstance := CreateIpNameLookupInstance(
+		networkInstance,
+		pollInstance,
+		c.resolver,
+	)
+	instances["wasi:sockets/ip-name-lookup@0.2.0"] = ipNameLookupInstance.Instance()
+
+	tcpInstance := CreateTcpInstance(
+		streamsInstance,
+		pollInstance,
+		networkInstance,
+	)
+	instances["wasi:sockets/tcp@0.2.0"] = tcpInstance.Instance()
+
+	udpInstance := CreateUdpInstance(
+		pollInstance,
+		networkInstance,
+	)
+	instances["wasi:sockets/udp@0.2.0"] = udpInstance.Instance()
+
+	tcpCreateSocketInstance := CreateTcpCreateSocketInstance(
+		networkInstance,
+		tcpInstance,
+	)
+	instances["wasi:sockets/tcp-create-socket@0.2.0"] = tcpCreateSocketInstance.Instance()
+
+	udpCreateSocketInstance := CreateUdpCreateSocketInstance(
+		networkInstance,
+		udpInstance,
+	)
+	instances["wasi:sockets/udp-create-socket@0.2.0"] = udpCreateSocketInstance.Instance()
+
+	instanceNetworkInstance := CreateInstanceNetworkInstance(
+		networkInstance,
+		c.allowNetwork,
+	)
+	instances["wasi:sockets/instance-network@0.2.0"] = instanceNetworkInstance.Instance()
+
+	httpTypesInstance := CreateHttpTypesInstance(
+		errorInstance,
+		streamsInstance,
+		pollInstance,
+	)
+	instances["wasi:http/types@0.2.0"] = httpTypesInstance.Instance()
+
+	httpOutgoingHandlerInstance := CreateHttpOutgoingHandlerInstance(
+		httpTypesInstance,
+		c.httpClient,
+	)
+	instances["wasi:http/outgoing-handler@0.2.0"] = httpOutgoingHandlerInstance.Instance()
+
+	return instances, httpTypesInstance, nil
+}
+
+// LimitedBuffer is an io.Writer that keeps the first limit bytes written to it
+// and silently discards the rest. It is safe for concurrent use.
+type LimitedBuffer struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func NewLimitedBuffer(limit int) *LimitedBuffer {
+	return &LimitedBuffer{limit: limit}
+}
+
+func (b *LimitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(p)
+	if room := b.limit - b.buf.Len(); len(p) > room {
+		p = p[:max(room, 0)]
+		b.truncated = true
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+// Bytes returns a copy of the captured output.
+func (b *LimitedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
+
+func (b *LimitedBuffer) String() string {
+	return string(b.Bytes())
+}
+
+// Truncated reports whether any output was discarded because of the limit.
+func (b *LimitedBuffer) Truncated() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.truncated
+}
@@ -0,0 +1,76 @@
+package p2
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWASIConfig_Environment(t *testing.T) {
+	t.Setenv("WACOGO_TEST_INHERITED", "host")
+	t.Setenv("WACOGO_TEST_OVERRIDDEN", "host")
+
+	config := NewWASIConfig().
+		InheritEnv().
+		Env("WACOGO_TEST_OVERRIDDEN", "guest").
+		Env("OTHER", "dropped").
+		FilterEnv(func(key string) bool { return strings.HasPrefix(key, "WACOGO_TEST_") })
+
+	got := make(map[string]string)
+	for _, v := range config.environment() {
+		if _, dup := got[v.Key]; dup {
+			t.Errorf("duplicate environment variable %s", v.Key)
+		}
+		got[v.Key] = v.Value
+	}
+	want := map[string]string{
+		"WACOGO_TEST_INHERITED":  "host",
+		"WACOGO_TEST_OVERRIDDEN": "guest",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("environment = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("environment[%s] = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestWASIConfig_PreopenDirError(t *testing.T) {
+	_, err := NewWASIConfig().PreopenDir(t.TempDir()+"/missing", "/", false).Instances()
+	if err == nil {
+		t.Error("Instances() should report a preopen of a missing directory")
+	}
+}
+
+func TestWASIConfig_Close(t *testing.T) {
+	config := NewWASIConfig().PreopenDir(t.TempDir(), "/", false)
+	if _, err := config.Instances(); err != nil {
+		t.Fatalf("Instances failed: %v", err)
+	}
+	root := config.preopens[0].descriptor()
+	if _, err := root.statAt(PathFlags{}, "."); err != nil {
+		t.Fatalf("statAt failed: %v", err)
+	}
+	if err := config.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := root.statAt(PathFlags{}, "."); err == nil {
+		t.Error("statAt succeeded after Close")
+	}
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	buf := NewLimitedBuffer(8)
+	for _, s := range []string{"hello", " world"} {
+		if n, err := buf.Write([]byte(s)); n != len(s) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v", s, n, err)
+		}
+	}
+	if got := buf.String(); got != "hello wo" {
+		t.Errorf("String() = %q, want %q", got, "hello wo")
+	}
+	if !buf.Truncated() {
+		t.Error("Truncated() = false after exceeding the limit")
+	}
+}
@@ -6,6 +6,11 @@ import (
 	"github.com/partite-ai/wacogo/componentmodel"
 )
 
+// CreateStandardWASIInstances creates the WASI 0.2 host instances with the given
+// stdio, arguments, environment and preopens. Network access is denied.
+//
+// Deprecated: Use WASIConfig, which also controls network access, clocks and
+// random sources. WASIConfig.AllowNetwork gives guests access to the network.
 func CreateStandardWASIInstances(
 	stdin io.Reader,
 	stdout io.Writer,
@@ -13,112 +18,19 @@ func CreateStandardWASIInstances(
 	args []string,
 	environment []*EnvVar,
 	initialCwd string,
+	preopens ...*Preopen,
 ) (map[string]*componentmodel.Instance, error) {
-	instances := make(map[string]*componentmodel.Instance)
-
-	cliInstance := CreateEnvironmentInstance(environment, args, initialCwd)
-	instances["wasi:cli/environment@0.2.0"] = cliInstance.Instance()
-
-	exitInstance := CreateExitInstance()
-	instances["wasi:cli/exit@0.2.0"] = exitInstance.Instance()
-
-	errorInstance := CreateErrorInstance()
-	instances["wasi:io/error@0.2.0"] = errorInstance.Instance()
-
-	pollInstance := CreatePollInstance()
-	instances["wasi:io/poll@0.2.0"] = pollInstance.Instance()
-
-	streamsInstance := CreateStreamsInstance(
-		errorInstance,
-		pollInstance,
-	)
-	instances["wasi:io/streams@0.2.0"] = streamsInstance.Instance()
-
-	stdinInstance := CreateStdinInstance(stdin, streamsInstance)
-	instances["wasi:cli/stdin@0.2.0"] = stdinInstance.Instance()
-
-	stdoutInstance := CreateStdoutInstance(stdout, streamsInstance)
-	instances["wasi:cli/stdout@0.2.0"] = stdoutInstance.Instance()
-
-	stderrInstance := CreateStderrInstance(stderr, streamsInstance)
-	instances["wasi:cli/stderr@0.2.0"] = stderrInstance.Instance()
-
-	randomInstance := CreateRandomInstance()
-	instances["wasi:random/random@0.2.0"] = randomInstance.Instance()
-
-	insecureRandomInstance := CreateInsecureRandomInstance()
-	instances["wasi:random/insecure@0.2.0"] = insecureRandomInstance.Instance()
-
-	insecureSeedInstance := CreateInsecureSeedInstance()
-	instances["wasi:random/insecure-seed@0.2.0"] = insecureSeedInstance.Instance()
-
-	monotonicClockInstance := CreateMonotonicClockInstance(pollInstance)
-	instances["wasi:clocks/monotonic-clock@0.2.0"] = monotonicClockInstance.Instance()
-
-	wallClockInstance := CreateWallClockInstance()
-	instances["wasi:clocks/wall-clock@0.2.0"] = wallClockInstance.Instance()
-
-	fsTypes := CreateFilesystemTypesInstance(
-		streamsInstance,
-		errorInstance,
-	)
-	instances["wasi:filesystem/types@0.2.0"] = fsTypes.Instance()
-
-	preopens := CreateFilesystemPreopensInstance(fsTypes)
-	instances["wasi:filesystem/preopens@0.2.0"] = preopens.Instance()
-
-	terminalInput := CreateTerminalInputInstance()
-	instances["wasi:cli/terminal-input@0.2.0"] = terminalInput.Instance()
-
-	terminalOutput := CreateTerminalOutputInstance()
-	instances["wasi:cli/terminal-output@0.2.0"] = terminalOutput.Instance()
-
-	terminalStdin := CreateTerminalStdinInstance(terminalInput)
-	instances["wasi:cli/terminal-stdin@0.2.0"] = terminalStdin.Instance()
-
-	terminalStdout := CreateTerminalStdoutInstance(terminalOutput)
-	instances["wasi:cli/terminal-stdout@0.2.0"] = terminalStdout.Instance()
-
-	terminalStderr := CreateTerminalStderrInstance(terminalOutput)
-	instances["wasi:cli/terminal-stderr@0.2.0"] = terminalStderr.Instance()
-
-	networkInstance := CreateNetworkInstance()
-	instances["wasi:sockets/network@0.2.0"] = networkInstance.Instance()
-
-	ipNameLookupInstance := CreateIpNameLookupInstance(
-		networkInstance,
-		pollInstance,
-	)
-	instances["wasi:sockets/ip-name-lookup@0.2.0"] = ipNameLookupInstance.Instance()
-
-	tcpInstance := CreateTcpInstance(
-		streamsInstance,
-		pollInstance,
-		networkInstance,
-	)
-	instances["wasi:sockets/tcp@0.2.0"] = tcpInstance.Instance()
-
-	udpInstance := CreateUdpInstance(
-		pollInstance,
-		networkInstance,
-	)
-	instances["wasi:sockets/udp@0.2.0"] = udpInstance.Instance()
-
-	tcpCreateSocketInstance := CreateTcpCreateSocketInstance(
-		networkInstance,
-		tcpInstance,
-	)
-	instances["wasi:sockets/tcp-create-socket@0.2.0"] = tcpCreateSocketInstance.Instance()
-
-	udpCreateSocketInstance := CreateUdpCreateSocketInstance(
-		networkInstance,
-		udpInstance,
-	)
-	instances["wasi:sockets/udp-create-socket@0.2.0"] = udpCreateSocketInstance.Instance()
-
-	instanceNetworkInstance := CreateInstanceNetworkInstance(
-		networkInstance,
-	)
-	instances["wasi:sockets/instance-network@0.2.0"] = instanceNetworkInstance.Instance()
-	return instances, nil
+	config := NewWASIConfig().
+		Stdin(stdin).
+		Stdout(stdout).
+		Stderr(stderr).
+		Args(args...).
+		Cwd(initialCwd)
+	for _, v := range environment {
+		config.Env(v.Key, v.Value)
+	}
+	for _, p := range preopens {
+		config.Preopen(p)
+	}
+	return config.Instances()
 }
@@ -1,14 +1,635 @@
 package p2
 
 import (
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"syscall"
+	"time"
+
 	"github.com/partite-ai/wacogo/componentmodel"
 	"github.com/partite-ai/wacogo/componentmodel/host"
 )
 
+// Preopen is a FileSystem exposed to guests through wasi:filesystem/preopens
+// under a guest-visible name. All descriptors derived from a preopen are confined
+// to its file system.
+type Preopen struct {
+	name     string
+	fsys     FileSystem
+	readOnly bool
+}
+
+// NewPreopen opens the host directory hostPath and exposes it to the guest as
+// name. If readOnly is set, guests may read but not create, modify or remove
+// anything beneath it.
+func NewPreopen(hostPath string, name string, readOnly bool) (*Preopen, error) {
+	fsys, err := NewOSFileSystem(hostPath)
+	if err != nil {
+		return nil, err
+	}
+	return NewFileSystemPreopen(fsys, name, readOnly), nil
+}
+
+// NewFileSystemPreopen exposes fsys to the guest as name.
+func NewFileSystemPreopen(fsys FileSystem, name string, readOnly bool) *Preopen {
+	return &Preopen{
+		name:     name,
+		fsys:     fsys,
+		readOnly: readOnly,
+	}
+}
+
+func (p *Preopen) Name() string {
+	return p.name
+}
+
+func (p *Preopen) ReadOnly() bool {
+	return p.readOnly
+}
+
+// Close closes the underlying file system if it implements io.Closer.
+func (p *Preopen) Close() error {
+	if closer, ok := p.fsys.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (p *Preopen) descriptor() *Descriptor {
+	return &Descriptor{
+		preopen:   p,
+		path:      ".",
+		read:      true,
+		mutateDir: !p.readOnly,
+	}
+}
+
+// Descriptor is an open file or directory. Directories are addressed by their
+// slash-separated path relative to the preopen root, while files additionally
+// hold an open handle.
 type Descriptor struct {
+	preopen   *Preopen
+	path      string
+	file      File
+	read      bool
+	write     bool
+	mutateDir bool
+}
+
+func (d *Descriptor) Close() error {
+	if d.file == nil {
+		return nil
+	}
+	return d.file.Close()
+}
+
+func (d *Descriptor) isDir() bool {
+	return d.file == nil
+}
+
+func (d *Descriptor) flags() DescriptorFlags {
+	flags := DescriptorFlags{}
+	if d.read {
+		flags["read"] = true
+	}
+	if d.write {
+		flags["write"] = true
+	}
+	if d.mutateDir {
+		flags["mutate-directory"] = true
+	}
+	return flags
+}
+
+func (d *Descriptor) resolve(p string) (string, error) {
+	if p == "" {
+		return "", ErrorCode("no-entry")
+	}
+	if path.IsAbs(p) {
+		return "", ErrorCode("not-permitted")
+	}
+	if !filepath.IsLocal(filepath.FromSlash(p)) {
+		return "", ErrorCode("not-permitted")
+	}
+	return path.Join(d.path, p), nil
+}
+
+func (d *Descriptor) dirPath(p string) (string, error) {
+	if !d.isDir() {
+		return "", ErrorCode("not-directory")
+	}
+	return d.resolve(p)
+}
+
+func (d *Descriptor) mutablePath(p string) (string, error) {
+	if !d.isDir() {
+		return "", ErrorCode("not-directory")
+	}
+	if !d.mutateDir {
+		return "", ErrorCode("not-permitted")
+	}
+	return d.resolve(p)
+}
+
+func (d *Descriptor) regularFile() (File, error) {
+	if d.isDir() {
+		return nil, ErrorCode("is-directory")
+	}
+	return d.file, nil
+}
+
+func (d *Descriptor) stat() (fs.FileInfo, error) {
+	if d.file != nil {
+		return d.file.Stat()
+	}
+	return d.preopen.fsys.Stat(d.path)
+}
+
+func (d *Descriptor) statAt(pathFlags PathFlags, p string) (fs.FileInfo, error) {
+	full, err := d.dirPath(p)
+	if err != nil {
+		return nil, err
+	}
+	if pathFlags["symlink-follow"] {
+		return d.preopen.fsys.Stat(full)
+	}
+	return d.preopen.fsys.Lstat(full)
+}
+
+func (d *Descriptor) openAt(pathFlags PathFlags, p string, openFlags OpenFlags, flags DescriptorFlags) (*Descriptor, error) {
+	full, err := d.dirPath(p)
+	if err != nil {
+		return nil, err
+	}
+
+	mutating := flags["write"] || flags["mutate-directory"] || openFlags["create"] || openFlags["truncate"]
+	if mutating && !d.mutateDir {
+		return nil, ErrorCode("not-permitted")
+	}
+	if flags["read"] && !d.read {
+		return nil, ErrorCode("not-permitted")
+	}
+
+	if !pathFlags["symlink-follow"] {
+		if fi, err := d.preopen.fsys.Lstat(full); err == nil && fi.Mode()&fs.ModeSymlink != 0 {
+			return nil, ErrorCode("loop")
+		}
+	}
+
+	if openFlags["directory"] {
+		if openFlags["create"] || openFlags["truncate"] || flags["write"] {
+			return nil, ErrorCode("invalid")
+		}
+		fi, err := d.preopen.fsys.Stat(full)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			return nil, ErrorCode("not-directory")
+		}
+		return d.childDir(full, flags), nil
+	}
+
+	if !openFlags["create"] {
+		if fi, err := d.preopen.fsys.Stat(full); err == nil && fi.IsDir() {
+			if flags["write"] || openFlags["truncate"] {
+				return nil, ErrorCode("is-directory")
+			}
+			return d.childDir(full, flags), nil
+		}
+	}
+
+	var mode int
+	switch {
+	case flags["read"] && flags["write"]:
+		mode = os.O_RDWR
+	case flags["write"]:
+		mode = os.O_WRONLY
+	default:
+		mode = os.O_RDONLY
+	}
+	if openFlags["create"] {
+		mode |= os.O_CREATE
+	}
+	if openFlags["exclusive"] {
+		mode |= os.O_EXCL
+	}
+	if openFlags["truncate"] {
+		mode |= os.O_TRUNC
+	}
+	if flags["file-integrity-sync"] || flags["data-integrity-sync"] {
+		mode |= os.O_SYNC
+	}
+
+	f, err := d.preopen.fsys.OpenFile(full, mode, 0o666)
+	if err != nil {
+		return nil, err
+	}
+	return &Descriptor{
+		preopen: d.preopen,
+		path:    full,
+		file:    f,
+		read:    flags["read"],
+		write:   flags["write"],
+	}, nil
+}
+
+func (d *Descriptor) childDir(full string, flags DescriptorFlags) *Descriptor {
+	return &Descriptor{
+		preopen:   d.preopen,
+		path:      full,
+		read:      flags["read"],
+		mutateDir: flags["mutate-directory"],
+	}
+}
+
+func (d *Descriptor) readAt(length uint64, offset uint64) ([]byte, bool, error) {
+	f, err := d.regularFile()
+	if err != nil {
+		return nil, false, err
+	}
+	if !d.read {
+		return nil, false, ErrorCode("bad-descriptor")
+	}
+	if offset > math.MaxInt64 {
+		return nil, false, ErrorCode("invalid")
+	}
+	buf := make([]byte, min(length, maxFileReadSize))
+	n, err := f.ReadAt(buf, int64(offset))
+	if errors.Is(err, io.EOF) {
+		return buf[:n], true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return buf[:n], false, nil
+}
+
+func (d *Descriptor) writeAt(data []byte, offset uint64) (uint64, error) {
+	f, err := d.regularFile()
+	if err != nil {
+		return 0, err
+	}
+	if !d.write {
+		return 0, ErrorCode("bad-descriptor")
+	}
+	if offset > math.MaxInt64 {
+		return 0, ErrorCode("invalid")
+	}
+	n, err := f.WriteAt(data, int64(offset))
+	return uint64(n), err
+}
+
+func (d *Descriptor) readDirectory() (*DirectoryEntryStream, error) {
+	if !d.isDir() {
+		return nil, ErrorCode("not-directory")
+	}
+	if !d.read {
+		return nil, ErrorCode("bad-descriptor")
+	}
+	f, err := d.preopen.fsys.OpenFile(d.path, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &DirectoryEntryStream{dir: f}, nil
+}
+
+func (d *Descriptor) sync() error {
+	if f := d.file; f != nil {
+		return f.Sync()
+	}
+	f, err := d.preopen.fsys.OpenFile(d.path, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
+func (d *Descriptor) setTimes(atime NewTimestamp, mtime NewTimestamp) error {
+	if d.isDir() && !d.mutateDir {
+		return ErrorCode("not-permitted")
+	}
+	if !d.isDir() && !d.write {
+		return ErrorCode("bad-descriptor")
+	}
+	return d.preopen.fsys.Chtimes(d.path, timeForNewTimestamp(atime), timeForNewTimestamp(mtime))
+}
+
+func (d *Descriptor) setTimesAt(pathFlags PathFlags, p string, atime NewTimestamp, mtime NewTimestamp) error {
+	full, err := d.mutablePath(p)
+	if err != nil {
+		return err
+	}
+	if !pathFlags["symlink-follow"] {
+		// There is no portable way to set the times of a symlink itself.
+		if fi, err := d.preopen.fsys.Lstat(full); err == nil && fi.Mode()&fs.ModeSymlink != 0 {
+			return ErrorCode("unsupported")
+		}
+	}
+	return d.preopen.fsys.Chtimes(full, timeForNewTimestamp(atime), timeForNewTimestamp(mtime))
+}
+
+func (d *Descriptor) linkAt(oldPathFlags PathFlags, oldPath string, newDescriptor *Descriptor, newPath string) error {
+	oldFull, err := d.mutablePath(oldPath)
+	if err != nil {
+		return err
+	}
+	newFull, err := newDescriptor.mutablePath(newPath)
+	if err != nil {
+		return err
+	}
+	if d.preopen != newDescriptor.preopen {
+		return ErrorCode("cross-device")
+	}
+	if oldPathFlags["symlink-follow"] {
+		return ErrorCode("invalid")
+	}
+	return d.preopen.fsys.Link(oldFull, newFull)
+}
+
+func (d *Descriptor) renameAt(oldPath string, newDescriptor *Descriptor, newPath string) error {
+	oldFull, err := d.mutablePath(oldPath)
+	if err != nil {
+		return err
+	}
+	newFull, err := newDescriptor.mutablePath(newPath)
+	if err != nil {
+		return err
+	}
+	if d.preopen != newDescriptor.preopen {
+		return ErrorCode("cross-device")
+	}
+	return d.preopen.fsys.Rename(oldFull, newFull)
+}
+
+func (d *Descriptor) symlinkAt(oldPath string, newPath string) error {
+	full, err := d.mutablePath(newPath)
+	if err != nil {
+		return err
+	}
+	if path.IsAbs(oldPath) {
+		return ErrorCode("not-permitted")
+	}
+	return d.preopen.fsys.Symlink(oldPath, full)
 }
 
+func (d *Descriptor) readlinkAt(p string) (string, error) {
+	full, err := d.dirPath(p)
+	if err != nil {
+		return "", err
+	}
+	return d.preopen.fsys.Readlink(full)
+}
+
+func (d *Descriptor) removeAt(p string, dir bool) error {
+	full, err := d.mutablePath(p)
+	if err != nil {
+		return err
+	}
+	fi, err := d.preopen.fsys.Lstat(full)
+	if err != nil {
+		return err
+	}
+	if dir && !fi.IsDir() {
+		return ErrorCode("not-directory")
+	}
+	if !dir && fi.IsDir() {
+		return ErrorCode("is-directory")
+	}
+	return d.preopen.fsys.Remove(full)
+}
+
+func (d *Descriptor) createDirectoryAt(p string) error {
+	full, err := d.mutablePath(p)
+	if err != nil {
+		return err
+	}
+	return d.preopen.fsys.Mkdir(full, 0o777)
+}
+
+// DirectoryEntryStream iterates the entries of a directory, excluding "." and "..".
 type DirectoryEntryStream struct {
+	dir     File
+	pending []fs.DirEntry
+	done    bool
+}
+
+func (s *DirectoryEntryStream) next() (fs.DirEntry, error) {
+	if len(s.pending) == 0 && !s.done {
+		entries, err := s.dir.ReadDir(64)
+		if errors.Is(err, io.EOF) {
+			s.done = true
+		} else if err != nil {
+			return nil, err
+		}
+		s.pending = entries
+	}
+	if len(s.pending) == 0 {
+		return nil, nil
+	}
+	entry := s.pending[0]
+	s.pending = s.pending[1:]
+	return entry, nil
+}
+
+func (s *DirectoryEntryStream) Close() error {
+	return s.dir.Close()
+}
+
+// appendWriter writes every buffer at the current end of the file.
+type appendWriter struct {
+	f File
+}
+
+func (w *appendWriter) Write(p []byte) (int, error) {
+	fi, err := w.f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return w.f.WriteAt(p, fi.Size())
+}
+
+const maxFileReadSize = 1 << 20
+
+func (e ErrorCode) Error() string {
+	return string(e)
+}
+
+func errorCodeFor(err error) ErrorCode {
+	var code ErrorCode
+	if errors.As(err, &code) {
+		return code
+	}
+
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		if code, ok := errnoErrorCodes[errno]; ok {
+			return code
+		}
+	}
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return ErrorCode("no-entry")
+	case errors.Is(err, fs.ErrExist):
+		return ErrorCode("exist")
+	case errors.Is(err, fs.ErrPermission):
+		return ErrorCode("access")
+	case errors.Is(err, fs.ErrInvalid):
+		return ErrorCode("invalid")
+	case errors.Is(err, os.ErrClosed):
+		return ErrorCode("bad-descriptor")
+	}
+
+	if errors.Is(err, errPathEscapes) {
+		return ErrorCode("not-permitted")
+	}
+
+	return ErrorCode("io")
+}
+
+var errnoErrorCodes = map[syscall.Errno]ErrorCode{
+	syscall.EACCES:       "access",
+	syscall.EAGAIN:       "would-block",
+	syscall.EALREADY:     "already",
+	syscall.EBADF:        "bad-descriptor",
+	syscall.EBUSY:        "busy",
+	syscall.EDEADLK:      "deadlock",
+	syscall.EDQUOT:       "quota",
+	syscall.EEXIST:       "exist",
+	syscall.EFBIG:        "file-too-large",
+	syscall.EILSEQ:       "illegal-byte-sequence",
+	syscall.EINPROGRESS:  "in-progress",
+	syscall.EINTR:        "interrupted",
+	syscall.EINVAL:       "invalid",
+	syscall.EIO:          "io",
+	syscall.EISDIR:       "is-directory",
+	syscall.ELOOP:        "loop",
+	syscall.EMLINK:       "too-many-links",
+	syscall.EMSGSIZE:     "message-size",
+	syscall.ENAMETOOLONG: "name-too-long",
+	syscall.ENODEV:       "no-device",
+	syscall.ENOENT:       "no-entry",
+	syscall.ENOLCK:       "no-lock",
+	syscall.ENOMEM:       "insufficient-memory",
+	syscall.ENOSPC:       "insufficient-space",
+	syscall.ENOTDIR:      "not-directory",
+	syscall.ENOTEMPTY:    "not-empty",
+	syscall.ENOTSUP:      "unsupported",
+	syscall.ENOTTY:       "no-tty",
+	syscall.ENXIO:        "no-such-device",
+	syscall.EOVERFLOW:    "overflow",
+	syscall.EPERM:        "not-permitted",
+	syscall.EPIPE:        "pipe",
+	syscall.EROFS:        "read-only",
+	syscall.ESPIPE:       "invalid-seek",
+	syscall.ETXTBSY:      "text-file-busy",
+	syscall.EXDEV:        "cross-device",
+}
+
+func fsResult[T any](v T, err error) Result[T, ErrorCode] {
+	if err != nil {
+		return ResultErr[T](errorCodeFor(err))
+	}
+	return ResultOk[ErrorCode](v)
+}
+
+func fsVoidResult(err error) Result[Void, ErrorCode] {
+	return fsResult(Void{}, err)
+}
+
+func descriptorTypeFor(mode fs.FileMode) DescriptorType {
+	switch {
+	case mode.IsRegular():
+		return DescriptorType("regular-file")
+	case mode.IsDir():
+		return DescriptorType("directory")
+	case mode&fs.ModeSymlink != 0:
+		return DescriptorType("symbolic-link")
+	case mode&fs.ModeNamedPipe != 0:
+		return DescriptorType("fifo")
+	case mode&fs.ModeSocket != 0:
+		return DescriptorType("socket")
+	case mode&fs.ModeCharDevice != 0:
+		return DescriptorType("character-device")
+	case mode&fs.ModeDevice != 0:
+		return DescriptorType("block-device")
+	default:
+		return DescriptorType("unknown")
+	}
+}
+
+func dateTimeFor(t time.Time) Option[DateTime] {
+	if t.IsZero() {
+		return OptionNone[DateTime]()
+	}
+	return OptionSome(NewDateTime(uint64(t.Unix()), uint32(t.Nanosecond())))
+}
+
+func timeForNewTimestamp(ts NewTimestamp) time.Time {
+	if ts.Now() {
+		return time.Now()
+	}
+	if dt, ok := ts.Timestamp(); ok {
+		return time.Unix(int64(dt.Fields.Seconds.Get(dt)), int64(dt.Fields.Nanoseconds.Get(dt)))
+	}
+	// The zero time leaves the timestamp unchanged.
+	return time.Time{}
+}
+
+func descriptorStatFor(fi fs.FileInfo) DescriptorStat {
+	sys := sysStatFor(fi)
+	return NewDescriptorStat(
+		descriptorTypeFor(fi.Mode()),
+		LinkCount(sys.linkCount),
+		Filesize(fi.Size()),
+		dateTimeFor(sys.accessTime),
+		dateTimeFor(fi.ModTime()),
+		dateTimeFor(sys.changeTime),
+	)
+}
+
+func metadataHashFor(fi fs.FileInfo) MetadataHashValue {
+	sys := sysStatFor(fi)
+	return NewMetadataHashValue(componentmodel.U64(sys.inode), componentmodel.U64(sys.device))
+}
+
+// fileSysStat holds the parts of a file's metadata that fs.FileInfo does not
+// expose portably. In-memory file systems return it from FileInfo.Sys.
+type fileSysStat struct {
+	linkCount  uint64
+	accessTime time.Time
+	changeTime time.Time
+	device     uint64
+	inode      uint64
+}
+
+func sysStatFor(fi fs.FileInfo) fileSysStat {
+	if st, ok := fi.Sys().(*fileSysStat); ok {
+		return *st
+	}
+	return fileSysStatFor(fi)
+}
+
+func sameFile(a, b fs.FileInfo) bool {
+	if os.SameFile(a, b) {
+		return true
+	}
+	sa, ok := a.Sys().(*fileSysStat)
+	if !ok {
+		return false
+	}
+	sb, ok := b.Sys().(*fileSysStat)
+	if !ok {
+		return false
+	}
+	return sa.device == sb.device && sa.inode == sb.inode
 }
 
 type Filesize uint64
@@ -235,126 +856,216 @@ func CreateFilesystemTypesInstance(
 	hi.AddTypeExport("descriptor", host.ResourceTypeFor[*Descriptor](hi, hi))
 	hi.AddTypeExport("directory-entry-stream", host.ResourceTypeFor[*DirectoryEntryStream](hi, hi))
 
-	/*
-		hc.RegisterFunction("[method]descriptor.", func(self *Descriptor){
-
-		})
-	*/
-
 	hi.AddFunction("[method]descriptor.read-via-stream", func(self host.Borrow[*Descriptor], offset Filesize) Result[host.Own[InputStream], ErrorCode] {
-		return ResultErr[host.Own[InputStream]](ErrorCode("unsupported"))
+		d := self.Resource()
+		f, err := d.regularFile()
+		if err != nil {
+			return ResultErr[host.Own[InputStream]](errorCodeFor(err))
+		}
+		if !d.read {
+			return ResultErr[host.Own[InputStream]](ErrorCode("bad-descriptor"))
+		}
+		if offset > math.MaxInt64 {
+			return ResultErr[host.Own[InputStream]](ErrorCode("invalid"))
+		}
+		r := io.NewSectionReader(f, int64(offset), math.MaxInt64-int64(offset))
+		return ResultOk[ErrorCode](host.NewOwn[InputStream](NewReaderInputStream(r, 32768, 4096, 8)))
 	})
 
 	hi.AddFunction("[method]descriptor.write-via-stream", func(self host.Borrow[*Descriptor], offset Filesize) Result[host.Own[OutputStream], ErrorCode] {
-		return ResultErr[host.Own[OutputStream]](ErrorCode("unsupported"))
+		d := self.Resource()
+		f, err := d.regularFile()
+		if err != nil {
+			return ResultErr[host.Own[OutputStream]](errorCodeFor(err))
+		}
+		if !d.write {
+			return ResultErr[host.Own[OutputStream]](ErrorCode("bad-descriptor"))
+		}
+		if offset > math.MaxInt64 {
+			return ResultErr[host.Own[OutputStream]](ErrorCode("invalid"))
+		}
+		w := io.NewOffsetWriter(f, int64(offset))
+		return ResultOk[ErrorCode](host.NewOwn[OutputStream](NewWriterOutputStream(w)))
 	})
 
 	hi.AddFunction("[method]descriptor.append-via-stream", func(self host.Borrow[*Descriptor]) Result[host.Own[OutputStream], ErrorCode] {
-		return ResultErr[host.Own[OutputStream]](ErrorCode("unsupported"))
+		d := self.Resource()
+		f, err := d.regularFile()
+		if err != nil {
+			return ResultErr[host.Own[OutputStream]](errorCodeFor(err))
+		}
+		if !d.write {
+			return ResultErr[host.Own[OutputStream]](ErrorCode("bad-descriptor"))
+		}
+		return ResultOk[ErrorCode](host.NewOwn[OutputStream](NewWriterOutputStream(&appendWriter{f: f})))
 	})
 
 	hi.AddFunction("[method]descriptor.advise", func(self host.Borrow[*Descriptor], offset Filesize, length Filesize, advice Advice) Result[Void, ErrorCode] {
-		return ResultErr[Void](ErrorCode("unsupported"))
+		_, err := self.Resource().regularFile()
+		return fsVoidResult(err)
 	})
 
 	hi.AddFunction("[method]descriptor.sync-data", func(self host.Borrow[*Descriptor]) Result[Void, ErrorCode] {
-		return ResultErr[Void](ErrorCode("unsupported"))
+		return fsVoidResult(self.Resource().sync())
 	})
 
 	hi.AddFunction("[method]descriptor.get-flags", func(self host.Borrow[*Descriptor]) Result[DescriptorFlags, ErrorCode] {
-		return ResultErr[DescriptorFlags](ErrorCode("unsupported"))
+		return ResultOk[ErrorCode](self.Resource().flags())
 	})
 
 	hi.AddFunction("[method]descriptor.get-type", func(self host.Borrow[*Descriptor]) Result[DescriptorType, ErrorCode] {
-		return ResultErr[DescriptorType](ErrorCode("unsupported"))
+		fi, err := self.Resource().stat()
+		if err != nil {
+			return ResultErr[DescriptorType](errorCodeFor(err))
+		}
+		return ResultOk[ErrorCode](descriptorTypeFor(fi.Mode()))
 	})
 
 	hi.AddFunction("[method]descriptor.set-size", func(self host.Borrow[*Descriptor], size Filesize) Result[Void, ErrorCode] {
-		return ResultErr[Void](ErrorCode("unsupported"))
+		d := self.Resource()
+		f, err := d.regularFile()
+		if err != nil {
+			return fsVoidResult(err)
+		}
+		if !d.write {
+			return ResultErr[Void](ErrorCode("bad-descriptor"))
+		}
+		if size > math.MaxInt64 {
+			return ResultErr[Void](ErrorCode("file-too-large"))
+		}
+		return fsVoidResult(f.Truncate(int64(size)))
 	})
 
 	hi.AddFunction("[method]descriptor.set-times", func(self host.Borrow[*Descriptor], dataAccessTimestamp NewTimestamp, dataModificationTimestamp NewTimestamp) Result[Void, ErrorCode] {
-		return ResultErr[Void](ErrorCode("unsupported"))
+		return fsVoidResult(self.Resource().setTimes(dataAccessTimestamp, dataModificationTimestamp))
 	})
 
 	hi.AddFunction("[method]descriptor.read", func(self host.Borrow[*Descriptor], length componentmodel.U64, offset componentmodel.U64) Result[Tuple2[componentmodel.ByteArray, componentmodel.Bool], ErrorCode] {
-		return ResultErr[Tuple2[componentmodel.ByteArray, componentmodel.Bool]](ErrorCode("unsupported"))
+		data, eof, err := self.Resource().readAt(uint64(length), uint64(offset))
+		return fsResult(NewTuple2(componentmodel.ByteArray(data), componentmodel.Bool(eof)), err)
 	})
 
 	hi.AddFunction("[method]descriptor.write", func(self host.Borrow[*Descriptor], buffer componentmodel.ByteArray, offset componentmodel.U64) Result[componentmodel.U64, ErrorCode] {
-		return ResultErr[componentmodel.U64](ErrorCode("unsupported"))
+		return fsResult(toU64(self.Resource().writeAt(buffer, uint64(offset))))
 	})
 
 	hi.AddFunction("[method]descriptor.read-directory", func(self host.Borrow[*Descriptor]) Result[host.Own[*DirectoryEntryStream], ErrorCode] {
-		return ResultErr[host.Own[*DirectoryEntryStream]](ErrorCode("unsupported"))
+		s, err := self.Resource().readDirectory()
+		if err != nil {
+			return ResultErr[host.Own[*DirectoryEntryStream]](errorCodeFor(err))
+		}
+		return ResultOk[ErrorCode](host.NewOwn(s))
 	})
 
 	hi.AddFunction("[method]descriptor.sync", func(self host.Borrow[*Descriptor]) Result[Void, ErrorCode] {
-		return ResultErr[Void](ErrorCode("unsupported"))
+		return fsVoidResult(self.Resource().sync())
 	})
 
 	hi.AddFunction("[method]descriptor.create-directory-at", func(self host.Borrow[*Descriptor], path componentmodel.String) Result[Void, ErrorCode] {
-		return ResultErr[Void](ErrorCode("unsupported"))
+		return fsVoidResult(self.Resource().createDirectoryAt(string(path)))
 	})
 
 	hi.AddFunction("[method]descriptor.stat", func(self host.Borrow[*Descriptor]) Result[DescriptorStat, ErrorCode] {
-		return ResultErr[DescriptorStat](ErrorCode("unsupported"))
+		fi, err := self.Resource().stat()
+		if err != nil {
+			return ResultErr[DescriptorStat](errorCodeFor(err))
+		}
+		return ResultOk[ErrorCode](descriptorStatFor(fi))
 	})
 
 	hi.AddFunction("[method]descriptor.stat-at", func(self host.Borrow[*Descriptor], pathFlags PathFlags, path componentmodel.String) Result[DescriptorStat, ErrorCode] {
-		return ResultErr[DescriptorStat](ErrorCode("unsupported"))
+		fi, err := self.Resource().statAt(pathFlags, string(path))
+		if err != nil {
+			return ResultErr[DescriptorStat](errorCodeFor(err))
+		}
+		return ResultOk[ErrorCode](descriptorStatFor(fi))
 	})
 
 	hi.AddFunction("[method]descriptor.set-times-at", func(self host.Borrow[*Descriptor], pathFlags PathFlags, path componentmodel.String, dataAccessTimestamp NewTimestamp, dataModificationTimestamp NewTimestamp) Result[Void, ErrorCode] {
-		return ResultErr[Void](ErrorCode("unsupported"))
+		return fsVoidResult(self.Resource().setTimesAt(pathFlags, string(path), dataAccessTimestamp, dataModificationTimestamp))
 	})
 
 	hi.AddFunction("[method]descriptor.link-at", func(self host.Borrow[*Descriptor], oldPathFlags PathFlags, oldPath componentmodel.String, newDescriptor host.Borrow[*Descriptor], newPath componentmodel.String) Result[Void, ErrorCode] {
-		return ResultErr[Void](ErrorCode("unsupported"))
+		return fsVoidResult(self.Resource().linkAt(oldPathFlags, string(oldPath), newDescriptor.Resource(), string(newPath)))
 	})
 
 	hi.AddFunction("[method]descriptor.open-at", func(self host.Borrow[*Descriptor], pathFlags PathFlags, path componentmodel.String, openFlags OpenFlags, flags DescriptorFlags) Result[host.Own[*Descriptor], ErrorCode] {
-		return ResultErr[host.Own[*Descriptor]](ErrorCode("unsupported"))
+		d, err := self.Resource().openAt(pathFlags, string(path), openFlags, flags)
+		if err != nil {
+			return ResultErr[host.Own[*Descriptor]](errorCodeFor(err))
+		}
+		return ResultOk[ErrorCode](host.NewOwn(d))
 	})
 
 	hi.AddFunction("[method]descriptor.readlink-at", func(self host.Borrow[*Descriptor], path componentmodel.String) Result[componentmodel.String, ErrorCode] {
-		return ResultErr[componentmodel.String](ErrorCode("unsupported"))
+		target, err := self.Resource().readlinkAt(string(path))
+		return fsResult(componentmodel.String(target), err)
 	})
 
 	hi.AddFunction("[method]descriptor.remove-directory-at", func(self host.Borrow[*Descriptor], path componentmodel.String) Result[Void, ErrorCode] {
-		return ResultErr[Void](ErrorCode("unsupported"))
+		return fsVoidResult(self.Resource().removeAt(string(path), true))
 	})
 
 	hi.AddFunction("[method]descriptor.rename-at", func(self host.Borrow[*Descriptor], oldPath string, newDescriptor host.Borrow[*Descriptor], newPath string) Result[Void, ErrorCode] {
-		return ResultErr[Void](ErrorCode("unsupported"))
+		return fsVoidResult(self.Resource().renameAt(oldPath, newDescriptor.Resource(), newPath))
 	})
 
 	hi.AddFunction("[method]descriptor.symlink-at", func(self host.Borrow[*Descriptor], oldPath string, newPath string) Result[Void, ErrorCode] {
-		return ResultErr[Void](ErrorCode("unsupported"))
+		return fsVoidResult(self.Resource().symlinkAt(oldPath, newPath))
 	})
 
 	hi.AddFunction("[method]descriptor.unlink-file-at", func(self host.Borrow[*Descriptor], path string) Result[Void, ErrorCode] {
-		return ResultErr[Void](ErrorCode("unsupported"))
+		return fsVoidResult(self.Resource().removeAt(path, false))
 	})
 
 	hi.AddFunction("[method]descriptor.is-same-object", func(self host.Borrow[*Descriptor], other host.Borrow[*Descriptor]) componentmodel.Bool {
-		return self.Resource() == other.Resource()
+		if self.Resource() == other.Resource() {
+			return true
+		}
+		fi, err := self.Resource().stat()
+		if err != nil {
+			return false
+		}
+		otherFi, err := other.Resource().stat()
+		if err != nil {
+			return false
+		}
+		return componentmodel.Bool(sameFile(fi, otherFi))
 	})
 
 	hi.AddFunction("[method]descriptor.metadata-hash", func(self host.Borrow[*Descriptor]) Result[MetadataHashValue, ErrorCode] {
-		return ResultErr[MetadataHashValue](ErrorCode("unsupported"))
+		fi, err := self.Resource().stat()
+		if err != nil {
+			return ResultErr[MetadataHashValue](errorCodeFor(err))
+		}
+		return ResultOk[ErrorCode](metadataHashFor(fi))
 	})
 
 	hi.AddFunction("[method]descriptor.metadata-hash-at", func(self host.Borrow[*Descriptor], pathFlags PathFlags, path componentmodel.String) Result[MetadataHashValue, ErrorCode] {
-		return ResultErr[MetadataHashValue](ErrorCode("unsupported"))
+		fi, err := self.Resource().statAt(pathFlags, string(path))
+		if err != nil {
+			return ResultErr[MetadataHashValue](errorCodeFor(err))
+		}
+		return ResultOk[ErrorCode](metadataHashFor(fi))
 	})
 
 	hi.AddFunction("[method]directory-entry-stream.read-directory-entry", func(self host.Borrow[*DirectoryEntryStream]) Result[Option[DirectoryEntry], ErrorCode] {
-		return ResultErr[Option[DirectoryEntry]](ErrorCode("unsupported"))
+		entry, err := self.Resource().next()
+		if err != nil {
+			return ResultErr[Option[DirectoryEntry]](errorCodeFor(err))
+		}
+		if entry == nil {
+			return ResultOk[ErrorCode](OptionNone[DirectoryEntry]())
+		}
+		return ResultOk[ErrorCode](OptionSome(NewDirectoryEntry(descriptorTypeFor(entry.Type()), entry.Name())))
 	})
 
 	hi.AddFunction("filesystem-error-code", func(err host.Borrow[IOError]) Option[ErrorCode] {
-		return OptionNone[ErrorCode]()
+		cause := err.Resource().cause
+		if cause == nil || errors.Is(cause, io.EOF) {
+			return OptionNone[ErrorCode]()
+		}
+		return OptionSome(errorCodeFor(cause))
 	})
 
 	return hi
@@ -362,11 +1073,16 @@ func CreateFilesystemTypesInstance(
 
 func CreateFilesystemPreopensInstance(
 	typesInstance *host.Instance,
+	preopens []*Preopen,
 ) *host.Instance {
 	hi := host.NewInstance()
 	hi.AddTypeExport("descriptor", host.ResourceTypeFor[*Descriptor](hi, typesInstance))
 	hi.AddFunction("get-directories", func() []Tuple2[host.Own[*Descriptor], string] {
-		return nil
+		dirs := make([]Tuple2[host.Own[*Descriptor], string], len(preopens))
+		for i, p := range preopens {
+			dirs[i] = NewTuple2(host.NewOwn(p.descriptor()), p.name)
+		}
+		return dirs
 	})
 	return hi
 }
@@ -0,0 +1,21 @@
+package p2
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+func fileSysStatFor(fi fs.FileInfo) fileSysStat {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileSysStat{linkCount: 1}
+	}
+	return fileSysStat{
+		linkCount:  uint64(st.Nlink),
+		accessTime: time.Unix(int64(st.Atimespec.Sec), int64(st.Atimespec.Nsec)),
+		changeTime: time.Unix(int64(st.Ctimespec.Sec), int64(st.Ctimespec.Nsec)),
+		device:     uint64(st.Dev),
+		inode:      uint64(st.Ino),
+	}
+}
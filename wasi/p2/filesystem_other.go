@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package p2
+
+import "io/fs"
+
+func fileSysStatFor(fi fs.FileInfo) fileSysStat {
+	return fileSysStat{linkCount: 1}
+}
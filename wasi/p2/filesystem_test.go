@@ -0,0 +1,326 @@
+package p2
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestPreopen(t *testing.T, readOnly bool) (*Preopen, string) {
+	t.Helper()
+	dir := t.TempDir()
+	p, err := NewPreopen(dir, "/", readOnly)
+	if err != nil {
+		t.Fatalf("NewPreopen failed: %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p, dir
+}
+
+func expectErrorCode(t *testing.T, err error, want ErrorCode) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected error %q, got nil", want)
+	}
+	if got := errorCodeFor(err); got != want {
+		t.Fatalf("error code = %q, want %q (%v)", got, want, err)
+	}
+}
+
+func TestDescriptor_OpenReadWrite(t *testing.T) {
+	p, dir := newTestPreopen(t, false)
+	root := p.descriptor()
+
+	f, err := root.openAt(PathFlags{}, "hello.txt", OpenFlags{"create": true}, DescriptorFlags{"read": true, "write": true})
+	if err != nil {
+		t.Fatalf("openAt failed: %v", err)
+	}
+	defer f.Close()
+
+	n, err := f.writeAt([]byte("hello world"), 0)
+	if err != nil {
+		t.Fatalf("writeAt failed: %v", err)
+	}
+	if n != 11 {
+		t.Errorf("writeAt() = %d, want 11", n)
+	}
+
+	data, eof, err := f.readAt(5, 6)
+	if err != nil {
+		t.Fatalf("readAt failed: %v", err)
+	}
+	if string(data) != "world" {
+		t.Errorf("readAt() = %q, want %q", data, "world")
+	}
+	if eof {
+		t.Error("readAt() reported eof before the end of the file")
+	}
+
+	_, eof, err = f.readAt(10, 6)
+	if err != nil {
+		t.Fatalf("readAt failed: %v", err)
+	}
+	if !eof {
+		t.Error("readAt() past the end of the file should report eof")
+	}
+
+	onDisk, err := os.ReadFile(filepath.Join(dir, "hello.txt"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if string(onDisk) != "hello world" {
+		t.Errorf("file contents = %q, want %q", onDisk, "hello world")
+	}
+}
+
+func TestDescriptor_ReadOnlyPreopen(t *testing.T) {
+	p, dir := newTestPreopen(t, true)
+	root := p.descriptor()
+
+	if err := os.WriteFile(filepath.Join(dir, "existing.txt"), []byte("data"), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	_, err := root.openAt(PathFlags{}, "new.txt", OpenFlags{"create": true}, DescriptorFlags{"write": true})
+	expectErrorCode(t, err, ErrorCode("not-permitted"))
+
+	expectErrorCode(t, root.createDirectoryAt("sub"), ErrorCode("not-permitted"))
+	expectErrorCode(t, root.removeAt("existing.txt", false), ErrorCode("not-permitted"))
+	expectErrorCode(t, root.setTimes(NewTimestampNow(), NewTimestampNow()), ErrorCode("not-permitted"))
+
+	f, err := root.openAt(PathFlags{}, "existing.txt", OpenFlags{}, DescriptorFlags{"read": true})
+	if err != nil {
+		t.Fatalf("openAt failed: %v", err)
+	}
+	defer f.Close()
+
+	_, err = f.writeAt([]byte("x"), 0)
+	expectErrorCode(t, err, ErrorCode("bad-descriptor"))
+}
+
+func TestDescriptor_PathEscape(t *testing.T) {
+	p, _ := newTestPreopen(t, false)
+	root := p.descriptor()
+
+	_, err := root.openAt(PathFlags{}, "../outside", OpenFlags{}, DescriptorFlags{"read": true})
+	expectErrorCode(t, err, ErrorCode("not-permitted"))
+
+	_, err = root.statAt(PathFlags{}, "/etc")
+	expectErrorCode(t, err, ErrorCode("not-permitted"))
+
+	_, err = root.statAt(PathFlags{}, "missing")
+	expectErrorCode(t, err, ErrorCode("no-entry"))
+
+	// Symlinks leading out of the preopen are only caught by os.Root.
+	if err := root.symlinkAt("..", "up"); err != nil {
+		t.Fatalf("symlinkAt failed: %v", err)
+	}
+	if err := root.createDirectoryAt("sub"); err != nil {
+		t.Fatalf("createDirectoryAt failed: %v", err)
+	}
+	if err := root.symlinkAt("../up/x", "sub/link"); err != nil {
+		t.Fatalf("symlinkAt failed: %v", err)
+	}
+	_, err = root.statAt(PathFlags{"symlink-follow": true}, "up")
+	expectErrorCode(t, err, ErrorCode("not-permitted"))
+	_, err = root.openAt(PathFlags{"symlink-follow": true}, "sub/link", OpenFlags{}, DescriptorFlags{"read": true})
+	expectErrorCode(t, err, ErrorCode("not-permitted"))
+	_, err = root.openAt(PathFlags{}, "up/missing", OpenFlags{}, DescriptorFlags{"read": true})
+	expectErrorCode(t, err, ErrorCode("not-permitted"))
+}
+
+func TestDescriptor_DirectoryOperations(t *testing.T) {
+	p, dir := newTestPreopen(t, false)
+	root := p.descriptor()
+
+	if err := root.createDirectoryAt("sub"); err != nil {
+		t.Fatalf("createDirectoryAt failed: %v", err)
+	}
+	sub, err := root.openAt(PathFlags{}, "sub", OpenFlags{"directory": true}, DescriptorFlags{"read": true, "mutate-directory": true})
+	if err != nil {
+		t.Fatalf("openAt failed: %v", err)
+	}
+
+	f, err := sub.openAt(PathFlags{}, "a.txt", OpenFlags{"create": true}, DescriptorFlags{"write": true})
+	if err != nil {
+		t.Fatalf("openAt failed: %v", err)
+	}
+	f.Close()
+
+	if err := sub.renameAt("a.txt", root, "b.txt"); err != nil {
+		t.Fatalf("renameAt failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.txt")); err != nil {
+		t.Errorf("renamed file missing: %v", err)
+	}
+
+	if err := root.symlinkAt("b.txt", "link"); err != nil {
+		t.Fatalf("symlinkAt failed: %v", err)
+	}
+	target, err := root.readlinkAt("link")
+	if err != nil {
+		t.Fatalf("readlinkAt failed: %v", err)
+	}
+	if target != "b.txt" {
+		t.Errorf("readlinkAt() = %q, want %q", target, "b.txt")
+	}
+
+	fi, err := root.statAt(PathFlags{}, "link")
+	if err != nil {
+		t.Fatalf("statAt failed: %v", err)
+	}
+	if typ := descriptorTypeFor(fi.Mode()); typ != DescriptorType("symbolic-link") {
+		t.Errorf("statAt() type = %q, want symbolic-link", typ)
+	}
+
+	stream, err := root.readDirectory()
+	if err != nil {
+		t.Fatalf("readDirectory failed: %v", err)
+	}
+	defer stream.Close()
+
+	var names []string
+	for {
+		entry, err := stream.next()
+		if err != nil {
+			t.Fatalf("next failed: %v", err)
+		}
+		if entry == nil {
+			break
+		}
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	want := []string{"b.txt", "link", "sub"}
+	if len(names) != len(want) {
+		t.Fatalf("directory entries = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("directory entries = %v, want %v", names, want)
+			break
+		}
+	}
+
+	expectErrorCode(t, root.removeAt("sub", false), ErrorCode("is-directory"))
+	if err := root.removeAt("sub", true); err != nil {
+		t.Fatalf("removeAt failed: %v", err)
+	}
+	if err := root.removeAt("link", false); err != nil {
+		t.Fatalf("removeAt failed: %v", err)
+	}
+}
+
+func TestDescriptor_MemFileSystem(t *testing.T) {
+	mfs := NewMemFileSystem()
+	if err := mfs.WriteFile("etc/config.txt", []byte("key=value"), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	root := NewFileSystemPreopen(mfs, "/", false).descriptor()
+
+	f, err := root.openAt(PathFlags{"symlink-follow": true}, "etc/config.txt", OpenFlags{}, DescriptorFlags{"read": true, "write": true})
+	if err != nil {
+		t.Fatalf("openAt failed: %v", err)
+	}
+	if _, err := f.writeAt([]byte("KEY"), 0); err != nil {
+		t.Fatalf("writeAt failed: %v", err)
+	}
+	data, eof, err := f.readAt(100, 0)
+	if err != nil {
+		t.Fatalf("readAt failed: %v", err)
+	}
+	if string(data) != "KEY=value" || !eof {
+		t.Errorf("readAt() = %q, %v, want %q, true", data, eof, "KEY=value")
+	}
+
+	if err := root.symlinkAt("etc", "link"); err != nil {
+		t.Fatalf("symlinkAt failed: %v", err)
+	}
+	fi, err := root.statAt(PathFlags{"symlink-follow": true}, "link/config.txt")
+	if err != nil {
+		t.Fatalf("statAt failed: %v", err)
+	}
+	other, err := f.stat()
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+	if !sameFile(fi, other) {
+		t.Error("file reached through a symlink should be the same object")
+	}
+
+	if err := root.symlinkAt("../..", "escape"); err != nil {
+		t.Fatalf("symlinkAt failed: %v", err)
+	}
+	_, err = root.statAt(PathFlags{"symlink-follow": true}, "escape")
+	expectErrorCode(t, err, ErrorCode("not-permitted"))
+
+	if err := root.renameAt("etc", root, "conf"); err != nil {
+		t.Fatalf("renameAt failed: %v", err)
+	}
+	_, err = root.statAt(PathFlags{}, "etc/config.txt")
+	expectErrorCode(t, err, ErrorCode("no-entry"))
+	expectErrorCode(t, root.removeAt("conf", true), ErrorCode("not-empty"))
+}
+
+func TestDescriptor_MemFileSparseWrite(t *testing.T) {
+	mfs := NewMemFileSystem()
+	if err := mfs.WriteFile("f", []byte("secret"), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	root := NewFileSystemPreopen(mfs, "/", false).descriptor()
+	d, err := root.openAt(PathFlags{}, "f", OpenFlags{}, DescriptorFlags{"read": true, "write": true})
+	if err != nil {
+		t.Fatalf("openAt failed: %v", err)
+	}
+	f, err := d.regularFile()
+	if err != nil {
+		t.Fatalf("regularFile failed: %v", err)
+	}
+	if err := f.Truncate(0); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+	if _, err := d.writeAt([]byte("x"), 6); err != nil {
+		t.Fatalf("writeAt failed: %v", err)
+	}
+	data, _, err := d.readAt(100, 0)
+	if err != nil {
+		t.Fatalf("readAt failed: %v", err)
+	}
+	if want := "\x00\x00\x00\x00\x00\x00x"; string(data) != want {
+		t.Errorf("readAt() = %q, want %q", data, want)
+	}
+}
+
+func TestDescriptor_IOFileSystem(t *testing.T) {
+	fsys := fstest.MapFS{
+		"docs/readme.md": &fstest.MapFile{Data: []byte("# readme")},
+	}
+	root := NewFileSystemPreopen(NewIOFileSystem(fsys), "/data", false).descriptor()
+
+	f, err := root.openAt(PathFlags{}, "docs/readme.md", OpenFlags{}, DescriptorFlags{"read": true})
+	if err != nil {
+		t.Fatalf("openAt failed: %v", err)
+	}
+	data, _, err := f.readAt(6, 2)
+	if err != nil {
+		t.Fatalf("readAt failed: %v", err)
+	}
+	if string(data) != "readme" {
+		t.Errorf("readAt() = %q, want %q", data, "readme")
+	}
+
+	_, err = root.openAt(PathFlags{}, "new.txt", OpenFlags{"create": true}, DescriptorFlags{"write": true})
+	expectErrorCode(t, err, ErrorCode("read-only"))
+	expectErrorCode(t, root.createDirectoryAt("tmp"), ErrorCode("read-only"))
+}
+
+func TestDescriptor_CrossPreopenRename(t *testing.T) {
+	a := NewFileSystemPreopen(NewMemFileSystem(), "/a", false).descriptor()
+	b := NewFileSystemPreopen(NewMemFileSystem(), "/b", false).descriptor()
+	if err := a.createDirectoryAt("dir"); err != nil {
+		t.Fatalf("createDirectoryAt failed: %v", err)
+	}
+	expectErrorCode(t, a.renameAt("dir", b, "dir"), ErrorCode("cross-device"))
+}
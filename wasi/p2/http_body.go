@@ -0,0 +1,260 @@
+package p2
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/partite-ai/wacogo/componentmodel/host"
+)
+
+// httpBodyBufferSize bounds how much of an outgoing body is buffered before the
+// receiving side reads it, so a guest can start writing a body before it hands
+// over the message.
+const httpBodyBufferSize = 64 * 1024
+
+var errHttpBodyNotFinished = errors.New("body was dropped without being finished")
+
+// httpBodyPipe carries an outgoing body from the guest's output stream to the
+// host, which reads it as an io.ReadCloser. The trailers passed to finish are
+// available once Read has returned io.EOF.
+type httpBodyPipe struct {
+	mu           sync.Mutex
+	cond         sync.Cond
+	buf          bytes.Buffer
+	err          error
+	trailer      http.Header
+	readerClosed bool
+}
+
+func newHttpBodyPipe() *httpBodyPipe {
+	p := &httpBodyPipe{}
+	p.cond.L = &p.mu
+	return p
+}
+
+func (p *httpBodyPipe) write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.buf.Len() >= httpBodyBufferSize && p.err == nil && !p.readerClosed {
+		p.cond.Wait()
+	}
+	if p.err != nil || p.readerClosed {
+		return 0, io.ErrClosedPipe
+	}
+	p.buf.Write(b)
+	p.cond.Broadcast()
+	return len(b), nil
+}
+
+// closeWrite ends the body, successfully with the given trailers if err is nil.
+// Only the first call has an effect.
+func (p *httpBodyPipe) closeWrite(trailer http.Header, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return
+	}
+	if err == nil {
+		err = io.EOF
+	}
+	p.err = err
+	p.trailer = trailer
+	p.cond.Broadcast()
+}
+
+func (p *httpBodyPipe) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.buf.Len() == 0 && p.err == nil && !p.readerClosed {
+		p.cond.Wait()
+	}
+	if p.readerClosed {
+		return 0, io.ErrClosedPipe
+	}
+	if p.buf.Len() > 0 {
+		n, _ := p.buf.Read(b)
+		p.cond.Broadcast()
+		return n, nil
+	}
+	return 0, p.err
+}
+
+func (p *httpBodyPipe) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.readerClosed = true
+	p.cond.Broadcast()
+	return nil
+}
+
+func (p *httpBodyPipe) Trailer() http.Header {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.trailer
+}
+
+// httpBodyWriter is the writer behind an outgoing body's output stream. It
+// doesn't implement io.Closer, since dropping the stream doesn't end the body.
+type httpBodyWriter struct {
+	p *httpBodyPipe
+}
+
+func (w httpBodyWriter) Write(b []byte) (int, error) {
+	return w.p.write(b)
+}
+
+type OutgoingBody struct {
+	pipe     *httpBodyPipe
+	stream   *WriterOutputStream
+	finished bool
+}
+
+func newOutgoingBody() *OutgoingBody {
+	return &OutgoingBody{
+		pipe: newHttpBodyPipe(),
+	}
+}
+
+func (b *OutgoingBody) write() (OutputStream, error) {
+	if b.stream != nil {
+		return nil, errors.New("body stream already taken")
+	}
+	b.stream = NewWriterOutputStream(httpBodyWriter{p: b.pipe})
+	return b.stream, nil
+}
+
+func (b *OutgoingBody) finish(trailer http.Header) error {
+	if b.stream != nil {
+		// Make sure a write the guest didn't flush reaches the pipe before
+		// the body ends. This is a no-op if the stream has been dropped.
+		b.stream.BlockingFlush()
+	}
+	b.finished = true
+	b.pipe.closeWrite(trailer, nil)
+	return nil
+}
+
+// Close is called when the guest drops the body. A body dropped without being
+// finished is incomplete, and the reader sees an error instead of io.EOF.
+func (b *OutgoingBody) Close() error {
+	if !b.finished {
+		b.pipe.closeWrite(nil, errHttpBodyNotFinished)
+	}
+	return nil
+}
+
+// httpBodyReader records how reading a body ended, so that finishing the body
+// can tell whether its trailers have been received.
+type httpBodyReader struct {
+	r   io.Reader
+	mu  sync.Mutex
+	err error
+}
+
+func (r *httpBodyReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil {
+		r.mu.Lock()
+		if r.err == nil {
+			r.err = err
+		}
+		r.mu.Unlock()
+	}
+	return n, err
+}
+
+func (r *httpBodyReader) result() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+type IncomingBody struct {
+	r           *httpBodyReader
+	closer      io.Closer
+	trailer     func() http.Header
+	streamTaken bool
+}
+
+func newIncomingBody(body io.ReadCloser, trailer func() http.Header) *IncomingBody {
+	return &IncomingBody{
+		r:       &httpBodyReader{r: body},
+		closer:  body,
+		trailer: trailer,
+	}
+}
+
+func (b *IncomingBody) stream() (InputStream, error) {
+	if b.streamTaken {
+		return nil, errors.New("body stream already taken")
+	}
+	b.streamTaken = true
+	return NewReaderInputStream(b.r, 65536, 8192, 8), nil
+}
+
+// finish resolves the body's trailers. They are only known once the body has
+// been read to the end; a body that was abandoned early has none.
+func (b *IncomingBody) finish() *FutureTrailers {
+	f := newFutureTrailers()
+	switch err := b.r.result(); {
+	case err == nil:
+		f.resolve(nil, nil)
+	case errors.Is(err, io.EOF):
+		f.resolve(b.trailer(), nil)
+	default:
+		f.resolve(nil, err)
+	}
+	return f
+}
+
+func (b *IncomingBody) Close() error {
+	return b.closer.Close()
+}
+
+type FutureTrailers struct {
+	done    chan struct{}
+	trailer http.Header
+	err     error
+	taken   bool
+}
+
+func newFutureTrailers() *FutureTrailers {
+	return &FutureTrailers{
+		done: make(chan struct{}),
+	}
+}
+
+func (f *FutureTrailers) resolve(trailer http.Header, err error) {
+	f.trailer = make(http.Header)
+	for k, v := range trailer {
+		if len(v) > 0 {
+			f.trailer[k] = v
+		}
+	}
+	f.err = err
+	close(f.done)
+}
+
+func (f *FutureTrailers) get() Option[Result[Result[Option[host.Own[*HttpFields]], HttpErrorCode], Void]] {
+	type result = Result[Result[Option[host.Own[*HttpFields]], HttpErrorCode], Void]
+	select {
+	case <-f.done:
+	default:
+		return OptionNone[result]()
+	}
+	if f.taken {
+		return OptionSome(ResultErr[Result[Option[host.Own[*HttpFields]], HttpErrorCode]](Void{}))
+	}
+	f.taken = true
+	if f.err != nil {
+		return OptionSome(ResultOk[Void](ResultErr[Option[host.Own[*HttpFields]]](httpErrorCodeFor(f.err))))
+	}
+	trailers := OptionNone[host.Own[*HttpFields]]()
+	if len(f.trailer) > 0 {
+		trailers = OptionSome(host.NewOwn(newHttpFields(f.trailer, true)))
+	}
+	return OptionSome(ResultOk[Void](ResultOk[HttpErrorCode](trailers)))
+}
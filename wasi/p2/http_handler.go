@@ -0,0 +1,189 @@
+package p2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/partite-ai/wacogo/componentmodel"
+	"github.com/partite-ai/wacogo/componentmodel/host"
+)
+
+var errHttpNoResponse = errors.New("incoming-handler returned without setting a response")
+
+// HttpHandler is an http.Handler that serves requests with a component
+// exporting wasi:http/incoming-handler, such as one targeting the
+// wasi:http/proxy world. By default every request is handled by a fresh instance
+// of the component, created with the WASI host instances described by config.
+type HttpHandler struct {
+	component *componentmodel.Component
+	config    *WASIConfig
+
+	reuse bool
+	mu    sync.Mutex
+	inst  *httpHandlerInstance
+}
+
+type httpHandlerInstance struct {
+	inst   *componentmodel.Instance
+	types  *host.Instance
+	handle *componentmodel.Function
+}
+
+func NewHttpHandler(component *componentmodel.Component, config *WASIConfig) *HttpHandler {
+	return &HttpHandler{
+		component: component,
+		config:    config,
+	}
+}
+
+// ReuseInstance makes the handler serve every request with the same instance,
+// one request at a time. An instance that traps is discarded and the next
+// request gets a new one.
+func (h *HttpHandler) ReuseInstance() *HttpHandler {
+	h.reuse = true
+	return h
+}
+
+func (h *HttpHandler) instantiate(ctx context.Context) (*httpHandlerInstance, error) {
+	instances, typesInstance, err := h.config.instances()
+	if err != nil {
+		return nil, err
+	}
+	args := make(map[string]any, len(instances))
+	for name, inst := range instances {
+		args[name] = inst
+	}
+
+	inst, err := h.component.Instantiate(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate incoming-handler: %w", err)
+	}
+	handle, err := exportedFunction(inst, "wasi:http/incoming-handler", "handle")
+	if err != nil {
+		inst.Close(ctx)
+		return nil, err
+	}
+	return &httpHandlerInstance{
+		inst:   inst,
+		types:  typesInstance,
+		handle: handle,
+	}, nil
+}
+
+func (h *HttpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if h.reuse {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+	}
+
+	hi := h.inst
+	if hi == nil {
+		var err error
+		hi, err = h.instantiate(ctx)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if h.reuse {
+			h.inst = hi
+		}
+	}
+	// The guest has returned by the time the response is written, so closing
+	// the instance doesn't wait on it, even once the request is cancelled.
+	closeCtx := context.WithoutCancel(ctx)
+	if !h.reuse {
+		defer hi.inst.Close(closeCtx)
+	}
+
+	handleErr, bodyErr := serveIncomingRequest(w, r, func(req *IncomingRequest, out *ResponseOutparam) error {
+		_, err := hi.handle.Invoke(
+			ctx,
+			componentmodel.NewResourceHandle(hi.inst, host.ResourceTypeFor[*IncomingRequest](hi.types, hi.types), req),
+			componentmodel.NewResourceHandle(hi.inst, host.ResourceTypeFor[*ResponseOutparam](hi.types, hi.types), out),
+		)
+		return err
+	})
+	if handleErr != nil && h.reuse {
+		hi.inst.Close(closeCtx)
+		h.inst = nil
+	}
+	if bodyErr != nil {
+		// The status and headers are already on their way, so the only way to
+		// tell the client the response is incomplete is to abort it.
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// serveIncomingRequest runs handle, which passes the request to the guest, and
+// writes the response the guest sets to w. The response is streamed to w while
+// handle is still running. It returns the error from handle, and the error that
+// cut the response body short, if any.
+func serveIncomingRequest(w http.ResponseWriter, r *http.Request, handle func(*IncomingRequest, *ResponseOutparam) error) (handleErr, bodyErr error) {
+	req := newIncomingRequest(r)
+	out := newResponseOutparam()
+	handled := make(chan error, 1)
+	go func() {
+		err := handle(req, out)
+		out.set(nil, errHttpNoResponse)
+		if out.response != nil {
+			out.response.body.pipe.closeWrite(nil, errHttpBodyNotFinished)
+		}
+		handled <- err
+	}()
+
+	<-out.done
+	resp := out.response
+	if resp == nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return <-handled, nil
+	}
+
+	header := w.Header()
+	for k, v := range resp.header {
+		header[k] = v
+	}
+	pipe := resp.body.pipe
+	if !resp.bodyTaken {
+		pipe.closeWrite(nil, nil)
+	}
+	w.WriteHeader(resp.status)
+	bodyErr = copyHttpBody(w, pipe)
+	if bodyErr == nil {
+		for k, vs := range pipe.Trailer() {
+			for _, v := range vs {
+				header.Add(http.TrailerPrefix+k, v)
+			}
+		}
+	}
+	return <-handled, bodyErr
+}
+
+// copyHttpBody copies a response body to w, flushing after every chunk so that
+// streamed responses reach the client as they are written.
+func copyHttpBody(w http.ResponseWriter, body io.ReadCloser) error {
+	defer body.Close()
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
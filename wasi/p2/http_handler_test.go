@@ -0,0 +1,159 @@
+package p2
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// readIncomingBody reads a request body the way a guest would, through the
+// input stream of the incoming body.
+func readIncomingBody(t *testing.T, req *IncomingRequest) string {
+	t.Helper()
+	body, err := req.consume()
+	if err != nil {
+		t.Fatalf("consume failed: %v", err)
+	}
+	stream, err := body.stream()
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	var sb strings.Builder
+	for {
+		data, err := stream.BlockingRead(1024)
+		sb.Write(data)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("BlockingRead failed: %v", err)
+		}
+	}
+	stream.(io.Closer).Close()
+	body.finish()
+	return sb.String()
+}
+
+func TestServeIncomingRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/echo?x=1", strings.NewReader("hello"))
+	r.Header.Set("X-Request", "yes")
+	w := httptest.NewRecorder()
+
+	handleErr, bodyErr := serveIncomingRequest(w, r, func(req *IncomingRequest, out *ResponseOutparam) error {
+		if req.method != http.MethodPost || req.pathWithQuery != "/echo?x=1" {
+			t.Errorf("request = %s %s, want POST /echo?x=1", req.method, req.pathWithQuery)
+		}
+		if got := newHttpFields(req.header, true).get("x-request"); len(got) != 1 || string(got[0]) != "yes" {
+			t.Errorf("x-request = %q, want yes", got)
+		}
+		data := readIncomingBody(t, req)
+
+		headers := newHttpFields(nil, false)
+		headers.set("content-type", [][]byte{[]byte("text/plain")})
+		resp := newOutgoingResponse(headers.header)
+		resp.setStatusCode(http.StatusCreated)
+		body, _ := resp.takeBody()
+		stream, _ := body.write()
+
+		// Start the body before handing over the response, which works as
+		// long as it fits in the buffer.
+		if err := stream.BlockingWriteAndFlush([]byte(data)); err != nil {
+			t.Fatalf("BlockingWriteAndFlush failed: %v", err)
+		}
+		out.set(resp, nil)
+		if err := stream.BlockingWriteAndFlush([]byte(" world")); err != nil {
+			t.Fatalf("BlockingWriteAndFlush failed: %v", err)
+		}
+		stream.(io.Closer).Close()
+		return body.finish(http.Header{"X-Trailer": {"done"}})
+	})
+	if handleErr != nil || bodyErr != nil {
+		t.Fatalf("serveIncomingRequest() = %v, %v", handleErr, bodyErr)
+	}
+
+	res := w.Result()
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("status = %d, want %d", res.StatusCode, http.StatusCreated)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "text/plain" {
+		t.Errorf("content-type = %q, want text/plain", ct)
+	}
+	data, _ := io.ReadAll(res.Body)
+	if string(data) != "hello world" {
+		t.Errorf("body = %q, want %q", data, "hello world")
+	}
+	if tr := res.Trailer.Get("X-Trailer"); tr != "done" {
+		t.Errorf("trailer = %q, want done", tr)
+	}
+}
+
+func TestServeIncomingRequest_NoResponse(t *testing.T) {
+	trap := errors.New("unreachable executed")
+	tests := []struct {
+		name   string
+		handle func(*IncomingRequest, *ResponseOutparam) error
+		want   error
+	}{
+		{"not set", func(*IncomingRequest, *ResponseOutparam) error { return nil }, nil},
+		{"error code", func(_ *IncomingRequest, out *ResponseOutparam) error {
+			out.set(nil, NewHttpErrorCode("HTTP-request-denied"))
+			return nil
+		}, nil},
+		{"trap", func(*IncomingRequest, *ResponseOutparam) error { return trap }, trap},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handleErr, _ := serveIncomingRequest(w, httptest.NewRequest(http.MethodGet, "/", nil), tt.handle)
+			if handleErr != tt.want {
+				t.Errorf("handle error = %v, want %v", handleErr, tt.want)
+			}
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+			}
+		})
+	}
+}
+
+func TestServeIncomingRequest_UnfinishedBody(t *testing.T) {
+	w := httptest.NewRecorder()
+	_, bodyErr := serveIncomingRequest(w, httptest.NewRequest(http.MethodGet, "/", nil), func(_ *IncomingRequest, out *ResponseOutparam) error {
+		resp := newOutgoingResponse(make(http.Header))
+		body, _ := resp.takeBody()
+		stream, _ := body.write()
+		out.set(resp, nil)
+		stream.BlockingWriteAndFlush([]byte("partial"))
+		return nil
+	})
+	if !errors.Is(bodyErr, errHttpBodyNotFinished) {
+		t.Errorf("body error = %v, want %v", bodyErr, errHttpBodyNotFinished)
+	}
+}
+
+func TestServeIncomingRequest_AbortUnfinishedBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, bodyErr := serveIncomingRequest(w, r, func(_ *IncomingRequest, out *ResponseOutparam) error {
+			resp := newOutgoingResponse(make(http.Header))
+			body, _ := resp.takeBody()
+			out.set(resp, nil)
+			body.Close()
+			return nil
+		})
+		if bodyErr != nil {
+			panic(http.ErrAbortHandler)
+		}
+	}))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	if err == nil {
+		_, err = io.ReadAll(res.Body)
+		res.Body.Close()
+	}
+	if err == nil {
+		t.Error("reading a response whose body was dropped unfinished should fail")
+	}
+}
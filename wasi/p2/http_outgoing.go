@@ -0,0 +1,369 @@
+package p2
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/partite-ai/wacogo/componentmodel/host"
+)
+
+// HttpClient sends the requests a guest makes with
+// wasi:http/outgoing-handler, and limits what those requests may do.
+type HttpClient struct {
+	// Transport sends the requests. If nil, http.DefaultTransport is used.
+	// Redirects are not followed; the guest sees them as responses.
+	Transport http.RoundTripper
+
+	// AllowedHosts lists the hosts requests may be sent to, either as a host
+	// name, which allows any port, or as host:port. A name starting with "*."
+	// allows any subdomain of the rest of it. If AllowedHosts is nil, requests
+	// may be sent to any host.
+	AllowedHosts []string
+
+	// RequestTimeout is the default for each of the timeouts in
+	// request-options, and the longest the guest may set them to. Zero
+	// leaves requests without a timeout unless the guest sets one.
+	RequestTimeout time.Duration
+
+	// MaxResponseBodySize is the largest response body the guest may read,
+	// in bytes. Reading past it fails with HTTP-response-body-size. Zero
+	// means no limit.
+	MaxResponseBodySize int64
+}
+
+func (c *HttpClient) transport() http.RoundTripper {
+	if c.Transport == nil {
+		return http.DefaultTransport
+	}
+	return c.Transport
+}
+
+func (c *HttpClient) allowsHost(u *url.URL) bool {
+	if c.AllowedHosts == nil {
+		return true
+	}
+	hostname := strings.ToLower(u.Hostname())
+	for _, allowed := range c.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		switch {
+		case allowed == hostname, allowed == strings.ToLower(u.Host):
+			return true
+		case strings.HasPrefix(allowed, "*.") && strings.HasSuffix(hostname, allowed[1:]):
+			return true
+		}
+	}
+	return false
+}
+
+// timeout is the timeout to use for a request-options timeout, capped by
+// RequestTimeout. A guest timeout of zero, or one too long for a
+// time.Duration, leaves RequestTimeout in place. Zero means none.
+func (c *HttpClient) timeout(option Option[Duration]) time.Duration {
+	v, ok := option.Some()
+	if !ok || v == 0 || uint64(v) > math.MaxInt64 {
+		return c.RequestTimeout
+	}
+	if c.RequestTimeout == 0 {
+		return time.Duration(v)
+	}
+	return min(time.Duration(v), c.RequestTimeout)
+}
+
+// newRequest converts an outgoing request to the http.Request to send. The
+// request's body, if the guest took it, becomes the http.Request's body.
+func (c *HttpClient) newRequest(r *OutgoingRequest) (*http.Request, error) {
+	scheme := r.scheme
+	if scheme == "" {
+		scheme = "https"
+	}
+	if scheme != "http" && scheme != "https" {
+		return nil, NewHttpErrorCode("HTTP-request-URI-invalid")
+	}
+	if r.authority == "" {
+		return nil, NewHttpErrorCode("HTTP-request-URI-invalid")
+	}
+	pathWithQuery := r.pathWithQuery
+	if pathWithQuery == "" {
+		pathWithQuery = "/"
+	}
+	u, err := url.Parse(scheme + "://" + r.authority + pathWithQuery)
+	if err != nil || u.User != nil {
+		return nil, NewHttpErrorCode("HTTP-request-URI-invalid")
+	}
+	if !c.allowsHost(u) {
+		return nil, NewHttpErrorCode("HTTP-request-denied")
+	}
+
+	req := &http.Request{
+		Method:     r.method,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     r.header.Clone(),
+		Host:       u.Host,
+		Body:       http.NoBody,
+	}
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	if !r.bodyTaken {
+		return req, nil
+	}
+
+	req.ContentLength = -1
+	if cl := req.Header.Get("Content-Length"); cl != "" {
+		n, err := strconv.ParseInt(cl, 10, 64)
+		if err != nil || n < 0 {
+			return nil, NewHttpErrorCode("HTTP-protocol-error")
+		}
+		req.ContentLength = n
+	}
+	// The trailers passed to finish are only known once the body has been
+	// written, which the transport allows as long as req.Trailer is set
+	// before it reaches the end of the body.
+	req.Trailer = make(http.Header)
+	req.Body = &httpRequestBody{pipe: r.body.pipe, trailer: req.Trailer}
+	return req, nil
+}
+
+// handle starts sending r. The returned future resolves once the response
+// headers have arrived or the request has failed. A nil client denies every
+// request.
+func (c *HttpClient) handle(r *OutgoingRequest, options *RequestOptions) (*FutureIncomingResponse, error) {
+	if c == nil {
+		r.body.pipe.Close()
+		return nil, NewHttpErrorCode("HTTP-request-denied")
+	}
+	req, err := c.newRequest(r)
+	if err != nil {
+		r.body.pipe.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	f := newFutureIncomingResponse()
+	f.cancel = func() { cancel(context.Canceled) }
+	go func() {
+		resp, err := c.send(ctx, cancel, req, options)
+		f.resolve(resp, err)
+	}()
+	return f, nil
+}
+
+func (c *HttpClient) send(
+	ctx context.Context,
+	cancel context.CancelCauseFunc,
+	req *http.Request,
+	options *RequestOptions,
+) (*IncomingResponse, error) {
+	cancelAfter := func(d time.Duration, code string) *time.Timer {
+		if d <= 0 {
+			return nil
+		}
+		return time.AfterFunc(d, func() { cancel(NewHttpErrorCode(code)) })
+	}
+
+	// The transport doesn't report when the connection is established unless
+	// it supports httptrace; if it doesn't, only the first-byte timeout applies.
+	connectTimer := cancelAfter(c.timeout(options.connectTimeout), "connection-timeout")
+	firstByteTimer := cancelAfter(c.timeout(options.firstByteTimeout), "connection-read-timeout")
+	trace := &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) {
+			stopTimer(connectTimer)
+		},
+	}
+	resp, err := c.transport().RoundTrip(req.WithContext(httptrace.WithClientTrace(ctx, trace)))
+	stopTimer(connectTimer)
+	stopTimer(firstByteTimer)
+	if err == nil && ctx.Err() != nil {
+		resp.Body.Close()
+		err = ctx.Err()
+	}
+	if err != nil {
+		cancel(err)
+		return nil, httpClientErrorCode(ctx, err)
+	}
+
+	if c.MaxResponseBodySize > 0 && resp.ContentLength > c.MaxResponseBodySize {
+		resp.Body.Close()
+		cancel(context.Canceled)
+		return nil, newHttpErrorCodeValue("HTTP-response-body-size", OptionSome(uint64(resp.ContentLength)))
+	}
+
+	header := resp.Header
+	for name := range httpForbiddenFields {
+		header.Del(name)
+	}
+	body := &httpResponseBody{
+		ctx:     ctx,
+		cancel:  cancel,
+		body:    resp.Body,
+		limit:   c.MaxResponseBodySize,
+		timeout: c.timeout(options.betweenBytesTimeout),
+	}
+	return &IncomingResponse{
+		status: resp.StatusCode,
+		header: header,
+		body:   newIncomingBody(body, func() http.Header { return resp.Trailer }),
+	}, nil
+}
+
+func stopTimer(t *time.Timer) {
+	if t != nil {
+		t.Stop()
+	}
+}
+
+// httpRequestBody is the body of a request sent by the guest. It copies the
+// trailers the guest finished the body with into the request once the body
+// has been read to the end.
+type httpRequestBody struct {
+	pipe    *httpBodyPipe
+	trailer http.Header
+}
+
+func (b *httpRequestBody) Read(p []byte) (int, error) {
+	n, err := b.pipe.Read(p)
+	if err == io.EOF {
+		for k, v := range b.pipe.Trailer() {
+			b.trailer[k] = v
+		}
+	}
+	return n, err
+}
+
+func (b *httpRequestBody) Close() error {
+	return b.pipe.Close()
+}
+
+// httpResponseBody is the body of a response to a request sent by the guest.
+// It enforces the between-bytes timeout and the maximum body size, and reports
+// read errors as error codes.
+type httpResponseBody struct {
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	body    io.ReadCloser
+	limit   int64
+	read    int64
+	timeout time.Duration
+}
+
+func (b *httpResponseBody) Read(p []byte) (int, error) {
+	var timer *time.Timer
+	if b.timeout > 0 {
+		timer = time.AfterFunc(b.timeout, func() {
+			b.cancel(NewHttpErrorCode("connection-read-timeout"))
+		})
+	}
+	n, err := b.body.Read(p)
+	stopTimer(timer)
+
+	b.read += int64(n)
+	if b.limit > 0 && b.read > b.limit {
+		n -= int(b.read - b.limit)
+		b.read = b.limit
+		return n, newHttpErrorCodeValue("HTTP-response-body-size", OptionNone[uint64]())
+	}
+	if err != nil && err != io.EOF {
+		err = httpClientErrorCode(b.ctx, err)
+	}
+	return n, err
+}
+
+func (b *httpResponseBody) Close() error {
+	b.cancel(context.Canceled)
+	return b.body.Close()
+}
+
+// httpClientErrorCode maps an error from sending a request or reading its
+// response to an error code. A timeout that cancelled the request takes
+// precedence over the error it caused.
+func httpClientErrorCode(ctx context.Context, err error) HttpErrorCode {
+	var code HttpErrorCode
+	if errors.As(context.Cause(ctx), &code) || errors.As(err, &code) {
+		return code
+	}
+
+	var dnsErr *net.DNSError
+	var alert tls.AlertError
+	var recordErr tls.RecordHeaderError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &dnsErr):
+		if dnsErr.IsTimeout {
+			return NewHttpErrorCode("DNS-timeout")
+		}
+		if dnsErr.IsNotFound {
+			return NewHttpErrorCode("destination-not-found")
+		}
+		return HttpErrorCodeDnsError(NewDnsErrorPayload(optionalString(dnsErr.Err), OptionNone[uint16]()))
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return NewHttpErrorCode("connection-refused")
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.ErrUnexpectedEOF):
+		return NewHttpErrorCode("connection-terminated")
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return NewHttpErrorCode("destination-unavailable")
+	case errors.As(err, &alert):
+		return newHttpErrorCodeValue("TLS-alert-received", NewTlsAlertReceivedPayload(
+			OptionSome(uint8(alert)),
+			OptionSome(alert.Error()),
+		))
+	case errors.As(err, &certErr), errors.As(err, &unknownAuthorityErr),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return NewHttpErrorCode("TLS-certificate-error")
+	case errors.As(err, &recordErr):
+		return NewHttpErrorCode("TLS-protocol-error")
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return NewHttpErrorCode("connection-timeout")
+	}
+	return HttpErrorCodeInternalError(OptionSome(err.Error()))
+}
+
+func CreateHttpOutgoingHandlerInstance(
+	typesInstance *host.Instance,
+	client *HttpClient,
+) *host.Instance {
+	hi := host.NewInstance()
+
+	hi.AddTypeExport("outgoing-request", host.ResourceTypeFor[*OutgoingRequest](hi, typesInstance))
+	hi.AddTypeExport("request-options", host.ResourceTypeFor[*RequestOptions](hi, typesInstance))
+	hi.AddTypeExport("future-incoming-response", host.ResourceTypeFor[*FutureIncomingResponse](hi, typesInstance))
+	hi.AddTypeExport("error-code", host.ValueTypeFor[HttpErrorCode](hi))
+
+	hi.MustAddFunction("handle", func(
+		request host.Own[*OutgoingRequest],
+		options Option[host.Own[*RequestOptions]],
+	) Result[host.Own[*FutureIncomingResponse], HttpErrorCode] {
+		req := request.Resource()
+		request.Drop()
+		opts := newRequestOptions()
+		if o, ok := options.Some(); ok {
+			opts = o.Resource()
+			o.Drop()
+		}
+		f, err := client.handle(req, opts)
+		if err != nil {
+			return ResultErr[host.Own[*FutureIncomingResponse]](httpErrorCodeFor(err))
+		}
+		return ResultOk[HttpErrorCode](host.NewOwn(f))
+	})
+
+	return hi
+}
@@ -0,0 +1,280 @@
+package p2
+
+import (
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// newTestOutgoingRequest builds the request a guest would send to rawURL.
+func newTestOutgoingRequest(t *testing.T, method, rawURL string) *OutgoingRequest {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newOutgoingRequest(make(http.Header))
+	r.method = method
+	r.scheme = u.Scheme
+	r.authority = u.Host
+	r.pathWithQuery = u.RequestURI()
+	return r
+}
+
+// awaitResponse waits for f to resolve and returns its response or error code.
+func awaitResponse(t *testing.T, f *FutureIncomingResponse) (*IncomingResponse, error) {
+	t.Helper()
+	select {
+	case <-f.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the response")
+	}
+	return f.response, f.err
+}
+
+// readIncomingResponse reads a response body the way a guest would, returning
+// the body and the error that ended it, if it wasn't io.EOF.
+func readIncomingResponse(t *testing.T, resp *IncomingResponse) (string, error) {
+	t.Helper()
+	body, err := resp.consume()
+	if err != nil {
+		t.Fatalf("consume failed: %v", err)
+	}
+	defer body.Close()
+	stream, _ := body.stream()
+	defer stream.(io.Closer).Close()
+	var sb strings.Builder
+	for {
+		data, err := stream.BlockingRead(1024)
+		sb.Write(data)
+		if errors.Is(err, io.EOF) {
+			return sb.String(), nil
+		}
+		if err != nil {
+			return sb.String(), err
+		}
+	}
+}
+
+func TestHttpClient_RoundTrip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("X-Trailer", r.Trailer.Get("X-Checksum"))
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(r.URL.RequestURI() + " " + string(data)))
+	}))
+	defer srv.Close()
+
+	client := &HttpClient{}
+	req := newTestOutgoingRequest(t, http.MethodPut, srv.URL+"/upload?x=1")
+	body, _ := req.takeBody()
+	stream, _ := body.write()
+
+	f, err := client.handle(req, newRequestOptions())
+	if err != nil {
+		t.Fatalf("handle failed: %v", err)
+	}
+	if err := stream.BlockingWriteAndFlush([]byte("payload")); err != nil {
+		t.Fatalf("BlockingWriteAndFlush failed: %v", err)
+	}
+	stream.(io.Closer).Close()
+	body.finish(http.Header{"X-Checksum": {"abc"}})
+
+	resp, err := awaitResponse(t, f)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.status != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", resp.status, http.StatusAccepted)
+	}
+	if m := resp.header.Get("X-Method"); m != http.MethodPut {
+		t.Errorf("method = %q, want PUT", m)
+	}
+	if tr := resp.header.Get("X-Trailer"); tr != "abc" {
+		t.Errorf("request trailer = %q, want abc", tr)
+	}
+	data, err := readIncomingResponse(t, resp)
+	if err != nil {
+		t.Fatalf("reading the body failed: %v", err)
+	}
+	if data != "/upload?x=1 payload" {
+		t.Errorf("body = %q, want %q", data, "/upload?x=1 payload")
+	}
+}
+
+func TestHttpClient_Denied(t *testing.T) {
+	tests := []struct {
+		name   string
+		client *HttpClient
+		url    string
+		want   string
+	}{
+		{"no client", nil, "http://example.com/", "HTTP-request-denied"},
+		{"host not allowed", &HttpClient{AllowedHosts: []string{"example.com"}}, "http://example.org/", "HTTP-request-denied"},
+		{"port not allowed", &HttpClient{AllowedHosts: []string{"example.com:443"}}, "http://example.com:8080/", "HTTP-request-denied"},
+		{"not a subdomain", &HttpClient{AllowedHosts: []string{"*.example.com"}}, "http://badexample.com/", "HTTP-request-denied"},
+		{"unsupported scheme", &HttpClient{}, "ftp://example.com/", "HTTP-request-URI-invalid"},
+		{"no authority", &HttpClient{}, "http:///", "HTTP-request-URI-invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newTestOutgoingRequest(t, http.MethodGet, tt.url)
+			_, err := tt.client.handle(req, newRequestOptions())
+			if err == nil || httpErrorCodeFor(err).Label() != tt.want {
+				t.Errorf("handle() = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHttpClient_AllowedHosts(t *testing.T) {
+	var got []string
+	client := &HttpClient{
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			got = append(got, r.URL.Host)
+			return &http.Response{StatusCode: http.StatusNoContent, Header: make(http.Header), Body: http.NoBody}, nil
+		}),
+		AllowedHosts: []string{"example.com", "api.example.org:8443", "*.example.net"},
+	}
+	for _, u := range []string{"https://example.com/", "http://EXAMPLE.com:8080/", "https://api.example.org:8443/", "https://a.b.example.net/"} {
+		f, err := client.handle(newTestOutgoingRequest(t, http.MethodGet, u), newRequestOptions())
+		if err != nil {
+			t.Errorf("handle(%s) failed: %v", u, err)
+			continue
+		}
+		if _, err := awaitResponse(t, f); err != nil {
+			t.Errorf("request to %s failed: %v", u, err)
+		}
+	}
+	if len(got) != 4 {
+		t.Errorf("transport saw %v, want 4 requests", got)
+	}
+}
+
+func TestHttpClient_TransportErrors(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{syscall.ECONNREFUSED, "connection-refused"},
+		{io.ErrUnexpectedEOF, "connection-terminated"},
+		{errors.New("boom"), "internal-error"},
+	}
+	for _, tt := range tests {
+		client := &HttpClient{
+			Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+				return nil, &url.Error{Op: "Get", URL: "http://example.com", Err: tt.err}
+			}),
+		}
+		f, err := client.handle(newTestOutgoingRequest(t, http.MethodGet, "http://example.com/"), newRequestOptions())
+		if err != nil {
+			t.Fatalf("handle failed: %v", err)
+		}
+		_, err = awaitResponse(t, f)
+		if err == nil || httpErrorCodeFor(err).Label() != tt.want {
+			t.Errorf("transport error %v: request failed with %v, want %s", tt.err, err, tt.want)
+		}
+	}
+}
+
+func TestHttpClient_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-body" {
+			w.Write([]byte("partial"))
+			w.(http.Flusher).Flush()
+		}
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client := &HttpClient{RequestTimeout: time.Hour}
+	opts := newRequestOptions()
+	opts.firstByteTimeout = OptionSome(Duration(50 * time.Millisecond))
+	f, _ := client.handle(newTestOutgoingRequest(t, http.MethodGet, srv.URL+"/slow-headers"), opts)
+	if _, err := awaitResponse(t, f); err == nil || httpErrorCodeFor(err).Label() != "connection-read-timeout" {
+		t.Errorf("request with a first-byte timeout = %v, want connection-read-timeout", err)
+	}
+
+	// RequestTimeout caps the timeout the guest sets.
+	client = &HttpClient{RequestTimeout: 50 * time.Millisecond}
+	opts = newRequestOptions()
+	opts.betweenBytesTimeout = OptionSome(Duration(time.Hour))
+	f, _ = client.handle(newTestOutgoingRequest(t, http.MethodGet, srv.URL+"/slow-body"), opts)
+	resp, err := awaitResponse(t, f)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	data, err := readIncomingResponse(t, resp)
+	if data != "partial" {
+		t.Errorf("body = %q, want partial", data)
+	}
+	if err == nil || httpErrorCodeFor(err).Label() != "connection-read-timeout" {
+		t.Errorf("reading a stalled body = %v, want connection-read-timeout", err)
+	}
+
+	// Neither a zero timeout nor one too long for a time.Duration lifts the
+	// cap.
+	for _, guest := range []Duration{0, math.MaxUint64} {
+		opts = newRequestOptions()
+		opts.firstByteTimeout = OptionSome(guest)
+		f, _ = client.handle(newTestOutgoingRequest(t, http.MethodGet, srv.URL+"/slow-headers"), opts)
+		if _, err := awaitResponse(t, f); err == nil || httpErrorCodeFor(err).Label() != "connection-read-timeout" {
+			t.Errorf("request with a first-byte timeout of %d = %v, want connection-read-timeout", guest, err)
+		}
+	}
+}
+
+func TestHttpClient_MaxResponseBodySize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer srv.Close()
+
+	client := &HttpClient{MaxResponseBodySize: 64}
+	f, _ := client.handle(newTestOutgoingRequest(t, http.MethodGet, srv.URL+"/sized"), newRequestOptions())
+	if _, err := awaitResponse(t, f); err == nil || httpErrorCodeFor(err).Label() != "HTTP-response-body-size" {
+		t.Errorf("request for a declared oversized body = %v, want HTTP-response-body-size", err)
+	}
+
+	f, _ = client.handle(newTestOutgoingRequest(t, http.MethodGet, srv.URL+"/chunked"), newRequestOptions())
+	resp, err := awaitResponse(t, f)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	data, err := readIncomingResponse(t, resp)
+	if len(data) != 64 {
+		t.Errorf("read %d bytes, want 64", len(data))
+	}
+	if err == nil || httpErrorCodeFor(err).Label() != "HTTP-response-body-size" {
+		t.Errorf("reading an oversized body = %v, want HTTP-response-body-size", err)
+	}
+}
+
+func TestCreateHttpOutgoingHandlerInstance(t *testing.T) {
+	errorInstance := CreateErrorInstance()
+	pollInstance := CreatePollInstance()
+	streamsInstance := CreateStreamsInstance(errorInstance, pollInstance)
+	typesInstance := CreateHttpTypesInstance(errorInstance, streamsInstance, pollInstance)
+	if inst := CreateHttpOutgoingHandlerInstance(typesInstance, nil).Instance(); inst == nil {
+		t.Fatal("CreateHttpOutgoingHandlerInstance returned no instance")
+	}
+}
@@ -0,0 +1,1014 @@
+package p2
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/partite-ai/wacogo/componentmodel"
+	"github.com/partite-ai/wacogo/componentmodel/host"
+)
+
+var httpMethodLabels = []string{
+	"get",
+	"head",
+	"post",
+	"put",
+	"delete",
+	"connect",
+	"options",
+	"trace",
+	"patch",
+}
+
+type HttpMethod host.Variant[HttpMethod]
+
+func (HttpMethod) ValueType(inst *host.Instance) componentmodel.ValueType {
+	cases := make([]*host.VariantCaseDef, 0, len(httpMethodLabels)+1)
+	for _, label := range httpMethodLabels {
+		cases = append(cases, host.VariantCase[HttpMethod](func() HttpMethod {
+			return host.VariantConstruct[HttpMethod](label)
+		}))
+	}
+	cases = append(cases, host.VariantCaseValue(HttpMethodOther))
+	return host.VariantType(inst, cases...)
+}
+
+func HttpMethodOther(method string) HttpMethod {
+	return host.VariantConstructValue[HttpMethod](
+		"other",
+		method,
+	)
+}
+
+// NewHttpMethod converts a Go request method, such as http.MethodGet, to a
+// method. Methods are case-sensitive, so only the upper-case forms of the
+// standard methods map to their own cases.
+func NewHttpMethod(method string) HttpMethod {
+	label := strings.ToLower(method)
+	if method == strings.ToUpper(method) && slices.Contains(httpMethodLabels, label) {
+		return host.VariantConstruct[HttpMethod](label)
+	}
+	return HttpMethodOther(method)
+}
+
+func (m HttpMethod) String() string {
+	if other, ok := host.VariantCast[string](m, "other"); ok {
+		return other
+	}
+	return strings.ToUpper(host.VariantCaseLabel(m))
+}
+
+type HttpScheme host.Variant[HttpScheme]
+
+func (HttpScheme) ValueType(inst *host.Instance) componentmodel.ValueType {
+	return host.VariantType(
+		inst,
+		host.VariantCase[HttpScheme](HttpSchemeHttp),
+		host.VariantCase[HttpScheme](HttpSchemeHttps),
+		host.VariantCaseValue(HttpSchemeOther),
+	)
+}
+
+func HttpSchemeHttp() HttpScheme {
+	return host.VariantConstruct[HttpScheme](
+		"HTTP",
+	)
+}
+
+func HttpSchemeHttps() HttpScheme {
+	return host.VariantConstruct[HttpScheme](
+		"HTTPS",
+	)
+}
+
+func HttpSchemeOther(scheme string) HttpScheme {
+	return host.VariantConstructValue[HttpScheme](
+		"other",
+		scheme,
+	)
+}
+
+// NewHttpScheme converts a URL scheme to a scheme.
+func NewHttpScheme(scheme string) HttpScheme {
+	switch strings.ToLower(scheme) {
+	case "http":
+		return HttpSchemeHttp()
+	case "https":
+		return HttpSchemeHttps()
+	}
+	return HttpSchemeOther(scheme)
+}
+
+func (s HttpScheme) String() string {
+	if other, ok := host.VariantCast[string](s, "other"); ok {
+		return other
+	}
+	return strings.ToLower(host.VariantCaseLabel(s))
+}
+
+type DnsErrorPayload host.Record[struct {
+	Rcode    host.RecordField[DnsErrorPayload, Option[string]]
+	InfoCode host.RecordField[DnsErrorPayload, Option[uint16]] `cm:"info-code"`
+}]
+
+func NewDnsErrorPayload(rcode Option[string], infoCode Option[uint16]) DnsErrorPayload {
+	rec := host.NewRecord[DnsErrorPayload]()
+	rec.Fields.Rcode.Set(rec, rcode)
+	rec.Fields.InfoCode.Set(rec, infoCode)
+	return rec.Record()
+}
+
+type TlsAlertReceivedPayload host.Record[struct {
+	AlertId      host.RecordField[TlsAlertReceivedPayload, Option[uint8]]  `cm:"alert-id"`
+	AlertMessage host.RecordField[TlsAlertReceivedPayload, Option[string]] `cm:"alert-message"`
+}]
+
+func NewTlsAlertReceivedPayload(alertId Option[uint8], alertMessage Option[string]) TlsAlertReceivedPayload {
+	rec := host.NewRecord[TlsAlertReceivedPayload]()
+	rec.Fields.AlertId.Set(rec, alertId)
+	rec.Fields.AlertMessage.Set(rec, alertMessage)
+	return rec.Record()
+}
+
+type FieldSizePayload host.Record[struct {
+	FieldName host.RecordField[FieldSizePayload, Option[string]] `cm:"field-name"`
+	FieldSize host.RecordField[FieldSizePayload, Option[uint32]] `cm:"field-size"`
+}]
+
+func NewFieldSizePayload(fieldName Option[string], fieldSize Option[uint32]) FieldSizePayload {
+	rec := host.NewRecord[FieldSizePayload]()
+	rec.Fields.FieldName.Set(rec, fieldName)
+	rec.Fields.FieldSize.Set(rec, fieldSize)
+	return rec.Record()
+}
+
+// HttpErrorCode is the error-code variant of wasi:http/types. Cases without a
+// payload are created with NewHttpErrorCode.
+type HttpErrorCode host.Variant[HttpErrorCode]
+
+func (HttpErrorCode) ValueType(inst *host.Instance) componentmodel.ValueType {
+	return host.VariantType(
+		inst,
+		httpErrorCodeCase("DNS-timeout"),
+		httpErrorCodeCaseValue[DnsErrorPayload]("DNS-error"),
+		httpErrorCodeCase("destination-not-found"),
+		httpErrorCodeCase("destination-unavailable"),
+		httpErrorCodeCase("destination-IP-prohibited"),
+		httpErrorCodeCase("destination-IP-unroutable"),
+		httpErrorCodeCase("connection-refused"),
+		httpErrorCodeCase("connection-terminated"),
+		httpErrorCodeCase("connection-timeout"),
+		httpErrorCodeCase("connection-read-timeout"),
+		httpErrorCodeCase("connection-write-timeout"),
+		httpErrorCodeCase("connection-limit-reached"),
+		httpErrorCodeCase("TLS-protocol-error"),
+		httpErrorCodeCase("TLS-certificate-error"),
+		httpErrorCodeCaseValue[TlsAlertReceivedPayload]("TLS-alert-received"),
+		httpErrorCodeCase("HTTP-request-denied"),
+		httpErrorCodeCase("HTTP-request-length-required"),
+		httpErrorCodeCaseValue[Option[uint64]]("HTTP-request-body-size"),
+		httpErrorCodeCase("HTTP-request-method-invalid"),
+		httpErrorCodeCase("HTTP-request-URI-invalid"),
+		httpErrorCodeCase("HTTP-request-URI-too-long"),
+		httpErrorCodeCaseValue[Option[uint32]]("HTTP-request-header-section-size"),
+		httpErrorCodeCaseValue[Option[FieldSizePayload]]("HTTP-request-header-size"),
+		httpErrorCodeCaseValue[Option[uint32]]("HTTP-request-trailer-section-size"),
+		httpErrorCodeCaseValue[FieldSizePayload]("HTTP-request-trailer-size"),
+		httpErrorCodeCase("HTTP-response-incomplete"),
+		httpErrorCodeCaseValue[Option[uint32]]("HTTP-response-header-section-size"),
+		httpErrorCodeCaseValue[FieldSizePayload]("HTTP-response-header-size"),
+		httpErrorCodeCaseValue[Option[uint64]]("HTTP-response-body-size"),
+		httpErrorCodeCaseValue[Option[uint32]]("HTTP-response-trailer-section-size"),
+		httpErrorCodeCaseValue[FieldSizePayload]("HTTP-response-trailer-size"),
+		httpErrorCodeCaseValue[Option[string]]("HTTP-response-transfer-coding"),
+		httpErrorCodeCaseValue[Option[string]]("HTTP-response-content-coding"),
+		httpErrorCodeCase("HTTP-response-timeout"),
+		httpErrorCodeCase("HTTP-upgrade-failed"),
+		httpErrorCodeCase("HTTP-protocol-error"),
+		httpErrorCodeCase("loop-detected"),
+		httpErrorCodeCase("configuration-error"),
+		httpErrorCodeCaseValue[Option[string]]("internal-error"),
+	)
+}
+
+func httpErrorCodeCase(label string) *host.VariantCaseDef {
+	return host.VariantCase[HttpErrorCode](func() HttpErrorCode {
+		return NewHttpErrorCode(label)
+	})
+}
+
+func httpErrorCodeCaseValue[T any](label string) *host.VariantCaseDef {
+	return host.VariantCaseValue(func(v T) HttpErrorCode {
+		return newHttpErrorCodeValue(label, v)
+	})
+}
+
+func NewHttpErrorCode(label string) HttpErrorCode {
+	return host.VariantConstruct[HttpErrorCode](
+		label,
+	)
+}
+
+func newHttpErrorCodeValue[T any](label string, v T) HttpErrorCode {
+	return host.VariantConstructValue[HttpErrorCode](
+		label,
+		v,
+	)
+}
+
+func HttpErrorCodeDnsError(payload DnsErrorPayload) HttpErrorCode {
+	return newHttpErrorCodeValue("DNS-error", payload)
+}
+
+func HttpErrorCodeInternalError(message Option[string]) HttpErrorCode {
+	return newHttpErrorCodeValue("internal-error", message)
+}
+
+func (e HttpErrorCode) Label() string {
+	return host.VariantCaseLabel(e)
+}
+
+func (e HttpErrorCode) Error() string {
+	if message, ok := host.VariantCast[Option[string]](e, "internal-error"); ok {
+		if s, ok := message.Some(); ok {
+			return "internal-error: " + s
+		}
+	}
+	return e.Label()
+}
+
+// httpErrorCodeFor maps err to an error code, falling back to internal-error
+// with the error's message.
+func httpErrorCodeFor(err error) HttpErrorCode {
+	var code HttpErrorCode
+	if errors.As(err, &code) {
+		return code
+	}
+	return HttpErrorCodeInternalError(OptionSome(err.Error()))
+}
+
+type HeaderError host.Variant[HeaderError]
+
+func (HeaderError) ValueType(inst *host.Instance) componentmodel.ValueType {
+	return host.VariantType(
+		inst,
+		host.VariantCase[HeaderError](HeaderErrorInvalidSyntax),
+		host.VariantCase[HeaderError](HeaderErrorForbidden),
+		host.VariantCase[HeaderError](HeaderErrorImmutable),
+	)
+}
+
+func HeaderErrorInvalidSyntax() HeaderError {
+	return host.VariantConstruct[HeaderError](
+		"invalid-syntax",
+	)
+}
+
+func HeaderErrorForbidden() HeaderError {
+	return host.VariantConstruct[HeaderError](
+		"forbidden",
+	)
+}
+
+func HeaderErrorImmutable() HeaderError {
+	return host.VariantConstruct[HeaderError](
+		"immutable",
+	)
+}
+
+func (e HeaderError) Error() string {
+	return host.VariantCaseLabel(e)
+}
+
+func headerErrorResult[T any](v T, err error) Result[T, HeaderError] {
+	if err != nil {
+		var headerErr HeaderError
+		if !errors.As(err, &headerErr) {
+			headerErr = HeaderErrorInvalidSyntax()
+		}
+		return ResultErr[T](headerErr)
+	}
+	return ResultOk[HeaderError](v)
+}
+
+// httpForbiddenFields are the hop-by-hop fields a guest may not set, because
+// they describe the connection rather than the message.
+var httpForbiddenFields = map[string]bool{
+	"connection":          true,
+	"keep-alive":          true,
+	"proxy-authenticate":  true,
+	"proxy-authorization": true,
+	"proxy-connection":    true,
+	"te":                  true,
+	"transfer-encoding":   true,
+	"upgrade":             true,
+	"host":                true,
+	"http2-settings":      true,
+}
+
+// validHttpToken reports whether s is a token as defined by RFC 9110, which is
+// the syntax of field names and methods.
+func validHttpToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func validHttpFieldValue(v []byte) bool {
+	for _, c := range v {
+		if c == '\r' || c == '\n' || c == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// HttpFields holds the header or trailer fields of a message. Field names are
+// case-insensitive and are reported in lower case. Fields that belong to a
+// request or response are immutable.
+type HttpFields struct {
+	header    http.Header
+	immutable bool
+}
+
+func newHttpFields(header http.Header, immutable bool) *HttpFields {
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &HttpFields{
+		header:    header,
+		immutable: immutable,
+	}
+}
+
+func (f *HttpFields) checkMutable(name string) error {
+	if f.immutable {
+		return HeaderErrorImmutable()
+	}
+	if !validHttpToken(name) {
+		return HeaderErrorInvalidSyntax()
+	}
+	if httpForbiddenFields[strings.ToLower(name)] {
+		return HeaderErrorForbidden()
+	}
+	return nil
+}
+
+func (f *HttpFields) get(name string) [][]byte {
+	values := f.header.Values(name)
+	result := make([][]byte, len(values))
+	for i, v := range values {
+		result[i] = []byte(v)
+	}
+	return result
+}
+
+func (f *HttpFields) has(name string) bool {
+	return len(f.header.Values(name)) > 0
+}
+
+func (f *HttpFields) set(name string, values [][]byte) error {
+	if err := f.checkMutable(name); err != nil {
+		return err
+	}
+	strs := make([]string, len(values))
+	for i, v := range values {
+		if !validHttpFieldValue(v) {
+			return HeaderErrorInvalidSyntax()
+		}
+		strs[i] = string(v)
+	}
+	f.header[http.CanonicalHeaderKey(name)] = strs
+	return nil
+}
+
+func (f *HttpFields) delete(name string) error {
+	if err := f.checkMutable(name); err != nil {
+		return err
+	}
+	f.header.Del(name)
+	return nil
+}
+
+func (f *HttpFields) append(name string, value []byte) error {
+	if err := f.checkMutable(name); err != nil {
+		return err
+	}
+	if !validHttpFieldValue(value) {
+		return HeaderErrorInvalidSyntax()
+	}
+	f.header.Add(name, string(value))
+	return nil
+}
+
+func (f *HttpFields) entries() []Tuple2[string, []byte] {
+	names := make([]string, 0, len(f.header))
+	for name := range f.header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var entries []Tuple2[string, []byte]
+	for _, name := range names {
+		lower := strings.ToLower(name)
+		for _, v := range f.header[name] {
+			entries = append(entries, NewTuple2(lower, []byte(v)))
+		}
+	}
+	return entries
+}
+
+// clone returns a mutable copy of the fields.
+func (f *HttpFields) clone() *HttpFields {
+	return newHttpFields(f.header.Clone(), false)
+}
+
+func httpFieldsFromList(entries []Tuple2[string, []byte]) (*HttpFields, error) {
+	f := newHttpFields(nil, false)
+	for _, entry := range entries {
+		if err := f.append(entry.A(), entry.B()); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// takeHttpFields consumes an owned fields handle passed by the guest and returns
+// a copy of its contents.
+func takeHttpFields(fields host.Own[*HttpFields]) http.Header {
+	header := fields.Resource().header.Clone()
+	fields.Drop()
+	return header
+}
+
+type IncomingRequest struct {
+	method        string
+	scheme        string
+	authority     string
+	pathWithQuery string
+	header        http.Header
+	body          *IncomingBody
+}
+
+func newIncomingRequest(r *http.Request) *IncomingRequest {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return &IncomingRequest{
+		method:        r.Method,
+		scheme:        scheme,
+		authority:     r.Host,
+		pathWithQuery: r.URL.RequestURI(),
+		header:        r.Header,
+		body:          newIncomingBody(r.Body, func() http.Header { return r.Trailer }),
+	}
+}
+
+func (r *IncomingRequest) consume() (*IncomingBody, error) {
+	if r.body == nil {
+		return nil, errors.New("request body already consumed")
+	}
+	body := r.body
+	r.body = nil
+	return body, nil
+}
+
+func (r *IncomingRequest) Close() error {
+	if r.body != nil {
+		return r.body.Close()
+	}
+	return nil
+}
+
+type OutgoingRequest struct {
+	method        string
+	scheme        string
+	authority     string
+	pathWithQuery string
+	header        http.Header
+	body          *OutgoingBody
+	bodyTaken     bool
+}
+
+func newOutgoingRequest(header http.Header) *OutgoingRequest {
+	return &OutgoingRequest{
+		method: http.MethodGet,
+		header: header,
+		body:   newOutgoingBody(),
+	}
+}
+
+func (r *OutgoingRequest) takeBody() (*OutgoingBody, error) {
+	if r.bodyTaken {
+		return nil, errors.New("request body already taken")
+	}
+	r.bodyTaken = true
+	return r.body, nil
+}
+
+func (r *OutgoingRequest) setMethod(method HttpMethod) error {
+	m := method.String()
+	if !validHttpToken(m) {
+		return errors.New("invalid method")
+	}
+	r.method = m
+	return nil
+}
+
+func (r *OutgoingRequest) setScheme(scheme Option[HttpScheme]) error {
+	s, ok := scheme.Some()
+	if !ok {
+		r.scheme = ""
+		return nil
+	}
+	if !validUrlScheme(s.String()) {
+		return errors.New("invalid scheme")
+	}
+	r.scheme = s.String()
+	return nil
+}
+
+func (r *OutgoingRequest) setAuthority(authority Option[string]) error {
+	a, _ := authority.Some()
+	if strings.ContainsAny(a, "/?#") || !validHttpRequestTarget(a) {
+		return errors.New("invalid authority")
+	}
+	r.authority = a
+	return nil
+}
+
+func (r *OutgoingRequest) setPathWithQuery(pathWithQuery Option[string]) error {
+	p, _ := pathWithQuery.Some()
+	if !validHttpRequestTarget(p) || (p != "" && p != "*" && p[0] != '/') {
+		return errors.New("invalid path with query")
+	}
+	r.pathWithQuery = p
+	return nil
+}
+
+func validUrlScheme(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && ('0' <= c && c <= '9' || c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func validHttpRequestTarget(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] <= ' ' || s[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+func optionalString(s string) Option[string] {
+	if s == "" {
+		return OptionNone[string]()
+	}
+	return OptionSome(s)
+}
+
+type RequestOptions struct {
+	connectTimeout      Option[Duration]
+	firstByteTimeout    Option[Duration]
+	betweenBytesTimeout Option[Duration]
+}
+
+func newRequestOptions() *RequestOptions {
+	return &RequestOptions{
+		connectTimeout:      OptionNone[Duration](),
+		firstByteTimeout:    OptionNone[Duration](),
+		betweenBytesTimeout: OptionNone[Duration](),
+	}
+}
+
+// ResponseOutparam receives the response to an incoming request. It is set at
+// most once, either with a response or with the error that prevented one.
+type ResponseOutparam struct {
+	once     sync.Once
+	done     chan struct{}
+	response *OutgoingResponse
+	err      error
+}
+
+func newResponseOutparam() *ResponseOutparam {
+	return &ResponseOutparam{
+		done: make(chan struct{}),
+	}
+}
+
+func (p *ResponseOutparam) set(response *OutgoingResponse, err error) {
+	p.once.Do(func() {
+		p.response = response
+		p.err = err
+		close(p.done)
+	})
+}
+
+type IncomingResponse struct {
+	status int
+	header http.Header
+	body   *IncomingBody
+}
+
+func (r *IncomingResponse) consume() (*IncomingBody, error) {
+	if r.body == nil {
+		return nil, errors.New("response body already consumed")
+	}
+	body := r.body
+	r.body = nil
+	return body, nil
+}
+
+func (r *IncomingResponse) Close() error {
+	if r.body != nil {
+		return r.body.Close()
+	}
+	return nil
+}
+
+type OutgoingResponse struct {
+	status    int
+	header    http.Header
+	body      *OutgoingBody
+	bodyTaken bool
+}
+
+func newOutgoingResponse(header http.Header) *OutgoingResponse {
+	return &OutgoingResponse{
+		status: http.StatusOK,
+		header: header,
+		body:   newOutgoingBody(),
+	}
+}
+
+func (r *OutgoingResponse) setStatusCode(status uint16) error {
+	if status < 100 || status > 999 {
+		return errors.New("invalid status code")
+	}
+	r.status = int(status)
+	return nil
+}
+
+func (r *OutgoingResponse) takeBody() (*OutgoingBody, error) {
+	if r.bodyTaken {
+		return nil, errors.New("response body already taken")
+	}
+	r.bodyTaken = true
+	return r.body, nil
+}
+
+// FutureIncomingResponse is the response to an outgoing request, which becomes
+// available once the response headers have been received.
+type FutureIncomingResponse struct {
+	done     chan struct{}
+	response *IncomingResponse
+	err      error
+	taken    bool
+
+	// cancel, if set, abandons the request when the future is dropped before
+	// the response arrives.
+	cancel func()
+}
+
+func newFutureIncomingResponse() *FutureIncomingResponse {
+	return &FutureIncomingResponse{
+		done: make(chan struct{}),
+	}
+}
+
+func (f *FutureIncomingResponse) resolve(response *IncomingResponse, err error) {
+	f.response = response
+	f.err = err
+	close(f.done)
+}
+
+func (f *FutureIncomingResponse) get() Option[Result[Result[host.Own[*IncomingResponse], HttpErrorCode], Void]] {
+	type result = Result[Result[host.Own[*IncomingResponse], HttpErrorCode], Void]
+	select {
+	case <-f.done:
+	default:
+		return OptionNone[result]()
+	}
+	if f.taken {
+		return OptionSome(ResultErr[Result[host.Own[*IncomingResponse], HttpErrorCode]](Void{}))
+	}
+	f.taken = true
+	if f.err != nil {
+		return OptionSome(ResultOk[Void](ResultErr[host.Own[*IncomingResponse]](httpErrorCodeFor(f.err))))
+	}
+	return OptionSome(ResultOk[Void](ResultOk[HttpErrorCode](host.NewOwn(f.response))))
+}
+
+func (f *FutureIncomingResponse) Close() error {
+	select {
+	case <-f.done:
+		if !f.taken && f.response != nil {
+			return f.response.Close()
+		}
+	default:
+		if f.cancel != nil {
+			f.cancel()
+		}
+	}
+	return nil
+}
+
+func CreateHttpTypesInstance(
+	errorInstance *host.Instance,
+	streamsInstance *host.Instance,
+	pollInstance *host.Instance,
+) *host.Instance {
+	hi := host.NewInstance()
+
+	hi.AddTypeExport("duration", host.ValueTypeFor[Duration](hi))
+	hi.AddTypeExport("input-stream", host.ResourceTypeFor[InputStream](hi, streamsInstance))
+	hi.AddTypeExport("output-stream", host.ResourceTypeFor[OutputStream](hi, streamsInstance))
+	hi.AddTypeExport("io-error", host.ResourceTypeFor[IOError](hi, errorInstance))
+	hi.AddTypeExport("pollable", host.ResourceTypeFor[Pollable](hi, pollInstance))
+
+	hi.AddTypeExport("method", host.ValueTypeFor[HttpMethod](hi))
+	hi.AddTypeExport("scheme", host.ValueTypeFor[HttpScheme](hi))
+	hi.AddTypeExport("DNS-error-payload", host.ValueTypeFor[DnsErrorPayload](hi))
+	hi.AddTypeExport("TLS-alert-received-payload", host.ValueTypeFor[TlsAlertReceivedPayload](hi))
+	hi.AddTypeExport("field-size-payload", host.ValueTypeFor[FieldSizePayload](hi))
+	hi.AddTypeExport("error-code", host.ValueTypeFor[HttpErrorCode](hi))
+	hi.AddTypeExport("header-error", host.ValueTypeFor[HeaderError](hi))
+	hi.AddTypeExport("field-key", host.ValueTypeFor[string](hi))
+	hi.AddTypeExport("field-name", host.ValueTypeFor[string](hi))
+	hi.AddTypeExport("field-value", host.ValueTypeFor[[]byte](hi))
+	hi.AddTypeExport("status-code", host.ValueTypeFor[uint16](hi))
+
+	fieldsType := host.ResourceTypeFor[*HttpFields](hi, hi)
+	hi.AddTypeExport("fields", fieldsType)
+	hi.AddTypeExport("headers", fieldsType)
+	hi.AddTypeExport("trailers", fieldsType)
+	hi.AddTypeExport("incoming-request", host.ResourceTypeFor[*IncomingRequest](hi, hi))
+	hi.AddTypeExport("outgoing-request", host.ResourceTypeFor[*OutgoingRequest](hi, hi))
+	hi.AddTypeExport("request-options", host.ResourceTypeFor[*RequestOptions](hi, hi))
+	hi.AddTypeExport("response-outparam", host.ResourceTypeFor[*ResponseOutparam](hi, hi))
+	hi.AddTypeExport("incoming-response", host.ResourceTypeFor[*IncomingResponse](hi, hi))
+	hi.AddTypeExport("incoming-body", host.ResourceTypeFor[*IncomingBody](hi, hi))
+	hi.AddTypeExport("future-trailers", host.ResourceTypeFor[*FutureTrailers](hi, hi))
+	hi.AddTypeExport("outgoing-response", host.ResourceTypeFor[*OutgoingResponse](hi, hi))
+	hi.AddTypeExport("outgoing-body", host.ResourceTypeFor[*OutgoingBody](hi, hi))
+	hi.AddTypeExport("future-incoming-response", host.ResourceTypeFor[*FutureIncomingResponse](hi, hi))
+
+	hi.MustAddFunction("[constructor]fields", func() host.Own[*HttpFields] {
+		return host.NewOwn(newHttpFields(nil, false))
+	})
+	hi.MustAddFunction("[static]fields.from-list", func(entries []Tuple2[string, []byte]) Result[host.Own[*HttpFields], HeaderError] {
+		f, err := httpFieldsFromList(entries)
+		if err != nil {
+			return headerErrorResult(host.Own[*HttpFields]{}, err)
+		}
+		return ResultOk[HeaderError](host.NewOwn(f))
+	})
+	hi.MustAddFunction("[method]fields.get", func(self host.Borrow[*HttpFields], name string) [][]byte {
+		return self.Resource().get(name)
+	})
+	hi.MustAddFunction("[method]fields.has", func(self host.Borrow[*HttpFields], name string) bool {
+		return self.Resource().has(name)
+	})
+	hi.MustAddFunction("[method]fields.set", func(self host.Borrow[*HttpFields], name string, values [][]byte) Result[Void, HeaderError] {
+		return headerErrorResult(Void{}, self.Resource().set(name, values))
+	})
+	hi.MustAddFunction("[method]fields.delete", func(self host.Borrow[*HttpFields], name string) Result[Void, HeaderError] {
+		return headerErrorResult(Void{}, self.Resource().delete(name))
+	})
+	hi.MustAddFunction("[method]fields.append", func(self host.Borrow[*HttpFields], name string, value []byte) Result[Void, HeaderError] {
+		return headerErrorResult(Void{}, self.Resource().append(name, value))
+	})
+	hi.MustAddFunction("[method]fields.entries", func(self host.Borrow[*HttpFields]) []Tuple2[string, []byte] {
+		return self.Resource().entries()
+	})
+	hi.MustAddFunction("[method]fields.clone", func(self host.Borrow[*HttpFields]) host.Own[*HttpFields] {
+		return host.NewOwn(self.Resource().clone())
+	})
+
+	hi.MustAddFunction("[method]incoming-request.method", func(self host.Borrow[*IncomingRequest]) HttpMethod {
+		return NewHttpMethod(self.Resource().method)
+	})
+	hi.MustAddFunction("[method]incoming-request.path-with-query", func(self host.Borrow[*IncomingRequest]) Option[string] {
+		return optionalString(self.Resource().pathWithQuery)
+	})
+	hi.MustAddFunction("[method]incoming-request.scheme", func(self host.Borrow[*IncomingRequest]) Option[HttpScheme] {
+		if s := self.Resource().scheme; s != "" {
+			return OptionSome(NewHttpScheme(s))
+		}
+		return OptionNone[HttpScheme]()
+	})
+	hi.MustAddFunction("[method]incoming-request.authority", func(self host.Borrow[*IncomingRequest]) Option[string] {
+		return optionalString(self.Resource().authority)
+	})
+	hi.MustAddFunction("[method]incoming-request.headers", func(self host.Borrow[*IncomingRequest]) host.Own[*HttpFields] {
+		return host.NewOwn(newHttpFields(self.Resource().header, true))
+	})
+	hi.MustAddFunction("[method]incoming-request.consume", func(self host.Borrow[*IncomingRequest]) Result[host.Own[*IncomingBody], Void] {
+		body, err := self.Resource().consume()
+		if err != nil {
+			return ResultErr[host.Own[*IncomingBody]](Void{})
+		}
+		return ResultOk[Void](host.NewOwn(body))
+	})
+
+	hi.MustAddFunction("[constructor]outgoing-request", func(headers host.Own[*HttpFields]) host.Own[*OutgoingRequest] {
+		return host.NewOwn(newOutgoingRequest(takeHttpFields(headers)))
+	})
+	hi.MustAddFunction("[method]outgoing-request.body", func(self host.Borrow[*OutgoingRequest]) Result[host.Own[*OutgoingBody], Void] {
+		body, err := self.Resource().takeBody()
+		if err != nil {
+			return ResultErr[host.Own[*OutgoingBody]](Void{})
+		}
+		return ResultOk[Void](host.NewOwn(body))
+	})
+	hi.MustAddFunction("[method]outgoing-request.method", func(self host.Borrow[*OutgoingRequest]) HttpMethod {
+		return NewHttpMethod(self.Resource().method)
+	})
+	hi.MustAddFunction("[method]outgoing-request.set-method", func(self host.Borrow[*OutgoingRequest], method HttpMethod) Result[Void, Void] {
+		return voidResult(self.Resource().setMethod(method))
+	})
+	hi.MustAddFunction("[method]outgoing-request.path-with-query", func(self host.Borrow[*OutgoingRequest]) Option[string] {
+		return optionalString(self.Resource().pathWithQuery)
+	})
+	hi.MustAddFunction("[method]outgoing-request.set-path-with-query", func(self host.Borrow[*OutgoingRequest], pathWithQuery Option[string]) Result[Void, Void] {
+		return voidResult(self.Resource().setPathWithQuery(pathWithQuery))
+	})
+	hi.MustAddFunction("[method]outgoing-request.scheme", func(self host.Borrow[*OutgoingRequest]) Option[HttpScheme] {
+		if s := self.Resource().scheme; s != "" {
+			return OptionSome(NewHttpScheme(s))
+		}
+		return OptionNone[HttpScheme]()
+	})
+	hi.MustAddFunction("[method]outgoing-request.set-scheme", func(self host.Borrow[*OutgoingRequest], scheme Option[HttpScheme]) Result[Void, Void] {
+		return voidResult(self.Resource().setScheme(scheme))
+	})
+	hi.MustAddFunction("[method]outgoing-request.authority", func(self host.Borrow[*OutgoingRequest]) Option[string] {
+		return optionalString(self.Resource().authority)
+	})
+	hi.MustAddFunction("[method]outgoing-request.set-authority", func(self host.Borrow[*OutgoingRequest], authority Option[string]) Result[Void, Void] {
+		return voidResult(self.Resource().setAuthority(authority))
+	})
+	hi.MustAddFunction("[method]outgoing-request.headers", func(self host.Borrow[*OutgoingRequest]) host.Own[*HttpFields] {
+		return host.NewOwn(newHttpFields(self.Resource().header, true))
+	})
+
+	hi.MustAddFunction("[constructor]request-options", func() host.Own[*RequestOptions] {
+		return host.NewOwn(newRequestOptions())
+	})
+	hi.MustAddFunction("[method]request-options.connect-timeout", func(self host.Borrow[*RequestOptions]) Option[Duration] {
+		return self.Resource().connectTimeout
+	})
+	hi.MustAddFunction("[method]request-options.set-connect-timeout", func(self host.Borrow[*RequestOptions], d Option[Duration]) Result[Void, Void] {
+		self.Resource().connectTimeout = d
+		return voidResult(nil)
+	})
+	hi.MustAddFunction("[method]request-options.first-byte-timeout", func(self host.Borrow[*RequestOptions]) Option[Duration] {
+		return self.Resource().firstByteTimeout
+	})
+	hi.MustAddFunction("[method]request-options.set-first-byte-timeout", func(self host.Borrow[*RequestOptions], d Option[Duration]) Result[Void, Void] {
+		self.Resource().firstByteTimeout = d
+		return voidResult(nil)
+	})
+	hi.MustAddFunction("[method]request-options.between-bytes-timeout", func(self host.Borrow[*RequestOptions]) Option[Duration] {
+		return self.Resource().betweenBytesTimeout
+	})
+	hi.MustAddFunction("[method]request-options.set-between-bytes-timeout", func(self host.Borrow[*RequestOptions], d Option[Duration]) Result[Void, Void] {
+		self.Resource().betweenBytesTimeout = d
+		return voidResult(nil)
+	})
+
+	hi.MustAddFunction("[static]response-outparam.set", func(param host.Own[*ResponseOutparam], response Result[host.Own[*OutgoingResponse], HttpErrorCode]) {
+		out := param.Resource()
+		param.Drop()
+		if resp, ok := response.Ok(); ok {
+			out.set(resp.Resource(), nil)
+			resp.Drop()
+			return
+		}
+		code, _ := response.Err()
+		out.set(nil, code)
+	})
+
+	hi.MustAddFunction("[method]incoming-response.status", func(self host.Borrow[*IncomingResponse]) uint16 {
+		return uint16(self.Resource().status)
+	})
+	hi.MustAddFunction("[method]incoming-response.headers", func(self host.Borrow[*IncomingResponse]) host.Own[*HttpFields] {
+		return host.NewOwn(newHttpFields(self.Resource().header, true))
+	})
+	hi.MustAddFunction("[method]incoming-response.consume", func(self host.Borrow[*IncomingResponse]) Result[host.Own[*IncomingBody], Void] {
+		body, err := self.Resource().consume()
+		if err != nil {
+			return ResultErr[host.Own[*IncomingBody]](Void{})
+		}
+		return ResultOk[Void](host.NewOwn(body))
+	})
+
+	hi.MustAddFunction("[method]incoming-body.stream", func(self host.Borrow[*IncomingBody]) Result[host.Own[InputStream], Void] {
+		stream, err := self.Resource().stream()
+		if err != nil {
+			return ResultErr[host.Own[InputStream]](Void{})
+		}
+		return ResultOk[Void](host.NewOwn(stream))
+	})
+	hi.MustAddFunction("[static]incoming-body.finish", func(this host.Own[*IncomingBody]) host.Own[*FutureTrailers] {
+		f := this.Resource().finish()
+		this.Drop()
+		return host.NewOwn(f)
+	})
+
+	hi.MustAddFunction("[method]future-trailers.subscribe", func(self host.Borrow[*FutureTrailers]) host.Own[Pollable] {
+		return host.NewOwn[Pollable](NewChanPollable(self.Resource().done))
+	})
+	hi.MustAddFunction("[method]future-trailers.get", func(self host.Borrow[*FutureTrailers]) Option[Result[Result[Option[host.Own[*HttpFields]], HttpErrorCode], Void]] {
+		return self.Resource().get()
+	})
+
+	hi.MustAddFunction("[constructor]outgoing-response", func(headers host.Own[*HttpFields]) host.Own[*OutgoingResponse] {
+		return host.NewOwn(newOutgoingResponse(takeHttpFields(headers)))
+	})
+	hi.MustAddFunction("[method]outgoing-response.status-code", func(self host.Borrow[*OutgoingResponse]) uint16 {
+		return uint16(self.Resource().status)
+	})
+	hi.MustAddFunction("[method]outgoing-response.set-status-code", func(self host.Borrow[*OutgoingResponse], status uint16) Result[Void, Void] {
+		return voidResult(self.Resource().setStatusCode(status))
+	})
+	hi.MustAddFunction("[method]outgoing-response.headers", func(self host.Borrow[*OutgoingResponse]) host.Own[*HttpFields] {
+		return host.NewOwn(newHttpFields(self.Resource().header, true))
+	})
+	hi.MustAddFunction("[method]outgoing-response.body", func(self host.Borrow[*OutgoingResponse]) Result[host.Own[*OutgoingBody], Void] {
+		body, err := self.Resource().takeBody()
+		if err != nil {
+			return ResultErr[host.Own[*OutgoingBody]](Void{})
+		}
+		return ResultOk[Void](host.NewOwn(body))
+	})
+
+	hi.MustAddFunction("[method]outgoing-body.write", func(self host.Borrow[*OutgoingBody]) Result[host.Own[OutputStream], Void] {
+		stream, err := self.Resource().write()
+		if err != nil {
+			return ResultErr[host.Own[OutputStream]](Void{})
+		}
+		return ResultOk[Void](host.NewOwn(stream))
+	})
+	hi.MustAddFunction("[static]outgoing-body.finish", func(this host.Own[*OutgoingBody], trailers Option[host.Own[*HttpFields]]) Result[Void, HttpErrorCode] {
+		var header http.Header
+		if t, ok := trailers.Some(); ok {
+			header = takeHttpFields(t)
+		}
+		err := this.Resource().finish(header)
+		this.Drop()
+		if err != nil {
+			return ResultErr[Void](httpErrorCodeFor(err))
+		}
+		return ResultOk[HttpErrorCode](Void{})
+	})
+
+	hi.MustAddFunction("[method]future-incoming-response.subscribe", func(self host.Borrow[*FutureIncomingResponse]) host.Own[Pollable] {
+		return host.NewOwn[Pollable](NewChanPollable(self.Resource().done))
+	})
+	hi.MustAddFunction("[method]future-incoming-response.get", func(self host.Borrow[*FutureIncomingResponse]) Option[Result[Result[host.Own[*IncomingResponse], HttpErrorCode], Void]] {
+		return self.Resource().get()
+	})
+
+	hi.MustAddFunction("http-error-code", func(err host.Borrow[IOError]) Option[HttpErrorCode] {
+		var code HttpErrorCode
+		if cause := err.Resource().cause; cause != nil && errors.As(cause, &code) {
+			return OptionSome(code)
+		}
+		return OptionNone[HttpErrorCode]()
+	})
+
+	return hi
+}
+
+func voidResult(err error) Result[Void, Void] {
+	if err != nil {
+		return ResultErr[Void](Void{})
+	}
+	return ResultOk[Void](Void{})
+}
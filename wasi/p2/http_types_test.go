@@ -0,0 +1,143 @@
+package p2
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHttpFields(t *testing.T) {
+	f := newHttpFields(nil, false)
+	if err := f.set("Content-Type", [][]byte{[]byte("text/plain")}); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	if err := f.append("x-custom", []byte("a")); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+	if err := f.append("X-Custom", []byte("b")); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+
+	if got := f.get("X-CUSTOM"); len(got) != 2 || string(got[0]) != "a" || string(got[1]) != "b" {
+		t.Errorf("get() = %q, want [a b]", got)
+	}
+	if !f.has("content-type") {
+		t.Error("has() = false for a field that was set")
+	}
+
+	entries := f.entries()
+	want := []string{"content-type: text/plain", "x-custom: a", "x-custom: b"}
+	if len(entries) != len(want) {
+		t.Fatalf("entries() returned %d entries, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		if got := e.A() + ": " + string(e.B()); got != want[i] {
+			t.Errorf("entries()[%d] = %q, want %q", i, got, want[i])
+		}
+	}
+
+	if err := f.delete("x-custom"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if f.has("x-custom") {
+		t.Error("has() = true for a deleted field")
+	}
+
+	tests := []struct {
+		name  string
+		field string
+		value string
+		want  HeaderError
+	}{
+		{"invalid name", "bad name", "x", HeaderErrorInvalidSyntax()},
+		{"invalid value", "x-ok", "a\r\nb", HeaderErrorInvalidSyntax()},
+		{"forbidden", "Connection", "close", HeaderErrorForbidden()},
+	}
+	for _, tt := range tests {
+		err := f.append(tt.field, []byte(tt.value))
+		if err == nil || err.Error() != tt.want.Error() {
+			t.Errorf("%s: append() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	immutable := newHttpFields(f.header, true)
+	if err := immutable.set("x-other", nil); err == nil || err.Error() != "immutable" {
+		t.Errorf("set() on immutable fields = %v, want immutable", err)
+	}
+	clone := immutable.clone()
+	if err := clone.set("x-other", [][]byte{[]byte("1")}); err != nil {
+		t.Errorf("set() on a clone of immutable fields failed: %v", err)
+	}
+	if f.has("x-other") {
+		t.Error("changing a clone changed the original fields")
+	}
+}
+
+func TestHttpFieldsFromList(t *testing.T) {
+	f, err := httpFieldsFromList([]Tuple2[string, []byte]{
+		NewTuple2("accept", []byte("*/*")),
+		NewTuple2("accept", []byte("text/html")),
+	})
+	if err != nil {
+		t.Fatalf("httpFieldsFromList failed: %v", err)
+	}
+	if got := f.get("Accept"); len(got) != 2 {
+		t.Errorf("get() = %q, want two values", got)
+	}
+
+	_, err = httpFieldsFromList([]Tuple2[string, []byte]{NewTuple2("transfer-encoding", []byte("chunked"))})
+	if err == nil || err.Error() != "forbidden" {
+		t.Errorf("httpFieldsFromList() with a forbidden field = %v, want forbidden", err)
+	}
+}
+
+func TestHttpMethodAndScheme(t *testing.T) {
+	for _, m := range []string{http.MethodGet, http.MethodPatch, "PURGE", "get"} {
+		if got := NewHttpMethod(m).String(); got != m {
+			t.Errorf("NewHttpMethod(%q).String() = %q", m, got)
+		}
+	}
+	if label := NewHttpErrorCode("connection-refused").Label(); label != "connection-refused" {
+		t.Errorf("Label() = %q, want connection-refused", label)
+	}
+	if got := NewHttpScheme("HTTPS").String(); got != "https" {
+		t.Errorf("NewHttpScheme(HTTPS).String() = %q, want https", got)
+	}
+	if got := NewHttpScheme("ws").String(); got != "ws" {
+		t.Errorf("NewHttpScheme(ws).String() = %q, want ws", got)
+	}
+}
+
+func TestOutgoingRequest_Setters(t *testing.T) {
+	r := newOutgoingRequest(make(http.Header))
+	if err := r.setMethod(HttpMethodOther("BAD METHOD")); err == nil {
+		t.Error("setMethod() accepted a method that isn't a token")
+	}
+	if err := r.setPathWithQuery(OptionSome("no-slash")); err == nil {
+		t.Error("setPathWithQuery() accepted a path without a leading slash")
+	}
+	if err := r.setPathWithQuery(OptionSome("/search?q=1")); err != nil {
+		t.Errorf("setPathWithQuery failed: %v", err)
+	}
+	if err := r.setAuthority(OptionSome("example.com/path")); err == nil {
+		t.Error("setAuthority() accepted an authority with a path")
+	}
+	if err := r.setScheme(OptionSome(HttpSchemeOther("1bad"))); err == nil {
+		t.Error("setScheme() accepted an invalid scheme")
+	}
+
+	if _, err := r.takeBody(); err != nil {
+		t.Fatalf("takeBody failed: %v", err)
+	}
+	if _, err := r.takeBody(); err == nil {
+		t.Error("takeBody() succeeded twice")
+	}
+}
+
+func TestCreateHttpTypesInstance(t *testing.T) {
+	errorInstance := CreateErrorInstance()
+	pollInstance := CreatePollInstance()
+	streamsInstance := CreateStreamsInstance(errorInstance, pollInstance)
+	if inst := CreateHttpTypesInstance(errorInstance, streamsInstance, pollInstance).Instance(); inst == nil {
+		t.Fatal("CreateHttpTypesInstance returned no instance")
+	}
+}
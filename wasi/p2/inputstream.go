@@ -111,6 +111,17 @@ func NewReaderInputStream(r io.Reader, maxReadSize, bufferSize, nBuffers int) *R
 				}
 				*slcp = (*slcp)[0:bufferSize:bufferSize]
 				n, err := r.Read(*slcp)
+				*slcp = (*slcp)[0:n]
+				// A reader may return the last of its data along with the
+				// error that ends it, so the data has to go out first.
+				if n > 0 || err == nil {
+					select {
+					case <-stream.done:
+						return
+					case pool.written <- buf:
+						stream.notifySubscriptions()
+					}
+				}
 				if err != nil {
 					select {
 					case <-stream.done:
@@ -119,13 +130,6 @@ func NewReaderInputStream(r io.Reader, maxReadSize, bufferSize, nBuffers int) *R
 					}
 					return
 				}
-				*slcp = (*slcp)[0:n]
-				select {
-				case <-stream.done:
-					return
-				case pool.written <- buf:
-					stream.notifySubscriptions()
-				}
 			}
 		}
 	}()
@@ -247,8 +251,15 @@ func (s *ReaderInputStream) Subscribe(fn func()) {
 		fn()
 	default:
 		s.subscriptionsMu.Lock()
-		defer s.subscriptionsMu.Unlock()
+		// A buffer written since the check above has already notified the
+		// subscribers, so fn would never be called.
+		if len(s.bufferPool.written) > 0 {
+			s.subscriptionsMu.Unlock()
+			fn()
+			return
+		}
 		s.subscriptions = append(s.subscriptions, fn)
+		s.subscriptionsMu.Unlock()
 	}
 }
 
@@ -266,9 +277,7 @@ func (s *ReaderInputStream) closeOnErr(err error) {
 	}
 	s.err = err
 	close(s.done)
-	for _, fn := range s.subscriptions {
-		fn()
-	}
+	s.notifySubscriptions()
 	close(s.bufferPool.free)
 }
 
@@ -1,6 +1,7 @@
 package p2
 
 import (
+	"context"
 	"errors"
 	"io"
 
@@ -10,42 +11,7 @@ import (
 
 type IOError struct {
 	DebugString string
-}
-
-type Pollable interface {
-	isReady() bool
-	block()
-}
-
-type AlwaysReadyPollable struct{}
-
-func (AlwaysReadyPollable) isReady() bool {
-	return true
-}
-
-func (AlwaysReadyPollable) block() {}
-
-type ChanPollable[T any] struct {
-	C <-chan T
-}
-
-func (p ChanPollable[T]) isReady() bool {
-	select {
-	case <-p.C:
-		return true
-	default:
-		return false
-	}
-}
-
-func (p ChanPollable[T]) block() {
-	<-p.C
-}
-
-func NewChanPollable[T any](ch <-chan T) ChanPollable[T] {
-	return ChanPollable[T]{
-		C: ch,
-	}
+	cause       error
 }
 
 type StreamError host.Variant[StreamError]
@@ -89,28 +55,6 @@ func CreateErrorInstance() *host.Instance {
 	return hi
 }
 
-func CreatePollInstance() *host.Instance {
-	hi := host.NewInstance()
-
-	hi.AddTypeExport("pollable", host.ResourceTypeFor[Pollable](hi, hi))
-	hi.AddFunction("[method]pollable.ready", func(self host.Borrow[Pollable]) componentmodel.Bool {
-		return componentmodel.Bool(self.Resource().isReady())
-	})
-	hi.AddFunction("[method]pollable.block", func(self host.Borrow[Pollable]) {
-		self.Resource().block()
-	})
-	hi.AddFunction("poll", func(pollables []host.Borrow[Pollable]) []componentmodel.U32 {
-		result := make([]componentmodel.U32, 0, len(pollables))
-		for i := range pollables {
-			if pollables[i].Resource().isReady() {
-				result = append(result, componentmodel.U32(i))
-			}
-		}
-		return result
-	})
-	return hi
-}
-
 func toByteArray(data []byte, err error) (componentmodel.ByteArray, error) {
 	if err != nil {
 		return componentmodel.ByteArray{}, err
@@ -134,7 +78,7 @@ func translateIOResponse[T any](data T, err error) Result[T, StreamError] {
 		}
 		return ResultErr[T](
 			StreamErrorLastOperationFailed(
-				host.NewOwn[IOError](IOError{DebugString: err.Error()}),
+				host.NewOwn[IOError](IOError{DebugString: err.Error(), cause: err}),
 			),
 		)
 	}
@@ -156,22 +100,19 @@ func CreateStreamsInstance(
 	hi.AddFunction("[method]input-stream.read", func(self host.Borrow[InputStream], len componentmodel.U64) Result[componentmodel.ByteArray, StreamError] {
 		return translateIOResponse(toByteArray(self.Resource().Read(uint64(len))))
 	})
-	hi.AddFunction("[method]input-stream.blocking-read", func(self host.Borrow[InputStream], len componentmodel.U64) Result[componentmodel.ByteArray, StreamError] {
-		return translateIOResponse(toByteArray(self.Resource().BlockingRead(uint64(len))))
+	hi.AddFunction("[method]input-stream.blocking-read", func(ctx context.Context, self host.Borrow[InputStream], len componentmodel.U64) Result[componentmodel.ByteArray, StreamError] {
+		waitSubscribed(ctx, self.Resource().Subscribe)
+		return translateIOResponse(toByteArray(self.Resource().Read(uint64(len))))
 	})
 	hi.AddFunction("[method]input-stream.skip", func(self host.Borrow[InputStream], n componentmodel.U64) Result[componentmodel.U64, StreamError] {
 		return translateIOResponse(toU64(self.Resource().Skip(uint64(n))))
 	})
-	hi.AddFunction("[method]input-stream.blocking-skip", func(self host.Borrow[InputStream], n componentmodel.U64) Result[componentmodel.U64, StreamError] {
-		return translateIOResponse(toU64(self.Resource().BlockingSkip(uint64(n))))
+	hi.AddFunction("[method]input-stream.blocking-skip", func(ctx context.Context, self host.Borrow[InputStream], n componentmodel.U64) Result[componentmodel.U64, StreamError] {
+		waitSubscribed(ctx, self.Resource().Subscribe)
+		return translateIOResponse(toU64(self.Resource().Skip(uint64(n))))
 	})
 	hi.AddFunction("[method]input-stream.subscribe", func(self host.Borrow[InputStream]) host.Own[Pollable] {
-		ch := make(chan struct{})
-		p := NewChanPollable(ch)
-		self.Resource().Subscribe(func() {
-			close(ch)
-		})
-		return host.NewOwn[Pollable](p)
+		return host.NewOwn[Pollable](subscribePollable(self.Resource().Subscribe))
 	})
 
 	hi.AddFunction("[method]output-stream.check-write", func(self host.Borrow[OutputStream]) Result[componentmodel.U64, StreamError] {
@@ -182,42 +123,97 @@ func CreateStreamsInstance(
 		return translateIOResponse(Void{}, self.Resource().Write(contents))
 	})
 
-	hi.AddFunction("[method]output-stream.blocking-write-and-flush", func(self host.Borrow[OutputStream], contents componentmodel.ByteArray) Result[Void, StreamError] {
-		return translateIOResponse(Void{}, self.Resource().BlockingWriteAndFlush(contents))
+	hi.AddFunction("[method]output-stream.blocking-write-and-flush", func(ctx context.Context, self host.Borrow[OutputStream], contents componentmodel.ByteArray) Result[Void, StreamError] {
+		return translateIOResponse(Void{}, blockingWriteAndFlush(ctx, self.Resource(), uint64(len(contents)), func(s OutputStream, offset, n uint64) error {
+			return s.Write(contents[offset : offset+n])
+		}))
 	})
 
 	hi.AddFunction("[method]output-stream.flush", func(self host.Borrow[OutputStream]) Result[Void, StreamError] {
 		return translateIOResponse(Void{}, self.Resource().Flush())
 	})
 
-	hi.AddFunction("[method]output-stream.blocking-flush", func(self host.Borrow[OutputStream]) Result[Void, StreamError] {
-		return translateIOResponse(Void{}, self.Resource().BlockingFlush())
+	hi.AddFunction("[method]output-stream.blocking-flush", func(ctx context.Context, self host.Borrow[OutputStream]) Result[Void, StreamError] {
+		return translateIOResponse(Void{}, blockingFlush(ctx, self.Resource()))
 	})
 
 	hi.AddFunction("[method]output-stream.subscribe", func(self host.Borrow[OutputStream]) host.Own[Pollable] {
-		ch := make(chan struct{})
-		p := NewChanPollable(ch)
-		self.Resource().Subscribe(func() {
-			close(ch)
-		})
-		return host.NewOwn[Pollable](p)
+		return host.NewOwn[Pollable](subscribePollable(self.Resource().Subscribe))
 	})
 
 	hi.AddFunction("[method]output-stream.write-zeroes", func(self host.Borrow[OutputStream], n componentmodel.U64) Result[Void, StreamError] {
 		return translateIOResponse(Void{}, self.Resource().WriteZeroes(uint64(n)))
 	})
 
-	hi.AddFunction("[method]output-stream.blocking-write-zeroes-and-flush", func(self host.Borrow[OutputStream], n componentmodel.U64) Result[Void, StreamError] {
-		return translateIOResponse(Void{}, self.Resource().BlockingWriteZeroesAndFlush(uint64(n)))
+	hi.AddFunction("[method]output-stream.blocking-write-zeroes-and-flush", func(ctx context.Context, self host.Borrow[OutputStream], n componentmodel.U64) Result[Void, StreamError] {
+		return translateIOResponse(Void{}, blockingWriteAndFlush(ctx, self.Resource(), uint64(n), func(s OutputStream, offset, n uint64) error {
+			return s.WriteZeroes(n)
+		}))
 	})
 
 	hi.AddFunction("[method]output-stream.splice", func(self host.Borrow[OutputStream], src host.Borrow[InputStream], n componentmodel.U64) Result[componentmodel.U64, StreamError] {
 		return translateIOResponse(toU64(self.Resource().Splice(src.Resource(), uint64(n))))
 	})
 
-	hi.AddFunction("[method]output-stream.blocking-splice", func(self host.Borrow[OutputStream], src host.Borrow[InputStream], n componentmodel.U64) Result[componentmodel.U64, StreamError] {
-		return translateIOResponse(toU64(self.Resource().BlockingSplice(src.Resource(), uint64(n))))
+	hi.AddFunction("[method]output-stream.blocking-splice", func(ctx context.Context, self host.Borrow[OutputStream], src host.Borrow[InputStream], n componentmodel.U64) Result[componentmodel.U64, StreamError] {
+		waitSubscribed(ctx, src.Resource().Subscribe)
+		waitSubscribed(ctx, self.Resource().Subscribe)
+		return translateIOResponse(toU64(self.Resource().Splice(src.Resource(), uint64(n))))
 	})
 
 	return hi
 }
+
+// subscribePollable returns a pollable that becomes ready once a stream calls
+// back the function passed to subscribe.
+func subscribePollable(subscribe func(func())) *ChanPollable[struct{}] {
+	ch := make(chan struct{})
+	subscribe(func() {
+		close(ch)
+	})
+	return NewChanPollable(ch)
+}
+
+// waitSubscribed blocks until a stream calls back the function passed to
+// subscribe, like mustPoll.
+func waitSubscribed(ctx context.Context, subscribe func(func())) {
+	p := subscribePollable(subscribe)
+	defer p.Close()
+	mustPoll(ctx, p)
+}
+
+// The blocking stream functions wait on the stream's readiness rather than
+// calling its blocking methods, so that a guest blocked on a stream is
+// interrupted when the context of its call is done.
+
+// blockingWriteAndFlush writes n bytes to s as it becomes ready, calling write
+// for each chunk the stream accepts, then flushes it.
+func blockingWriteAndFlush(ctx context.Context, s OutputStream, n uint64, write func(s OutputStream, offset, n uint64) error) error {
+	var offset uint64
+	for offset < n {
+		waitSubscribed(ctx, s.Subscribe)
+		writable, err := s.CheckWrite()
+		if err != nil {
+			return err
+		}
+		chunk := min(writable, n-offset)
+		if chunk == 0 {
+			continue
+		}
+		if err := write(s, offset, chunk); err != nil {
+			return err
+		}
+		offset += chunk
+	}
+	return blockingFlush(ctx, s)
+}
+
+// blockingFlush flushes s and waits until the flush has completed.
+func blockingFlush(ctx context.Context, s OutputStream) error {
+	if err := s.Flush(); err != nil {
+		return err
+	}
+	waitSubscribed(ctx, s.Subscribe)
+	_, err := s.CheckWrite()
+	return err
+}
@@ -0,0 +1,75 @@
+package p2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// stalledWriter blocks every write until release is closed.
+type stalledWriter struct {
+	release chan struct{}
+}
+
+func (w *stalledWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return len(p), nil
+}
+
+func expectPollInterrupted(t *testing.T, want error) {
+	t.Helper()
+	r := recover()
+	err, ok := r.(error)
+	var interrupted *errPollInterrupted
+	if !ok || !errors.As(err, &interrupted) || !errors.Is(err, want) {
+		t.Errorf("recovered %v, want a poll interrupted by %v", r, want)
+	}
+}
+
+func TestBlockingWriteAndFlush(t *testing.T) {
+	w := newMockWriter()
+	s := NewWriterOutputStream(w)
+	contents := bytes.Repeat([]byte("abcdefgh"), 1500)
+
+	err := blockingWriteAndFlush(context.Background(), s, uint64(len(contents)), func(s OutputStream, offset, n uint64) error {
+		return s.Write(contents[offset : offset+n])
+	})
+	if err != nil {
+		t.Fatalf("blockingWriteAndFlush failed: %v", err)
+	}
+	if !bytes.Equal(w.buf.Bytes(), contents) {
+		t.Errorf("wrote %d bytes, want %d", w.buf.Len(), len(contents))
+	}
+}
+
+func TestBlockingFlush_Cancelled(t *testing.T) {
+	w := &stalledWriter{release: make(chan struct{})}
+	defer close(w.release)
+	s := NewWriterOutputStream(w)
+	if _, err := s.CheckWrite(); err != nil {
+		t.Fatalf("CheckWrite failed: %v", err)
+	}
+	if err := s.Write([]byte("data")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	defer expectPollInterrupted(t, context.DeadlineExceeded)
+	blockingFlush(ctx, s)
+}
+
+func TestBlockingRead_Cancelled(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	s := NewReaderInputStream(r, 1024, 1024, 1)
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	defer expectPollInterrupted(t, context.Canceled)
+	waitSubscribed(ctx, s.Subscribe)
+}
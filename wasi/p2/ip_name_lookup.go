@@ -1,19 +1,143 @@
 package p2
 
 import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+
 	"github.com/partite-ai/wacogo/componentmodel/host"
 )
 
+// Resolver looks up the IP addresses of a host name for
+// wasi:sockets/ip-name-lookup.
+type Resolver interface {
+	Lookup(ctx context.Context, name string) ([]netip.Addr, error)
+}
+
+// NetResolver adapts a *net.Resolver to Resolver. A nil r uses
+// net.DefaultResolver.
+func NetResolver(r *net.Resolver) Resolver {
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	return netResolver{r: r}
+}
+
+type netResolver struct {
+	r *net.Resolver
+}
+
+func (r netResolver) Lookup(ctx context.Context, name string) ([]netip.Addr, error) {
+	return r.r.LookupNetIP(ctx, "ip", name)
+}
+
+// StaticResolver resolves names from a fixed table, without touching the
+// network. Names that aren't in the table fail with name-unresolvable.
+type StaticResolver map[string][]netip.Addr
+
+func (r StaticResolver) Lookup(ctx context.Context, name string) ([]netip.Addr, error) {
+	addrs, ok := r[strings.ToLower(name)]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
 type ResolveAddressStream struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+	addrs  []netip.Addr
+	err    error
+}
+
+func resolveAddresses(resolver Resolver, name string) (*ResolveAddressStream, error) {
+	if addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(name, "["), "]")); err == nil {
+		done := make(chan struct{})
+		close(done)
+		return &ResolveAddressStream{done: done, cancel: func() {}, addrs: []netip.Addr{addr.Unmap()}}, nil
+	}
+	if !validHostName(name) {
+		return nil, NetworkErrorCode("invalid-argument")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &ResolveAddressStream{
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+	go func() {
+		defer close(s.done)
+		defer cancel()
+		found, err := resolver.Lookup(ctx, name)
+		// The resolver may return a slice it shares, such as the addresses of
+		// a StaticResolver, so the unmapped addresses go in a new one.
+		addrs := make([]netip.Addr, len(found))
+		for i, addr := range found {
+			addrs[i] = addr.Unmap()
+		}
+		s.addrs, s.err = addrs, err
+	}()
+	return s, nil
+}
+
+// validHostName reports whether name is syntactically a DNS name.
+func validHostName(name string) bool {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range []byte(label) {
+			switch {
+			case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (s *ResolveAddressStream) next() (Option[IpAddress], error) {
+	select {
+	case <-s.done:
+	default:
+		return OptionNone[IpAddress](), NetworkErrorCode("would-block")
+	}
+	if err := s.err; err != nil {
+		s.err = nil
+		s.addrs = nil
+		return OptionNone[IpAddress](), err
+	}
+	if len(s.addrs) == 0 {
+		return OptionNone[IpAddress](), nil
+	}
+	addr := s.addrs[0]
+	s.addrs = s.addrs[1:]
+	return OptionSome(NewIpAddress(addr)), nil
+}
+
+func (s *ResolveAddressStream) Close() error {
+	s.cancel()
+	return nil
 }
 
 func CreateIpNameLookupInstance(
 	networkInstance *host.Instance,
 	pollInstance *host.Instance,
+	resolver Resolver,
 ) *host.Instance {
+	if resolver == nil {
+		resolver = NetResolver(nil)
+	}
+
 	hi := host.NewInstance()
 
-	hi.AddTypeExport("resolve-address-stream", host.ResourceTypeFor[ResolveAddressStream](hi, hi))
+	hi.AddTypeExport("resolve-address-stream", host.ResourceTypeFor[*ResolveAddressStream](hi, hi))
 	hi.AddTypeExport("network", host.ResourceTypeFor[Network](hi, networkInstance))
 	hi.AddTypeExport("error-code", host.ValueTypeFor[NetworkErrorCode](hi))
 	hi.AddTypeExport("ip-address", host.ValueTypeFor[IpAddress](hi))
@@ -22,20 +146,27 @@ func CreateIpNameLookupInstance(
 	hi.MustAddFunction("resolve-addresses", func(
 		network host.Borrow[Network],
 		name string,
-	) Result[host.Own[ResolveAddressStream], NetworkErrorCode] {
-		return ResultErr[host.Own[ResolveAddressStream]](NetworkErrorCode("not-supported"))
+	) Result[host.Own[*ResolveAddressStream], NetworkErrorCode] {
+		if err := network.Resource().check(); err != nil {
+			return ResultErr[host.Own[*ResolveAddressStream]](networkErrorCodeFor(err))
+		}
+		s, err := resolveAddresses(resolver, name)
+		if err != nil {
+			return ResultErr[host.Own[*ResolveAddressStream]](networkErrorCodeFor(err))
+		}
+		return ResultOk[NetworkErrorCode](host.NewOwn(s))
 	})
 
 	hi.MustAddFunction("[method]resolve-address-stream.resolve-next-address", func(
-		self host.Borrow[ResolveAddressStream],
+		self host.Borrow[*ResolveAddressStream],
 	) Result[Option[IpAddress], NetworkErrorCode] {
-		return ResultErr[Option[IpAddress]](NetworkErrorCode("not-supported"))
+		return networkResult(self.Resource().next())
 	})
 
 	hi.MustAddFunction("[method]resolve-address-stream.subscribe", func(
-		self host.Borrow[ResolveAddressStream],
+		self host.Borrow[*ResolveAddressStream],
 	) host.Own[Pollable] {
-		return host.NewOwn(Pollable(AlwaysReadyPollable{}))
+		return host.NewOwn(Pollable(NewChanPollable(self.Resource().done)))
 	})
 
 	return hi
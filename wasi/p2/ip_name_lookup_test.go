@@ -0,0 +1,75 @@
+package p2
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func resolveAll(t *testing.T, resolver Resolver, name string) ([]netip.Addr, error) {
+	t.Helper()
+	s, err := resolveAddresses(resolver, name)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	<-s.done
+
+	var addrs []netip.Addr
+	for {
+		addr, err := s.next()
+		if err != nil {
+			return addrs, err
+		}
+		ip, ok := addr.Some()
+		if !ok {
+			return addrs, nil
+		}
+		addrs = append(addrs, ip.ToNetipAddr())
+	}
+}
+
+func TestResolveAddresses(t *testing.T) {
+	resolver := StaticResolver{
+		"example.test": {netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("2001:db8::1")},
+	}
+
+	addrs, err := resolveAll(t, resolver, "Example.test")
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	if len(addrs) != 2 || addrs[0] != resolver["example.test"][0] || addrs[1] != resolver["example.test"][1] {
+		t.Errorf("resolved %v, want %v", addrs, resolver["example.test"])
+	}
+
+	// Unmapping the resolved addresses leaves the resolver's table alone.
+	mapped := netip.MustParseAddr("::ffff:192.0.2.2")
+	resolver["mapped.test"] = []netip.Addr{mapped}
+	addrs, err = resolveAll(t, resolver, "mapped.test")
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	if len(addrs) != 1 || addrs[0] != mapped.Unmap() {
+		t.Errorf("resolved %v, want [%v]", addrs, mapped.Unmap())
+	}
+	if resolver["mapped.test"][0] != mapped {
+		t.Errorf("resolver table changed to %v", resolver["mapped.test"])
+	}
+
+	addrs, err = resolveAll(t, resolver, "[::1]")
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	if len(addrs) != 1 || addrs[0] != netip.IPv6Loopback() {
+		t.Errorf("resolved %v, want [::1]", addrs)
+	}
+
+	_, err = resolveAll(t, resolver, "missing.test")
+	if code := networkErrorCodeFor(err); code != NetworkErrorCode("name-unresolvable") {
+		t.Errorf("resolve of unknown name = %v, want name-unresolvable", err)
+	}
+
+	_, err = resolveAll(t, resolver, "bad name")
+	if code := networkErrorCodeFor(err); code != NetworkErrorCode("invalid-argument") {
+		t.Errorf("resolve of invalid name = %v, want invalid-argument", err)
+	}
+}
@@ -0,0 +1,521 @@
+package p2
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const maxSymlinkFollows = 40
+
+var memFileSystemDevices atomic.Uint64
+
+// MemFileSystem is an in-memory FileSystem. It is safe for concurrent use, so a
+// single tree may be shared between several preopens or instances.
+type MemFileSystem struct {
+	mu      sync.Mutex
+	root    *memNode
+	device  uint64
+	nextIno uint64
+}
+
+type memNode struct {
+	ino      uint64
+	mode     fs.FileMode
+	data     []byte
+	target   string
+	children map[string]*memNode
+	nlink    uint64
+	atime    time.Time
+	mtime    time.Time
+	ctime    time.Time
+}
+
+func NewMemFileSystem() *MemFileSystem {
+	m := &MemFileSystem{
+		device: memFileSystemDevices.Add(1),
+	}
+	m.root = m.newNode(fs.ModeDir | 0o777)
+	return m
+}
+
+// WriteFile creates or replaces the file name with data, creating any missing
+// parent directories.
+func (m *MemFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if dir := path.Dir(name); dir != "." {
+		if err := m.MkdirAll(dir, 0o777); err != nil {
+			return err
+		}
+	}
+	f, err := m.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteAt(data, 0)
+	return err
+}
+
+// MkdirAll creates the directory name along with any missing parents.
+func (m *MemFileSystem) MkdirAll(name string, perm fs.FileMode) error {
+	parts := strings.Split(path.Clean(name), "/")
+	for i := range parts {
+		err := m.Mkdir(strings.Join(parts[:i+1], "/"), perm)
+		if err != nil && !os.IsExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemFileSystem) newNode(mode fs.FileMode) *memNode {
+	m.nextIno++
+	now := time.Now()
+	n := &memNode{
+		ino:   m.nextIno,
+		mode:  mode,
+		nlink: 1,
+		atime: now,
+		mtime: now,
+		ctime: now,
+	}
+	if mode.IsDir() {
+		n.children = make(map[string]*memNode)
+	}
+	return n
+}
+
+// resolve walks name from the root, following symlinks in every component but
+// the last, which is only followed if follow is set. It returns the directory
+// holding the final component, the component's name and its node, which is nil
+// if it does not exist. A nil directory means name refers to the root itself.
+func (m *MemFileSystem) resolve(op string, name string, follow bool) (*memNode, string, *memNode, error) {
+	for range maxSymlinkFollows {
+		name = path.Clean(name)
+		if name == ".." || strings.HasPrefix(name, "../") {
+			return nil, "", nil, &fs.PathError{Op: op, Path: name, Err: errPathEscapes}
+		}
+		if name == "." {
+			return nil, ".", m.root, nil
+		}
+
+		parts := strings.Split(name, "/")
+		dir := m.root
+		for i, part := range parts {
+			child := dir.children[part]
+			last := i == len(parts)-1
+			if child != nil && child.mode&fs.ModeSymlink != 0 && (!last || follow) {
+				target := child.target
+				if path.IsAbs(target) {
+					target = "." + target
+				} else {
+					target = path.Join(strings.Join(parts[:i], "/"), target)
+				}
+				name = path.Join(append([]string{target}, parts[i+1:]...)...)
+				break
+			}
+			if last {
+				return dir, part, child, nil
+			}
+			if child == nil {
+				return nil, "", nil, &fs.PathError{Op: op, Path: name, Err: syscall.ENOENT}
+			}
+			if !child.mode.IsDir() {
+				return nil, "", nil, &fs.PathError{Op: op, Path: name, Err: syscall.ENOTDIR}
+			}
+			dir = child
+		}
+	}
+	return nil, "", nil, &fs.PathError{Op: op, Path: name, Err: syscall.ELOOP}
+}
+
+func (m *MemFileSystem) lookup(op string, name string, follow bool) (*memNode, error) {
+	_, _, node, err := m.resolve(op, name, follow)
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: syscall.ENOENT}
+	}
+	return node, nil
+}
+
+func (m *MemFileSystem) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir, base, node, err := m.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	switch {
+	case node == nil && flag&os.O_CREATE == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.ENOENT}
+	case node == nil:
+		node = m.newNode(perm & fs.ModePerm)
+		dir.children[base] = node
+		dir.mtime = node.mtime
+	case flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EEXIST}
+	case node.mode.IsDir() && (writable || flag&os.O_TRUNC != 0):
+		return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+	}
+
+	if flag&os.O_TRUNC != 0 {
+		node.data = nil
+		node.mtime = time.Now()
+	}
+
+	return &memFile{
+		m:     m,
+		node:  node,
+		name:  path.Base(name),
+		read:  flag&os.O_WRONLY == 0,
+		write: writable,
+	}, nil
+}
+
+func (m *MemFileSystem) Stat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	node, err := m.lookup("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return m.fileInfo(path.Base(name), node), nil
+}
+
+func (m *MemFileSystem) Lstat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	node, err := m.lookup("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	return m.fileInfo(path.Base(name), node), nil
+}
+
+func (m *MemFileSystem) Mkdir(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	dir, base, node, err := m.resolve("mkdir", name, false)
+	if err != nil {
+		return err
+	}
+	if dir == nil || node != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: syscall.EEXIST}
+	}
+	node = m.newNode(fs.ModeDir | perm&fs.ModePerm)
+	dir.children[base] = node
+	dir.mtime = node.mtime
+	return nil
+}
+
+func (m *MemFileSystem) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	dir, base, node, err := m.resolve("remove", name, false)
+	if err != nil {
+		return err
+	}
+	if dir == nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: syscall.EBUSY}
+	}
+	if node == nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: syscall.ENOENT}
+	}
+	if node.mode.IsDir() && len(node.children) > 0 {
+		return &fs.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+	}
+	delete(dir.children, base)
+	node.nlink--
+	now := time.Now()
+	node.ctime = now
+	dir.mtime = now
+	return nil
+}
+
+func (m *MemFileSystem) Rename(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	linkErr := func(err syscall.Errno) error {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+
+	oldDir, oldBase, node, err := m.resolve("rename", oldname, false)
+	if err != nil {
+		return err
+	}
+	newDir, newBase, existing, err := m.resolve("rename", newname, false)
+	if err != nil {
+		return err
+	}
+	if oldDir == nil || newDir == nil {
+		return linkErr(syscall.EBUSY)
+	}
+	if node == nil {
+		return linkErr(syscall.ENOENT)
+	}
+	if existing == node {
+		return nil
+	}
+	if node.mode.IsDir() && (newDir == node || node.contains(newDir)) {
+		return linkErr(syscall.EINVAL)
+	}
+	if existing != nil {
+		switch {
+		case node.mode.IsDir() && !existing.mode.IsDir():
+			return linkErr(syscall.ENOTDIR)
+		case !node.mode.IsDir() && existing.mode.IsDir():
+			return linkErr(syscall.EISDIR)
+		case existing.mode.IsDir() && len(existing.children) > 0:
+			return linkErr(syscall.ENOTEMPTY)
+		}
+		existing.nlink--
+	}
+
+	delete(oldDir.children, oldBase)
+	newDir.children[newBase] = node
+	now := time.Now()
+	node.ctime = now
+	oldDir.mtime = now
+	newDir.mtime = now
+	return nil
+}
+
+func (n *memNode) contains(other *memNode) bool {
+	for _, child := range n.children {
+		if child == other || (child.mode.IsDir() && child.contains(other)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemFileSystem) Link(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	node, err := m.lookup("link", oldname, false)
+	if err != nil {
+		return err
+	}
+	if node.mode.IsDir() {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: syscall.EPERM}
+	}
+	dir, base, existing, err := m.resolve("link", newname, false)
+	if err != nil {
+		return err
+	}
+	if dir == nil || existing != nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: syscall.EEXIST}
+	}
+	dir.children[base] = node
+	node.nlink++
+	now := time.Now()
+	node.ctime = now
+	dir.mtime = now
+	return nil
+}
+
+func (m *MemFileSystem) Symlink(target, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	dir, base, existing, err := m.resolve("symlink", newname, false)
+	if err != nil {
+		return err
+	}
+	if dir == nil || existing != nil {
+		return &os.LinkError{Op: "symlink", Old: target, New: newname, Err: syscall.EEXIST}
+	}
+	node := m.newNode(fs.ModeSymlink | 0o777)
+	node.target = target
+	dir.children[base] = node
+	dir.mtime = node.mtime
+	return nil
+}
+
+func (m *MemFileSystem) Readlink(name string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	node, err := m.lookup("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if node.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: syscall.EINVAL}
+	}
+	return node.target, nil
+}
+
+func (m *MemFileSystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	node, err := m.lookup("chtimes", name, true)
+	if err != nil {
+		return err
+	}
+	if !atime.IsZero() {
+		node.atime = atime
+	}
+	if !mtime.IsZero() {
+		node.mtime = mtime
+	}
+	node.ctime = time.Now()
+	return nil
+}
+
+func (m *MemFileSystem) fileInfo(name string, node *memNode) *memFileInfo {
+	return &memFileInfo{
+		name: name,
+		mode: node.mode,
+		size: int64(len(node.data)),
+		sys: &fileSysStat{
+			linkCount:  node.nlink,
+			accessTime: node.atime,
+			changeTime: node.ctime,
+			device:     m.device,
+			inode:      node.ino,
+		},
+		mtime: node.mtime,
+	}
+}
+
+type memFileInfo struct {
+	name  string
+	mode  fs.FileMode
+	size  int64
+	mtime time.Time
+	sys   *fileSysStat
+}
+
+func (fi *memFileInfo) Name() string       { return fi.name }
+func (fi *memFileInfo) Size() int64        { return fi.size }
+func (fi *memFileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi *memFileInfo) ModTime() time.Time { return fi.mtime }
+func (fi *memFileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *memFileInfo) Sys() any           { return fi.sys }
+
+type memFile struct {
+	m       *MemFileSystem
+	node    *memNode
+	name    string
+	read    bool
+	write   bool
+	entries []fs.DirEntry
+	listed  bool
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	f.m.mu.Lock()
+	defer f.m.mu.Unlock()
+	if !f.read {
+		return 0, syscall.EBADF
+	}
+	if f.node.mode.IsDir() {
+		return 0, syscall.EISDIR
+	}
+	if off < 0 {
+		return 0, syscall.EINVAL
+	}
+	f.node.atime = time.Now()
+	if off >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *memFile) WriteAt(p []byte, off int64) (int, error) {
+	f.m.mu.Lock()
+	defer f.m.mu.Unlock()
+	if !f.write {
+		return 0, syscall.EBADF
+	}
+	if off < 0 {
+		return 0, syscall.EINVAL
+	}
+	end := off + int64(len(p))
+	if end > int64(len(f.node.data)) {
+		size := len(f.node.data)
+		f.node.data = slices.Grow(f.node.data, int(end)-size)[:end]
+		// The reused capacity may hold bytes cut off by an earlier truncate,
+		// so the gap before a write past the end is cleared.
+		clear(f.node.data[size:max(off, int64(size))])
+	}
+	copy(f.node.data[off:], p)
+	f.node.mtime = time.Now()
+	return len(p), nil
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	f.m.mu.Lock()
+	defer f.m.mu.Unlock()
+	return f.m.fileInfo(f.name, f.node), nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	f.m.mu.Lock()
+	defer f.m.mu.Unlock()
+	if !f.write {
+		return syscall.EBADF
+	}
+	if size < 0 {
+		return syscall.EINVAL
+	}
+	if size > int64(len(f.node.data)) {
+		f.node.data = slices.Grow(f.node.data, int(size)-len(f.node.data))
+	}
+	clear(f.node.data[min(size, int64(len(f.node.data))):size])
+	f.node.data = f.node.data[:size]
+	f.node.mtime = time.Now()
+	return nil
+}
+
+func (f *memFile) Sync() error {
+	return nil
+}
+
+func (f *memFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	f.m.mu.Lock()
+	defer f.m.mu.Unlock()
+	if !f.node.mode.IsDir() {
+		return nil, syscall.ENOTDIR
+	}
+	if !f.listed {
+		f.listed = true
+		for name, child := range f.node.children {
+			f.entries = append(f.entries, fs.FileInfoToDirEntry(f.m.fileInfo(name, child)))
+		}
+		slices.SortFunc(f.entries, func(a, b fs.DirEntry) int {
+			return strings.Compare(a.Name(), b.Name())
+		})
+	}
+	if n <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(f.entries))
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+	return entries, nil
+}
@@ -1,15 +1,35 @@
 package p2
 
 import (
+	"errors"
 	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"syscall"
 
 	"github.com/partite-ai/wacogo/componentmodel"
 	"github.com/partite-ai/wacogo/componentmodel/host"
 )
 
+// Network is the handle guests pass to socket operations. Operations on a
+// network that doesn't allow access fail with access-denied.
 type Network struct {
+	allowed bool
 }
 
+func (n Network) check() error {
+	if !n.allowed {
+		return NetworkErrorCode("access-denied")
+	}
+	return nil
+}
+
+const (
+	defaultSocketBufferSize = 64 * 1024
+	maxSocketBufferSize     = 16 * 1024 * 1024
+)
+
 type NetworkErrorCode host.Enum[NetworkErrorCode]
 
 func (NetworkErrorCode) EnumValues() []string {
@@ -147,6 +167,29 @@ func IpAddressIPV6(addr IpV6Address) IpAddress {
 	)
 }
 
+func (v IpAddress) IPV4() (IpV4Address, bool) {
+	return host.VariantCast[IpV4Address](v, "ipv4")
+}
+
+func (v IpAddress) IPV6() (IpV6Address, bool) {
+	return host.VariantCast[IpV6Address](v, "ipv6")
+}
+
+func NewIpAddress(addr netip.Addr) IpAddress {
+	if addr.Is4() {
+		return IpAddressIPV4(NewIpV4Address(addr.AsSlice()))
+	}
+	return IpAddressIPV6(NewIpV6Address(addr.AsSlice()))
+}
+
+func (v IpAddress) ToNetipAddr() netip.Addr {
+	if a, ok := v.IPV4(); ok {
+		return netip.AddrFrom4([4]byte(a.ToNetIP().To4()))
+	}
+	a, _ := v.IPV6()
+	return netip.AddrFrom16([16]byte(a.ToNetIP()))
+}
+
 type IpV4SocketAddress host.Record[struct {
 	Port    host.RecordField[IpV4SocketAddress, uint16]
 	Address host.RecordField[IpV4SocketAddress, IpV4Address]
@@ -199,6 +242,156 @@ func IpSocketAddressIPV6(addr IpV6SocketAddress) IpSocketAddress {
 	)
 }
 
+func (v IpSocketAddress) IPV4() (IpV4SocketAddress, bool) {
+	return host.VariantCast[IpV4SocketAddress](v, "ipv4")
+}
+
+func (v IpSocketAddress) IPV6() (IpV6SocketAddress, bool) {
+	return host.VariantCast[IpV6SocketAddress](v, "ipv6")
+}
+
+func (v IpSocketAddress) Family() IpAddressFamily {
+	if _, ok := v.IPV4(); ok {
+		return IpAddressFamily("ipv4")
+	}
+	return IpAddressFamily("ipv6")
+}
+
+func NewIpSocketAddress(addrPort netip.AddrPort) IpSocketAddress {
+	addr := addrPort.Addr()
+	if addr.Is4() {
+		return IpSocketAddressIPV4(NewIpV4SocketAddress(addrPort.Port(), NewIpV4Address(addr.AsSlice())))
+	}
+	var scopeID uint32
+	if zone := addr.Zone(); zone != "" {
+		if id, err := strconv.ParseUint(zone, 10, 32); err == nil {
+			scopeID = uint32(id)
+		} else if iface, err := net.InterfaceByName(zone); err == nil {
+			scopeID = uint32(iface.Index)
+		}
+	}
+	return IpSocketAddressIPV6(NewIpV6SocketAddress(addrPort.Port(), 0, NewIpV6Address(addr.AsSlice()), scopeID))
+}
+
+func (v IpSocketAddress) ToNetipAddrPort() netip.AddrPort {
+	if a, ok := v.IPV4(); ok {
+		ip := a.Fields.Address.Get(a).ToNetIP().To4()
+		return netip.AddrPortFrom(netip.AddrFrom4([4]byte(ip)), a.Fields.Port.Get(a))
+	}
+	a, _ := v.IPV6()
+	addr := netip.AddrFrom16([16]byte(a.Fields.Address.Get(a).ToNetIP()))
+	if scopeID := a.Fields.ScopeID.Get(a); scopeID != 0 {
+		addr = addr.WithZone(strconv.FormatUint(uint64(scopeID), 10))
+	}
+	return netip.AddrPortFrom(addr, a.Fields.Port.Get(a))
+}
+
+// validateSocketAddress checks that addr can be used with a socket of the given
+// family. IPv4-mapped IPv6 addresses are rejected, as required by wasi:sockets.
+func validateSocketAddress(family IpAddressFamily, addr IpSocketAddress) (netip.AddrPort, error) {
+	if addr.Family() != family {
+		return netip.AddrPort{}, NetworkErrorCode("invalid-argument")
+	}
+	addrPort := addr.ToNetipAddrPort()
+	if addrPort.Addr().Is4In6() {
+		return netip.AddrPort{}, NetworkErrorCode("invalid-argument")
+	}
+	return addrPort, nil
+}
+
+// validateRemoteAddress checks that addr can be used as the peer of a socket of
+// the given family. Unlike validateSocketAddress it accepts IPv4-mapped IPv6
+// addresses, through which a dual-stack ipv6 socket reaches IPv4 peers.
+func validateRemoteAddress(family IpAddressFamily, addr IpSocketAddress) (netip.AddrPort, error) {
+	if addr.Family() != family {
+		return netip.AddrPort{}, NetworkErrorCode("invalid-argument")
+	}
+	return addr.ToNetipAddrPort(), nil
+}
+
+// unmapAddrPort strips the IPv4-mapped prefix from addr, so that an IPv4 peer
+// compares equal however it is addressed.
+func unmapAddrPort(addr netip.AddrPort) netip.AddrPort {
+	return netip.AddrPortFrom(addr.Addr().Unmap(), addr.Port())
+}
+
+func (e NetworkErrorCode) Error() string {
+	return string(e)
+}
+
+func networkErrorCodeFor(err error) NetworkErrorCode {
+	var code NetworkErrorCode
+	if errors.As(err, &code) {
+		return code
+	}
+
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		if code, ok := errnoNetworkErrorCodes[errno]; ok {
+			return code
+		}
+	}
+
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &dnsErr):
+		switch {
+		case dnsErr.IsNotFound:
+			return NetworkErrorCode("name-unresolvable")
+		case dnsErr.IsTemporary, dnsErr.IsTimeout:
+			return NetworkErrorCode("temporary-resolver-failure")
+		default:
+			return NetworkErrorCode("permanent-resolver-failure")
+		}
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return NetworkErrorCode("timeout")
+	case errors.Is(err, net.ErrClosed):
+		return NetworkErrorCode("invalid-state")
+	case errors.Is(err, errors.ErrUnsupported):
+		return NetworkErrorCode("not-supported")
+	}
+
+	return NetworkErrorCode("unknown")
+}
+
+var errnoNetworkErrorCodes = map[syscall.Errno]NetworkErrorCode{
+	syscall.EACCES:        "access-denied",
+	syscall.EPERM:         "access-denied",
+	syscall.EAFNOSUPPORT:  "not-supported",
+	syscall.EOPNOTSUPP:    "not-supported",
+	syscall.EINVAL:        "invalid-argument",
+	syscall.ENOMEM:        "out-of-memory",
+	syscall.ENOBUFS:       "out-of-memory",
+	syscall.ETIMEDOUT:     "timeout",
+	syscall.EALREADY:      "concurrency-conflict",
+	syscall.EAGAIN:        "would-block",
+	syscall.EISCONN:       "invalid-state",
+	syscall.ENOTCONN:      "invalid-state",
+	syscall.EMFILE:        "new-socket-limit",
+	syscall.ENFILE:        "new-socket-limit",
+	syscall.EADDRNOTAVAIL: "address-not-bindable",
+	syscall.EADDRINUSE:    "address-in-use",
+	syscall.ENETUNREACH:   "remote-unreachable",
+	syscall.EHOSTUNREACH:  "remote-unreachable",
+	syscall.ENETDOWN:      "remote-unreachable",
+	syscall.ECONNREFUSED:  "connection-refused",
+	syscall.ECONNRESET:    "connection-reset",
+	syscall.EPIPE:         "connection-reset",
+	syscall.ECONNABORTED:  "connection-aborted",
+	syscall.EMSGSIZE:      "datagram-too-large",
+}
+
+func networkResult[T any](v T, err error) Result[T, NetworkErrorCode] {
+	if err != nil {
+		return ResultErr[T](networkErrorCodeFor(err))
+	}
+	return ResultOk[NetworkErrorCode](v)
+}
+
+func networkVoidResult(err error) Result[Void, NetworkErrorCode] {
+	return networkResult(Void{}, err)
+}
+
 func CreateNetworkInstance() *host.Instance {
 	hi := host.NewInstance()
 
@@ -215,15 +408,19 @@ func CreateNetworkInstance() *host.Instance {
 	return hi
 }
 
+// CreateInstanceNetworkInstance creates wasi:sockets/instance-network. Unless
+// allowNetwork is set, the network it hands out denies all socket and name
+// lookup operations.
 func CreateInstanceNetworkInstance(
 	networkInstance *host.Instance,
+	allowNetwork bool,
 ) *host.Instance {
 	hi := host.NewInstance()
 
 	hi.AddTypeExport("network", host.ResourceTypeFor[Network](hi, networkInstance))
 
 	hi.MustAddFunction("instance-network", func() host.Own[Network] {
-		return host.NewOwn(Network{})
+		return host.NewOwn(Network{allowed: allowNetwork})
 	})
 
 	return hi
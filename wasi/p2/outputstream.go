@@ -153,6 +153,13 @@ func (s *WriterOutputStream) Subscribe(fn func()) {
 	}
 
 	s.subscriptionsMu.Lock()
+	// A buffer freed since the check above has already notified the
+	// subscribers, so fn would never be called.
+	if len(s.bufferPool.free) > 0 {
+		s.subscriptionsMu.Unlock()
+		fn()
+		return
+	}
 	s.subscriptions = append(s.subscriptions, fn)
 	s.subscriptionsMu.Unlock()
 }
@@ -0,0 +1,286 @@
+package p2
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/partite-ai/wacogo/componentmodel"
+	"github.com/partite-ai/wacogo/componentmodel/host"
+)
+
+// Pollable is a source of readiness for wasi:io/poll. Host libraries implement
+// it to let guests wait on their own resources alongside the standard ones.
+//
+// Ready reports whether the pollable is ready. When it isn't, it also returns a
+// channel that is closed once the readiness may have changed, after which Ready
+// is called again. Ready must not block, and a pollable must stay ready once it
+// has reported ready until the guest acts on it.
+//
+// A pollable that also implements io.Closer is closed when the guest drops it,
+// which lets it release whatever Ready started waiting on.
+type Pollable interface {
+	Ready() (bool, <-chan struct{})
+}
+
+type AlwaysReadyPollable struct{}
+
+func (AlwaysReadyPollable) Ready() (bool, <-chan struct{}) {
+	return true, nil
+}
+
+// ChanPollable becomes ready once C yields a value or is closed, and stays ready
+// from then on. Close stops it waiting on C.
+type ChanPollable[T any] struct {
+	C      <-chan T
+	mu     sync.Mutex
+	fired  bool
+	closed bool
+	done   chan struct{}
+	stop   chan struct{}
+}
+
+func NewChanPollable[T any](ch <-chan T) *ChanPollable[T] {
+	return &ChanPollable[T]{
+		C: ch,
+	}
+}
+
+func (p *ChanPollable[T]) Ready() (bool, <-chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fired {
+		return true, nil
+	}
+	if p.done == nil {
+		select {
+		case <-p.C:
+			p.fired = true
+			return true, nil
+		default:
+		}
+		p.done = make(chan struct{})
+		if p.closed {
+			return false, p.done
+		}
+		p.stop = make(chan struct{})
+		go func(done, stop chan struct{}) {
+			select {
+			case <-p.C:
+			case <-stop:
+				return
+			}
+			p.mu.Lock()
+			p.fired = true
+			p.mu.Unlock()
+			close(done)
+		}(p.done, p.stop)
+	}
+	return false, p.done
+}
+
+// Close releases the goroutine that Ready starts to wait on C. The pollable
+// never becomes ready after Close unless it already was.
+func (p *ChanPollable[T]) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		p.closed = true
+		if p.stop != nil {
+			close(p.stop)
+		}
+	}
+	return nil
+}
+
+// DeadlinePollable becomes ready once its clock reaches the deadline. It
+// waits on a system timer for the time remaining, and reads the clock again
+// when the timer fires.
+type DeadlinePollable struct {
+	clock    Clock
+	deadline time.Time
+	mu       sync.Mutex
+	expired  chan struct{}
+}
+
+func NewDeadlinePollable(deadline time.Time) *DeadlinePollable {
+	return NewClockDeadlinePollable(SystemClock, deadline)
+}
+
+// NewClockDeadlinePollable returns a pollable that becomes ready once clock
+// reaches deadline.
+func NewClockDeadlinePollable(clock Clock, deadline time.Time) *DeadlinePollable {
+	return &DeadlinePollable{
+		clock:    clock,
+		deadline: deadline,
+	}
+}
+
+func (p *DeadlinePollable) Deadline() time.Time {
+	return p.deadline
+}
+
+func (p *DeadlinePollable) Ready() (bool, <-chan struct{}) {
+	d := p.deadline.Sub(p.clock.Now())
+	if d <= 0 {
+		return true, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.expired == nil {
+		expired := make(chan struct{})
+		p.expired = expired
+		time.AfterFunc(d, func() {
+			p.mu.Lock()
+			p.expired = nil
+			p.mu.Unlock()
+			close(expired)
+		})
+	}
+	return false, p.expired
+}
+
+// CondPollable is ready whenever cond returns true. cond is evaluated with C.L
+// held, and whoever changes its outcome must hold C.L and call C.Broadcast.
+// Close stops it waiting on C.
+type CondPollable struct {
+	C    *sync.Cond
+	cond func() bool
+
+	mu      sync.Mutex
+	waiting chan struct{}
+	closed  bool // guarded by C.L
+}
+
+func NewCondPollable(c *sync.Cond, cond func() bool) *CondPollable {
+	return &CondPollable{
+		C:    c,
+		cond: cond,
+	}
+}
+
+func (p *CondPollable) Ready() (bool, <-chan struct{}) {
+	p.C.L.Lock()
+	ready := p.cond()
+	p.C.L.Unlock()
+	if ready {
+		return true, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.waiting == nil {
+		waiting := make(chan struct{})
+		p.waiting = waiting
+		go func() {
+			p.C.L.Lock()
+			for !p.cond() && !p.closed {
+				p.C.Wait()
+			}
+			p.C.L.Unlock()
+
+			p.mu.Lock()
+			p.waiting = nil
+			p.mu.Unlock()
+			close(waiting)
+		}()
+	}
+	return false, p.waiting
+}
+
+// Close releases the goroutine that Ready starts to wait on C.
+func (p *CondPollable) Close() error {
+	p.C.L.Lock()
+	p.closed = true
+	p.C.L.Unlock()
+	p.C.Broadcast()
+	return nil
+}
+
+// Poll returns the indices of the pollables that are ready, blocking until at
+// least one of them is. It returns the context's error if ctx is done first.
+func Poll(ctx context.Context, pollables ...Pollable) ([]uint32, error) {
+	var cases []reflect.SelectCase
+	for {
+		var readyIndices []uint32
+		cases = append(cases[:0], reflect.SelectCase{
+			Dir:  reflect.SelectRecv,
+			Chan: reflect.ValueOf(ctx.Done()),
+		})
+		for i, p := range pollables {
+			ready, changed := p.Ready()
+			if ready {
+				readyIndices = append(readyIndices, uint32(i))
+			} else if readyIndices == nil {
+				cases = append(cases, reflect.SelectCase{
+					Dir:  reflect.SelectRecv,
+					Chan: reflect.ValueOf(changed),
+				})
+			}
+		}
+		if readyIndices != nil {
+			return readyIndices, nil
+		}
+
+		if chosen, _, _ := reflect.Select(cases); chosen == 0 {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// errPollInterrupted is raised as a trap when a blocking poll is interrupted by
+// the cancellation of the calling context.
+type errPollInterrupted struct {
+	cause error
+}
+
+func (e *errPollInterrupted) Error() string {
+	return "wasi:io/poll: blocking poll interrupted: " + e.cause.Error()
+}
+
+func (e *errPollInterrupted) Unwrap() error {
+	return e.cause
+}
+
+func mustPoll(ctx context.Context, pollables ...Pollable) []uint32 {
+	indices, err := Poll(ctx, pollables...)
+	if err != nil {
+		panic(&errPollInterrupted{cause: err})
+	}
+	return indices
+}
+
+func CreatePollInstance() *host.Instance {
+	hi := host.NewInstance()
+
+	hi.AddTypeExport("pollable", host.ResourceTypeWithDestructor(hi, func(ctx context.Context, p Pollable) {
+		if c, ok := p.(io.Closer); ok {
+			c.Close()
+		}
+	}))
+	hi.AddFunction("[method]pollable.ready", func(self host.Borrow[Pollable]) componentmodel.Bool {
+		ready, _ := self.Resource().Ready()
+		return componentmodel.Bool(ready)
+	})
+	hi.AddFunction("[method]pollable.block", func(ctx context.Context, self host.Borrow[Pollable]) {
+		mustPoll(ctx, self.Resource())
+	})
+	hi.AddFunction("poll", func(ctx context.Context, pollables []host.Borrow[Pollable]) []componentmodel.U32 {
+		if len(pollables) == 0 {
+			panic("poll called with an empty list of pollables")
+		}
+		ps := make([]Pollable, len(pollables))
+		for i := range pollables {
+			ps[i] = pollables[i].Resource()
+		}
+		indices := mustPoll(ctx, ps...)
+		result := make([]componentmodel.U32, len(indices))
+		for i, idx := range indices {
+			result[i] = componentmodel.U32(idx)
+		}
+		return result
+	})
+	return hi
+}
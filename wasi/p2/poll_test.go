@@ -0,0 +1,154 @@
+package p2
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPoll_BlocksUntilReady(t *testing.T) {
+	ch := make(chan struct{})
+	pollables := []Pollable{
+		NewDeadlinePollable(time.Now().Add(time.Hour)),
+		NewChanPollable(ch),
+	}
+	time.AfterFunc(10*time.Millisecond, func() { close(ch) })
+
+	indices, err := Poll(context.Background(), pollables...)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(indices) != 1 || indices[0] != 1 {
+		t.Errorf("Poll() = %v, want [1]", indices)
+	}
+}
+
+func TestPoll_Deadline(t *testing.T) {
+	start := time.Now()
+	p := NewDeadlinePollable(start.Add(20 * time.Millisecond))
+	if ready, _ := p.Ready(); ready {
+		t.Fatal("deadline pollable ready before its deadline")
+	}
+	if _, err := Poll(context.Background(), p); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Poll() returned after %v, before the deadline", elapsed)
+	}
+}
+
+// offsetClock runs a fixed duration ahead of the system clock.
+type offsetClock time.Duration
+
+func (c offsetClock) Now() time.Time {
+	return time.Now().Add(time.Duration(c))
+}
+
+func TestPoll_ClockDeadline(t *testing.T) {
+	clock := offsetClock(time.Hour)
+	p := NewClockDeadlinePollable(clock, clock.Now().Add(20*time.Millisecond))
+	if ready, _ := p.Ready(); ready {
+		t.Fatal("deadline pollable ready before its deadline")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := Poll(ctx, p); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+
+	far := NewClockDeadlinePollable(clock, clock.Now().Add(saturatedDuration(math.MaxUint64)))
+	if ready, _ := far.Ready(); ready {
+		t.Error("deadline pollable for the longest duration is ready")
+	}
+}
+
+func TestChanPollable_StaysReady(t *testing.T) {
+	p := NewChanPollable(time.After(time.Millisecond))
+	if _, err := Poll(context.Background(), p); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	for range 2 {
+		if ready, _ := p.Ready(); !ready {
+			t.Fatal("ChanPollable should stay ready once its channel has fired")
+		}
+	}
+}
+
+func TestChanPollable_Close(t *testing.T) {
+	ch := make(chan struct{})
+	p := NewChanPollable(ch)
+	if ready, _ := p.Ready(); ready {
+		t.Fatal("ChanPollable ready before its channel has fired")
+	}
+	p.Close()
+	time.Sleep(10 * time.Millisecond)
+
+	// Nothing receives from the channel once the waiting goroutine has exited.
+	select {
+	case ch <- struct{}{}:
+		t.Error("ChanPollable still waits on its channel after Close")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestPoll_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := Poll(ctx, NewChanPollable(make(chan struct{})))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Poll() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	defer func() {
+		r := recover()
+		err, ok := r.(error)
+		if !ok || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("mustPoll() panicked with %v, want an error wrapping %v", r, context.DeadlineExceeded)
+		}
+	}()
+	mustPoll(ctx, NewChanPollable(make(chan struct{})))
+}
+
+func TestCondPollable(t *testing.T) {
+	var mu sync.Mutex
+	cond := sync.NewCond(&mu)
+	queued := 0
+	p := NewCondPollable(cond, func() bool { return queued > 0 })
+
+	if ready, _ := p.Ready(); ready {
+		t.Fatal("CondPollable ready before its condition holds")
+	}
+	time.AfterFunc(10*time.Millisecond, func() {
+		mu.Lock()
+		queued++
+		mu.Unlock()
+		cond.Broadcast()
+	})
+
+	indices, err := Poll(context.Background(), NewDeadlinePollable(time.Now().Add(time.Hour)), p)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(indices) != 1 || indices[0] != 1 {
+		t.Errorf("Poll() = %v, want [1]", indices)
+	}
+}
+
+func TestCondPollable_Close(t *testing.T) {
+	var mu sync.Mutex
+	p := NewCondPollable(sync.NewCond(&mu), func() bool { return false })
+	ready, waiting := p.Ready()
+	if ready {
+		t.Fatal("CondPollable ready before its condition holds")
+	}
+	p.Close()
+	select {
+	case <-waiting:
+	case <-time.After(time.Second):
+		t.Fatal("CondPollable still waits on its condition after Close")
+	}
+}
@@ -3,16 +3,23 @@ package p2
 import (
 	"crypto/rand"
 	"encoding/binary"
+	"io"
 
 	"github.com/partite-ai/wacogo/componentmodel"
 	"github.com/partite-ai/wacogo/componentmodel/host"
 )
 
-func CreateRandomInstance() *host.Instance {
+// CreateRandomInstance creates wasi:random/random reading from r, or from
+// crypto/rand if r is nil.
+func CreateRandomInstance(r io.Reader) *host.Instance {
+	if r == nil {
+		r = rand.Reader
+	}
+
 	hi := host.NewInstance()
 	hi.AddFunction("get-random-bytes", func(n componentmodel.U64) componentmodel.ByteArray {
 		buf := make([]byte, n)
-		_, err := rand.Read(buf)
+		_, err := io.ReadFull(r, buf)
 		if err != nil {
 			panic("failed to read random bytes: " + err.Error())
 		}
@@ -20,7 +27,7 @@ func CreateRandomInstance() *host.Instance {
 	})
 	hi.AddFunction("get-random-u64", func() componentmodel.U64 {
 		var bytes [8]byte
-		_, err := rand.Read(bytes[:])
+		_, err := io.ReadFull(r, bytes[:])
 		if err != nil {
 			panic("failed to read random u64: " + err.Error())
 		}
@@ -29,11 +36,17 @@ func CreateRandomInstance() *host.Instance {
 	return hi
 }
 
-func CreateInsecureRandomInstance() *host.Instance {
+// CreateInsecureRandomInstance creates wasi:random/insecure reading from r, or
+// from crypto/rand if r is nil.
+func CreateInsecureRandomInstance(r io.Reader) *host.Instance {
+	if r == nil {
+		r = rand.Reader
+	}
+
 	hi := host.NewInstance()
 	hi.AddFunction("get-insecure-random-bytes", func(n componentmodel.U64) componentmodel.ByteArray {
 		buf := make([]byte, n)
-		_, err := rand.Read(buf)
+		_, err := io.ReadFull(r, buf)
 		if err != nil {
 			panic("failed to read random bytes: " + err.Error())
 		}
@@ -41,7 +54,7 @@ func CreateInsecureRandomInstance() *host.Instance {
 	})
 	hi.AddFunction("get-insecure-random-u64", func() componentmodel.U64 {
 		var bytes [8]byte
-		_, err := rand.Read(bytes[:])
+		_, err := io.ReadFull(r, bytes[:])
 		if err != nil {
 			panic("failed to read random u64: " + err.Error())
 		}
@@ -50,11 +63,17 @@ func CreateInsecureRandomInstance() *host.Instance {
 	return hi
 }
 
-func CreateInsecureSeedInstance() *host.Instance {
+// CreateInsecureSeedInstance creates wasi:random/insecure-seed reading from r,
+// or from crypto/rand if r is nil.
+func CreateInsecureSeedInstance(r io.Reader) *host.Instance {
+	if r == nil {
+		r = rand.Reader
+	}
+
 	hi := host.NewInstance()
 	hi.AddFunction("insecure-seed", func() Tuple2[componentmodel.U64, componentmodel.U64] {
 		var bytes [16]byte
-		_, err := rand.Read(bytes[:])
+		_, err := io.ReadFull(r, bytes[:])
 		if err != nil {
 			panic("failed to read random u64: " + err.Error())
 		}
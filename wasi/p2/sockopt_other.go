@@ -0,0 +1,12 @@
+//go:build !unix
+
+package p2
+
+import (
+	"errors"
+	"syscall"
+)
+
+func setHopLimit(c syscall.RawConn, family IpAddressFamily, value uint8) error {
+	return errors.ErrUnsupported
+}
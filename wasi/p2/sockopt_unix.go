@@ -0,0 +1,19 @@
+//go:build unix
+
+package p2
+
+import "syscall"
+
+func setHopLimit(c syscall.RawConn, family IpAddressFamily, value uint8) error {
+	var err error
+	if cerr := c.Control(func(fd uintptr) {
+		if family == IpAddressFamily("ipv4") {
+			err = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TTL, int(value))
+		} else {
+			err = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, int(value))
+		}
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
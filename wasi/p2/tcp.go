@@ -1,6 +1,15 @@
 package p2
 
-import "github.com/partite-ai/wacogo/componentmodel/host"
+import (
+	"context"
+	"math"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/partite-ai/wacogo/componentmodel/host"
+)
 
 type ShutdownType host.Enum[ShutdownType]
 
@@ -12,7 +21,483 @@ func (ShutdownType) EnumValues() []string {
 	}
 }
 
+type tcpState int
+
+const (
+	tcpStateUnbound tcpState = iota
+	tcpStateBindStarted
+	tcpStateBound
+	tcpStateListenStarted
+	tcpStateListening
+	tcpStateConnectStarted
+	tcpStateConnected
+	tcpStateClosed
+)
+
+const defaultTcpListenBacklog = 128
+
+// TcpSocket implements the wasi:sockets/tcp state machine on top of the net
+// package. A bound socket is held as a raw socket that is not yet accepting
+// connections; listening and connecting both happen on that same socket.
 type TcpSocket struct {
+	mu      sync.Mutex
+	changed chan struct{}
+	family  IpAddressFamily
+	state   tcpState
+
+	binding  *tcpBinding
+	bindErr  error
+	listener *net.TCPListener
+
+	conn          *net.TCPConn
+	connectDone   chan struct{}
+	connectErr    error
+	cancelConnect context.CancelFunc
+
+	accepted  chan *net.TCPConn
+	acceptErr error
+
+	backlog           uint64
+	keepAlive         net.KeepAliveConfig
+	hopLimit          uint8
+	receiveBufferSize uint64
+	sendBufferSize    uint64
+}
+
+func newTcpSocket(family IpAddressFamily) *TcpSocket {
+	return &TcpSocket{
+		changed: make(chan struct{}),
+		family:  family,
+		backlog: defaultTcpListenBacklog,
+		keepAlive: net.KeepAliveConfig{
+			Idle:     2 * time.Hour,
+			Interval: 75 * time.Second,
+			Count:    9,
+		},
+	}
+}
+
+func (s *TcpSocket) network() string {
+	if s.family == IpAddressFamily("ipv4") {
+		return "tcp4"
+	}
+	return "tcp6"
+}
+
+// signal wakes up everything blocked on a state change. Must be called with mu held.
+func (s *TcpSocket) signal() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *TcpSocket) startBind(localAddress IpSocketAddress) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkNotInProgress(); err != nil {
+		return err
+	}
+	if s.state != tcpStateUnbound {
+		return NetworkErrorCode("invalid-state")
+	}
+	addrPort, err := validateSocketAddress(s.family, localAddress)
+	if err != nil {
+		return err
+	}
+
+	s.state = tcpStateBindStarted
+	b, err := bindTcp(s.network(), addrPort)
+	if err != nil {
+		s.bindErr = err
+		return nil
+	}
+	s.binding = b
+	return nil
+}
+
+func (s *TcpSocket) finishBind() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != tcpStateBindStarted {
+		return NetworkErrorCode("not-in-progress")
+	}
+	if err := s.bindErr; err != nil {
+		s.bindErr = nil
+		s.state = tcpStateUnbound
+		return err
+	}
+	s.state = tcpStateBound
+	return nil
+}
+
+func (s *TcpSocket) startConnect(remoteAddress IpSocketAddress) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkNotInProgress(); err != nil {
+		return err
+	}
+	if s.state != tcpStateUnbound && s.state != tcpStateBound {
+		return NetworkErrorCode("invalid-state")
+	}
+	addrPort, err := validateSocketAddress(s.family, remoteAddress)
+	if err != nil {
+		return err
+	}
+	if addrPort.Addr().IsUnspecified() || addrPort.Port() == 0 {
+		return NetworkErrorCode("invalid-argument")
+	}
+
+	binding := s.binding
+	s.binding = nil
+	dialer := &net.Dialer{
+		KeepAlive:       -1,
+		KeepAliveConfig: s.keepAlive,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.state = tcpStateConnectStarted
+	s.cancelConnect = cancel
+	s.connectDone = make(chan struct{})
+	go func() {
+		defer cancel()
+		var c net.Conn
+		var err error
+		if binding != nil {
+			c, err = binding.connect(ctx, addrPort)
+		} else {
+			c, err = dialer.DialContext(ctx, s.network(), addrPort.String())
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		defer close(s.connectDone)
+		defer s.signal()
+		if err != nil {
+			s.connectErr = err
+			return
+		}
+		if s.state == tcpStateClosed {
+			c.Close()
+			return
+		}
+		s.conn = c.(*net.TCPConn)
+		s.connectErr = s.applyConnOptions(s.conn)
+	}()
+	return nil
+}
+
+func (s *TcpSocket) finishConnect() (InputStream, OutputStream, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != tcpStateConnectStarted {
+		return nil, nil, NetworkErrorCode("not-in-progress")
+	}
+	select {
+	case <-s.connectDone:
+	default:
+		return nil, nil, NetworkErrorCode("would-block")
+	}
+	if err := s.connectErr; err != nil {
+		s.state = tcpStateClosed
+		if s.conn != nil {
+			s.conn.Close()
+		}
+		return nil, nil, err
+	}
+	s.state = tcpStateConnected
+	in, out := tcpStreams(s.conn)
+	return in, out, nil
+}
+
+func (s *TcpSocket) startListen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkNotInProgress(); err != nil {
+		return err
+	}
+	if s.state != tcpStateBound {
+		return NetworkErrorCode("invalid-state")
+	}
+	s.state = tcpStateListenStarted
+	return nil
+}
+
+func (s *TcpSocket) finishListen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != tcpStateListenStarted {
+		return NetworkErrorCode("not-in-progress")
+	}
+	l, err := s.binding.listen(int(min(s.backlog, math.MaxInt32)))
+	if err != nil {
+		s.state = tcpStateBound
+		return err
+	}
+	s.binding = nil
+	s.listener = l
+	s.state = tcpStateListening
+	// Pending connections queue up in the kernel, sized by the backlog; the
+	// channel only hands them over from the accept loop one at a time.
+	s.accepted = make(chan *net.TCPConn, 1)
+	go s.acceptLoop(s.listener, s.accepted)
+	return nil
+}
+
+func (s *TcpSocket) acceptLoop(l *net.TCPListener, accepted chan<- *net.TCPConn) {
+	defer close(accepted)
+	for {
+		c, err := l.AcceptTCP()
+		if err != nil {
+			s.mu.Lock()
+			s.acceptErr = err
+			s.signal()
+			s.mu.Unlock()
+			return
+		}
+		accepted <- c
+		s.mu.Lock()
+		s.signal()
+		s.mu.Unlock()
+	}
+}
+
+func (s *TcpSocket) accept() (*TcpSocket, InputStream, OutputStream, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != tcpStateListening {
+		return nil, nil, nil, NetworkErrorCode("invalid-state")
+	}
+
+	var c *net.TCPConn
+	select {
+	case c = <-s.accepted:
+	default:
+	}
+	if c == nil {
+		if s.acceptErr != nil {
+			return nil, nil, nil, s.acceptErr
+		}
+		return nil, nil, nil, NetworkErrorCode("would-block")
+	}
+
+	child := newTcpSocket(s.family)
+	child.state = tcpStateConnected
+	child.conn = c
+	child.keepAlive = s.keepAlive
+	child.hopLimit = s.hopLimit
+	child.receiveBufferSize = s.receiveBufferSize
+	child.sendBufferSize = s.sendBufferSize
+	if err := child.applyConnOptions(c); err != nil {
+		c.Close()
+		return nil, nil, nil, err
+	}
+	in, out := tcpStreams(c)
+	return child, in, out, nil
+}
+
+func (s *TcpSocket) checkNotInProgress() error {
+	switch s.state {
+	case tcpStateBindStarted, tcpStateListenStarted, tcpStateConnectStarted:
+		return NetworkErrorCode("concurrency-conflict")
+	}
+	return nil
+}
+
+func (s *TcpSocket) applyConnOptions(c *net.TCPConn) error {
+	if err := c.SetKeepAliveConfig(s.keepAlive); err != nil {
+		return err
+	}
+	if s.receiveBufferSize != 0 {
+		if err := c.SetReadBuffer(int(s.receiveBufferSize)); err != nil {
+			return err
+		}
+	}
+	if s.sendBufferSize != 0 {
+		if err := c.SetWriteBuffer(int(s.sendBufferSize)); err != nil {
+			return err
+		}
+	}
+	if s.hopLimit != 0 {
+		raw, err := c.SyscallConn()
+		if err != nil {
+			return err
+		}
+		if err := setHopLimit(raw, s.family, s.hopLimit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *TcpSocket) localAddress() (IpSocketAddress, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var addr net.Addr
+	switch {
+	case s.binding != nil && s.state != tcpStateBindStarted:
+		addr = s.binding.localAddr()
+	case s.listener != nil:
+		addr = s.listener.Addr()
+	case s.conn != nil && s.state == tcpStateConnected:
+		addr = s.conn.LocalAddr()
+	default:
+		return IpSocketAddress{}, NetworkErrorCode("invalid-state")
+	}
+	return s.socketAddress(addr), nil
+}
+
+func (s *TcpSocket) remoteAddress() (IpSocketAddress, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != tcpStateConnected {
+		return IpSocketAddress{}, NetworkErrorCode("invalid-state")
+	}
+	return s.socketAddress(s.conn.RemoteAddr()), nil
+}
+
+func (s *TcpSocket) socketAddress(addr net.Addr) IpSocketAddress {
+	addrPort := addr.(*net.TCPAddr).AddrPort()
+	if s.family == IpAddressFamily("ipv4") {
+		addrPort = netip.AddrPortFrom(addrPort.Addr().Unmap(), addrPort.Port())
+	}
+	return NewIpSocketAddress(addrPort)
+}
+
+func (s *TcpSocket) isListening() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state == tcpStateListening
+}
+
+func (s *TcpSocket) setListenBacklogSize(size uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if size == 0 {
+		return NetworkErrorCode("invalid-argument")
+	}
+	switch s.state {
+	case tcpStateListenStarted, tcpStateListening:
+		return NetworkErrorCode("not-supported")
+	case tcpStateConnectStarted, tcpStateConnected:
+		return NetworkErrorCode("invalid-state")
+	}
+	s.backlog = size
+	return nil
+}
+
+// setOption updates an option and applies it to the live connection, if any.
+func (s *TcpSocket) setOption(update func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update()
+	if s.state == tcpStateConnected {
+		return s.applyConnOptions(s.conn)
+	}
+	return nil
+}
+
+func (s *TcpSocket) shutdown(shutdownType ShutdownType) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != tcpStateConnected {
+		return NetworkErrorCode("invalid-state")
+	}
+	if shutdownType != ShutdownType("send") {
+		if err := s.conn.CloseRead(); err != nil {
+			return err
+		}
+	}
+	if shutdownType != ShutdownType("receive") {
+		if err := s.conn.CloseWrite(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *TcpSocket) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = tcpStateClosed
+	if s.cancelConnect != nil {
+		s.cancelConnect()
+	}
+	if s.binding != nil {
+		s.binding.Close()
+		s.binding = nil
+	}
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+	}
+	if s.accepted != nil {
+		go func(accepted <-chan *net.TCPConn) {
+			for c := range accepted {
+				c.Close()
+			}
+		}(s.accepted)
+		s.accepted = nil
+	}
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.signal()
+	return nil
+}
+
+// tcpPollable is ready once a pending connect has completed or a listening
+// socket has a connection to accept. In any other state it is always ready.
+type tcpPollable struct {
+	s *TcpSocket
+}
+
+func (p tcpPollable) Ready() (bool, <-chan struct{}) {
+	p.s.mu.Lock()
+	defer p.s.mu.Unlock()
+	switch p.s.state {
+	case tcpStateConnectStarted:
+		select {
+		case <-p.s.connectDone:
+			return true, nil
+		default:
+		}
+	case tcpStateListening:
+		if len(p.s.accepted) > 0 || p.s.acceptErr != nil {
+			return true, nil
+		}
+	default:
+		return true, nil
+	}
+	return false, p.s.changed
+}
+
+// tcpReader and tcpWriter shut down their half of the connection when the
+// corresponding stream is dropped.
+type tcpReader struct {
+	c *net.TCPConn
+}
+
+func (r tcpReader) Read(p []byte) (int, error) {
+	return r.c.Read(p)
+}
+
+func (r tcpReader) Close() error {
+	return r.c.CloseRead()
+}
+
+type tcpWriter struct {
+	c *net.TCPConn
+}
+
+func (w tcpWriter) Write(p []byte) (int, error) {
+	return w.c.Write(p)
+}
+
+func (w tcpWriter) Close() error {
+	return w.c.CloseWrite()
+}
+
+func tcpStreams(c *net.TCPConn) (InputStream, OutputStream) {
+	return NewReaderInputStream(tcpReader{c: c}, 65536, 8192, 8), NewWriterOutputStream(tcpWriter{c: c})
 }
 
 func CreateTcpInstance(
@@ -22,7 +507,7 @@ func CreateTcpInstance(
 ) *host.Instance {
 	hi := host.NewInstance()
 
-	hi.AddTypeExport("tcp-socket", host.ResourceTypeFor[TcpSocket](hi, hi))
+	hi.AddTypeExport("tcp-socket", host.ResourceTypeFor[*TcpSocket](hi, hi))
 	hi.AddTypeExport("network", host.ResourceTypeFor[Network](hi, networkInstance))
 	hi.AddTypeExport("error-code", host.ValueTypeFor[NetworkErrorCode](hi))
 	hi.AddTypeExport("input-stream", host.ResourceTypeFor[InputStream](hi, streamsInstance))
@@ -33,116 +518,185 @@ func CreateTcpInstance(
 	hi.AddTypeExport("pollable", host.ResourceTypeFor[Pollable](hi, pollInstance))
 	hi.AddTypeExport("shutdown-type", host.ValueTypeFor[ShutdownType](hi))
 
-	hi.MustAddFunction("[method]tcp-socket.start-bind", func(self host.Borrow[TcpSocket], network host.Borrow[Network], localAddress IpSocketAddress) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.start-bind", func(self host.Borrow[*TcpSocket], network host.Borrow[Network], localAddress IpSocketAddress) Result[Void, NetworkErrorCode] {
+		if err := network.Resource().check(); err != nil {
+			return networkVoidResult(err)
+		}
+		return networkVoidResult(self.Resource().startBind(localAddress))
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.finish-bind", func(self host.Borrow[TcpSocket]) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.finish-bind", func(self host.Borrow[*TcpSocket]) Result[Void, NetworkErrorCode] {
+		return networkVoidResult(self.Resource().finishBind())
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.start-connect", func(self host.Borrow[TcpSocket], network host.Borrow[Network], remoteAddress IpSocketAddress) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.start-connect", func(self host.Borrow[*TcpSocket], network host.Borrow[Network], remoteAddress IpSocketAddress) Result[Void, NetworkErrorCode] {
+		if err := network.Resource().check(); err != nil {
+			return networkVoidResult(err)
+		}
+		return networkVoidResult(self.Resource().startConnect(remoteAddress))
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.finish-connect", func(self host.Borrow[TcpSocket]) Result[Tuple2[host.Own[InputStream], host.Own[OutputStream]], NetworkErrorCode] {
-		return ResultErr[Tuple2[host.Own[InputStream], host.Own[OutputStream]]](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.finish-connect", func(self host.Borrow[*TcpSocket]) Result[Tuple2[host.Own[InputStream], host.Own[OutputStream]], NetworkErrorCode] {
+		in, out, err := self.Resource().finishConnect()
+		if err != nil {
+			return ResultErr[Tuple2[host.Own[InputStream], host.Own[OutputStream]]](networkErrorCodeFor(err))
+		}
+		return ResultOk[NetworkErrorCode](NewTuple2(host.NewOwn(in), host.NewOwn(out)))
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.start-listen", func(self host.Borrow[TcpSocket]) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.start-listen", func(self host.Borrow[*TcpSocket]) Result[Void, NetworkErrorCode] {
+		return networkVoidResult(self.Resource().startListen())
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.finish-listen", func(self host.Borrow[TcpSocket]) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.finish-listen", func(self host.Borrow[*TcpSocket]) Result[Void, NetworkErrorCode] {
+		return networkVoidResult(self.Resource().finishListen())
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.accept", func(self host.Borrow[TcpSocket]) Result[Tuple3[host.Own[TcpSocket], host.Own[InputStream], host.Own[OutputStream]], NetworkErrorCode] {
-		return ResultErr[Tuple3[host.Own[TcpSocket], host.Own[InputStream], host.Own[OutputStream]]](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.accept", func(self host.Borrow[*TcpSocket]) Result[Tuple3[host.Own[*TcpSocket], host.Own[InputStream], host.Own[OutputStream]], NetworkErrorCode] {
+		s, in, out, err := self.Resource().accept()
+		if err != nil {
+			return ResultErr[Tuple3[host.Own[*TcpSocket], host.Own[InputStream], host.Own[OutputStream]]](networkErrorCodeFor(err))
+		}
+		return ResultOk[NetworkErrorCode](NewTuple3(host.NewOwn(s), host.NewOwn(in), host.NewOwn(out)))
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.local-address", func(self host.Borrow[TcpSocket]) Result[IpSocketAddress, NetworkErrorCode] {
-		return ResultErr[IpSocketAddress](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.local-address", func(self host.Borrow[*TcpSocket]) Result[IpSocketAddress, NetworkErrorCode] {
+		return networkResult(self.Resource().localAddress())
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.remote-address", func(self host.Borrow[TcpSocket]) Result[IpSocketAddress, NetworkErrorCode] {
-		return ResultErr[IpSocketAddress](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.remote-address", func(self host.Borrow[*TcpSocket]) Result[IpSocketAddress, NetworkErrorCode] {
+		return networkResult(self.Resource().remoteAddress())
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.is-listening", func(self host.Borrow[TcpSocket]) bool {
-		return false
+	hi.MustAddFunction("[method]tcp-socket.is-listening", func(self host.Borrow[*TcpSocket]) bool {
+		return self.Resource().isListening()
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.address-family", func(self host.Borrow[TcpSocket]) IpAddressFamily {
-		return IpAddressFamily("ipv4")
+	hi.MustAddFunction("[method]tcp-socket.address-family", func(self host.Borrow[*TcpSocket]) IpAddressFamily {
+		return self.Resource().family
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.set-listen-backlog-size", func(self host.Borrow[TcpSocket], size uint64) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.set-listen-backlog-size", func(self host.Borrow[*TcpSocket], size uint64) Result[Void, NetworkErrorCode] {
+		return networkVoidResult(self.Resource().setListenBacklogSize(size))
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.keep-alive-enabled", func(self host.Borrow[TcpSocket]) Result[bool, NetworkErrorCode] {
-		return ResultErr[bool](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.keep-alive-enabled", func(self host.Borrow[*TcpSocket]) Result[bool, NetworkErrorCode] {
+		s := self.Resource()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return ResultOk[NetworkErrorCode](s.keepAlive.Enable)
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.set-keep-alive-enabled", func(self host.Borrow[TcpSocket], value bool) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.set-keep-alive-enabled", func(self host.Borrow[*TcpSocket], value bool) Result[Void, NetworkErrorCode] {
+		s := self.Resource()
+		return networkVoidResult(s.setOption(func() { s.keepAlive.Enable = value }))
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.keep-alive-idle-time", func(self host.Borrow[TcpSocket]) Result[Duration, NetworkErrorCode] {
-		return ResultErr[Duration](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.keep-alive-idle-time", func(self host.Borrow[*TcpSocket]) Result[Duration, NetworkErrorCode] {
+		s := self.Resource()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return ResultOk[NetworkErrorCode](Duration(s.keepAlive.Idle))
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.set-keep-alive-idle-time", func(self host.Borrow[TcpSocket], value Duration) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.set-keep-alive-idle-time", func(self host.Borrow[*TcpSocket], value Duration) Result[Void, NetworkErrorCode] {
+		if value == 0 {
+			return ResultErr[Void](NetworkErrorCode("invalid-argument"))
+		}
+		s := self.Resource()
+		return networkVoidResult(s.setOption(func() { s.keepAlive.Idle = max(time.Duration(value), time.Second) }))
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.keep-alive-interval", func(self host.Borrow[TcpSocket]) Result[Duration, NetworkErrorCode] {
-		return ResultErr[Duration](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.keep-alive-interval", func(self host.Borrow[*TcpSocket]) Result[Duration, NetworkErrorCode] {
+		s := self.Resource()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return ResultOk[NetworkErrorCode](Duration(s.keepAlive.Interval))
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.set-keep-alive-interval", func(self host.Borrow[TcpSocket], value Duration) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.set-keep-alive-interval", func(self host.Borrow[*TcpSocket], value Duration) Result[Void, NetworkErrorCode] {
+		if value == 0 {
+			return ResultErr[Void](NetworkErrorCode("invalid-argument"))
+		}
+		s := self.Resource()
+		return networkVoidResult(s.setOption(func() { s.keepAlive.Interval = max(time.Duration(value), time.Second) }))
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.keep-alive-count", func(self host.Borrow[TcpSocket]) Result[uint32, NetworkErrorCode] {
-		return ResultErr[uint32](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.keep-alive-count", func(self host.Borrow[*TcpSocket]) Result[uint32, NetworkErrorCode] {
+		s := self.Resource()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return ResultOk[NetworkErrorCode](uint32(s.keepAlive.Count))
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.set-keep-alive-count", func(self host.Borrow[TcpSocket], value uint32) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.set-keep-alive-count", func(self host.Borrow[*TcpSocket], value uint32) Result[Void, NetworkErrorCode] {
+		if value == 0 {
+			return ResultErr[Void](NetworkErrorCode("invalid-argument"))
+		}
+		s := self.Resource()
+		return networkVoidResult(s.setOption(func() { s.keepAlive.Count = int(min(value, 127)) }))
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.hop-limit", func(self host.Borrow[TcpSocket]) Result[uint8, NetworkErrorCode] {
-		return ResultErr[uint8](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.hop-limit", func(self host.Borrow[*TcpSocket]) Result[uint8, NetworkErrorCode] {
+		s := self.Resource()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.hopLimit == 0 {
+			return ResultOk[NetworkErrorCode](uint8(64))
+		}
+		return ResultOk[NetworkErrorCode](s.hopLimit)
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.set-hop-limit", func(self host.Borrow[TcpSocket], value uint8) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.set-hop-limit", func(self host.Borrow[*TcpSocket], value uint8) Result[Void, NetworkErrorCode] {
+		if value == 0 {
+			return ResultErr[Void](NetworkErrorCode("invalid-argument"))
+		}
+		s := self.Resource()
+		return networkVoidResult(s.setOption(func() { s.hopLimit = value }))
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.receive-buffer-size", func(self host.Borrow[TcpSocket]) Result[uint64, NetworkErrorCode] {
-		return ResultErr[uint64](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.receive-buffer-size", func(self host.Borrow[*TcpSocket]) Result[uint64, NetworkErrorCode] {
+		s := self.Resource()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.receiveBufferSize == 0 {
+			return ResultOk[NetworkErrorCode](uint64(defaultSocketBufferSize))
+		}
+		return ResultOk[NetworkErrorCode](s.receiveBufferSize)
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.set-receive-buffer-size", func(self host.Borrow[TcpSocket], value uint64) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.set-receive-buffer-size", func(self host.Borrow[*TcpSocket], value uint64) Result[Void, NetworkErrorCode] {
+		if value == 0 {
+			return ResultErr[Void](NetworkErrorCode("invalid-argument"))
+		}
+		s := self.Resource()
+		return networkVoidResult(s.setOption(func() { s.receiveBufferSize = min(value, maxSocketBufferSize) }))
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.send-buffer-size", func(self host.Borrow[TcpSocket]) Result[uint64, NetworkErrorCode] {
-		return ResultErr[uint64](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.send-buffer-size", func(self host.Borrow[*TcpSocket]) Result[uint64, NetworkErrorCode] {
+		s := self.Resource()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.sendBufferSize == 0 {
+			return ResultOk[NetworkErrorCode](uint64(defaultSocketBufferSize))
+		}
+		return ResultOk[NetworkErrorCode](s.sendBufferSize)
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.set-send-buffer-size", func(self host.Borrow[TcpSocket], value uint64) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.set-send-buffer-size", func(self host.Borrow[*TcpSocket], value uint64) Result[Void, NetworkErrorCode] {
+		if value == 0 {
+			return ResultErr[Void](NetworkErrorCode("invalid-argument"))
+		}
+		s := self.Resource()
+		return networkVoidResult(s.setOption(func() { s.sendBufferSize = min(value, maxSocketBufferSize) }))
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.subscribe", func(self host.Borrow[TcpSocket]) host.Own[Pollable] {
-		return host.NewOwn[Pollable](AlwaysReadyPollable{})
+	hi.MustAddFunction("[method]tcp-socket.subscribe", func(self host.Borrow[*TcpSocket]) host.Own[Pollable] {
+		return host.NewOwn[Pollable](tcpPollable{s: self.Resource()})
 	})
 
-	hi.MustAddFunction("[method]tcp-socket.shutdown", func(self host.Borrow[TcpSocket], shutdownType ShutdownType) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]tcp-socket.shutdown", func(self host.Borrow[*TcpSocket], shutdownType ShutdownType) Result[Void, NetworkErrorCode] {
+		return networkVoidResult(self.Resource().shutdown(shutdownType))
 	})
 
 	return hi
@@ -154,12 +708,12 @@ func CreateTcpCreateSocketInstance(
 ) *host.Instance {
 	hi := host.NewInstance()
 	hi.AddTypeExport("network", host.ResourceTypeFor[Network](hi, networkInstance))
-	hi.AddTypeExport("tcp-socket", host.ResourceTypeFor[TcpSocket](hi, tcpInstance))
+	hi.AddTypeExport("tcp-socket", host.ResourceTypeFor[*TcpSocket](hi, tcpInstance))
 	hi.AddTypeExport("ip-address-family", host.ValueTypeFor[IpAddressFamily](hi))
 	hi.AddTypeExport("error-code", host.ValueTypeFor[NetworkErrorCode](hi))
 
-	hi.MustAddFunction("create-tcp-socket", func(addressFamily IpAddressFamily) Result[host.Own[TcpSocket], NetworkErrorCode] {
-		return ResultErr[host.Own[TcpSocket]](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("create-tcp-socket", func(addressFamily IpAddressFamily) Result[host.Own[*TcpSocket], NetworkErrorCode] {
+		return ResultOk[NetworkErrorCode](host.NewOwn(newTcpSocket(addressFamily)))
 	})
 	return hi
 }
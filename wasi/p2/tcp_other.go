@@ -0,0 +1,47 @@
+//go:build !unix
+
+package p2
+
+import (
+	"context"
+	"net"
+	"net/netip"
+)
+
+// tcpBinding reserves a local address. Without access to raw sockets there is
+// no way to bind without also listening, so the address is held by a listener
+// that is released again when the socket connects.
+type tcpBinding struct {
+	l *net.TCPListener
+}
+
+func bindTcp(network string, addr netip.AddrPort) (*tcpBinding, error) {
+	l, err := net.ListenTCP(network, net.TCPAddrFromAddrPort(addr))
+	if err != nil {
+		return nil, err
+	}
+	return &tcpBinding{l: l}, nil
+}
+
+func (b *tcpBinding) localAddr() net.Addr {
+	return b.l.Addr()
+}
+
+func (b *tcpBinding) listen(backlog int) (*net.TCPListener, error) {
+	return b.l, nil
+}
+
+func (b *tcpBinding) connect(ctx context.Context, remote netip.AddrPort) (*net.TCPConn, error) {
+	laddr := b.l.Addr()
+	b.l.Close()
+	dialer := &net.Dialer{LocalAddr: laddr, KeepAlive: -1}
+	c, err := dialer.DialContext(ctx, laddr.Network(), remote.String())
+	if err != nil {
+		return nil, err
+	}
+	return c.(*net.TCPConn), nil
+}
+
+func (b *tcpBinding) Close() error {
+	return b.l.Close()
+}
@@ -0,0 +1,184 @@
+package p2
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func loopbackAddress(port uint16) IpSocketAddress {
+	return NewIpSocketAddress(netip.AddrPortFrom(netip.MustParseAddr("127.0.0.1"), port))
+}
+
+func TestTcpSocket_ConnectAccept(t *testing.T) {
+	server := newTcpSocket(IpAddressFamily("ipv4"))
+	defer server.Close()
+
+	if err := server.startBind(loopbackAddress(0)); err != nil {
+		t.Fatalf("startBind failed: %v", err)
+	}
+	if err := server.finishBind(); err != nil {
+		t.Fatalf("finishBind failed: %v", err)
+	}
+	if err := server.startListen(); err != nil {
+		t.Fatalf("startListen failed: %v", err)
+	}
+	if err := server.finishListen(); err != nil {
+		t.Fatalf("finishListen failed: %v", err)
+	}
+	listenAddr, err := server.localAddress()
+	if err != nil {
+		t.Fatalf("localAddress failed: %v", err)
+	}
+
+	_, _, _, err = server.accept()
+	if code := networkErrorCodeFor(err); code != NetworkErrorCode("would-block") {
+		t.Fatalf("accept() error = %v, want would-block", err)
+	}
+
+	client := newTcpSocket(IpAddressFamily("ipv4"))
+	defer client.Close()
+	if err := client.startConnect(listenAddr); err != nil {
+		t.Fatalf("startConnect failed: %v", err)
+	}
+	if _, err := Poll(context.Background(), tcpPollable{s: client}); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	clientIn, clientOut, err := client.finishConnect()
+	if err != nil {
+		t.Fatalf("finishConnect failed: %v", err)
+	}
+
+	if _, err := Poll(context.Background(), tcpPollable{s: server}); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	conn, serverIn, serverOut, err := server.accept()
+	if err != nil {
+		t.Fatalf("accept failed: %v", err)
+	}
+	defer conn.Close()
+
+	remote, err := conn.remoteAddress()
+	if err != nil {
+		t.Fatalf("remoteAddress failed: %v", err)
+	}
+	local, err := client.localAddress()
+	if err != nil {
+		t.Fatalf("localAddress failed: %v", err)
+	}
+	if remote.ToNetipAddrPort() != local.ToNetipAddrPort() {
+		t.Errorf("remoteAddress() = %v, want %v", remote.ToNetipAddrPort(), local.ToNetipAddrPort())
+	}
+
+	if err := clientOut.BlockingWriteAndFlush([]byte("ping")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	data, err := serverIn.BlockingRead(4)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if string(data) != "ping" {
+		t.Errorf("server read %q, want %q", data, "ping")
+	}
+
+	if err := serverOut.BlockingWriteAndFlush([]byte("pong")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	data, err = clientIn.BlockingRead(4)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if string(data) != "pong" {
+		t.Errorf("client read %q, want %q", data, "pong")
+	}
+}
+
+func TestTcpSocket_BoundSocketDoesNotListen(t *testing.T) {
+	s := newTcpSocket(IpAddressFamily("ipv4"))
+	defer s.Close()
+
+	if err := s.startBind(loopbackAddress(0)); err != nil {
+		t.Fatalf("startBind failed: %v", err)
+	}
+	if err := s.finishBind(); err != nil {
+		t.Fatalf("finishBind failed: %v", err)
+	}
+	local, err := s.localAddress()
+	if err != nil {
+		t.Fatalf("localAddress failed: %v", err)
+	}
+
+	c, err := net.Dial("tcp4", local.ToNetipAddrPort().String())
+	if err == nil {
+		c.Close()
+		t.Fatal("dialing a bound socket that is not listening succeeded")
+	}
+	if code := networkErrorCodeFor(err); code != NetworkErrorCode("connection-refused") {
+		t.Errorf("dial error = %v, want connection-refused", err)
+	}
+}
+
+func TestTcpSocket_ConnectFromBoundAddress(t *testing.T) {
+	l, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenTCP failed: %v", err)
+	}
+	defer l.Close()
+
+	s := newTcpSocket(IpAddressFamily("ipv4"))
+	defer s.Close()
+	if err := s.startBind(loopbackAddress(0)); err != nil {
+		t.Fatalf("startBind failed: %v", err)
+	}
+	if err := s.finishBind(); err != nil {
+		t.Fatalf("finishBind failed: %v", err)
+	}
+	bound, err := s.localAddress()
+	if err != nil {
+		t.Fatalf("localAddress failed: %v", err)
+	}
+
+	remote := NewIpSocketAddress(l.Addr().(*net.TCPAddr).AddrPort())
+	if err := s.startConnect(remote); err != nil {
+		t.Fatalf("startConnect failed: %v", err)
+	}
+	if _, err := Poll(context.Background(), tcpPollable{s: s}); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if _, _, err := s.finishConnect(); err != nil {
+		t.Fatalf("finishConnect failed: %v", err)
+	}
+
+	c, err := l.AcceptTCP()
+	if err != nil {
+		t.Fatalf("AcceptTCP failed: %v", err)
+	}
+	defer c.Close()
+	if got := c.RemoteAddr().(*net.TCPAddr).AddrPort(); got != bound.ToNetipAddrPort() {
+		t.Errorf("peer address = %v, want %v", got, bound.ToNetipAddrPort())
+	}
+	got, err := s.remoteAddress()
+	if err != nil {
+		t.Fatalf("remoteAddress failed: %v", err)
+	}
+	if got.ToNetipAddrPort() != remote.ToNetipAddrPort() {
+		t.Errorf("remoteAddress() = %v, want %v", got.ToNetipAddrPort(), remote.ToNetipAddrPort())
+	}
+}
+
+func TestTcpSocket_InvalidState(t *testing.T) {
+	s := newTcpSocket(IpAddressFamily("ipv4"))
+	defer s.Close()
+
+	if err := s.startListen(); networkErrorCodeFor(err) != NetworkErrorCode("invalid-state") {
+		t.Errorf("startListen() on unbound socket = %v, want invalid-state", err)
+	}
+	if err := s.finishBind(); networkErrorCodeFor(err) != NetworkErrorCode("not-in-progress") {
+		t.Errorf("finishBind() without startBind = %v, want not-in-progress", err)
+	}
+	v6 := NewIpSocketAddress(netip.AddrPortFrom(netip.IPv6Loopback(), 0))
+	if err := s.startBind(v6); networkErrorCodeFor(err) != NetworkErrorCode("invalid-argument") {
+		t.Errorf("startBind() with mismatched family = %v, want invalid-argument", err)
+	}
+}
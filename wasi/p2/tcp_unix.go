@@ -0,0 +1,203 @@
+//go:build unix
+
+package p2
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"syscall"
+)
+
+// tcpBinding is a socket bound to a local address that is neither listening
+// nor connected yet. It is consumed by listen or connect.
+type tcpBinding struct {
+	fd    int
+	laddr *net.TCPAddr
+}
+
+func bindTcp(network string, addr netip.AddrPort) (*tcpBinding, error) {
+	domain := syscall.AF_INET6
+	if network == "tcp4" {
+		domain = syscall.AF_INET
+	}
+
+	syscall.ForkLock.RLock()
+	fd, err := syscall.Socket(domain, syscall.SOCK_STREAM, syscall.IPPROTO_TCP)
+	if err == nil {
+		syscall.CloseOnExec(fd)
+	}
+	syscall.ForkLock.RUnlock()
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+
+	b := &tcpBinding{fd: fd}
+	if err := b.bind(domain, addr); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *tcpBinding) bind(domain int, addr netip.AddrPort) error {
+	if domain == syscall.AF_INET6 {
+		if err := syscall.SetsockoptInt(b.fd, syscall.IPPROTO_IPV6, syscall.IPV6_V6ONLY, 1); err != nil {
+			return os.NewSyscallError("setsockopt", err)
+		}
+	}
+	if err := syscall.SetsockoptInt(b.fd, syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1); err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	if err := syscall.Bind(b.fd, tcpSockaddr(addr)); err != nil {
+		return os.NewSyscallError("bind", err)
+	}
+	sa, err := syscall.Getsockname(b.fd)
+	if err != nil {
+		return os.NewSyscallError("getsockname", err)
+	}
+	b.laddr = tcpAddrFromSockaddr(sa)
+	return nil
+}
+
+func (b *tcpBinding) localAddr() net.Addr {
+	return b.laddr
+}
+
+// listen puts the socket into the listening state with the given backlog and
+// hands it over to the net package.
+func (b *tcpBinding) listen(backlog int) (*net.TCPListener, error) {
+	if err := syscall.Listen(b.fd, backlog); err != nil {
+		return nil, os.NewSyscallError("listen", err)
+	}
+	f := b.release()
+	defer f.Close()
+	l, err := net.FileListener(f)
+	if err != nil {
+		return nil, err
+	}
+	return l.(*net.TCPListener), nil
+}
+
+// connect connects the bound socket to remote. Cancelling ctx aborts a
+// connection attempt that is still in progress.
+func (b *tcpBinding) connect(ctx context.Context, remote netip.AddrPort) (*net.TCPConn, error) {
+	if err := syscall.SetNonblock(b.fd, true); err != nil {
+		b.Close()
+		return nil, os.NewSyscallError("setnonblock", err)
+	}
+	switch err := syscall.Connect(b.fd, tcpSockaddr(remote)); err {
+	case nil, syscall.EINPROGRESS, syscall.EINTR:
+	default:
+		b.Close()
+		return nil, os.NewSyscallError("connect", err)
+	}
+
+	f := b.release()
+	defer f.Close()
+	c, err := net.FileConn(f)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	stop := context.AfterFunc(ctx, func() { c.Close() })
+	defer stop()
+	if err := waitConnected(c.(*net.TCPConn)); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+
+	// The connection was wrapped before the peer was known, so wrap it again
+	// now that it has a remote address.
+	cf, err := c.(*net.TCPConn).File()
+	if err != nil {
+		return nil, err
+	}
+	defer cf.Close()
+	connected, err := net.FileConn(cf)
+	if err != nil {
+		return nil, err
+	}
+	return connected.(*net.TCPConn), nil
+}
+
+// waitConnected blocks until a non-blocking connect on c has completed.
+func waitConnected(c *net.TCPConn) error {
+	raw, err := c.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var connectErr error
+	if err := raw.Write(func(fd uintptr) bool {
+		soErr, err := syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_ERROR)
+		if err != nil {
+			connectErr = os.NewSyscallError("getsockopt", err)
+			return true
+		}
+		switch errno := syscall.Errno(soErr); errno {
+		case 0:
+			if _, err := syscall.Getpeername(int(fd)); errors.Is(err, syscall.ENOTCONN) {
+				return false
+			}
+			return true
+		case syscall.EINPROGRESS, syscall.EALREADY, syscall.EINTR:
+			return false
+		default:
+			connectErr = os.NewSyscallError("connect", errno)
+			return true
+		}
+	}); err != nil {
+		return err
+	}
+	return connectErr
+}
+
+// release transfers ownership of the descriptor to the returned file.
+func (b *tcpBinding) release() *os.File {
+	f := os.NewFile(uintptr(b.fd), "tcp")
+	b.fd = -1
+	return f
+}
+
+func (b *tcpBinding) Close() error {
+	if b.fd < 0 {
+		return nil
+	}
+	err := syscall.Close(b.fd)
+	b.fd = -1
+	return err
+}
+
+func tcpSockaddr(addr netip.AddrPort) syscall.Sockaddr {
+	if addr.Addr().Is4() {
+		return &syscall.SockaddrInet4{Port: int(addr.Port()), Addr: addr.Addr().As4()}
+	}
+	sa := &syscall.SockaddrInet6{Port: int(addr.Port()), Addr: addr.Addr().As16()}
+	if zone := addr.Addr().Zone(); zone != "" {
+		if ifi, err := net.InterfaceByName(zone); err == nil {
+			sa.ZoneId = uint32(ifi.Index)
+		}
+	}
+	return sa
+}
+
+func tcpAddrFromSockaddr(sa syscall.Sockaddr) *net.TCPAddr {
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(netip.AddrFrom4(sa.Addr), uint16(sa.Port)))
+	case *syscall.SockaddrInet6:
+		addr := netip.AddrFrom16(sa.Addr)
+		if sa.ZoneId != 0 {
+			if ifi, err := net.InterfaceByIndex(int(sa.ZoneId)); err == nil {
+				addr = addr.WithZone(ifi.Name)
+			}
+		}
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, uint16(sa.Port)))
+	}
+	return &net.TCPAddr{}
+}
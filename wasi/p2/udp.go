@@ -1,6 +1,11 @@
 package p2
 
 import (
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+
 	"github.com/partite-ai/wacogo/componentmodel"
 	"github.com/partite-ai/wacogo/componentmodel/host"
 )
@@ -296,13 +301,387 @@ interface udp {
 }
 */
 
+type udpState int
+
+const (
+	udpStateUnbound udpState = iota
+	udpStateBindStarted
+	udpStateBound
+	udpStateClosed
+)
+
+const (
+	maxUdpDatagramSize     = 65535
+	maxQueuedUdpDatagrams  = 64
+	udpSendPermitsPerCheck = 64
+)
+
+type udpDatagram struct {
+	data []byte
+	addr netip.AddrPort
+}
+
+// UdpSocket implements wasi:sockets/udp on top of a net.UDPConn. The net
+// package can't connect a socket after it has been bound, so a socket streaming
+// to a specific remote address stays unconnected and filters incoming datagrams
+// and checks outgoing ones itself. An ipv6 socket is dual-stack, and exchanges
+// datagrams with IPv4 peers through their IPv4-mapped addresses.
 type UdpSocket struct {
+	mu      sync.Mutex
+	changed chan struct{}
+	family  IpAddressFamily
+	state   udpState
+
+	conn    *net.UDPConn
+	bindErr error
+
+	incoming chan udpDatagram
+	readErr  error
+
+	remote     netip.AddrPort
+	generation int
+
+	hopLimit          uint8
+	receiveBufferSize uint64
+	sendBufferSize    uint64
+}
+
+func newUdpSocket(family IpAddressFamily) *UdpSocket {
+	return &UdpSocket{
+		changed: make(chan struct{}),
+		family:  family,
+	}
+}
+
+func (s *UdpSocket) network() string {
+	if s.family == IpAddressFamily("ipv4") {
+		return "udp4"
+	}
+	// The net package only leaves IPV6_V6ONLY off for "udp".
+	return "udp"
+}
+
+// signal wakes up everything blocked on a state change. Must be called with mu held.
+func (s *UdpSocket) signal() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *UdpSocket) startBind(localAddress IpSocketAddress) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == udpStateBindStarted {
+		return NetworkErrorCode("concurrency-conflict")
+	}
+	if s.state != udpStateUnbound {
+		return NetworkErrorCode("invalid-state")
+	}
+	addrPort, err := validateSocketAddress(s.family, localAddress)
+	if err != nil {
+		return err
+	}
+
+	s.state = udpStateBindStarted
+	c, err := net.ListenUDP(s.network(), net.UDPAddrFromAddrPort(addrPort))
+	if err != nil {
+		s.bindErr = err
+		return nil
+	}
+	if err := s.applyConnOptions(c); err != nil {
+		c.Close()
+		s.bindErr = err
+		return nil
+	}
+	s.conn = c
+	return nil
+}
+
+func (s *UdpSocket) finishBind() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != udpStateBindStarted {
+		return NetworkErrorCode("not-in-progress")
+	}
+	if err := s.bindErr; err != nil {
+		s.bindErr = nil
+		s.state = udpStateUnbound
+		return err
+	}
+	s.state = udpStateBound
+	s.incoming = make(chan udpDatagram, maxQueuedUdpDatagrams)
+	go s.readLoop(s.conn, s.incoming)
+	return nil
+}
+
+func (s *UdpSocket) readLoop(c *net.UDPConn, incoming chan<- udpDatagram) {
+	defer close(incoming)
+	buf := make([]byte, maxUdpDatagramSize)
+	for {
+		n, addr, err := c.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			s.mu.Lock()
+			s.readErr = err
+			s.signal()
+			s.mu.Unlock()
+			return
+		}
+		incoming <- udpDatagram{
+			data: append([]byte(nil), buf[:n]...),
+			addr: addr,
+		}
+		s.mu.Lock()
+		s.signal()
+		s.mu.Unlock()
+	}
+}
+
+// stream (re)associates the socket with remoteAddress and returns a new pair of
+// datagram streams. Streams returned by earlier calls stop working.
+func (s *UdpSocket) stream(remoteAddress Option[IpSocketAddress]) (*IncomingDatagramStream, *OutgoingDatagramStream, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != udpStateBound {
+		return nil, nil, NetworkErrorCode("invalid-state")
+	}
+
+	var remote netip.AddrPort
+	if addr, ok := remoteAddress.Some(); ok {
+		addrPort, err := validateRemoteAddress(s.family, addr)
+		if err != nil {
+			return nil, nil, err
+		}
+		if addrPort.Addr().Unmap().IsUnspecified() || addrPort.Port() == 0 {
+			return nil, nil, NetworkErrorCode("invalid-argument")
+		}
+		remote = addrPort
+	}
+
+	s.remote = remote
+	s.generation++
+	in := &IncomingDatagramStream{s: s, generation: s.generation, remote: remote}
+	out := &OutgoingDatagramStream{s: s, generation: s.generation, remote: remote}
+	return in, out, nil
+}
+
+func (s *UdpSocket) localAddress() (IpSocketAddress, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != udpStateBound {
+		return IpSocketAddress{}, NetworkErrorCode("invalid-state")
+	}
+	return s.socketAddress(s.conn.LocalAddr().(*net.UDPAddr).AddrPort()), nil
+}
+
+func (s *UdpSocket) remoteAddress() (IpSocketAddress, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != udpStateBound || !s.remote.IsValid() {
+		return IpSocketAddress{}, NetworkErrorCode("invalid-state")
+	}
+	return s.socketAddress(s.remote), nil
+}
+
+func (s *UdpSocket) socketAddress(addrPort netip.AddrPort) IpSocketAddress {
+	if s.family == IpAddressFamily("ipv4") {
+		addrPort = unmapAddrPort(addrPort)
+	}
+	return NewIpSocketAddress(addrPort)
+}
+
+func (s *UdpSocket) applyConnOptions(c *net.UDPConn) error {
+	if s.receiveBufferSize != 0 {
+		if err := c.SetReadBuffer(int(s.receiveBufferSize)); err != nil {
+			return err
+		}
+	}
+	if s.sendBufferSize != 0 {
+		if err := c.SetWriteBuffer(int(s.sendBufferSize)); err != nil {
+			return err
+		}
+	}
+	if s.hopLimit != 0 {
+		raw, err := c.SyscallConn()
+		if err != nil {
+			return err
+		}
+		if err := setHopLimit(raw, s.family, s.hopLimit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setOption updates an option and applies it to the bound socket, if any.
+func (s *UdpSocket) setOption(update func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update()
+	if s.state == udpStateBound {
+		return s.applyConnOptions(s.conn)
+	}
+	return nil
+}
+
+func (s *UdpSocket) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = udpStateClosed
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	if s.incoming != nil {
+		go func(incoming <-chan udpDatagram) {
+			for range incoming {
+			}
+		}(s.incoming)
+		s.incoming = nil
+	}
+	s.signal()
+	return nil
 }
 
 type IncomingDatagramStream struct {
+	s          *UdpSocket
+	generation int
+	remote     netip.AddrPort
+	pending    *udpDatagram
+}
+
+// next returns the next queued datagram from the stream's remote address, or nil
+// if there is none. Must be called with s.mu held.
+func (st *IncomingDatagramStream) next() *udpDatagram {
+	if st.pending != nil {
+		d := st.pending
+		st.pending = nil
+		return d
+	}
+	for {
+		select {
+		case d, ok := <-st.s.incoming:
+			if !ok {
+				return nil
+			}
+			if st.remote.IsValid() && unmapAddrPort(d.addr) != unmapAddrPort(st.remote) {
+				continue
+			}
+			return &d
+		default:
+			return nil
+		}
+	}
+}
+
+func (st *IncomingDatagramStream) receive(maxResults uint64) ([]IncomingDatagram, error) {
+	s := st.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != udpStateBound || st.generation != s.generation {
+		return nil, NetworkErrorCode("invalid-state")
+	}
+
+	var datagrams []IncomingDatagram
+	for uint64(len(datagrams)) < maxResults {
+		d := st.next()
+		if d == nil {
+			break
+		}
+		datagrams = append(datagrams, NewIncomingDatagram(d.data, s.socketAddress(d.addr)))
+	}
+	if len(datagrams) == 0 && maxResults > 0 && s.readErr != nil && !errors.Is(s.readErr, net.ErrClosed) {
+		return nil, s.readErr
+	}
+	return datagrams, nil
+}
+
+// incomingDatagramPollable is ready once the stream has a datagram to receive,
+// or receive would fail.
+type incomingDatagramPollable struct {
+	st *IncomingDatagramStream
+}
+
+func (p incomingDatagramPollable) Ready() (bool, <-chan struct{}) {
+	s := p.st.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != udpStateBound || p.st.generation != s.generation || s.readErr != nil {
+		return true, nil
+	}
+	if d := p.st.next(); d != nil {
+		p.st.pending = d
+		return true, nil
+	}
+	return false, s.changed
 }
 
 type OutgoingDatagramStream struct {
+	s          *UdpSocket
+	generation int
+	remote     netip.AddrPort
+	permitted  uint64
+}
+
+func (st *OutgoingDatagramStream) checkSend() (uint64, error) {
+	s := st.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != udpStateBound || st.generation != s.generation {
+		return 0, NetworkErrorCode("invalid-state")
+	}
+	st.permitted = udpSendPermitsPerCheck
+	return st.permitted, nil
+}
+
+func (st *OutgoingDatagramStream) send(datagrams []OutgoingDatagram) (uint64, error) {
+	if uint64(len(datagrams)) > st.permitted {
+		panic("send called with more datagrams than permitted by check-send")
+	}
+	st.permitted -= uint64(len(datagrams))
+
+	s := st.s
+	s.mu.Lock()
+	if s.state != udpStateBound || st.generation != s.generation {
+		s.mu.Unlock()
+		return 0, NetworkErrorCode("invalid-state")
+	}
+	conn := s.conn
+	s.mu.Unlock()
+
+	var sent uint64
+	for _, d := range datagrams {
+		dest, err := st.destination(d)
+		if err == nil {
+			_, err = conn.WriteToUDPAddrPort(d.Fields.Data.Get(d), dest)
+		}
+		if err != nil {
+			if sent > 0 {
+				return sent, nil
+			}
+			return 0, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+func (st *OutgoingDatagramStream) destination(d OutgoingDatagram) (netip.AddrPort, error) {
+	addr, ok := d.Fields.RemoteAddress.Get(d).Some()
+	if !ok {
+		if !st.remote.IsValid() {
+			return netip.AddrPort{}, NetworkErrorCode("invalid-argument")
+		}
+		return st.remote, nil
+	}
+	addrPort, err := validateRemoteAddress(st.s.family, addr)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	if addrPort.Addr().Unmap().IsUnspecified() || addrPort.Port() == 0 {
+		return netip.AddrPort{}, NetworkErrorCode("invalid-argument")
+	}
+	if st.remote.IsValid() && unmapAddrPort(addrPort) != unmapAddrPort(st.remote) {
+		return netip.AddrPort{}, NetworkErrorCode("invalid-argument")
+	}
+	return addrPort, nil
 }
 
 type IncomingDatagram host.Record[struct {
@@ -335,9 +714,9 @@ func CreateUdpInstance(
 ) *host.Instance {
 	hi := host.NewInstance()
 
-	hi.AddTypeExport("udp-socket", host.ResourceTypeFor[UdpSocket](hi, hi))
-	hi.AddTypeExport("incoming-datagram-stream", host.ResourceTypeFor[IncomingDatagramStream](hi, hi))
-	hi.AddTypeExport("outgoing-datagram-stream", host.ResourceTypeFor[OutgoingDatagramStream](hi, hi))
+	hi.AddTypeExport("udp-socket", host.ResourceTypeFor[*UdpSocket](hi, hi))
+	hi.AddTypeExport("incoming-datagram-stream", host.ResourceTypeFor[*IncomingDatagramStream](hi, hi))
+	hi.AddTypeExport("outgoing-datagram-stream", host.ResourceTypeFor[*OutgoingDatagramStream](hi, hi))
 	hi.AddTypeExport("incoming-datagram", host.ValueTypeFor[IncomingDatagram](hi))
 	hi.AddTypeExport("outgoing-datagram", host.ValueTypeFor[OutgoingDatagram](hi))
 	hi.AddTypeExport("network", host.ResourceTypeFor[Network](hi, networkInstance))
@@ -346,75 +725,114 @@ func CreateUdpInstance(
 	hi.AddTypeExport("ip-address-family", host.ValueTypeFor[IpAddressFamily](hi))
 	hi.AddTypeExport("pollable", host.ResourceTypeFor[Pollable](hi, pollInstance))
 
-	hi.MustAddFunction("[method]udp-socket.start-bind", func(self host.Borrow[UdpSocket], network host.Borrow[Network], localAddress IpSocketAddress) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]udp-socket.start-bind", func(self host.Borrow[*UdpSocket], network host.Borrow[Network], localAddress IpSocketAddress) Result[Void, NetworkErrorCode] {
+		if err := network.Resource().check(); err != nil {
+			return networkVoidResult(err)
+		}
+		return networkVoidResult(self.Resource().startBind(localAddress))
 	})
 
-	hi.MustAddFunction("[method]udp-socket.finish-bind", func(self host.Borrow[UdpSocket]) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]udp-socket.finish-bind", func(self host.Borrow[*UdpSocket]) Result[Void, NetworkErrorCode] {
+		return networkVoidResult(self.Resource().finishBind())
 	})
 
-	hi.MustAddFunction("[method]udp-socket.stream", func(self host.Borrow[UdpSocket], remoteAddress Option[IpSocketAddress]) Result[Tuple2[host.Own[IncomingDatagramStream], host.Own[OutgoingDatagramStream]], NetworkErrorCode] {
-		return ResultErr[Tuple2[host.Own[IncomingDatagramStream], host.Own[OutgoingDatagramStream]]](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]udp-socket.stream", func(self host.Borrow[*UdpSocket], remoteAddress Option[IpSocketAddress]) Result[Tuple2[host.Own[*IncomingDatagramStream], host.Own[*OutgoingDatagramStream]], NetworkErrorCode] {
+		in, out, err := self.Resource().stream(remoteAddress)
+		if err != nil {
+			return ResultErr[Tuple2[host.Own[*IncomingDatagramStream], host.Own[*OutgoingDatagramStream]]](networkErrorCodeFor(err))
+		}
+		return ResultOk[NetworkErrorCode](NewTuple2(host.NewOwn(in), host.NewOwn(out)))
 	})
 
-	hi.MustAddFunction("[method]udp-socket.local-address", func(self host.Borrow[UdpSocket]) Result[IpSocketAddress, NetworkErrorCode] {
-		return ResultErr[IpSocketAddress](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]udp-socket.local-address", func(self host.Borrow[*UdpSocket]) Result[IpSocketAddress, NetworkErrorCode] {
+		return networkResult(self.Resource().localAddress())
 	})
 
-	hi.MustAddFunction("[method]udp-socket.remote-address", func(self host.Borrow[UdpSocket]) Result[IpSocketAddress, NetworkErrorCode] {
-		return ResultErr[IpSocketAddress](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]udp-socket.remote-address", func(self host.Borrow[*UdpSocket]) Result[IpSocketAddress, NetworkErrorCode] {
+		return networkResult(self.Resource().remoteAddress())
 	})
 
-	hi.MustAddFunction("[method]udp-socket.address-family", func(self host.Borrow[UdpSocket]) IpAddressFamily {
-		return IpAddressFamily("ipv4")
+	hi.MustAddFunction("[method]udp-socket.address-family", func(self host.Borrow[*UdpSocket]) IpAddressFamily {
+		return self.Resource().family
 	})
 
-	hi.MustAddFunction("[method]udp-socket.unicast-hop-limit", func(self host.Borrow[UdpSocket]) Result[uint8, NetworkErrorCode] {
-		return ResultErr[uint8](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]udp-socket.unicast-hop-limit", func(self host.Borrow[*UdpSocket]) Result[uint8, NetworkErrorCode] {
+		s := self.Resource()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.hopLimit == 0 {
+			return ResultOk[NetworkErrorCode](uint8(64))
+		}
+		return ResultOk[NetworkErrorCode](s.hopLimit)
 	})
 
-	hi.MustAddFunction("[method]udp-socket.set-unicast-hop-limit", func(self host.Borrow[UdpSocket], value uint8) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]udp-socket.set-unicast-hop-limit", func(self host.Borrow[*UdpSocket], value uint8) Result[Void, NetworkErrorCode] {
+		if value == 0 {
+			return ResultErr[Void](NetworkErrorCode("invalid-argument"))
+		}
+		s := self.Resource()
+		return networkVoidResult(s.setOption(func() { s.hopLimit = value }))
 	})
 
-	hi.MustAddFunction("[method]udp-socket.receive-buffer-size", func(self host.Borrow[UdpSocket]) Result[uint64, NetworkErrorCode] {
-		return ResultErr[uint64](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]udp-socket.receive-buffer-size", func(self host.Borrow[*UdpSocket]) Result[uint64, NetworkErrorCode] {
+		s := self.Resource()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.receiveBufferSize == 0 {
+			return ResultOk[NetworkErrorCode](uint64(defaultSocketBufferSize))
+		}
+		return ResultOk[NetworkErrorCode](s.receiveBufferSize)
 	})
 
-	hi.MustAddFunction("[method]udp-socket.set-receive-buffer-size", func(self host.Borrow[UdpSocket], value uint64) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]udp-socket.set-receive-buffer-size", func(self host.Borrow[*UdpSocket], value uint64) Result[Void, NetworkErrorCode] {
+		if value == 0 {
+			return ResultErr[Void](NetworkErrorCode("invalid-argument"))
+		}
+		s := self.Resource()
+		return networkVoidResult(s.setOption(func() { s.receiveBufferSize = min(value, maxSocketBufferSize) }))
 	})
 
-	hi.MustAddFunction("[method]udp-socket.send-buffer-size", func(self host.Borrow[UdpSocket]) Result[uint64, NetworkErrorCode] {
-		return ResultErr[uint64](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]udp-socket.send-buffer-size", func(self host.Borrow[*UdpSocket]) Result[uint64, NetworkErrorCode] {
+		s := self.Resource()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.sendBufferSize == 0 {
+			return ResultOk[NetworkErrorCode](uint64(defaultSocketBufferSize))
+		}
+		return ResultOk[NetworkErrorCode](s.sendBufferSize)
 	})
 
-	hi.MustAddFunction("[method]udp-socket.set-send-buffer-size", func(self host.Borrow[UdpSocket], value uint64) Result[Void, NetworkErrorCode] {
-		return ResultErr[Void](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]udp-socket.set-send-buffer-size", func(self host.Borrow[*UdpSocket], value uint64) Result[Void, NetworkErrorCode] {
+		if value == 0 {
+			return ResultErr[Void](NetworkErrorCode("invalid-argument"))
+		}
+		s := self.Resource()
+		return networkVoidResult(s.setOption(func() { s.sendBufferSize = min(value, maxSocketBufferSize) }))
 	})
 
-	hi.MustAddFunction("[method]udp-socket.subscribe", func(self host.Borrow[UdpSocket]) host.Own[Pollable] {
+	hi.MustAddFunction("[method]udp-socket.subscribe", func(self host.Borrow[*UdpSocket]) host.Own[Pollable] {
 		return host.NewOwn[Pollable](AlwaysReadyPollable{})
 	})
 
-	hi.MustAddFunction("[method]incoming-datagram-stream.receive", func(self host.Borrow[IncomingDatagramStream], maxResults uint64) Result[[]IncomingDatagram, NetworkErrorCode] {
-		return ResultErr[[]IncomingDatagram](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]incoming-datagram-stream.receive", func(self host.Borrow[*IncomingDatagramStream], maxResults uint64) Result[[]IncomingDatagram, NetworkErrorCode] {
+		return networkResult(self.Resource().receive(maxResults))
 	})
 
-	hi.MustAddFunction("[method]incoming-datagram-stream.subscribe", func(self host.Borrow[IncomingDatagramStream]) host.Own[Pollable] {
-		return host.NewOwn[Pollable](AlwaysReadyPollable{})
+	hi.MustAddFunction("[method]incoming-datagram-stream.subscribe", func(self host.Borrow[*IncomingDatagramStream]) host.Own[Pollable] {
+		return host.NewOwn[Pollable](incomingDatagramPollable{st: self.Resource()})
 	})
 
-	hi.MustAddFunction("[method]outgoing-datagram-stream.check-send", func(self host.Borrow[OutgoingDatagramStream]) Result[uint64, NetworkErrorCode] {
-		return ResultErr[uint64](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]outgoing-datagram-stream.check-send", func(self host.Borrow[*OutgoingDatagramStream]) Result[uint64, NetworkErrorCode] {
+		return networkResult(self.Resource().checkSend())
 	})
 
-	hi.MustAddFunction("[method]outgoing-datagram-stream.send", func(self host.Borrow[OutgoingDatagramStream], datagrams []OutgoingDatagram) Result[uint64, NetworkErrorCode] {
-		return ResultErr[uint64](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("[method]outgoing-datagram-stream.send", func(self host.Borrow[*OutgoingDatagramStream], datagrams []OutgoingDatagram) Result[uint64, NetworkErrorCode] {
+		return networkResult(self.Resource().send(datagrams))
 	})
 
-	hi.MustAddFunction("[method]outgoing-datagram-stream.subscribe", func(self host.Borrow[OutgoingDatagramStream]) host.Own[Pollable] {
+	// Sending never blocks for long enough to be worth waiting on, so the
+	// outgoing stream is always writable.
+	hi.MustAddFunction("[method]outgoing-datagram-stream.subscribe", func(self host.Borrow[*OutgoingDatagramStream]) host.Own[Pollable] {
 		return host.NewOwn[Pollable](AlwaysReadyPollable{})
 	})
 
@@ -427,12 +845,12 @@ func CreateUdpCreateSocketInstance(
 ) *host.Instance {
 	hi := host.NewInstance()
 	hi.AddTypeExport("network", host.ResourceTypeFor[Network](hi, networkInstance))
-	hi.AddTypeExport("udp-socket", host.ResourceTypeFor[UdpSocket](hi, udpInstance))
+	hi.AddTypeExport("udp-socket", host.ResourceTypeFor[*UdpSocket](hi, udpInstance))
 	hi.AddTypeExport("ip-address-family", host.ValueTypeFor[IpAddressFamily](hi))
 	hi.AddTypeExport("error-code", host.ValueTypeFor[NetworkErrorCode](hi))
 
-	hi.MustAddFunction("create-udp-socket", func(addressFamily IpAddressFamily) Result[host.Own[UdpSocket], NetworkErrorCode] {
-		return ResultErr[host.Own[UdpSocket]](NetworkErrorCode("not-supported"))
+	hi.MustAddFunction("create-udp-socket", func(addressFamily IpAddressFamily) Result[host.Own[*UdpSocket], NetworkErrorCode] {
+		return ResultOk[NetworkErrorCode](host.NewOwn(newUdpSocket(addressFamily)))
 	})
 	return hi
 }
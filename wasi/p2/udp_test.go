@@ -0,0 +1,175 @@
+package p2
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func newBoundUdpSocket(t *testing.T) *UdpSocket {
+	t.Helper()
+	s := newUdpSocket(IpAddressFamily("ipv4"))
+	t.Cleanup(func() { s.Close() })
+	if err := s.startBind(loopbackAddress(0)); err != nil {
+		t.Fatalf("startBind failed: %v", err)
+	}
+	if err := s.finishBind(); err != nil {
+		t.Fatalf("finishBind failed: %v", err)
+	}
+	return s
+}
+
+func TestUdpSocket_SendReceive(t *testing.T) {
+	a := newBoundUdpSocket(t)
+	b := newBoundUdpSocket(t)
+	aAddr, err := a.localAddress()
+	if err != nil {
+		t.Fatalf("localAddress failed: %v", err)
+	}
+	bAddr, err := b.localAddress()
+	if err != nil {
+		t.Fatalf("localAddress failed: %v", err)
+	}
+
+	_, aOut, err := a.stream(OptionNone[IpSocketAddress]())
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	bIn, _, err := b.stream(OptionSome(aAddr))
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if remote, err := b.remoteAddress(); err != nil || remote.ToNetipAddrPort() != aAddr.ToNetipAddrPort() {
+		t.Errorf("remoteAddress() = %v, %v, want %v", remote.ToNetipAddrPort(), err, aAddr.ToNetipAddrPort())
+	}
+
+	if _, err := aOut.checkSend(); err != nil {
+		t.Fatalf("checkSend failed: %v", err)
+	}
+	_, err = aOut.send([]OutgoingDatagram{NewOutgoingDatagram([]byte("hello"), OptionNone[IpSocketAddress]())})
+	if code := networkErrorCodeFor(err); code != NetworkErrorCode("invalid-argument") {
+		t.Fatalf("send() without a remote address = %v, want invalid-argument", err)
+	}
+
+	n, err := aOut.send([]OutgoingDatagram{
+		NewOutgoingDatagram([]byte("one"), OptionSome(bAddr)),
+		NewOutgoingDatagram([]byte("two"), OptionSome(bAddr)),
+	})
+	if err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("send() = %d, want 2", n)
+	}
+
+	var got []string
+	for len(got) < 2 {
+		if _, err := Poll(context.Background(), incomingDatagramPollable{st: bIn}); err != nil {
+			t.Fatalf("Poll failed: %v", err)
+		}
+		datagrams, err := bIn.receive(10)
+		if err != nil {
+			t.Fatalf("receive failed: %v", err)
+		}
+		for _, d := range datagrams {
+			got = append(got, string(d.Fields.Data.Get(d)))
+			if addr := d.Fields.RemoteAddress.Get(d); addr.ToNetipAddrPort() != aAddr.ToNetipAddrPort() {
+				t.Errorf("datagram remote address = %v, want %v", addr.ToNetipAddrPort(), aAddr.ToNetipAddrPort())
+			}
+		}
+	}
+	if got[0] != "one" || got[1] != "two" {
+		t.Errorf("received %v, want [one two]", got)
+	}
+
+	if _, _, err := b.stream(OptionNone[IpSocketAddress]()); err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if _, err := bIn.receive(1); networkErrorCodeFor(err) != NetworkErrorCode("invalid-state") {
+		t.Errorf("receive() on a replaced stream = %v, want invalid-state", err)
+	}
+}
+
+func TestUdpSocket_MappedRemote(t *testing.T) {
+	peer, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP failed: %v", err)
+	}
+	defer peer.Close()
+	peerAddr := peer.LocalAddr().(*net.UDPAddr).AddrPort()
+	mapped := netip.AddrPortFrom(netip.AddrFrom16(peerAddr.Addr().As16()), peerAddr.Port())
+
+	s := newUdpSocket(IpAddressFamily("ipv6"))
+	defer s.Close()
+	if err := s.startBind(NewIpSocketAddress(netip.AddrPortFrom(netip.IPv6Unspecified(), 0))); err != nil {
+		t.Fatalf("startBind failed: %v", err)
+	}
+	if err := s.finishBind(); err != nil {
+		t.Fatalf("finishBind failed: %v", err)
+	}
+	local, err := s.localAddress()
+	if err != nil {
+		t.Fatalf("localAddress failed: %v", err)
+	}
+	in, out, err := s.stream(OptionSome(NewIpSocketAddress(mapped)))
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+
+	if _, err := out.checkSend(); err != nil {
+		t.Fatalf("checkSend failed: %v", err)
+	}
+	if _, err := out.send([]OutgoingDatagram{NewOutgoingDatagram([]byte("ping"), OptionNone[IpSocketAddress]())}); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	buf := make([]byte, 16)
+	n, from, err := peer.ReadFromUDPAddrPort(buf)
+	if err != nil {
+		t.Fatalf("ReadFromUDPAddrPort failed: %v", err)
+	}
+	if string(buf[:n]) != "ping" {
+		t.Errorf("peer read %q, want %q", buf[:n], "ping")
+	}
+
+	if _, err := peer.WriteToUDPAddrPort([]byte("pong"), from); err != nil {
+		t.Fatalf("WriteToUDPAddrPort failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := Poll(ctx, incomingDatagramPollable{st: in}); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	datagrams, err := in.receive(1)
+	if err != nil {
+		t.Fatalf("receive failed: %v", err)
+	}
+	if len(datagrams) != 1 {
+		t.Fatalf("received %d datagrams, want 1", len(datagrams))
+	}
+	d := datagrams[0]
+	if string(d.Fields.Data.Get(d)) != "pong" {
+		t.Errorf("received %q, want %q", d.Fields.Data.Get(d), "pong")
+	}
+	if addr := d.Fields.RemoteAddress.Get(d); addr.ToNetipAddrPort() != mapped {
+		t.Errorf("datagram remote address = %v, want %v", addr.ToNetipAddrPort(), mapped)
+	}
+	if from.Port() != local.ToNetipAddrPort().Port() {
+		t.Errorf("datagram sent from port %d, want %d", from.Port(), local.ToNetipAddrPort().Port())
+	}
+}
+
+func TestUdpSocket_SendWithoutPermit(t *testing.T) {
+	s := newBoundUdpSocket(t)
+	_, out, err := s.stream(OptionNone[IpSocketAddress]())
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("send() without check-send should trap")
+		}
+	}()
+	out.send([]OutgoingDatagram{NewOutgoingDatagram([]byte("x"), OptionSome(loopbackAddress(9)))})
+}
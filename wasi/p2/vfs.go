@@ -0,0 +1,296 @@
+package p2
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FileSystem is a directory tree that descriptors dispatch to. Names are
+// slash-separated, relative to the root of the file system and have already been
+// checked not to contain absolute or parent-relative components, although
+// symlinks encountered while resolving them may still point outside the tree and
+// must be rejected by the implementation.
+//
+// Errors should wrap a syscall.Errno or one of the fs.Err* values so they can be
+// mapped to a wasi:filesystem error-code.
+type FileSystem interface {
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	Stat(name string) (fs.FileInfo, error)
+	Lstat(name string) (fs.FileInfo, error)
+	Mkdir(name string, perm fs.FileMode) error
+	Remove(name string) error
+	Rename(oldname, newname string) error
+	Link(oldname, newname string) error
+	Symlink(target, newname string) error
+	Readlink(name string) (string, error)
+	Chtimes(name string, atime time.Time, mtime time.Time) error
+}
+
+// File is an open file or directory in a FileSystem. *os.File implements File.
+type File interface {
+	io.ReaderAt
+	io.WriterAt
+	io.Closer
+	Stat() (fs.FileInfo, error)
+	Truncate(size int64) error
+	Sync() error
+	ReadDir(n int) ([]fs.DirEntry, error)
+}
+
+var errPathEscapes = errors.New("path escapes from file system")
+
+// OSFileSystem is a FileSystem backed by a host directory through os.Root.
+type OSFileSystem struct {
+	root *os.Root
+}
+
+func NewOSFileSystem(hostPath string) (*OSFileSystem, error) {
+	root, err := os.OpenRoot(hostPath)
+	if err != nil {
+		return nil, err
+	}
+	return &OSFileSystem{root: root}, nil
+}
+
+func (o *OSFileSystem) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	f, err := o.root.OpenFile(filepath.FromSlash(name), flag, perm)
+	if err != nil {
+		return nil, o.error(err)
+	}
+	return f, nil
+}
+
+func (o *OSFileSystem) Stat(name string) (fs.FileInfo, error) {
+	fi, err := o.root.Stat(filepath.FromSlash(name))
+	return fi, o.error(err)
+}
+
+func (o *OSFileSystem) Lstat(name string) (fs.FileInfo, error) {
+	fi, err := o.root.Lstat(filepath.FromSlash(name))
+	return fi, o.error(err)
+}
+
+func (o *OSFileSystem) Mkdir(name string, perm fs.FileMode) error {
+	return o.error(o.root.Mkdir(filepath.FromSlash(name), perm))
+}
+
+func (o *OSFileSystem) Remove(name string) error {
+	return o.error(o.root.Remove(filepath.FromSlash(name)))
+}
+
+func (o *OSFileSystem) Rename(oldname, newname string) error {
+	return o.error(o.root.Rename(filepath.FromSlash(oldname), filepath.FromSlash(newname)))
+}
+
+func (o *OSFileSystem) Link(oldname, newname string) error {
+	return o.error(o.root.Link(filepath.FromSlash(oldname), filepath.FromSlash(newname)))
+}
+
+func (o *OSFileSystem) Symlink(target, newname string) error {
+	return o.error(o.root.Symlink(filepath.FromSlash(target), filepath.FromSlash(newname)))
+}
+
+func (o *OSFileSystem) Readlink(name string) (string, error) {
+	target, err := o.root.Readlink(filepath.FromSlash(name))
+	if err != nil {
+		return "", o.error(err)
+	}
+	return filepath.ToSlash(target), nil
+}
+
+func (o *OSFileSystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return o.error(o.root.Chtimes(filepath.FromSlash(name), atime, mtime))
+}
+
+func (o *OSFileSystem) Close() error {
+	return o.root.Close()
+}
+
+// error replaces the error os.Root returns for names that leave the root with
+// errPathEscapes. os.Root doesn't export that error, so errors that don't come
+// from the OS are checked against where the symlinks in their paths lead.
+func (o *OSFileSystem) error(err error) error {
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+	var errno syscall.Errno
+	switch {
+	case err == nil || errors.As(err, &errno):
+		return err
+	case errors.As(err, &pathErr):
+		if o.escapes(pathErr.Path) {
+			return &fs.PathError{Op: pathErr.Op, Path: pathErr.Path, Err: errPathEscapes}
+		}
+	case errors.As(err, &linkErr):
+		if o.escapes(linkErr.Old) || o.escapes(linkErr.New) {
+			return &os.LinkError{Op: linkErr.Op, Old: linkErr.Old, New: linkErr.New, Err: errPathEscapes}
+		}
+	}
+	return err
+}
+
+// escapes reports whether resolving the symlinks in name leads outside the
+// root. Resolution stops at the first component that doesn't exist.
+func (o *OSFileSystem) escapes(name string) bool {
+	if !filepath.IsLocal(name) {
+		return true
+	}
+	var resolved string
+	pending := strings.Split(name, string(filepath.Separator))
+	for links := 0; len(pending) > 0; {
+		next := filepath.Join(resolved, pending[0])
+		pending = pending[1:]
+		if !filepath.IsLocal(next) {
+			return true
+		}
+		fi, err := o.root.Lstat(next)
+		if err != nil {
+			return false
+		}
+		if fi.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		if links++; links > maxSymlinkFollows {
+			return false
+		}
+		target, err := o.root.Readlink(next)
+		if err != nil {
+			return false
+		}
+		if filepath.IsAbs(target) {
+			return true
+		}
+		pending = append(strings.Split(target, string(filepath.Separator)), pending...)
+	}
+	return false
+}
+
+// IOFileSystem is a read-only FileSystem backed by an fs.FS such as an
+// embed.FS or a zip.Reader.
+type IOFileSystem struct {
+	fsys fs.FS
+}
+
+func NewIOFileSystem(fsys fs.FS) *IOFileSystem {
+	return &IOFileSystem{fsys: fsys}
+}
+
+func (i *IOFileSystem) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EROFS}
+	}
+	f, err := i.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &ioFile{f: f}, nil
+}
+
+func (i *IOFileSystem) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(i.fsys, name)
+}
+
+func (i *IOFileSystem) Lstat(name string) (fs.FileInfo, error) {
+	return fs.Lstat(i.fsys, name)
+}
+
+func (i *IOFileSystem) Mkdir(name string, perm fs.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: name, Err: syscall.EROFS}
+}
+
+func (i *IOFileSystem) Remove(name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: syscall.EROFS}
+}
+
+func (i *IOFileSystem) Rename(oldname, newname string) error {
+	return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: syscall.EROFS}
+}
+
+func (i *IOFileSystem) Link(oldname, newname string) error {
+	return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: syscall.EROFS}
+}
+
+func (i *IOFileSystem) Symlink(target, newname string) error {
+	return &os.LinkError{Op: "symlink", Old: target, New: newname, Err: syscall.EROFS}
+}
+
+func (i *IOFileSystem) Readlink(name string) (string, error) {
+	return fs.ReadLink(i.fsys, name)
+}
+
+func (i *IOFileSystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return &fs.PathError{Op: "chtimes", Path: name, Err: syscall.EROFS}
+}
+
+// ioFile adapts an fs.File to File. Files that implement neither io.ReaderAt nor
+// io.Seeker are read into memory on first access.
+type ioFile struct {
+	mu       sync.Mutex
+	f        fs.File
+	contents *bytes.Reader
+}
+
+func (f *ioFile) ReadAt(p []byte, off int64) (int, error) {
+	if ra, ok := f.f.(io.ReaderAt); ok {
+		return ra.ReadAt(p, off)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if seeker, ok := f.f.(io.Seeker); ok {
+		if _, err := seeker.Seek(off, io.SeekStart); err != nil {
+			return 0, err
+		}
+		n, err := io.ReadFull(f.f, p)
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			err = io.EOF
+		}
+		return n, err
+	}
+
+	if f.contents == nil {
+		data, err := io.ReadAll(f.f)
+		if err != nil {
+			return 0, err
+		}
+		f.contents = bytes.NewReader(data)
+	}
+	return f.contents.ReadAt(p, off)
+}
+
+func (f *ioFile) WriteAt(p []byte, off int64) (int, error) {
+	return 0, syscall.EBADF
+}
+
+func (f *ioFile) Close() error {
+	return f.f.Close()
+}
+
+func (f *ioFile) Stat() (fs.FileInfo, error) {
+	return f.f.Stat()
+}
+
+func (f *ioFile) Truncate(size int64) error {
+	return syscall.EBADF
+}
+
+func (f *ioFile) Sync() error {
+	return nil
+}
+
+func (f *ioFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	dir, ok := f.f.(fs.ReadDirFile)
+	if !ok {
+		return nil, syscall.ENOTDIR
+	}
+	return dir.ReadDir(n)
+}
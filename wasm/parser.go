@@ -8,6 +8,9 @@ import (
 type Externs struct {
 	Imports *Imports
 	Exports *Exports
+	// Memories are the memories defined by the module, not counting
+	// imported ones.
+	Memories []*MemoryType
 }
 
 type ModuleName struct {
@@ -43,6 +46,7 @@ func ReadExterns(mod []byte) (*Externs, error) {
 	importedMemories := make(map[ModuleName]uint32)
 	tableTypes := make(map[uint32]*TableType)
 	memoryTypes := make(map[uint32]*MemoryType)
+	var definedMemories []*MemoryType
 	globalTypes := make(map[uint32]*GlobalType)
 
 	var tableTypeOffset uint32 = 0
@@ -181,6 +185,7 @@ func ReadExterns(mod []byte) (*Externs, error) {
 				}
 
 				memoryTypes[memoryTypeOffset] = memoryType
+				definedMemories = append(definedMemories, memoryType)
 				memoryTypeOffset++
 			}
 		case 6: // Global section
@@ -326,8 +331,9 @@ func ReadExterns(mod []byte) (*Externs, error) {
 	}
 
 	return &Externs{
-		Imports: imports,
-		Exports: exports,
+		Imports:  imports,
+		Exports:  exports,
+		Memories: definedMemories,
 	}, nil
 }
 
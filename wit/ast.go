@@ -0,0 +1,313 @@
+package wit
+
+import "strings"
+
+// PackageName names a WIT package, like wasi:io@0.2.0.
+type PackageName struct {
+	Namespace string
+	Name      string
+	// Version is empty for an unversioned package.
+	Version string
+}
+
+func (n PackageName) String() string {
+	s := n.Namespace + ":" + n.Name
+	if n.Version != "" {
+		s += "@" + n.Version
+	}
+	return s
+}
+
+// interfaceName returns the qualified name of an interface in the package.
+func (n PackageName) interfaceName(iface string) string {
+	s := n.Namespace + ":" + n.Name + "/" + iface
+	if n.Version != "" {
+		s += "@" + n.Version
+	}
+	return s
+}
+
+// Stability records the feature gates of an item.
+type Stability struct {
+	// Since is the version of an item gated by @since.
+	Since string
+	// Feature is the feature of an item gated by @unstable, which is only
+	// included when the feature is enabled.
+	Feature  string
+	Unstable bool
+	// Deprecated is the version of an item gated by @deprecated.
+	Deprecated string
+}
+
+// Document is a parsed WIT file.
+type Document struct {
+	Filename string
+	// Package is nil for a file containing only nested packages.
+	Package    *PackageName
+	Uses       []*TopLevelUse
+	Interfaces []*InterfaceDecl
+	Worlds     []*WorldDecl
+	// Packages are the packages declared with package a:b { ... }.
+	Packages []*NestedPackage
+}
+
+// NestedPackage is a package declared inside another file.
+type NestedPackage struct {
+	Name       PackageName
+	Interfaces []*InterfaceDecl
+	Worlds     []*WorldDecl
+}
+
+// TopLevelUse gives a name to an interface for the rest of the file, as in
+// use wasi:io/streams@0.2.0 as streams;
+type TopLevelUse struct {
+	Path UsePath
+	As   string
+}
+
+// UsePath refers to an interface or world, either by its name in the current
+// package or qualified by its package.
+type UsePath struct {
+	// Package is nil for an interface or world of the current package.
+	Package *PackageName
+	Name    string
+}
+
+func (p UsePath) String() string {
+	if p.Package == nil {
+		return p.Name
+	}
+	return p.Package.interfaceName(p.Name)
+}
+
+type InterfaceDecl struct {
+	Name      string
+	Docs      string
+	Stability Stability
+	Items     []InterfaceItem
+}
+
+// InterfaceItem is one of *UseDecl, *TypeDecl or *FuncDecl.
+type InterfaceItem interface {
+	isInterfaceItem()
+}
+
+// UseDecl imports types from another interface, as in
+// use types.{descriptor, error-code as code};
+type UseDecl struct {
+	Path      UsePath
+	Names     []UseName
+	Stability Stability
+}
+
+func (*UseDecl) isInterfaceItem() {}
+func (*UseDecl) isWorldItem()     {}
+
+type UseName struct {
+	Name string
+	// As is the local name of the type, or empty to keep Name.
+	As string
+}
+
+// localName returns the name the type is known by after the use.
+func (n UseName) localName() string {
+	if n.As != "" {
+		return n.As
+	}
+	return n.Name
+}
+
+type TypeDecl struct {
+	Name      string
+	Docs      string
+	Stability Stability
+	Def       TypeDef
+}
+
+func (*TypeDecl) isInterfaceItem() {}
+func (*TypeDecl) isWorldItem()     {}
+
+// TypeDef is one of *AliasDef, *RecordDef, *VariantDef, *EnumDef, *FlagsDef
+// or *ResourceDef.
+type TypeDef interface {
+	isTypeDef()
+}
+
+// AliasDef is a type declared as type name = ty;
+type AliasDef struct {
+	Type Ty
+}
+
+type RecordDef struct {
+	Fields []*Field
+}
+
+type Field struct {
+	Name string
+	Docs string
+	Type Ty
+}
+
+type VariantDef struct {
+	Cases []*Case
+}
+
+type Case struct {
+	Name string
+	Docs string
+	// Type is nil for a case without a payload.
+	Type Ty
+}
+
+type EnumDef struct {
+	Cases []string
+}
+
+type FlagsDef struct {
+	Flags []string
+}
+
+type ResourceDef struct {
+	Funcs []*ResourceFunc
+}
+
+type ResourceFuncKind int
+
+const (
+	ResourceConstructor ResourceFuncKind = iota
+	ResourceMethod
+	ResourceStatic
+)
+
+type ResourceFunc struct {
+	Kind ResourceFuncKind
+	// Func is named after the function, or "constructor" for a constructor.
+	Func *FuncDecl
+}
+
+func (*AliasDef) isTypeDef()    {}
+func (*RecordDef) isTypeDef()   {}
+func (*VariantDef) isTypeDef()  {}
+func (*EnumDef) isTypeDef()     {}
+func (*FlagsDef) isTypeDef()    {}
+func (*ResourceDef) isTypeDef() {}
+
+type FuncDecl struct {
+	Name      string
+	Docs      string
+	Stability Stability
+	Async     bool
+	Params    []*Param
+	// Result is nil for a function without a result.
+	Result Ty
+}
+
+func (*FuncDecl) isInterfaceItem() {}
+
+type Param struct {
+	Name string
+	Type Ty
+}
+
+// Ty is a reference to a type: one of *PrimitiveTy, *NamedTy, *ListTy,
+// *OptionTy, *ResultTy, *TupleTy, *HandleTy, *StreamTy or *FutureTy.
+type Ty interface {
+	isTy()
+}
+
+// PrimitiveTy is a built-in type, like u32, string or error-context.
+type PrimitiveTy struct {
+	Name string
+}
+
+// NamedTy refers to a type declared or used in the enclosing interface or
+// world. A name referring to a resource stands for an owned handle.
+type NamedTy struct {
+	Name string
+}
+
+type ListTy struct {
+	Element Ty
+	// Length is zero for a list of any length.
+	Length uint32
+}
+
+type OptionTy struct {
+	Type Ty
+}
+
+type ResultTy struct {
+	// Ok and Err are nil when absent.
+	Ok  Ty
+	Err Ty
+}
+
+type TupleTy struct {
+	Types []Ty
+}
+
+// HandleTy is own<resource> or borrow<resource>.
+type HandleTy struct {
+	Resource string
+	Borrow   bool
+}
+
+type StreamTy struct {
+	// Element is nil for a stream without elements.
+	Element Ty
+}
+
+type FutureTy struct {
+	// Type is nil for a future without a value.
+	Type Ty
+}
+
+func (*PrimitiveTy) isTy() {}
+func (*NamedTy) isTy()     {}
+func (*ListTy) isTy()      {}
+func (*OptionTy) isTy()    {}
+func (*ResultTy) isTy()    {}
+func (*TupleTy) isTy()     {}
+func (*HandleTy) isTy()    {}
+func (*StreamTy) isTy()    {}
+func (*FutureTy) isTy()    {}
+
+type WorldDecl struct {
+	Name      string
+	Docs      string
+	Stability Stability
+	Items     []WorldItem
+}
+
+// WorldItem is one of *UseDecl, *TypeDecl, *ExternDecl or *IncludeDecl.
+type WorldItem interface {
+	isWorldItem()
+}
+
+// ExternDecl is an import or export of a world.
+type ExternDecl struct {
+	Export    bool
+	Docs      string
+	Stability Stability
+	// Exactly one of Func, Interface and Path is set. Func and Interface are
+	// named by Name.
+	Name      string
+	Func      *FuncDecl
+	Interface *InterfaceDecl
+	Path      *UsePath
+}
+
+func (*ExternDecl) isWorldItem() {}
+
+// IncludeDecl includes the imports and exports of another world.
+type IncludeDecl struct {
+	Path      UsePath
+	With      []UseName
+	Stability Stability
+}
+
+func (*IncludeDecl) isWorldItem() {}
+
+func joinDocs(lines []string) string {
+	return strings.Join(lines, "\n")
+}
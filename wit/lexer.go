@@ -0,0 +1,259 @@
+package wit
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenInt
+	tokenPunct
+	tokenIllegal
+)
+
+type token struct {
+	kind tokenKind
+	// text is the identifier without its leading %, the digits of an integer,
+	// or the punctuation.
+	text string
+	// explicit is set for an identifier written with a leading %, which is
+	// never a keyword.
+	explicit bool
+	// docs are the doc comments preceding the token.
+	docs      []string
+	line, col int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of file"
+	case tokenIdent:
+		if t.explicit {
+			return "%" + t.text
+		}
+		return t.text
+	default:
+		return t.text
+	}
+}
+
+type lexer struct {
+	src       string
+	off       int
+	line, col int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src, line: 1, col: 1}
+}
+
+func (l *lexer) peekByte(ahead int) byte {
+	if l.off+ahead < len(l.src) {
+		return l.src[l.off+ahead]
+	}
+	return 0
+}
+
+func (l *lexer) advance(n int) {
+	for range n {
+		if l.src[l.off] == '\n' {
+			l.line++
+			l.col = 1
+		} else {
+			l.col++
+		}
+		l.off++
+	}
+}
+
+// skipTrivia skips whitespace and comments, returning the doc comments.
+func (l *lexer) skipTrivia() ([]string, error) {
+	var docs []string
+	for l.off < len(l.src) {
+		switch c := l.src[l.off]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			l.advance(1)
+		case strings.HasPrefix(l.src[l.off:], "//"):
+			end := strings.IndexByte(l.src[l.off:], '\n')
+			if end < 0 {
+				end = len(l.src) - l.off
+			}
+			comment := l.src[l.off : l.off+end]
+			if strings.HasPrefix(comment, "///") && !strings.HasPrefix(comment, "////") {
+				docs = append(docs, strings.TrimSpace(strings.TrimPrefix(comment, "///")))
+			}
+			l.advance(end)
+		case strings.HasPrefix(l.src[l.off:], "/*"):
+			doc := strings.HasPrefix(l.src[l.off:], "/**") && !strings.HasPrefix(l.src[l.off:], "/**/")
+			start, line, col := l.off, l.line, l.col
+			if err := l.skipBlockComment(); err != nil {
+				return nil, &lexError{line: line, col: col, err: err}
+			}
+			if doc {
+				body := strings.TrimSuffix(l.src[start+3:l.off], "*/")
+				for _, line := range strings.Split(body, "\n") {
+					line = strings.TrimSpace(line)
+					line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+					if line != "" {
+						docs = append(docs, line)
+					}
+				}
+			}
+		default:
+			return docs, nil
+		}
+	}
+	return docs, nil
+}
+
+// skipBlockComment skips a block comment, which may contain nested ones.
+func (l *lexer) skipBlockComment() error {
+	depth := 0
+	for l.off < len(l.src) {
+		switch {
+		case strings.HasPrefix(l.src[l.off:], "/*"):
+			depth++
+			l.advance(2)
+		case strings.HasPrefix(l.src[l.off:], "*/"):
+			depth--
+			l.advance(2)
+			if depth == 0 {
+				return nil
+			}
+		default:
+			l.advance(1)
+		}
+	}
+	return errors.New("unterminated block comment")
+}
+
+// lexError is an error in the trivia before a token, at the position where the
+// offending trivia starts.
+type lexError struct {
+	line, col int
+	err       error
+}
+
+func (e *lexError) Error() string {
+	return e.err.Error()
+}
+
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (l *lexer) next() token {
+	docs, err := l.skipTrivia()
+	tok := token{docs: docs, line: l.line, col: l.col}
+	if err != nil {
+		tok.kind = tokenIllegal
+		tok.text = err.Error()
+		if lexErr, ok := err.(*lexError); ok {
+			tok.line, tok.col = lexErr.line, lexErr.col
+		}
+		return tok
+	}
+	if l.off >= len(l.src) {
+		tok.kind = tokenEOF
+		return tok
+	}
+
+	c := l.src[l.off]
+	switch {
+	case c == '%' && isIdentStart(l.peekByte(1)):
+		l.advance(1)
+		tok.kind = tokenIdent
+		tok.text = l.scanIdent()
+		tok.explicit = true
+	case isIdentStart(c):
+		tok.kind = tokenIdent
+		tok.text = l.scanIdent()
+	case isDigit(c):
+		start := l.off
+		for isDigit(l.peekByte(0)) {
+			l.advance(1)
+		}
+		tok.kind = tokenInt
+		tok.text = l.src[start:l.off]
+	case strings.HasPrefix(l.src[l.off:], "->"):
+		l.advance(2)
+		tok.kind = tokenPunct
+		tok.text = "->"
+	case strings.IndexByte(":;,.{}()<>=/*@_", c) >= 0:
+		l.advance(1)
+		tok.kind = tokenPunct
+		tok.text = string(c)
+	default:
+		l.advance(1)
+		tok.kind = tokenIllegal
+		tok.text = fmt.Sprintf("unexpected character %q", c)
+	}
+	return tok
+}
+
+// scanIdent scans a kebab-case identifier, whose words are separated by
+// single hyphens.
+func (l *lexer) scanIdent() string {
+	start := l.off
+	for {
+		for isIdentChar(l.peekByte(0)) {
+			l.advance(1)
+		}
+		if l.peekByte(0) != '-' || !isIdentChar(l.peekByte(1)) {
+			return l.src[start:l.off]
+		}
+		l.advance(1)
+	}
+}
+
+// scanVersion scans a semantic version directly following the current
+// position, like 0.2.0 or 1.0.0-rc.1+build.
+func (l *lexer) scanVersion() (string, bool) {
+	start := l.off
+	for i := range 3 {
+		if i > 0 {
+			if l.peekByte(0) != '.' || !isDigit(l.peekByte(1)) {
+				return "", false
+			}
+			l.advance(1)
+		}
+		if !isDigit(l.peekByte(0)) {
+			return "", false
+		}
+		for isDigit(l.peekByte(0)) {
+			l.advance(1)
+		}
+	}
+	for _, sep := range []byte{'-', '+'} {
+		if l.peekByte(0) != sep || !isIdentChar(l.peekByte(1)) {
+			continue
+		}
+		l.advance(1)
+		for {
+			for isIdentChar(l.peekByte(0)) || l.peekByte(0) == '-' {
+				l.advance(1)
+			}
+			// A dot only continues the version if an identifier follows,
+			// so the one in wasi:io/streams@0.2.0.{error} ends it.
+			if l.peekByte(0) != '.' || !isIdentChar(l.peekByte(1)) {
+				break
+			}
+			l.advance(1)
+		}
+	}
+	return l.src[start:l.off], true
+}
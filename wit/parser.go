@@ -0,0 +1,879 @@
+package wit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+var keywords = map[string]bool{
+	"as": true, "async": true, "bool": true, "borrow": true, "char": true,
+	"constructor": true, "enum": true, "error-context": true, "export": true,
+	"f32": true, "f64": true, "flags": true, "float32": true, "float64": true,
+	"func": true, "future": true, "import": true, "include": true,
+	"interface": true, "list": true, "option": true, "own": true,
+	"package": true, "record": true, "resource": true, "result": true,
+	"s8": true, "s16": true, "s32": true, "s64": true, "static": true,
+	"stream": true, "string": true, "tuple": true, "type": true, "u8": true,
+	"u16": true, "u32": true, "u64": true, "use": true, "variant": true,
+	"with": true, "world": true,
+}
+
+var primitives = map[string]string{
+	"bool": "bool", "u8": "u8", "u16": "u16", "u32": "u32", "u64": "u64",
+	"s8": "s8", "s16": "s16", "s32": "s32", "s64": "s64", "f32": "f32",
+	"f64": "f64", "float32": "f32", "float64": "f64", "char": "char",
+	"string": "string", "error-context": "error-context",
+}
+
+// Parse parses a WIT file, which must declare at least one package.
+func Parse(filename string, src []byte) (*Document, error) {
+	p := &parser{filename: filename, lex: newLexer(string(src))}
+	p.advance()
+	doc, err := p.parseDocument()
+	if err != nil {
+		return nil, err
+	}
+	doc.Filename = filename
+	return doc, nil
+}
+
+// ParseFile parses the WIT file at path.
+func ParseFile(path string) (*Document, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, src)
+}
+
+// ParseDir parses the .wit files in dir, and those under its deps directory,
+// where wit-bindgen and wasm-tools look for dependencies.
+func ParseDir(dir string) ([]*Document, error) {
+	var docs []*Document
+	paths, err := filepath.Glob(filepath.Join(dir, "*.wit"))
+	if err != nil {
+		return nil, err
+	}
+	err = filepath.WalkDir(filepath.Join(dir, "deps"), func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == ".wit" {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		doc, err := ParseFile(path)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+type parser struct {
+	filename string
+	lex      *lexer
+	tok      token
+}
+
+func (p *parser) advance() {
+	p.tok = p.lex.next()
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	if p.tok.kind == tokenIllegal {
+		return fmt.Errorf("%s:%d:%d: %s", p.filename, p.tok.line, p.tok.col, p.tok.text)
+	}
+	return fmt.Errorf("%s:%d:%d: %s", p.filename, p.tok.line, p.tok.col, fmt.Sprintf(format, args...))
+}
+
+// is reports whether the current token is the punctuation or keyword text.
+func (p *parser) is(text string) bool {
+	switch p.tok.kind {
+	case tokenPunct:
+		return p.tok.text == text
+	case tokenIdent:
+		return !p.tok.explicit && p.tok.text == text
+	}
+	return false
+}
+
+func (p *parser) accept(text string) bool {
+	if p.is(text) {
+		p.advance()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.errorf("expected `%s`, found `%s`", text, p.tok)
+	}
+	return nil
+}
+
+// ident parses an identifier, which can only be a keyword if written with a
+// leading %.
+func (p *parser) ident() (string, error) {
+	if p.tok.kind != tokenIdent || (!p.tok.explicit && keywords[p.tok.text]) {
+		return "", p.errorf("expected an identifier, found `%s`", p.tok)
+	}
+	name := p.tok.text
+	p.advance()
+	return name, nil
+}
+
+func (p *parser) isIdent() bool {
+	return p.tok.kind == tokenIdent && (p.tok.explicit || !keywords[p.tok.text])
+}
+
+// list parses items separated by commas up to close, allowing a trailing
+// comma.
+func (p *parser) list(close string, item func() error) error {
+	for !p.accept(close) {
+		if err := item(); err != nil {
+			return err
+		}
+		if !p.accept(",") {
+			return p.expect(close)
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseDocument() (*Document, error) {
+	doc := &Document{}
+	if p.is("package") {
+		p.advance()
+		name, err := p.packageName()
+		if err != nil {
+			return nil, err
+		}
+		if p.is("{") {
+			nested, err := p.nestedPackage(name)
+			if err != nil {
+				return nil, err
+			}
+			doc.Packages = append(doc.Packages, nested)
+		} else {
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+			doc.Package = &name
+		}
+	}
+
+	for p.tok.kind != tokenEOF {
+		docs := joinDocs(p.tok.docs)
+		stability, err := p.gates()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case p.accept("package"):
+			name, err := p.packageName()
+			if err != nil {
+				return nil, err
+			}
+			nested, err := p.nestedPackage(name)
+			if err != nil {
+				return nil, err
+			}
+			doc.Packages = append(doc.Packages, nested)
+		case p.accept("use"):
+			path, err := p.usePath()
+			if err != nil {
+				return nil, err
+			}
+			use := &TopLevelUse{Path: path, As: path.Name}
+			if p.accept("as") {
+				if use.As, err = p.ident(); err != nil {
+					return nil, err
+				}
+			}
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+			doc.Uses = append(doc.Uses, use)
+		case p.accept("interface"):
+			iface, err := p.interfaceDecl(docs, stability)
+			if err != nil {
+				return nil, err
+			}
+			doc.Interfaces = append(doc.Interfaces, iface)
+		case p.accept("world"):
+			world, err := p.worldDecl(docs, stability)
+			if err != nil {
+				return nil, err
+			}
+			doc.Worlds = append(doc.Worlds, world)
+		default:
+			return nil, p.errorf("expected `interface`, `world`, `use` or `package`, found `%s`", p.tok)
+		}
+	}
+	if doc.Package == nil && len(doc.Packages) == 0 {
+		return nil, fmt.Errorf("%s: missing package declaration", p.filename)
+	}
+	return doc, nil
+}
+
+func (p *parser) nestedPackage(name PackageName) (*NestedPackage, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	pkg := &NestedPackage{Name: name}
+	for !p.accept("}") {
+		docs := joinDocs(p.tok.docs)
+		stability, err := p.gates()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case p.accept("interface"):
+			iface, err := p.interfaceDecl(docs, stability)
+			if err != nil {
+				return nil, err
+			}
+			pkg.Interfaces = append(pkg.Interfaces, iface)
+		case p.accept("world"):
+			world, err := p.worldDecl(docs, stability)
+			if err != nil {
+				return nil, err
+			}
+			pkg.Worlds = append(pkg.Worlds, world)
+		default:
+			return nil, p.errorf("expected `interface` or `world`, found `%s`", p.tok)
+		}
+	}
+	return pkg, nil
+}
+
+func (p *parser) packageName() (PackageName, error) {
+	var name PackageName
+	var err error
+	if name.Namespace, err = p.ident(); err != nil {
+		return name, err
+	}
+	if err := p.expect(":"); err != nil {
+		return name, err
+	}
+	if name.Name, err = p.ident(); err != nil {
+		return name, err
+	}
+	name.Version, err = p.version()
+	return name, err
+}
+
+// version parses an optional @version.
+func (p *parser) version() (string, error) {
+	if !p.is("@") {
+		return "", nil
+	}
+	// The version directly follows the @, so it is scanned before the lexer
+	// moves on to the next token.
+	version, ok := p.lex.scanVersion()
+	p.advance()
+	if !ok {
+		return "", p.errorf("expected a semantic version")
+	}
+	return version, nil
+}
+
+// usePath parses the name of an interface or world, which is qualified by
+// its package if it contains a colon.
+func (p *parser) usePath() (UsePath, error) {
+	name, err := p.ident()
+	if err != nil {
+		return UsePath{}, err
+	}
+	if !p.accept(":") {
+		return UsePath{Name: name}, nil
+	}
+	return p.qualifiedPath(name)
+}
+
+// qualifiedPath parses the rest of a path like wasi:io/streams@0.2.0 after
+// the namespace and colon.
+func (p *parser) qualifiedPath(namespace string) (UsePath, error) {
+	pkg := &PackageName{Namespace: namespace}
+	var err error
+	if pkg.Name, err = p.ident(); err != nil {
+		return UsePath{}, err
+	}
+	if err := p.expect("/"); err != nil {
+		return UsePath{}, err
+	}
+	path := UsePath{Package: pkg}
+	if path.Name, err = p.ident(); err != nil {
+		return UsePath{}, err
+	}
+	if pkg.Version, err = p.version(); err != nil {
+		return UsePath{}, err
+	}
+	return path, nil
+}
+
+// gates parses the @since, @unstable and @deprecated gates before an item.
+func (p *parser) gates() (Stability, error) {
+	var s Stability
+	for p.accept("@") {
+		gate, err := p.ident()
+		if err != nil {
+			return s, err
+		}
+		if err := p.expect("("); err != nil {
+			return s, err
+		}
+		args := make(map[string]string)
+		err = p.list(")", func() error {
+			key, err := p.ident()
+			if err != nil {
+				return err
+			}
+			if !p.is("=") {
+				return p.errorf("expected `=`, found `%s`", p.tok)
+			}
+			if key == "version" {
+				// As after the @ of a package name, the version is scanned
+				// before the lexer splits it into numbers and dots.
+				if _, err := p.lex.skipTrivia(); err != nil {
+					return err
+				}
+				version, ok := p.lex.scanVersion()
+				p.advance()
+				if !ok {
+					return p.errorf("expected a semantic version")
+				}
+				args[key] = version
+				return nil
+			}
+			p.advance()
+			value, err := p.ident()
+			if err != nil {
+				return err
+			}
+			args[key] = value
+			return nil
+		})
+		if err != nil {
+			return s, err
+		}
+		switch gate {
+		case "since":
+			if args["version"] == "" {
+				return s, p.errorf("@since requires a version")
+			}
+			s.Since = args["version"]
+		case "unstable":
+			if args["feature"] == "" {
+				return s, p.errorf("@unstable requires a feature")
+			}
+			s.Unstable = true
+			s.Feature = args["feature"]
+		case "deprecated":
+			if args["version"] == "" {
+				return s, p.errorf("@deprecated requires a version")
+			}
+			s.Deprecated = args["version"]
+		default:
+			return s, p.errorf("unknown gate @%s", gate)
+		}
+	}
+	return s, nil
+}
+
+func (p *parser) interfaceDecl(docs string, stability Stability) (*InterfaceDecl, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	items, err := p.interfaceItems()
+	if err != nil {
+		return nil, err
+	}
+	return &InterfaceDecl{Name: name, Docs: docs, Stability: stability, Items: items}, nil
+}
+
+func (p *parser) interfaceItems() ([]InterfaceItem, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var items []InterfaceItem
+	for !p.accept("}") {
+		docs := joinDocs(p.tok.docs)
+		stability, err := p.gates()
+		if err != nil {
+			return nil, err
+		}
+		var item InterfaceItem
+		switch {
+		case p.accept("use"):
+			item, err = p.useDecl(stability)
+		case p.isIdent():
+			item, err = p.funcItem(docs, stability)
+		default:
+			item, err = p.typeDecl(docs, stability)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (p *parser) useDecl(stability Stability) (*UseDecl, error) {
+	path, err := p.usePath()
+	if err != nil {
+		return nil, err
+	}
+	use := &UseDecl{Path: path, Stability: stability}
+	if err := p.expect("."); err != nil {
+		return nil, err
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	err = p.list("}", func() error {
+		var n UseName
+		var err error
+		if n.Name, err = p.ident(); err != nil {
+			return err
+		}
+		if p.accept("as") {
+			if n.As, err = p.ident(); err != nil {
+				return err
+			}
+		}
+		use.Names = append(use.Names, n)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return use, p.expect(";")
+}
+
+// funcItem parses name: func(...);
+func (p *parser) funcItem(docs string, stability Stability) (*FuncDecl, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	fn, err := p.funcType(name, docs, stability)
+	if err != nil {
+		return nil, err
+	}
+	return fn, p.expect(";")
+}
+
+func (p *parser) funcType(name, docs string, stability Stability) (*FuncDecl, error) {
+	fn := &FuncDecl{Name: name, Docs: docs, Stability: stability}
+	fn.Async = p.accept("async")
+	if err := p.expect("func"); err != nil {
+		return nil, err
+	}
+	if err := p.params(fn); err != nil {
+		return nil, err
+	}
+	if p.accept("->") {
+		var err error
+		if fn.Result, err = p.ty(); err != nil {
+			return nil, err
+		}
+	}
+	return fn, nil
+}
+
+func (p *parser) params(fn *FuncDecl) error {
+	if err := p.expect("("); err != nil {
+		return err
+	}
+	return p.list(")", func() error {
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		ty, err := p.ty()
+		if err != nil {
+			return err
+		}
+		fn.Params = append(fn.Params, &Param{Name: name, Type: ty})
+		return nil
+	})
+}
+
+func (p *parser) typeDecl(docs string, stability Stability) (*TypeDecl, error) {
+	decl := &TypeDecl{Docs: docs, Stability: stability}
+	var kind string
+	switch {
+	case p.is("type"), p.is("record"), p.is("variant"), p.is("enum"), p.is("flags"), p.is("resource"):
+		kind = p.tok.text
+		p.advance()
+	default:
+		return nil, p.errorf("expected an item, found `%s`", p.tok)
+	}
+	var err error
+	if decl.Name, err = p.ident(); err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case "type":
+		if err := p.expect("="); err != nil {
+			return nil, err
+		}
+		ty, err := p.ty()
+		if err != nil {
+			return nil, err
+		}
+		decl.Def = &AliasDef{Type: ty}
+		return decl, p.expect(";")
+	case "record":
+		def := &RecordDef{}
+		decl.Def = def
+		if err := p.expect("{"); err != nil {
+			return nil, err
+		}
+		return decl, p.list("}", func() error {
+			field := &Field{Docs: joinDocs(p.tok.docs)}
+			var err error
+			if field.Name, err = p.ident(); err != nil {
+				return err
+			}
+			if err := p.expect(":"); err != nil {
+				return err
+			}
+			if field.Type, err = p.ty(); err != nil {
+				return err
+			}
+			def.Fields = append(def.Fields, field)
+			return nil
+		})
+	case "variant":
+		def := &VariantDef{}
+		decl.Def = def
+		if err := p.expect("{"); err != nil {
+			return nil, err
+		}
+		return decl, p.list("}", func() error {
+			c := &Case{Docs: joinDocs(p.tok.docs)}
+			var err error
+			if c.Name, err = p.ident(); err != nil {
+				return err
+			}
+			if p.accept("(") {
+				if c.Type, err = p.ty(); err != nil {
+					return err
+				}
+				if err := p.expect(")"); err != nil {
+					return err
+				}
+			}
+			def.Cases = append(def.Cases, c)
+			return nil
+		})
+	case "enum", "flags":
+		var names []string
+		if err := p.expect("{"); err != nil {
+			return nil, err
+		}
+		err := p.list("}", func() error {
+			name, err := p.ident()
+			names = append(names, name)
+			return err
+		})
+		if kind == "enum" {
+			decl.Def = &EnumDef{Cases: names}
+		} else {
+			decl.Def = &FlagsDef{Flags: names}
+		}
+		return decl, err
+	default:
+		def := &ResourceDef{}
+		decl.Def = def
+		if p.accept(";") {
+			return decl, nil
+		}
+		if err := p.expect("{"); err != nil {
+			return nil, err
+		}
+		for !p.accept("}") {
+			fn, err := p.resourceFunc()
+			if err != nil {
+				return nil, err
+			}
+			def.Funcs = append(def.Funcs, fn)
+		}
+		return decl, nil
+	}
+}
+
+func (p *parser) resourceFunc() (*ResourceFunc, error) {
+	docs := joinDocs(p.tok.docs)
+	stability, err := p.gates()
+	if err != nil {
+		return nil, err
+	}
+	if p.accept("constructor") {
+		fn := &FuncDecl{Name: "constructor", Docs: docs, Stability: stability}
+		if err := p.params(fn); err != nil {
+			return nil, err
+		}
+		if p.accept("->") {
+			if fn.Result, err = p.ty(); err != nil {
+				return nil, err
+			}
+		}
+		return &ResourceFunc{Kind: ResourceConstructor, Func: fn}, p.expect(";")
+	}
+
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	kind := ResourceMethod
+	if p.accept("static") {
+		kind = ResourceStatic
+	}
+	fn, err := p.funcType(name, docs, stability)
+	if err != nil {
+		return nil, err
+	}
+	return &ResourceFunc{Kind: kind, Func: fn}, p.expect(";")
+}
+
+func (p *parser) ty() (Ty, error) {
+	if p.isIdent() {
+		name, _ := p.ident()
+		return &NamedTy{Name: name}, nil
+	}
+	if p.tok.kind != tokenIdent {
+		return nil, p.errorf("expected a type, found `%s`", p.tok)
+	}
+	keyword := p.tok.text
+	if prim, ok := primitives[keyword]; ok {
+		p.advance()
+		return &PrimitiveTy{Name: prim}, nil
+	}
+	p.advance()
+
+	// elem parses <ty> after types taking a single parameter.
+	elem := func() (Ty, error) {
+		if err := p.expect("<"); err != nil {
+			return nil, err
+		}
+		ty, err := p.ty()
+		if err != nil {
+			return nil, err
+		}
+		return ty, p.expect(">")
+	}
+
+	switch keyword {
+	case "list":
+		if err := p.expect("<"); err != nil {
+			return nil, err
+		}
+		ty, err := p.ty()
+		if err != nil {
+			return nil, err
+		}
+		list := &ListTy{Element: ty}
+		if p.accept(",") {
+			if p.tok.kind != tokenInt {
+				return nil, p.errorf("expected a list length, found `%s`", p.tok)
+			}
+			n, err := strconv.ParseUint(p.tok.text, 10, 32)
+			if err != nil || n == 0 {
+				return nil, p.errorf("invalid list length %s", p.tok.text)
+			}
+			list.Length = uint32(n)
+			p.advance()
+		}
+		return list, p.expect(">")
+	case "option":
+		ty, err := elem()
+		return &OptionTy{Type: ty}, err
+	case "result":
+		result := &ResultTy{}
+		if !p.accept("<") {
+			return result, nil
+		}
+		if !p.accept("_") {
+			var err error
+			if result.Ok, err = p.ty(); err != nil {
+				return nil, err
+			}
+			if p.accept(">") {
+				return result, nil
+			}
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		var err error
+		if result.Err, err = p.ty(); err != nil {
+			return nil, err
+		}
+		return result, p.expect(">")
+	case "tuple":
+		tuple := &TupleTy{}
+		if err := p.expect("<"); err != nil {
+			return nil, err
+		}
+		return tuple, p.list(">", func() error {
+			ty, err := p.ty()
+			tuple.Types = append(tuple.Types, ty)
+			return err
+		})
+	case "own", "borrow":
+		if err := p.expect("<"); err != nil {
+			return nil, err
+		}
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		return &HandleTy{Resource: name, Borrow: keyword == "borrow"}, p.expect(">")
+	case "stream":
+		if !p.is("<") {
+			return &StreamTy{}, nil
+		}
+		ty, err := elem()
+		return &StreamTy{Element: ty}, err
+	case "future":
+		if !p.is("<") {
+			return &FutureTy{}, nil
+		}
+		ty, err := elem()
+		return &FutureTy{Type: ty}, err
+	default:
+		return nil, p.errorf("expected a type, found `%s`", keyword)
+	}
+}
+
+func (p *parser) worldDecl(docs string, stability Stability) (*WorldDecl, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	world := &WorldDecl{Name: name, Docs: docs, Stability: stability}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for !p.accept("}") {
+		docs := joinDocs(p.tok.docs)
+		stability, err := p.gates()
+		if err != nil {
+			return nil, err
+		}
+		var item WorldItem
+		switch {
+		case p.accept("use"):
+			item, err = p.useDecl(stability)
+		case p.is("import"), p.is("export"):
+			export := p.is("export")
+			p.advance()
+			item, err = p.externDecl(export, docs, stability)
+		case p.accept("include"):
+			item, err = p.includeDecl(stability)
+		default:
+			item, err = p.typeDecl(docs, stability)
+		}
+		if err != nil {
+			return nil, err
+		}
+		world.Items = append(world.Items, item)
+	}
+	return world, nil
+}
+
+func (p *parser) externDecl(export bool, docs string, stability Stability) (*ExternDecl, error) {
+	decl := &ExternDecl{Export: export, Docs: docs, Stability: stability}
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept(":") {
+		decl.Path = &UsePath{Name: name}
+		return decl, p.expect(";")
+	}
+
+	switch {
+	case p.is("func"), p.is("async"):
+		decl.Name = name
+		if decl.Func, err = p.funcType(name, docs, stability); err != nil {
+			return nil, err
+		}
+		return decl, p.expect(";")
+	case p.accept("interface"):
+		decl.Name = name
+		items, err := p.interfaceItems()
+		if err != nil {
+			return nil, err
+		}
+		decl.Interface = &InterfaceDecl{Name: name, Docs: docs, Stability: stability, Items: items}
+		return decl, nil
+	default:
+		path, err := p.qualifiedPath(name)
+		if err != nil {
+			return nil, err
+		}
+		decl.Path = &path
+		return decl, p.expect(";")
+	}
+}
+
+func (p *parser) includeDecl(stability Stability) (*IncludeDecl, error) {
+	path, err := p.usePath()
+	if err != nil {
+		return nil, err
+	}
+	include := &IncludeDecl{Path: path, Stability: stability}
+	if p.accept("with") {
+		if err := p.expect("{"); err != nil {
+			return nil, err
+		}
+		err := p.list("}", func() error {
+			var n UseName
+			var err error
+			if n.Name, err = p.ident(); err != nil {
+				return err
+			}
+			if err := p.expect("as"); err != nil {
+				return err
+			}
+			if n.As, err = p.ident(); err != nil {
+				return err
+			}
+			include.With = append(include.With, n)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return include, p.expect(";")
+}
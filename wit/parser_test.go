@@ -0,0 +1,181 @@
+package wit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := Parse("streams.wit", []byte(`
+package wasi:io@0.2.0;
+
+use wasi:clocks/monotonic-clock@0.2.0 as clock;
+
+/// An input stream.
+@since(version = 0.2.0)
+interface streams {
+  use error.{error as io-error};
+  use wasi:poll/poll@0.2.0-rc.1.{pollable};
+
+  variant stream-error {
+    last-operation-failed(io-error),
+    closed,
+  }
+
+  resource input-stream {
+    /** Reads up to len bytes. */
+    read: func(len: u64) -> result<list<u8>, stream-error>;
+    subscribe: func() -> pollable;
+    constructor(fd: u32);
+    open: static func(path: string) -> result<input-stream>;
+  }
+
+  @unstable(feature = fixed)
+  type block = list<u8, 16>;
+  type pair = tuple<float32, %list>;
+  flags mode { read, write, }
+  enum whence { start, end }
+  skip: async func(s: borrow<input-stream>, n: u64) -> result<_, stream-error>;
+}
+
+world imports {
+  import streams;
+  import wasi:clocks/wall-clock@0.2.0;
+  import log: func(msg: string);
+  export run: interface {
+    go: func() -> stream<u8>;
+  }
+  include wasi:cli/command@0.2.0 with { environment as env };
+}
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if got, want := doc.Package.String(), "wasi:io@0.2.0"; got != want {
+		t.Errorf("package = %s, want %s", got, want)
+	}
+	if got, want := doc.Uses[0].Path.String(), "wasi:clocks/monotonic-clock@0.2.0"; got != want || doc.Uses[0].As != "clock" {
+		t.Errorf("use = %s as %s, want %s as clock", got, doc.Uses[0].As, want)
+	}
+
+	iface := doc.Interfaces[0]
+	if iface.Docs != "An input stream." || iface.Stability.Since != "0.2.0" {
+		t.Errorf("docs = %q, since = %q", iface.Docs, iface.Stability.Since)
+	}
+	use := iface.Items[0].(*UseDecl)
+	if !reflect.DeepEqual(use.Names, []UseName{{Name: "error", As: "io-error"}}) {
+		t.Errorf("use names = %+v", use.Names)
+	}
+	if got := iface.Items[1].(*UseDecl).Path.String(); got != "wasi:poll/poll@0.2.0-rc.1" {
+		t.Errorf("use path = %s", got)
+	}
+
+	variant := iface.Items[2].(*TypeDecl).Def.(*VariantDef)
+	if !reflect.DeepEqual(variant.Cases[0].Type, &NamedTy{Name: "io-error"}) || variant.Cases[1].Type != nil {
+		t.Errorf("variant cases = %+v, %+v", variant.Cases[0], variant.Cases[1])
+	}
+
+	resource := iface.Items[3].(*TypeDecl).Def.(*ResourceDef)
+	var kinds []ResourceFuncKind
+	for _, f := range resource.Funcs {
+		kinds = append(kinds, f.Kind)
+	}
+	if !reflect.DeepEqual(kinds, []ResourceFuncKind{ResourceMethod, ResourceMethod, ResourceConstructor, ResourceStatic}) {
+		t.Errorf("resource func kinds = %v", kinds)
+	}
+	if resource.Funcs[0].Func.Docs != "Reads up to len bytes." {
+		t.Errorf("read docs = %q", resource.Funcs[0].Func.Docs)
+	}
+	wantRead := &ResultTy{Ok: &ListTy{Element: &PrimitiveTy{Name: "u8"}}, Err: &NamedTy{Name: "stream-error"}}
+	if !reflect.DeepEqual(resource.Funcs[0].Func.Result, wantRead) {
+		t.Errorf("read result = %+v", resource.Funcs[0].Func.Result)
+	}
+	if got := resource.Funcs[3].Func.Result.(*ResultTy); got.Err != nil || got.Ok == nil {
+		t.Errorf("open result = %+v", got)
+	}
+
+	block := iface.Items[4].(*TypeDecl)
+	if !block.Stability.Unstable || block.Stability.Feature != "fixed" {
+		t.Errorf("block stability = %+v", block.Stability)
+	}
+	if got := block.Def.(*AliasDef).Type.(*ListTy).Length; got != 16 {
+		t.Errorf("block length = %d", got)
+	}
+	wantPair := &TupleTy{Types: []Ty{&PrimitiveTy{Name: "f32"}, &NamedTy{Name: "list"}}}
+	if got := iface.Items[5].(*TypeDecl).Def.(*AliasDef).Type; !reflect.DeepEqual(got, wantPair) {
+		t.Errorf("pair = %+v", got)
+	}
+	if got := iface.Items[6].(*TypeDecl).Def.(*FlagsDef).Flags; !reflect.DeepEqual(got, []string{"read", "write"}) {
+		t.Errorf("flags = %v", got)
+	}
+	skip := iface.Items[8].(*FuncDecl)
+	if !skip.Async || !reflect.DeepEqual(skip.Params[0].Type, &HandleTy{Resource: "input-stream", Borrow: true}) {
+		t.Errorf("skip = %+v", skip)
+	}
+	if got := skip.Result.(*ResultTy); got.Ok != nil || got.Err == nil {
+		t.Errorf("skip result = %+v", got)
+	}
+
+	world := doc.Worlds[0]
+	if got := world.Items[0].(*ExternDecl).Path.String(); got != "streams" {
+		t.Errorf("import = %s", got)
+	}
+	if got := world.Items[1].(*ExternDecl).Path.String(); got != "wasi:clocks/wall-clock@0.2.0" {
+		t.Errorf("import = %s", got)
+	}
+	if log := world.Items[2].(*ExternDecl); log.Name != "log" || log.Func == nil || log.Export {
+		t.Errorf("log = %+v", log)
+	}
+	if run := world.Items[3].(*ExternDecl); run.Name != "run" || run.Interface == nil || !run.Export {
+		t.Errorf("run = %+v", run)
+	}
+	include := world.Items[4].(*IncludeDecl)
+	if include.Path.String() != "wasi:cli/command@0.2.0" || !reflect.DeepEqual(include.With, []UseName{{Name: "environment", As: "env"}}) {
+		t.Errorf("include = %+v", include)
+	}
+}
+
+func TestParseNestedPackages(t *testing.T) {
+	doc, err := Parse("deps.wit", []byte(`
+package a:b {
+  interface x {}
+}
+package c:d@1.0.0 {
+  world w {}
+}
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if doc.Package != nil || len(doc.Packages) != 2 {
+		t.Fatalf("package = %v, nested = %d", doc.Package, len(doc.Packages))
+	}
+	if doc.Packages[1].Name.String() != "c:d@1.0.0" || doc.Packages[1].Worlds[0].Name != "w" {
+		t.Errorf("nested = %+v", doc.Packages[1])
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"package a:b;\ninterface type {}", "test.wit:2:11: expected an identifier, found `type`"},
+		{"package a:b;\ninterface x { f: func(; }", "test.wit:2:23: expected an identifier, found `;`"},
+		{"package a:b@1;", "expected a semantic version"},
+		{"package a:b;\ninterface x { type y = list<u8, 0>; }", "invalid list length 0"},
+		{"package a:b;\n@since(feature = x)\ninterface x {}", "@since requires a version"},
+		{"package a:b;\n/* open", "test.wit:2:1: unterminated block comment"},
+		{"", "test.wit: missing package declaration"},
+		{"// just a comment\n", "test.wit: missing package declaration"},
+		{"package a:b;\ninterface x { f: func() -> $; }", "unexpected character '$'"},
+	}
+	for _, tt := range tests {
+		_, err := Parse("test.wit", []byte(tt.src))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
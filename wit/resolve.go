@@ -0,0 +1,960 @@
+package wit
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/partite-ai/wacogo/ast"
+	"github.com/partite-ai/wacogo/componentmodel"
+)
+
+// Package is a resolved WIT package.
+type Package struct {
+	Name       PackageName
+	Interfaces []*Interface
+	Worlds     []*World
+}
+
+// Interface returns the interface of the package with the given name, or nil.
+func (p *Package) Interface(name string) *Interface {
+	for _, iface := range p.Interfaces {
+		if iface.Name == name {
+			return iface
+		}
+	}
+	return nil
+}
+
+// World returns the world of the package with the given name, or nil.
+func (p *Package) World(name string) *World {
+	for _, world := range p.Worlds {
+		if world.Name == name {
+			return world
+		}
+	}
+	return nil
+}
+
+// Interface is a resolved WIT interface.
+type Interface struct {
+	Name string
+	// Package is nil for an interface declared inline in a world.
+	Package *Package
+	Docs    string
+	// Exports are the types, including those used from other interfaces, and
+	// functions of the interface in declaration order. The functions of a
+	// resource follow it, named like [method]resource.name.
+	Exports []componentmodel.Extern
+	// Type is the type of instances implementing the interface.
+	Type *componentmodel.InstanceType
+}
+
+// QualifiedName returns the name the interface is imported or exported by,
+// like wasi:io/streams@0.2.0.
+func (i *Interface) QualifiedName() string {
+	if i.Package == nil {
+		return i.Name
+	}
+	return i.Package.Name.interfaceName(i.Name)
+}
+
+// World is a resolved WIT world.
+type World struct {
+	Name    string
+	Package *Package
+	Docs    string
+	// Imports include the interfaces used by the imported and exported
+	// interfaces, ahead of the interfaces using them.
+	Imports []componentmodel.Extern
+	Exports []componentmodel.Extern
+}
+
+// Resolver resolves parsed WIT documents into component model types.
+type Resolver struct {
+	features map[string]bool
+	docs     []*Document
+}
+
+// NewResolver creates a resolver with no documents.
+func NewResolver() *Resolver {
+	return &Resolver{
+		features: make(map[string]bool),
+	}
+}
+
+// WithFeatures enables the items gated by @unstable on the given features,
+// which are left out otherwise.
+func (r *Resolver) WithFeatures(features ...string) *Resolver {
+	for _, f := range features {
+		r.features[f] = true
+	}
+	return r
+}
+
+// Add adds documents to be resolved. The documents of a package may be split
+// across several files.
+func (r *Resolver) Add(docs ...*Document) *Resolver {
+	r.docs = append(r.docs, docs...)
+	return r
+}
+
+// Resolve resolves the documents added so far, returning their packages in
+// the order they were first declared.
+func (r *Resolver) Resolve() ([]*Package, error) {
+	rs := &resolveState{features: r.features}
+	for _, doc := range r.docs {
+		if err := rs.addDocument(doc); err != nil {
+			return nil, err
+		}
+	}
+	for _, ps := range rs.packages {
+		for _, is := range ps.interfaces {
+			if _, err := rs.resolveInterface(is); err != nil {
+				return nil, err
+			}
+		}
+		for _, ws := range ps.worlds {
+			if _, err := rs.resolveWorld(ws); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	packages := make([]*Package, len(rs.packages))
+	for i, ps := range rs.packages {
+		packages[i] = ps.pkg
+	}
+	return packages, nil
+}
+
+type resolveState struct {
+	features map[string]bool
+	packages []*packageState
+}
+
+type packageState struct {
+	pkg        *Package
+	interfaces []*interfaceState
+	worlds     []*worldState
+}
+
+// fileState is what the names in a file are resolved against.
+type fileState struct {
+	name string
+	pkg  *packageState
+	uses map[string]UsePath
+}
+
+type interfaceState struct {
+	decl      *InterfaceDecl
+	file      *fileState
+	iface     *Interface
+	resolving bool
+	// types are the types of the interface by name, for use elsewhere.
+	types map[string]componentmodel.Type
+	// deps are the interfaces the interface uses types from.
+	deps []*interfaceState
+}
+
+type worldState struct {
+	decl      *WorldDecl
+	file      *fileState
+	world     *World
+	resolving bool
+	resolved  bool
+}
+
+func (rs *resolveState) enabled(s Stability) bool {
+	return !s.Unstable || rs.features[s.Feature]
+}
+
+func (rs *resolveState) packageNamed(name PackageName) *packageState {
+	for _, ps := range rs.packages {
+		if ps.pkg.Name == name {
+			return ps
+		}
+	}
+	ps := &packageState{pkg: &Package{Name: name}}
+	rs.packages = append(rs.packages, ps)
+	return ps
+}
+
+func (rs *resolveState) addDocument(doc *Document) error {
+	if doc.Package == nil && (len(doc.Interfaces) > 0 || len(doc.Worlds) > 0) {
+		return fmt.Errorf("%s: missing package declaration", doc.Filename)
+	}
+	uses := make(map[string]UsePath)
+	for _, use := range doc.Uses {
+		if _, ok := uses[use.As]; ok {
+			return fmt.Errorf("%s: duplicate use of %s", doc.Filename, use.As)
+		}
+		uses[use.As] = use.Path
+	}
+	if doc.Package != nil {
+		file := &fileState{name: doc.Filename, pkg: rs.packageNamed(*doc.Package), uses: uses}
+		if err := rs.addDecls(file, doc.Interfaces, doc.Worlds); err != nil {
+			return err
+		}
+	}
+	for _, nested := range doc.Packages {
+		file := &fileState{name: doc.Filename, pkg: rs.packageNamed(nested.Name)}
+		if err := rs.addDecls(file, nested.Interfaces, nested.Worlds); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rs *resolveState) addDecls(file *fileState, interfaces []*InterfaceDecl, worlds []*WorldDecl) error {
+	ps := file.pkg
+	for _, decl := range interfaces {
+		if !rs.enabled(decl.Stability) {
+			continue
+		}
+		if ps.pkg.Interface(decl.Name) != nil {
+			return fmt.Errorf("%s: duplicate interface %s in package %s", file.name, decl.Name, ps.pkg.Name)
+		}
+		iface := &Interface{Name: decl.Name, Package: ps.pkg, Docs: decl.Docs}
+		ps.pkg.Interfaces = append(ps.pkg.Interfaces, iface)
+		ps.interfaces = append(ps.interfaces, &interfaceState{decl: decl, file: file, iface: iface})
+	}
+	for _, decl := range worlds {
+		if !rs.enabled(decl.Stability) {
+			continue
+		}
+		if ps.pkg.World(decl.Name) != nil {
+			return fmt.Errorf("%s: duplicate world %s in package %s", file.name, decl.Name, ps.pkg.Name)
+		}
+		world := &World{Name: decl.Name, Package: ps.pkg, Docs: decl.Docs}
+		ps.pkg.Worlds = append(ps.pkg.Worlds, world)
+		ps.worlds = append(ps.worlds, &worldState{decl: decl, file: file, world: world})
+	}
+	return nil
+}
+
+// lookupPackage finds the package a path refers to. A path without a version
+// refers to the only version of the package.
+func (rs *resolveState) lookupPackage(file *fileState, path UsePath) (*packageState, error) {
+	if path.Package == nil {
+		return file.pkg, nil
+	}
+	var found *packageState
+	for _, ps := range rs.packages {
+		name := ps.pkg.Name
+		if name.Namespace != path.Package.Namespace || name.Name != path.Package.Name {
+			continue
+		}
+		if name.Version == path.Package.Version {
+			return ps, nil
+		}
+		if path.Package.Version == "" {
+			if found != nil {
+				return nil, fmt.Errorf("%s: package %s is ambiguous between versions %s and %s", file.name, path.Package, found.pkg.Name.Version, name.Version)
+			}
+			found = ps
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%s: package %s not found", file.name, path.Package)
+	}
+	return found, nil
+}
+
+// expandPath replaces a name given to an interface or world by a top-level
+// use with the path it stands for.
+func (file *fileState) expandPath(path UsePath) UsePath {
+	if path.Package == nil {
+		if used, ok := file.uses[path.Name]; ok {
+			return used
+		}
+	}
+	return path
+}
+
+func (rs *resolveState) lookupInterface(file *fileState, path UsePath) (*interfaceState, error) {
+	path = file.expandPath(path)
+	ps, err := rs.lookupPackage(file, path)
+	if err != nil {
+		return nil, err
+	}
+	for _, is := range ps.interfaces {
+		if is.decl.Name == path.Name {
+			return is, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: interface %s not found", file.name, path)
+}
+
+func (rs *resolveState) lookupWorld(file *fileState, path UsePath) (*worldState, error) {
+	path = file.expandPath(path)
+	ps, err := rs.lookupPackage(file, path)
+	if err != nil {
+		return nil, err
+	}
+	for _, ws := range ps.worlds {
+		if ws.decl.Name == path.Name {
+			return ws, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: world %s not found", file.name, path)
+}
+
+func (rs *resolveState) resolveInterface(is *interfaceState) (*Interface, error) {
+	if is.types != nil {
+		return is.iface, nil
+	}
+	if is.resolving {
+		return nil, fmt.Errorf("%s: interface %s uses itself", is.file.name, is.iface.QualifiedName())
+	}
+	is.resolving = true
+	defer func() { is.resolving = false }()
+
+	scope := newTypeScope(rs, is.file)
+	var exports []componentmodel.Extern
+	err := scope.addItems(is.decl.Items, func(e componentmodel.Extern) error {
+		exports = append(exports, e)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %w", is.iface.QualifiedName(), err)
+	}
+	typ, err := componentmodel.NewInstanceType(exports)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %w", is.iface.QualifiedName(), err)
+	}
+	is.iface.Exports = exports
+	is.iface.Type = typ
+	is.types = scope.types
+	is.deps = scope.deps
+	return is.iface, nil
+}
+
+// typeScope resolves the type names of an interface or world.
+type typeScope struct {
+	rs        *resolveState
+	file      *fileState
+	decls     map[string]*TypeDecl
+	types     map[string]componentmodel.Type
+	resolving map[string]bool
+	deps      []*interfaceState
+}
+
+func newTypeScope(rs *resolveState, file *fileState) *typeScope {
+	return &typeScope{
+		rs:        rs,
+		file:      file,
+		decls:     make(map[string]*TypeDecl),
+		types:     make(map[string]componentmodel.Type),
+		resolving: make(map[string]bool),
+	}
+}
+
+func (s *typeScope) declare(name string) error {
+	if _, ok := s.decls[name]; ok {
+		return fmt.Errorf("duplicate type %s", name)
+	}
+	if _, ok := s.types[name]; ok {
+		return fmt.Errorf("duplicate type %s", name)
+	}
+	return nil
+}
+
+// use resolves a use declaration, adding the types it names to the scope.
+func (s *typeScope) use(decl *UseDecl) (*interfaceState, error) {
+	is, err := s.rs.lookupInterface(s.file, decl.Path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.rs.resolveInterface(is); err != nil {
+		return nil, err
+	}
+	for _, n := range decl.Names {
+		typ, ok := is.types[n.Name]
+		if !ok {
+			return nil, fmt.Errorf("type %s not found in interface %s", n.Name, is.iface.QualifiedName())
+		}
+		if err := s.declare(n.localName()); err != nil {
+			return nil, err
+		}
+		s.types[n.localName()] = typ
+	}
+	if !slices.Contains(s.deps, is) {
+		s.deps = append(s.deps, is)
+	}
+	return is, nil
+}
+
+// addItems resolves the items of an interface, passing the types and
+// functions to add in declaration order.
+func (s *typeScope) addItems(items []InterfaceItem, add func(componentmodel.Extern) error) error {
+	// Names can be used before they are declared, so all are known before
+	// any is resolved.
+	for _, item := range items {
+		switch item := item.(type) {
+		case *UseDecl:
+			if !s.rs.enabled(item.Stability) {
+				continue
+			}
+			if _, err := s.use(item); err != nil {
+				return err
+			}
+		case *TypeDecl:
+			if !s.rs.enabled(item.Stability) {
+				continue
+			}
+			if err := s.declare(item.Name); err != nil {
+				return err
+			}
+			s.decls[item.Name] = item
+		}
+	}
+
+	for _, item := range items {
+		switch item := item.(type) {
+		case *UseDecl:
+			if !s.rs.enabled(item.Stability) {
+				continue
+			}
+			for _, n := range item.Names {
+				err := add(componentmodel.Extern{Name: n.localName(), Sort: ast.SortType, Type: s.types[n.localName()]})
+				if err != nil {
+					return err
+				}
+			}
+		case *TypeDecl:
+			if !s.rs.enabled(item.Stability) {
+				continue
+			}
+			if err := s.addTypeDecl(item, add); err != nil {
+				return err
+			}
+		case *FuncDecl:
+			if !s.rs.enabled(item.Stability) {
+				continue
+			}
+			ft, err := s.funcType(item, nil)
+			if err != nil {
+				return err
+			}
+			if err := add(componentmodel.Extern{Name: item.Name, Sort: ast.SortFunc, Type: ft}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addTypeDecl passes the type declared and, for a resource, its functions.
+func (s *typeScope) addTypeDecl(decl *TypeDecl, add func(componentmodel.Extern) error) error {
+	typ, err := s.lookup(decl.Name)
+	if err != nil {
+		return err
+	}
+	if err := add(componentmodel.Extern{Name: decl.Name, Sort: ast.SortType, Type: typ}); err != nil {
+		return err
+	}
+	def, ok := decl.Def.(*ResourceDef)
+	if !ok {
+		return nil
+	}
+	resource := typ.(*componentmodel.ResourceType)
+	for _, rf := range def.Funcs {
+		if !s.rs.enabled(rf.Func.Stability) {
+			continue
+		}
+		var name string
+		var self *componentmodel.FunctionParameter
+		switch rf.Kind {
+		case ResourceConstructor:
+			name = "[constructor]" + decl.Name
+		case ResourceMethod:
+			name = "[method]" + decl.Name + "." + rf.Func.Name
+			self = &componentmodel.FunctionParameter{Name: "self", Type: componentmodel.BorrowType{ResourceType: resource}}
+		case ResourceStatic:
+			name = "[static]" + decl.Name + "." + rf.Func.Name
+		}
+		ft, err := s.funcType(rf.Func, self)
+		if err != nil {
+			return err
+		}
+		if rf.Kind == ResourceConstructor && ft.ResultType == nil {
+			ft.ResultType = componentmodel.OwnType{ResourceType: resource}
+		}
+		if err := add(componentmodel.Extern{Name: name, Sort: ast.SortFunc, Type: ft}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookup returns the type with the given name, resolving its declaration if
+// it has not been yet.
+func (s *typeScope) lookup(name string) (componentmodel.Type, error) {
+	if typ, ok := s.types[name]; ok {
+		return typ, nil
+	}
+	decl, ok := s.decls[name]
+	if !ok {
+		return nil, fmt.Errorf("type %s not found", name)
+	}
+	if s.resolving[name] {
+		return nil, fmt.Errorf("type %s depends on itself", name)
+	}
+	s.resolving[name] = true
+	defer delete(s.resolving, name)
+
+	typ, err := s.typeDef(decl.Def)
+	if err != nil {
+		return nil, fmt.Errorf("type %s: %w", name, err)
+	}
+	s.types[name] = typ
+	return typ, nil
+}
+
+func (s *typeScope) typeDef(def TypeDef) (componentmodel.Type, error) {
+	switch def := def.(type) {
+	case *AliasDef:
+		// An alias of a resource names the resource rather than a handle.
+		if named, ok := def.Type.(*NamedTy); ok {
+			return s.lookup(named.Name)
+		}
+		return s.valueType(def.Type)
+	case *RecordDef:
+		if len(def.Fields) == 0 {
+			return nil, fmt.Errorf("record has no fields")
+		}
+		names := make([]string, len(def.Fields))
+		for i, f := range def.Fields {
+			names[i] = f.Name
+		}
+		if name, ok := duplicateName(names); ok {
+			return nil, fmt.Errorf("duplicate field %s", name)
+		}
+		fields := make([]*componentmodel.RecordField, len(def.Fields))
+		for i, f := range def.Fields {
+			typ, err := s.valueType(f.Type)
+			if err != nil {
+				return nil, err
+			}
+			fields[i] = &componentmodel.RecordField{Name: f.Name, Type: typ}
+		}
+		return &componentmodel.RecordType{Fields: fields}, nil
+	case *VariantDef:
+		if len(def.Cases) == 0 {
+			return nil, fmt.Errorf("variant has no cases")
+		}
+		names := make([]string, len(def.Cases))
+		for i, c := range def.Cases {
+			names[i] = c.Name
+		}
+		if name, ok := duplicateName(names); ok {
+			return nil, fmt.Errorf("duplicate case %s", name)
+		}
+		cases := make([]*componentmodel.VariantCase, len(def.Cases))
+		for i, c := range def.Cases {
+			typ, err := s.optionalValueType(c.Type)
+			if err != nil {
+				return nil, err
+			}
+			cases[i] = &componentmodel.VariantCase{Name: c.Name, Type: typ}
+		}
+		return &componentmodel.VariantType{Cases: cases}, nil
+	case *EnumDef:
+		if len(def.Cases) == 0 {
+			return nil, fmt.Errorf("enum has no cases")
+		}
+		if name, ok := duplicateName(def.Cases); ok {
+			return nil, fmt.Errorf("duplicate case %s", name)
+		}
+		return componentmodel.NewEnumType(def.Cases...), nil
+	case *FlagsDef:
+		if len(def.Flags) == 0 {
+			return nil, fmt.Errorf("flags has no flags")
+		}
+		if name, ok := duplicateName(def.Flags); ok {
+			return nil, fmt.Errorf("duplicate flag %s", name)
+		}
+		return &componentmodel.FlagsType{FlagNames: def.Flags}, nil
+	case *ResourceDef:
+		return componentmodel.NewResourceTypeBound(), nil
+	default:
+		return nil, fmt.Errorf("unsupported type definition: %T", def)
+	}
+}
+
+func (s *typeScope) optionalValueType(ty Ty) (componentmodel.ValueType, error) {
+	if ty == nil {
+		return nil, nil
+	}
+	return s.valueType(ty)
+}
+
+func (s *typeScope) resource(name string) (*componentmodel.ResourceType, error) {
+	typ, err := s.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	resource, ok := typ.(*componentmodel.ResourceType)
+	if !ok {
+		return nil, fmt.Errorf("type %s is not a resource", name)
+	}
+	return resource, nil
+}
+
+var primitiveTypes = map[string]componentmodel.ValueType{
+	"bool":          componentmodel.BoolType{},
+	"u8":            componentmodel.U8Type{},
+	"u16":           componentmodel.U16Type{},
+	"u32":           componentmodel.U32Type{},
+	"u64":           componentmodel.U64Type{},
+	"s8":            componentmodel.S8Type{},
+	"s16":           componentmodel.S16Type{},
+	"s32":           componentmodel.S32Type{},
+	"s64":           componentmodel.S64Type{},
+	"f32":           componentmodel.F32Type{},
+	"f64":           componentmodel.F64Type{},
+	"char":          componentmodel.CharType{},
+	"string":        componentmodel.StringType{},
+	"error-context": componentmodel.ErrorContextType{},
+}
+
+func (s *typeScope) valueType(ty Ty) (componentmodel.ValueType, error) {
+	switch ty := ty.(type) {
+	case *PrimitiveTy:
+		typ, ok := primitiveTypes[ty.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown type %s", ty.Name)
+		}
+		return typ, nil
+	case *NamedTy:
+		typ, err := s.lookup(ty.Name)
+		if err != nil {
+			return nil, err
+		}
+		switch typ := typ.(type) {
+		case *componentmodel.ResourceType:
+			return componentmodel.OwnType{ResourceType: typ}, nil
+		case componentmodel.ValueType:
+			return typ, nil
+		default:
+			return nil, fmt.Errorf("type %s is not a value type", ty.Name)
+		}
+	case *ListTy:
+		elem, err := s.valueType(ty.Element)
+		if err != nil {
+			return nil, err
+		}
+		if ty.Length > 0 {
+			return &componentmodel.FixedListType{ElementType: elem, Length: ty.Length}, nil
+		}
+		// Like lists of bytes in binary components, list<u8> is a byte array.
+		if _, ok := elem.(componentmodel.U8Type); ok {
+			return componentmodel.ByteArrayType{}, nil
+		}
+		return &componentmodel.ListType{ElementType: elem}, nil
+	case *OptionTy:
+		elem, err := s.valueType(ty.Type)
+		if err != nil {
+			return nil, err
+		}
+		return componentmodel.NewOptionType(elem), nil
+	case *ResultTy:
+		ok, err := s.optionalValueType(ty.Ok)
+		if err != nil {
+			return nil, err
+		}
+		e, err := s.optionalValueType(ty.Err)
+		if err != nil {
+			return nil, err
+		}
+		return componentmodel.NewResultType(ok, e), nil
+	case *TupleTy:
+		if len(ty.Types) == 0 {
+			return nil, fmt.Errorf("tuple has no elements")
+		}
+		elems := make([]componentmodel.ValueType, len(ty.Types))
+		for i, t := range ty.Types {
+			elem, err := s.valueType(t)
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = elem
+		}
+		return componentmodel.NewTupleType(elems...), nil
+	case *HandleTy:
+		resource, err := s.resource(ty.Resource)
+		if err != nil {
+			return nil, err
+		}
+		if ty.Borrow {
+			return componentmodel.BorrowType{ResourceType: resource}, nil
+		}
+		return componentmodel.OwnType{ResourceType: resource}, nil
+	case *StreamTy:
+		elem, err := s.optionalValueType(ty.Element)
+		if err != nil {
+			return nil, err
+		}
+		return componentmodel.NewStreamType(elem), nil
+	case *FutureTy:
+		elem, err := s.optionalValueType(ty.Type)
+		if err != nil {
+			return nil, err
+		}
+		return componentmodel.NewFutureType(elem), nil
+	default:
+		return nil, fmt.Errorf("unsupported type: %T", ty)
+	}
+}
+
+// funcType resolves the type of a function, with self as its first parameter
+// for a resource method. Whether the function is async is not part of its
+// type.
+func (s *typeScope) funcType(fn *FuncDecl, self *componentmodel.FunctionParameter) (*componentmodel.FunctionType, error) {
+	ft := &componentmodel.FunctionType{}
+	var names []string
+	if self != nil {
+		ft.Parameters = append(ft.Parameters, self)
+		names = append(names, self.Name)
+	}
+	for _, p := range fn.Params {
+		names = append(names, p.Name)
+	}
+	if name, ok := duplicateName(names); ok {
+		return nil, fmt.Errorf("function %s: duplicate parameter %s", fn.Name, name)
+	}
+	for _, p := range fn.Params {
+		typ, err := s.valueType(p.Type)
+		if err != nil {
+			return nil, fmt.Errorf("function %s: %w", fn.Name, err)
+		}
+		ft.Parameters = append(ft.Parameters, &componentmodel.FunctionParameter{Name: p.Name, Type: typ})
+	}
+	result, err := s.optionalValueType(fn.Result)
+	if err != nil {
+		return nil, fmt.Errorf("function %s: %w", fn.Name, err)
+	}
+	ft.ResultType = result
+	return ft, nil
+}
+
+// duplicateName returns the first name that appears more than once in names.
+func duplicateName(names []string) (string, bool) {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			return name, true
+		}
+		seen[name] = true
+	}
+	return "", false
+}
+
+// worldExterns collects the imports or exports of a world, rejecting
+// duplicate names.
+type worldExterns struct {
+	externs []componentmodel.Extern
+	names   map[string]componentmodel.Type
+}
+
+func (we *worldExterns) add(e componentmodel.Extern) error {
+	if we.names == nil {
+		we.names = make(map[string]componentmodel.Type)
+	}
+	if typ, ok := we.names[e.Name]; ok {
+		// The same interface can be reached more than once, through
+		// includes or as a dependency of several others.
+		if e.Sort == ast.SortInstance && typ == e.Type {
+			return nil
+		}
+		return fmt.Errorf("duplicate name %s", e.Name)
+	}
+	we.names[e.Name] = e.Type
+	we.externs = append(we.externs, e)
+	return nil
+}
+
+func (we *worldExterns) has(name string) bool {
+	_, ok := we.names[name]
+	return ok
+}
+
+func (rs *resolveState) resolveWorld(ws *worldState) (*World, error) {
+	if ws.resolved {
+		return ws.world, nil
+	}
+	if ws.resolving {
+		return nil, fmt.Errorf("%s: world %s includes itself", ws.file.name, ws.decl.Name)
+	}
+	ws.resolving = true
+	defer func() { ws.resolving = false }()
+
+	if err := rs.resolveWorldItems(ws); err != nil {
+		return nil, fmt.Errorf("world %s: %w", ws.world.Package.Name.interfaceName(ws.decl.Name), err)
+	}
+	ws.resolved = true
+	return ws.world, nil
+}
+
+func (rs *resolveState) resolveWorldItems(ws *worldState) error {
+	var imports, exports worldExterns
+	scope := newTypeScope(rs, ws.file)
+
+	// importInterface imports an interface after the interfaces it uses.
+	var importInterface func(is *interfaceState) error
+	importInterface = func(is *interfaceState) error {
+		name := is.iface.QualifiedName()
+		if imports.has(name) {
+			return nil
+		}
+		for _, dep := range is.deps {
+			if err := importInterface(dep); err != nil {
+				return err
+			}
+		}
+		return imports.add(componentmodel.Extern{Name: name, Sort: ast.SortInstance, Type: is.iface.Type})
+	}
+	// importDeps imports the interfaces used by an exported interface, unless
+	// they are exported too.
+	var exportDeps []*interfaceState
+	importDeps := func() error {
+		for _, is := range exportDeps {
+			for _, dep := range is.deps {
+				if !exports.has(dep.iface.QualifiedName()) {
+					if err := importInterface(dep); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	}
+
+	var items []InterfaceItem
+	for _, item := range ws.decl.Items {
+		switch item := item.(type) {
+		case *UseDecl:
+			if !rs.enabled(item.Stability) {
+				continue
+			}
+			is, err := scope.use(item)
+			if err != nil {
+				return err
+			}
+			if err := importInterface(is); err != nil {
+				return err
+			}
+		case *TypeDecl:
+			items = append(items, item)
+		}
+	}
+	// The types of the world are imported, along with the functions of its
+	// resources.
+	if err := scope.addItems(items, imports.add); err != nil {
+		return err
+	}
+
+	for _, item := range ws.decl.Items {
+		switch item := item.(type) {
+		case *ExternDecl:
+			if !rs.enabled(item.Stability) {
+				continue
+			}
+			externs := &imports
+			if item.Export {
+				externs = &exports
+			}
+			switch {
+			case item.Func != nil:
+				ft, err := scope.funcType(item.Func, nil)
+				if err != nil {
+					return err
+				}
+				err = externs.add(componentmodel.Extern{Name: item.Name, Sort: ast.SortFunc, Type: ft})
+				if err != nil {
+					return err
+				}
+			case item.Interface != nil:
+				is := &interfaceState{
+					decl:  item.Interface,
+					file:  ws.file,
+					iface: &Interface{Name: item.Name, Docs: item.Docs},
+				}
+				if err := rs.inlineInterface(is, externs, item.Export, importInterface, &exportDeps); err != nil {
+					return err
+				}
+			default:
+				is, err := rs.lookupInterface(ws.file, *item.Path)
+				if err != nil {
+					return err
+				}
+				if err := rs.inlineInterface(is, externs, item.Export, importInterface, &exportDeps); err != nil {
+					return err
+				}
+			}
+		case *IncludeDecl:
+			if !rs.enabled(item.Stability) {
+				continue
+			}
+			if err := rs.include(ws, item, &imports, &exports); err != nil {
+				return err
+			}
+		}
+	}
+	if err := importDeps(); err != nil {
+		return err
+	}
+
+	ws.world.Imports = imports.externs
+	ws.world.Exports = exports.externs
+	return nil
+}
+
+// inlineInterface resolves an interface imported or exported by a world and
+// adds it, after importing the interfaces it uses.
+func (rs *resolveState) inlineInterface(is *interfaceState, externs *worldExterns, export bool, importInterface func(*interfaceState) error, exportDeps *[]*interfaceState) error {
+	iface, err := rs.resolveInterface(is)
+	if err != nil {
+		return err
+	}
+	if export {
+		*exportDeps = append(*exportDeps, is)
+		return externs.add(componentmodel.Extern{Name: iface.QualifiedName(), Sort: ast.SortInstance, Type: iface.Type})
+	}
+	return importInterface(is)
+}
+
+// include adds the imports and exports of another world, renaming those named
+// in the with clause.
+func (rs *resolveState) include(ws *worldState, decl *IncludeDecl, imports, exports *worldExterns) error {
+	included, err := rs.lookupWorld(ws.file, decl.Path)
+	if err != nil {
+		return err
+	}
+	world, err := rs.resolveWorld(included)
+	if err != nil {
+		return err
+	}
+	renames := make(map[string]string, len(decl.With))
+	for _, n := range decl.With {
+		renames[n.Name] = n.As
+	}
+	for _, list := range []struct {
+		from []componentmodel.Extern
+		to   *worldExterns
+	}{{world.Imports, imports}, {world.Exports, exports}} {
+		for _, e := range list.from {
+			if as, ok := renames[e.Name]; ok {
+				delete(renames, e.Name)
+				e.Name = as
+			}
+			if err := list.to.add(e); err != nil {
+				return err
+			}
+		}
+	}
+	for name := range renames {
+		return fmt.Errorf("include of %s renames %s, which it does not have", decl.Path, name)
+	}
+	return nil
+}
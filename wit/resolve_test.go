@@ -0,0 +1,230 @@
+package wit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/partite-ai/wacogo/componentmodel"
+)
+
+func resolve(t *testing.T, features []string, files ...string) []*Package {
+	t.Helper()
+	r := NewResolver().WithFeatures(features...)
+	for i, src := range files {
+		doc, err := Parse("test.wit", []byte(src))
+		if err != nil {
+			t.Fatalf("Parse of file %d failed: %v", i, err)
+		}
+		r.Add(doc)
+	}
+	packages, err := r.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	return packages
+}
+
+func externNames(externs []componentmodel.Extern) []string {
+	names := make([]string, len(externs))
+	for i, e := range externs {
+		names[i] = e.Name + ":" + e.Sort.String()
+	}
+	return names
+}
+
+const ioWIT = `
+package test:io@0.1.0;
+
+interface error {
+  resource error;
+}
+
+interface streams {
+  use error.{error};
+
+  variant stream-error {
+    failed(error),
+    closed,
+  }
+
+  resource input-stream {
+    constructor(fd: u32);
+    read: func(len: u64) -> result<list<u8>, stream-error>;
+    open: static func(path: string) -> input-stream;
+    @unstable(feature = peek)
+    peek: func() -> option<u8>;
+  }
+
+  type bytes = list<u8>;
+}
+`
+
+func TestResolveInterface(t *testing.T) {
+	packages := resolve(t, nil, ioWIT)
+	if len(packages) != 1 || packages[0].Name.String() != "test:io@0.1.0" {
+		t.Fatalf("packages = %v", packages)
+	}
+	streams := packages[0].Interface("streams")
+	if got := streams.QualifiedName(); got != "test:io/streams@0.1.0" {
+		t.Errorf("QualifiedName() = %s", got)
+	}
+
+	want := []string{
+		"error:type",
+		"stream-error:type",
+		"input-stream:type",
+		"[constructor]input-stream:func",
+		"[method]input-stream.read:func",
+		"[static]input-stream.open:func",
+		"bytes:type",
+	}
+	if got := externNames(streams.Exports); !reflect.DeepEqual(got, want) {
+		t.Errorf("exports = %v, want %v", got, want)
+	}
+	if got := externNames(streams.Type.Exports()); len(got) != len(want) {
+		t.Errorf("instance type exports = %v", got)
+	}
+
+	// The used resource is the one declared by the error interface.
+	errorType := packages[0].Interface("error").Exports[0].Type
+	if streams.Exports[0].Type != errorType {
+		t.Errorf("used error type differs from the declared one")
+	}
+	variant := streams.Exports[1].Type.(*componentmodel.VariantType)
+	if own, ok := variant.Cases[0].Type.(componentmodel.OwnType); !ok || own.ResourceType != errorType {
+		t.Errorf("failed case = %#v, want own<error>", variant.Cases[0].Type)
+	}
+
+	stream := streams.Exports[2].Type.(*componentmodel.ResourceType)
+	ctor := streams.Exports[3].Type.(*componentmodel.FunctionType)
+	if own, ok := ctor.ResultType.(componentmodel.OwnType); !ok || own.ResourceType != stream {
+		t.Errorf("constructor result = %#v", ctor.ResultType)
+	}
+	read := streams.Exports[4].Type.(*componentmodel.FunctionType)
+	if len(read.Parameters) != 2 || read.Parameters[0].Name != "self" {
+		t.Fatalf("read parameters = %v", read.Parameters)
+	}
+	if borrow, ok := read.Parameters[0].Type.(componentmodel.BorrowType); !ok || borrow.ResourceType != stream {
+		t.Errorf("self = %#v", read.Parameters[0].Type)
+	}
+	result := read.ResultType.(*componentmodel.ResultType)
+	if _, ok := result.OkType().(componentmodel.ByteArrayType); !ok {
+		t.Errorf("ok type = %#v", result.OkType())
+	}
+	if result.ErrType() != variant {
+		t.Errorf("err type differs from stream-error")
+	}
+	if got := streams.Exports[6].Type; got != (componentmodel.ByteArrayType{}) {
+		t.Errorf("bytes = %#v", got)
+	}
+}
+
+func TestResolveFeatures(t *testing.T) {
+	packages := resolve(t, []string{"peek"}, ioWIT)
+	names := externNames(packages[0].Interface("streams").Exports)
+	found := false
+	for _, name := range names {
+		found = found || name == "[method]input-stream.peek:func"
+	}
+	if !found {
+		t.Errorf("exports = %v, want peek with the feature enabled", names)
+	}
+}
+
+func TestResolveWorld(t *testing.T) {
+	packages := resolve(t, nil, ioWIT, `
+package test:app;
+
+use test:io/streams as s;
+
+world base {
+  import log: func(msg: string);
+  export handler: interface {
+    handle: func() -> u32;
+  }
+}
+
+world app {
+  use s.{input-stream};
+  record config {
+    input: input-stream,
+  }
+  export run: func(c: config);
+  export test:io/streams@0.1.0;
+  include base with { log as print };
+}
+`)
+	if len(packages) != 2 {
+		t.Fatalf("packages = %d, want 2", len(packages))
+	}
+	app := packages[1].World("app")
+	wantImports := []string{
+		"test:io/error@0.1.0:instance",
+		"test:io/streams@0.1.0:instance",
+		"config:type",
+		"print:func",
+	}
+	if got := externNames(app.Imports); !reflect.DeepEqual(got, wantImports) {
+		t.Errorf("imports = %v, want %v", got, wantImports)
+	}
+	wantExports := []string{
+		"run:func",
+		"test:io/streams@0.1.0:instance",
+		"handler:instance",
+	}
+	if got := externNames(app.Exports); !reflect.DeepEqual(got, wantExports) {
+		t.Errorf("exports = %v, want %v", got, wantExports)
+	}
+
+	streams := packages[0].Interface("streams")
+	if app.Imports[1].Type != streams.Type {
+		t.Errorf("streams import differs from the interface type")
+	}
+	config := app.Imports[2].Type.(*componentmodel.RecordType)
+	if own, ok := config.Fields[0].Type.(componentmodel.OwnType); !ok || own.ResourceType != streams.Exports[2].Type {
+		t.Errorf("config input = %#v", config.Fields[0].Type)
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		files []string
+		want  string
+	}{
+		{[]string{"package a:b;\ninterface x { f: func() -> y; }"}, "interface a:b/x: function f: type y not found"},
+		{[]string{"package a:b;\ninterface x { type y = list<y>; }"}, "type y depends on itself"},
+		{[]string{"package a:b;\ninterface x { use y.{t}; type u = u32; }\ninterface y { use x.{u}; type t = u32; }"}, "uses itself"},
+		{[]string{"package a:b;\ninterface x { use c:d/y.{t}; }"}, "package c:d not found"},
+		{[]string{"package a:b;\ninterface x { type y = u32; f: func(a: borrow<y>); }"}, "type y is not a resource"},
+		{[]string{"package a:b;\nworld w { include w; }"}, "world w includes itself"},
+		{[]string{"package a:b;\nworld w { import f: func(); import f: func(); }"}, "duplicate name f"},
+		{[]string{"package a:b;\ninterface x {}", "package a:b;\ninterface x {}"}, "duplicate interface x"},
+		{[]string{"package a:b@1.0.0;", "package a:b@2.0.0;", "package c:d;\ninterface x { use a:b/y.{t}; }"}, "ambiguous"},
+		{[]string{"package a:b;\ninterface x { f: func(a: u32, a: u32); }"}, "function f: duplicate parameter a"},
+		{[]string{"package a:b;\ninterface x { resource r { f: func(self: u32); } }"}, "duplicate parameter self"},
+		{[]string{"package a:b;\ninterface x { record r { a: u32, a: u32 } }"}, "type r: duplicate field a"},
+		{[]string{"package a:b;\ninterface x { variant v { a, a(u32) } }"}, "type v: duplicate case a"},
+		{[]string{"package a:b;\ninterface x { enum e { a, a } }"}, "type e: duplicate case a"},
+		{[]string{"package a:b;\ninterface x { flags f { a, a } }"}, "type f: duplicate flag a"},
+		{[]string{"package a:b;\ninterface x { record r {} }"}, "type r: record has no fields"},
+		{[]string{"package a:b;\ninterface x { variant v {} }"}, "type v: variant has no cases"},
+		{[]string{"package a:b;\ninterface x { enum e {} }"}, "type e: enum has no cases"},
+		{[]string{"package a:b;\ninterface x { flags f {} }"}, "type f: flags has no flags"},
+		{[]string{"package a:b;\ninterface x { f: func() -> tuple<>; }"}, "function f: tuple has no elements"},
+	}
+	for _, tt := range tests {
+		r := NewResolver()
+		for _, src := range tt.files {
+			doc, err := Parse("test.wit", []byte(src))
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", src, err)
+			}
+			r.Add(doc)
+		}
+		_, err := r.Resolve()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Resolve(%q) error = %v, want %q", tt.files, err, tt.want)
+		}
+	}
+}